@@ -0,0 +1,6 @@
+package compression
+
+import "errors"
+
+// ErrInvalidCompressionLevel signals that an invalid compression level has been provided
+var ErrInvalidCompressionLevel = errors.New("invalid compression level")
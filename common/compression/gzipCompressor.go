@@ -0,0 +1,62 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+)
+
+type gzipCompressor struct {
+	level int
+}
+
+// NewGzipCompressor creates a new gzip compressor using the provided compression level
+func NewGzipCompressor(level int) (*gzipCompressor, error) {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return nil, ErrInvalidCompressionLevel
+	}
+
+	return &gzipCompressor{
+		level: level,
+	}, nil
+}
+
+// Compress returns the gzip compressed form of the provided data
+func (gc *gzipCompressor) Compress(data []byte) ([]byte, error) {
+	buff := &bytes.Buffer{}
+	writer, err := gzip.NewWriterLevel(buff, gc.level)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = writer.Write(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buff.Bytes(), nil
+}
+
+// Decompress returns the original data from the provided gzip compressed data
+func (gc *gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	return ioutil.ReadAll(reader)
+}
+
+// IsInterfaceNil returns true if there is no value under the interface
+func (gc *gzipCompressor) IsInterfaceNil() bool {
+	return gc == nil
+}
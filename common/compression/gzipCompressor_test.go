@@ -0,0 +1,48 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/ElrondNetwork/elrond-go-core/core/check"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewGzipCompressor(t *testing.T) {
+	t.Parallel()
+
+	t.Run("invalid level should error", func(t *testing.T) {
+		t.Parallel()
+
+		compressor, err := NewGzipCompressor(gzip.BestCompression + 1)
+		assert.Equal(t, ErrInvalidCompressionLevel, err)
+		assert.True(t, check.IfNil(compressor))
+	})
+	t.Run("should work", func(t *testing.T) {
+		t.Parallel()
+
+		compressor, err := NewGzipCompressor(gzip.BestSpeed)
+		assert.Nil(t, err)
+		assert.False(t, check.IfNil(compressor))
+	})
+}
+
+func TestGzipCompressor_CompressDecompress(t *testing.T) {
+	t.Parallel()
+
+	compressor, _ := NewGzipCompressor(gzip.DefaultCompression)
+	data := bytes.Repeat([]byte("scheduled data"), 100)
+
+	compressed, err := compressor.Compress(data)
+	require.Nil(t, err)
+	assert.True(t, len(compressed) < len(data))
+
+	decompressed, err := compressor.Decompress(compressed)
+	require.Nil(t, err)
+	assert.Equal(t, data, decompressed)
+
+	_, err = compressor.Decompress(data)
+	assert.NotNil(t, err)
+}
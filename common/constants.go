@@ -662,6 +662,31 @@ const MetricP2PNumConnectedPeersClassification = "erd_p2p_num_connected_peers_cl
 // to process VM queries
 const MetricAreVMQueriesReady = "erd_are_vm_queries_ready"
 
+// MetricScheduledTxsExecutedPercent is the metric for monitoring the progress of the scheduled txs execution [%]
+const MetricScheduledTxsExecutedPercent = "erd_scheduled_txs_executed_percent"
+
+// MetricScheduledTxsRemainingTimeMs is the metric for monitoring the time left for the scheduled txs execution [ms]
+const MetricScheduledTxsRemainingTimeMs = "erd_scheduled_txs_remaining_time_ms"
+
+// MetricScheduledTxsExecuted is the metric for monitoring the number of scheduled txs executed in the last execution
+const MetricScheduledTxsExecuted = "erd_scheduled_txs_executed"
+
+// MetricScheduledTxsFailed is the metric for monitoring the number of scheduled txs failed in the last execution
+const MetricScheduledTxsFailed = "erd_scheduled_txs_failed"
+
+// MetricScheduledTxsExecutionTimeMs is the metric for monitoring the duration of the last scheduled txs execution [ms]
+const MetricScheduledTxsExecutionTimeMs = "erd_scheduled_txs_execution_time_ms"
+
+// MetricScheduledTxsDuplicates is the metric for monitoring the number of scheduled txs skipped as already scheduled
+const MetricScheduledTxsDuplicates = "erd_scheduled_txs_duplicates"
+
+// MetricTrieNodeChunksReferences is the metric for monitoring the number of large trie nodes being assembled from chunks
+const MetricTrieNodeChunksReferences = "erd_trie_node_chunks_references"
+
+// MetricTrieNodeChunksMissing is the metric for monitoring the number of chunks missing from the large trie nodes being
+// assembled
+const MetricTrieNodeChunksMissing = "erd_trie_node_chunks_missing"
+
 // HighestRoundFromBootStorage is the key for the highest round that is saved in storage
 const HighestRoundFromBootStorage = "highestRoundFromBootStorage"
 
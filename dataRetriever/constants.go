@@ -11,3 +11,6 @@ const UnsignedTxPoolName = "uTxPool"
 
 // RewardTxPoolName defines the name of the reward transactions pool
 const RewardTxPoolName = "rewardTxPool"
+
+// RequestDataVersion1 defines the request data version assumed for the requests not setting one
+const RequestDataVersion1 = uint32(1)
@@ -247,3 +247,9 @@ var ErrNilPayloadValidator = errors.New("nil payload validator")
 
 // ErrWrongTypeAssertion signals that an type assertion failed
 var ErrWrongTypeAssertion = errors.New("wrong type assertion")
+
+// ErrMessageTooLarge signals that a received message exceeds the maximum allowed size
+var ErrMessageTooLarge = errors.New("message too large")
+
+// ErrUnsupportedRequestVersion signals that a received request has a version higher than the supported one
+var ErrUnsupportedRequestVersion = errors.New("unsupported request version")
@@ -122,6 +122,14 @@ type ResolverThrottler interface {
 	IsInterfaceNil() bool
 }
 
+// PeerThrottler defines what a throttler limiting the number of requests processed in parallel for each peer should do
+type PeerThrottler interface {
+	CanProcess(pid core.PeerID) bool
+	StartProcessing(pid core.PeerID)
+	EndProcessing(pid core.PeerID)
+	IsInterfaceNil() bool
+}
+
 // Resolver defines what a data resolver should do
 type Resolver interface {
 	RequestDataFromHash(hash []byte, epoch uint32) error
@@ -446,3 +454,17 @@ type PeerAuthenticationPayloadValidator interface {
 	ValidateTimestamp(payloadTimestamp int64) error
 	IsInterfaceNil() bool
 }
+
+// ResolverMetricsHandler defines the behavior of a component able to count the received requests that could not be
+// parsed, grouped by the failure reason
+type ResolverMetricsHandler interface {
+	IncrementParseFailure(topic string, reason string)
+	IsInterfaceNil() bool
+}
+
+// ResolverRejectionCounter defines the behavior of a component able to count the received requests that were rejected
+// before being processed, grouped by the rejection reason
+type ResolverRejectionCounter interface {
+	IncRejected(reason string)
+	IsInterfaceNil() bool
+}
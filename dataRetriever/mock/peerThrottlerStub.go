@@ -0,0 +1,38 @@
+package mock
+
+import "github.com/ElrondNetwork/elrond-go-core/core"
+
+// PeerThrottlerStub -
+type PeerThrottlerStub struct {
+	CanProcessCalled      func(pid core.PeerID) bool
+	StartProcessingCalled func(pid core.PeerID)
+	EndProcessingCalled   func(pid core.PeerID)
+}
+
+// CanProcess -
+func (stub *PeerThrottlerStub) CanProcess(pid core.PeerID) bool {
+	if stub.CanProcessCalled != nil {
+		return stub.CanProcessCalled(pid)
+	}
+
+	return true
+}
+
+// StartProcessing -
+func (stub *PeerThrottlerStub) StartProcessing(pid core.PeerID) {
+	if stub.StartProcessingCalled != nil {
+		stub.StartProcessingCalled(pid)
+	}
+}
+
+// EndProcessing -
+func (stub *PeerThrottlerStub) EndProcessing(pid core.PeerID) {
+	if stub.EndProcessingCalled != nil {
+		stub.EndProcessingCalled(pid)
+	}
+}
+
+// IsInterfaceNil -
+func (stub *PeerThrottlerStub) IsInterfaceNil() bool {
+	return stub == nil
+}
@@ -0,0 +1,18 @@
+package mock
+
+// ResolverMetricsHandlerStub -
+type ResolverMetricsHandlerStub struct {
+	IncrementParseFailureCalled func(topic string, reason string)
+}
+
+// IncrementParseFailure -
+func (stub *ResolverMetricsHandlerStub) IncrementParseFailure(topic string, reason string) {
+	if stub.IncrementParseFailureCalled != nil {
+		stub.IncrementParseFailureCalled(topic, reason)
+	}
+}
+
+// IsInterfaceNil -
+func (stub *ResolverMetricsHandlerStub) IsInterfaceNil() bool {
+	return stub == nil
+}
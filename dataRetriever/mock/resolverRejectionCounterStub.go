@@ -0,0 +1,18 @@
+package mock
+
+// ResolverRejectionCounterStub -
+type ResolverRejectionCounterStub struct {
+	IncRejectedCalled func(reason string)
+}
+
+// IncRejected -
+func (stub *ResolverRejectionCounterStub) IncRejected(reason string) {
+	if stub.IncRejectedCalled != nil {
+		stub.IncRejectedCalled(reason)
+	}
+}
+
+// IsInterfaceNil -
+func (stub *ResolverRejectionCounterStub) IsInterfaceNil() bool {
+	return stub == nil
+}
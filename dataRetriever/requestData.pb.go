@@ -74,6 +74,7 @@ type RequestData struct {
 	Value      []byte          `protobuf:"bytes,2,opt,name=Value,proto3" json:"value"`
 	Epoch      uint32          `protobuf:"varint,3,opt,name=Epoch,proto3" json:"epoch"`
 	ChunkIndex uint32          `protobuf:"varint,4,opt,name=ChunkIndex,proto3" json:"chunkIndex"`
+	Version    uint32          `protobuf:"varint,5,opt,name=Version,proto3" json:"version"`
 }
 
 func (m *RequestData) Reset()      { *m = RequestData{} }
@@ -132,6 +133,13 @@ func (m *RequestData) GetChunkIndex() uint32 {
 	return 0
 }
 
+func (m *RequestData) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterEnum("proto.RequestDataType", RequestDataType_name, RequestDataType_value)
 	proto.RegisterType((*RequestData)(nil), "proto.RequestData")
@@ -140,29 +148,29 @@ func init() {
 func init() { proto.RegisterFile("requestData.proto", fileDescriptor_d2e280b7501d5666) }
 
 var fileDescriptor_d2e280b7501d5666 = []byte{
-	// 339 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x5c, 0x90, 0xb1, 0x4e, 0xc2, 0x40,
-	0x18, 0x80, 0x7b, 0xd0, 0x1a, 0x38, 0x28, 0xc8, 0x0d, 0xa6, 0x71, 0xf8, 0x4b, 0x9c, 0x88, 0x89,
-	0x25, 0x51, 0x5f, 0x40, 0xd4, 0x28, 0x8b, 0x43, 0x63, 0x1c, 0xdc, 0x8e, 0x72, 0x52, 0x22, 0xb6,
-	0xb5, 0x5c, 0x89, 0x6c, 0x3e, 0x82, 0x8f, 0xe1, 0x0b, 0xf8, 0x0e, 0x8e, 0x8c, 0x4c, 0x44, 0x8e,
-	0xc5, 0x30, 0xf1, 0x08, 0xe6, 0xfe, 0x26, 0x4a, 0x9c, 0xda, 0xef, 0xbb, 0xef, 0xee, 0xfe, 0x1c,
-	0x6d, 0xa4, 0xe2, 0x39, 0x13, 0x63, 0x79, 0xc1, 0x25, 0xf7, 0x92, 0x34, 0x96, 0x31, 0xb3, 0xf0,
-	0xb3, 0x7f, 0x34, 0x18, 0xca, 0x30, 0xeb, 0x79, 0x41, 0xfc, 0xd4, 0x1e, 0xc4, 0x83, 0xb8, 0x8d,
-	0xba, 0x97, 0x3d, 0x20, 0x21, 0xe0, 0x5f, 0xbe, 0xeb, 0xe0, 0x83, 0xd0, 0x8a, 0xff, 0x77, 0x16,
-	0x3b, 0xa5, 0xe6, 0xed, 0x34, 0x11, 0x0e, 0x69, 0x92, 0x56, 0xed, 0x78, 0x2f, 0xaf, 0xbc, 0xad,
-	0x42, 0xaf, 0x76, 0x4a, 0xeb, 0x85, 0x6b, 0xca, 0x69, 0x22, 0x7c, 0xac, 0x99, 0x4b, 0xad, 0x3b,
-	0x3e, 0xca, 0x84, 0x53, 0x68, 0x92, 0x56, 0xb5, 0x53, 0x5e, 0x2f, 0x5c, 0x6b, 0xa2, 0x85, 0x9f,
-	0x7b, 0x1d, 0x5c, 0x26, 0x71, 0x10, 0x3a, 0xc5, 0x26, 0x69, 0xd9, 0x79, 0x20, 0xb4, 0xf0, 0x73,
-	0xcf, 0x3c, 0x4a, 0xcf, 0xc3, 0x2c, 0x7a, 0xec, 0x46, 0x7d, 0xf1, 0xe2, 0x98, 0x58, 0xd5, 0xd6,
-	0x0b, 0x97, 0x06, 0xbf, 0xd6, 0xdf, 0x2a, 0x0e, 0x13, 0x5a, 0xff, 0x37, 0x14, 0xab, 0xd3, 0x4a,
-	0x37, 0x9a, 0xf0, 0xd1, 0xb0, 0xaf, 0x71, 0xd7, 0x60, 0x55, 0x5a, 0xba, 0xe6, 0xe3, 0x10, 0x89,
-	0xb0, 0x06, 0xb5, 0x35, 0x9d, 0xa5, 0x29, 0x9f, 0xa2, 0x2a, 0x30, 0x9b, 0x96, 0x6f, 0xe2, 0x28,
-	0x10, 0x88, 0x45, 0x8d, 0x38, 0x0c, 0xa2, 0xa9, 0x11, 0x2f, 0x44, 0xb4, 0x3a, 0x57, 0xb3, 0x25,
-	0x18, 0xf3, 0x25, 0x18, 0x9b, 0x25, 0x90, 0x57, 0x05, 0xe4, 0x5d, 0x01, 0xf9, 0x54, 0x40, 0x66,
-	0x0a, 0xc8, 0x5c, 0x01, 0xf9, 0x52, 0x40, 0xbe, 0x15, 0x18, 0x1b, 0x05, 0xe4, 0x6d, 0x05, 0xc6,
-	0x6c, 0x05, 0xc6, 0x7c, 0x05, 0xc6, 0xbd, 0xdd, 0xe7, 0x92, 0xfb, 0x42, 0xa6, 0x43, 0x31, 0x11,
-	0x69, 0x6f, 0x07, 0xdf, 0xf4, 0xe4, 0x27, 0x00, 0x00, 0xff, 0xff, 0xd7, 0xda, 0x08, 0x2e, 0xc4,
-	0x01, 0x00, 0x00,
+	// 342 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x03, 0x5d, 0x90, 0xc1, 0x4e, 0xc2, 0x40,
+	0x14, 0x45, 0x29, 0xb4, 0x02, 0x0f, 0x0a, 0x32, 0x0b, 0xd3, 0xb8, 0x28, 0xc6, 0xc4, 0xc4, 0x98,
+	0x58, 0x12, 0xf5, 0x07, 0x44, 0x8d, 0xb2, 0x71, 0x31, 0x31, 0x2c, 0xdc, 0x95, 0x32, 0xd2, 0x46,
+	0xed, 0xd4, 0xd2, 0x12, 0xd9, 0xf9, 0x09, 0x7e, 0x86, 0x9f, 0xe2, 0x92, 0x65, 0x17, 0xc6, 0x08,
+	0x6e, 0x8c, 0x2b, 0x3f, 0xc1, 0x37, 0xaf, 0x51, 0x89, 0x8b, 0x9b, 0x99, 0x7b, 0xdf, 0x99, 0xc9,
+	0x9d, 0x81, 0x56, 0x2c, 0xee, 0x52, 0x31, 0x4e, 0x8e, 0xdd, 0xc4, 0x75, 0xa2, 0x58, 0x26, 0x92,
+	0x19, 0xb4, 0xac, 0xef, 0x8e, 0x82, 0xc4, 0x4f, 0x07, 0x8e, 0x27, 0x6f, 0x3b, 0x23, 0x39, 0x92,
+	0x1d, 0x8a, 0x07, 0xe9, 0x15, 0x39, 0x32, 0xb4, 0xcb, 0x4f, 0x6d, 0xbe, 0x68, 0x50, 0xe3, 0x7f,
+	0x77, 0xb1, 0x03, 0xd0, 0x2f, 0xa6, 0x91, 0xb0, 0xb4, 0x0d, 0x6d, 0xbb, 0xb1, 0xb7, 0x96, 0x53,
+	0xce, 0x12, 0xa1, 0xa6, 0xdd, 0xca, 0xe7, 0x6b, 0x5b, 0x4f, 0x70, 0xc7, 0x89, 0x66, 0x6d, 0x30,
+	0xfa, 0xee, 0x4d, 0x2a, 0xac, 0x22, 0x1e, 0xab, 0x77, 0xab, 0x38, 0x36, 0x26, 0x2a, 0xe0, 0x79,
+	0xae, 0x80, 0x93, 0x48, 0x7a, 0xbe, 0x55, 0x42, 0xc0, 0xcc, 0x01, 0xa1, 0x02, 0x9e, 0xe7, 0xcc,
+	0x01, 0x38, 0xf2, 0xd3, 0xf0, 0xba, 0x17, 0x0e, 0xc5, 0xbd, 0xa5, 0x13, 0xd5, 0x40, 0x0a, 0xbc,
+	0xdf, 0x94, 0x2f, 0x11, 0x6c, 0x0b, 0xca, 0x7d, 0x11, 0x8f, 0x03, 0x19, 0x5a, 0x06, 0xc1, 0x35,
+	0x84, 0xcb, 0x93, 0x3c, 0xe2, 0x3f, 0xb3, 0x9d, 0x08, 0x9a, 0xff, 0xba, 0xb3, 0x26, 0xd4, 0x7a,
+	0x21, 0x96, 0x0b, 0x86, 0xca, 0xae, 0x16, 0x58, 0x1d, 0x2a, 0x67, 0xee, 0xd8, 0x27, 0xa7, 0xb1,
+	0x16, 0x98, 0xca, 0x1d, 0xc6, 0xb1, 0x3b, 0xa5, 0xa8, 0xc8, 0x4c, 0xa8, 0x9e, 0xcb, 0xd0, 0x13,
+	0x64, 0x4b, 0xca, 0x52, 0x67, 0xb2, 0xba, 0xb2, 0xd4, 0x8b, 0xac, 0xd1, 0x3d, 0x9d, 0xcd, 0xed,
+	0x42, 0x86, 0xfa, 0x9a, 0xdb, 0xda, 0xc3, 0xc2, 0xd6, 0x9e, 0x50, 0xcf, 0xa8, 0x19, 0x2a, 0x43,
+	0xbd, 0xa1, 0x3e, 0x16, 0x38, 0xc7, 0xf5, 0xf1, 0xdd, 0x2e, 0xcc, 0x50, 0x19, 0xea, 0xd2, 0x1c,
+	0x62, 0x45, 0x2e, 0x92, 0x38, 0x10, 0xf8, 0x8e, 0xc1, 0x0a, 0x7d, 0xfd, 0xfe, 0x37, 0xda, 0xab,
+	0xf3, 0x33, 0xeb, 0x01, 0x00, 0x00,
 }
 
 func (x RequestDataType) String() string {
@@ -203,18 +211,22 @@ func (this *RequestData) Equal(that interface{}) bool {
 	if this.ChunkIndex != that1.ChunkIndex {
 		return false
 	}
+	if this.Version != that1.Version {
+		return false
+	}
 	return true
 }
 func (this *RequestData) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 8)
+	s := make([]string, 0, 9)
 	s = append(s, "&dataRetriever.RequestData{")
 	s = append(s, "Type: "+fmt.Sprintf("%#v", this.Type)+",\n")
 	s = append(s, "Value: "+fmt.Sprintf("%#v", this.Value)+",\n")
 	s = append(s, "Epoch: "+fmt.Sprintf("%#v", this.Epoch)+",\n")
 	s = append(s, "ChunkIndex: "+fmt.Sprintf("%#v", this.ChunkIndex)+",\n")
+	s = append(s, "Version: "+fmt.Sprintf("%#v", this.Version)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -246,6 +258,11 @@ func (m *RequestData) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.Version != 0 {
+		i = encodeVarintRequestData(dAtA, i, uint64(m.Version))
+		i--
+		dAtA[i] = 0x28
+	}
 	if m.ChunkIndex != 0 {
 		i = encodeVarintRequestData(dAtA, i, uint64(m.ChunkIndex))
 		i--
@@ -301,6 +318,9 @@ func (m *RequestData) Size() (n int) {
 	if m.ChunkIndex != 0 {
 		n += 1 + sovRequestData(uint64(m.ChunkIndex))
 	}
+	if m.Version != 0 {
+		n += 1 + sovRequestData(uint64(m.Version))
+	}
 	return n
 }
 
@@ -319,6 +339,7 @@ func (this *RequestData) String() string {
 		`Value:` + fmt.Sprintf("%v", this.Value) + `,`,
 		`Epoch:` + fmt.Sprintf("%v", this.Epoch) + `,`,
 		`ChunkIndex:` + fmt.Sprintf("%v", this.ChunkIndex) + `,`,
+		`Version:` + fmt.Sprintf("%v", this.Version) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -451,6 +472,25 @@ func (m *RequestData) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
+			}
+			m.Version = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowRequestData
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Version |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipRequestData(dAtA[iNdEx:])
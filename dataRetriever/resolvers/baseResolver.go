@@ -12,6 +12,15 @@ type ArgBaseResolver struct {
 	Marshaller       marshal.Marshalizer
 	AntifloodHandler dataRetriever.P2PAntifloodHandler
 	Throttler        dataRetriever.ResolverThrottler
+	MetricsHandler   dataRetriever.ResolverMetricsHandler
+	PeerThrottler    dataRetriever.PeerThrottler
+	RejectionCounter dataRetriever.ResolverRejectionCounter
+	// MaxMessageSize is the maximum size of a request message that is unmarshalled. Zero means that the size is not
+	// checked
+	MaxMessageSize uint64
+	// MaxSupportedVersion is the highest request data version handled, the requests with a higher version being rejected.
+	// Zero means that only dataRetriever.RequestDataVersion1 is supported
+	MaxSupportedVersion uint32
 }
 
 type baseResolver struct {
@@ -58,10 +58,15 @@ func NewHeaderResolver(arg ArgHeaderResolver) (*HeaderResolver, error) {
 		epochHandler:        epochHandler,
 		shardCoordinator:    arg.ShardCoordinator,
 		messageProcessor: messageProcessor{
-			marshalizer:      arg.Marshaller,
-			antifloodHandler: arg.AntifloodHandler,
-			topic:            arg.SenderResolver.RequestTopic(),
-			throttler:        arg.Throttler,
+			marshalizer:         arg.Marshaller,
+			antifloodHandler:    arg.AntifloodHandler,
+			topic:               arg.SenderResolver.RequestTopic(),
+			throttler:           arg.Throttler,
+			metricsHandler:      arg.MetricsHandler,
+			peerThrottler:       arg.PeerThrottler,
+			rejectionCounter:    arg.RejectionCounter,
+			maxMessageSize:      arg.MaxMessageSize,
+			maxSupportedVersion: arg.MaxSupportedVersion,
 		},
 	}
 
@@ -104,13 +109,13 @@ func (hdrRes *HeaderResolver) SetEpochHandler(epochHandler dataRetriever.EpochHa
 // ProcessReceivedMessage will be the callback func from the p2p.Messenger and will be called each time a new message was received
 // (for the topic this validator was registered to, usually a request topic)
 func (hdrRes *HeaderResolver) ProcessReceivedMessage(message p2p.MessageP2P, fromConnectedPeer core.PeerID) error {
-	err := hdrRes.canProcessMessage(message, fromConnectedPeer)
+	throttler, err := hdrRes.canProcessMessage(message, fromConnectedPeer)
 	if err != nil {
 		return err
 	}
 
-	hdrRes.throttler.StartProcessing()
-	defer hdrRes.throttler.EndProcessing()
+	hdrRes.startProcessing(throttler, fromConnectedPeer)
+	defer hdrRes.endProcessing(throttler, fromConnectedPeer)
 
 	rd, err := hdrRes.parseReceivedMessage(message, fromConnectedPeer)
 	if err != nil {
@@ -179,7 +179,7 @@ func TestHeaderResolver_ProcessReceivedMessageNilValueShouldErr(t *testing.T) {
 	hdrRes, _ := resolvers.NewHeaderResolver(arg)
 
 	err := hdrRes.ProcessReceivedMessage(createRequestMsg(dataRetriever.NonceType, nil), fromConnectedPeerId)
-	assert.Equal(t, dataRetriever.ErrNilValue, err)
+	assert.True(t, errors.Is(err, dataRetriever.ErrNilValue))
 	assert.True(t, arg.Throttler.(*mock.ThrottlerStub).StartWasCalled)
 	assert.True(t, arg.Throttler.(*mock.ThrottlerStub).EndWasCalled)
 }
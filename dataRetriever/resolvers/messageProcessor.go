@@ -2,6 +2,7 @@ package resolvers
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/ElrondNetwork/elrond-go-core/core"
 	"github.com/ElrondNetwork/elrond-go-core/core/check"
@@ -11,35 +12,116 @@ import (
 	"github.com/ElrondNetwork/elrond-go/p2p"
 )
 
+const (
+	parseFailureReasonUnmarshal = "unmarshal"
+	parseFailureReasonNilValue  = "nil value"
+	parseFailureReasonTooLarge  = "too large"
+	parseFailureReasonVersion   = "unsupported version"
+
+	rejectionReasonAntiflood     = "antiflood"
+	rejectionReasonTopic         = "topic"
+	rejectionReasonThrottler     = "throttler"
+	rejectionReasonPeerThrottler = "peer throttler"
+)
+
 // messageProcessor is used for basic message validity and parsing
 type messageProcessor struct {
-	marshalizer      marshal.Marshalizer
-	antifloodHandler dataRetriever.P2PAntifloodHandler
-	throttler        dataRetriever.ResolverThrottler
-	topic            string
+	marshalizer         marshal.Marshalizer
+	antifloodHandler    dataRetriever.P2PAntifloodHandler
+	throttler           dataRetriever.ResolverThrottler
+	metricsHandler      dataRetriever.ResolverMetricsHandler
+	peerThrottler       dataRetriever.PeerThrottler
+	rejectionCounter    dataRetriever.ResolverRejectionCounter
+	topic               string
+	maxMessageSize      uint64
+	maxSupportedVersion uint32
+	mutThrottlers       sync.RWMutex
+	topicThrottlers     map[string]dataRetriever.ResolverThrottler
+}
+
+// SetTopicThrottler sets the throttler used for the provided topic instead of the default one, so that the heavy topics
+// can be limited separately. The messages already being processed keep the throttler they were checked against
+func (mp *messageProcessor) SetTopicThrottler(topic string, throttler dataRetriever.ResolverThrottler) error {
+	if check.IfNil(throttler) {
+		return dataRetriever.ErrNilThrottler
+	}
+
+	mp.mutThrottlers.Lock()
+	defer mp.mutThrottlers.Unlock()
+
+	if mp.topicThrottlers == nil {
+		mp.topicThrottlers = make(map[string]dataRetriever.ResolverThrottler)
+	}
+	mp.topicThrottlers[topic] = throttler
+
+	return nil
 }
 
-func (mp *messageProcessor) canProcessMessage(message p2p.MessageP2P, fromConnectedPeer core.PeerID) error {
+// getThrottler returns the throttler set for the topic of this message processor, falling back to the default one
+func (mp *messageProcessor) getThrottler() dataRetriever.ResolverThrottler {
+	mp.mutThrottlers.RLock()
+	defer mp.mutThrottlers.RUnlock()
+
+	throttler, found := mp.topicThrottlers[mp.topic]
+	if found {
+		return throttler
+	}
+
+	return mp.throttler
+}
+
+// canProcessMessage returns the throttler the message was checked against, which has to be used to start and to end
+// the processing of the message
+func (mp *messageProcessor) canProcessMessage(message p2p.MessageP2P, fromConnectedPeer core.PeerID) (dataRetriever.ResolverThrottler, error) {
 	if check.IfNil(message) {
-		return dataRetriever.ErrNilMessage
+		return nil, dataRetriever.ErrNilMessage
 	}
 	err := mp.antifloodHandler.CanProcessMessage(message, fromConnectedPeer)
 	if err != nil {
-		return fmt.Errorf("%w on resolver topic %s", err, mp.topic)
+		mp.countRejection(rejectionReasonAntiflood)
+		return nil, fmt.Errorf("%w on resolver topic %s", err, mp.topic)
 	}
 	err = mp.antifloodHandler.CanProcessMessagesOnTopic(fromConnectedPeer, mp.topic, 1, uint64(len(message.Data())), message.SeqNo())
 	if err != nil {
-		return fmt.Errorf("%w on resolver topic %s", err, mp.topic)
+		mp.countRejection(rejectionReasonTopic)
+		return nil, fmt.Errorf("%w on resolver topic %s", err, mp.topic)
+	}
+	throttler := mp.getThrottler()
+	if !throttler.CanProcess() {
+		mp.countRejection(rejectionReasonThrottler)
+		return nil, fmt.Errorf("%w on resolver topic %s", dataRetriever.ErrSystemBusy, mp.topic)
 	}
-	if !mp.throttler.CanProcess() {
-		return fmt.Errorf("%w on resolver topic %s", dataRetriever.ErrSystemBusy, mp.topic)
+	if !check.IfNil(mp.peerThrottler) && !mp.peerThrottler.CanProcess(fromConnectedPeer) {
+		mp.countRejection(rejectionReasonPeerThrottler)
+		return nil, fmt.Errorf("%w on resolver topic %s for peer %s", dataRetriever.ErrSystemBusy, mp.topic, fromConnectedPeer.Pretty())
 	}
 
-	return nil
+	return throttler, nil
+}
+
+func (mp *messageProcessor) startProcessing(throttler dataRetriever.ResolverThrottler, fromConnectedPeer core.PeerID) {
+	throttler.StartProcessing()
+	if !check.IfNil(mp.peerThrottler) {
+		mp.peerThrottler.StartProcessing(fromConnectedPeer)
+	}
+}
+
+func (mp *messageProcessor) endProcessing(throttler dataRetriever.ResolverThrottler, fromConnectedPeer core.PeerID) {
+	throttler.EndProcessing()
+	if !check.IfNil(mp.peerThrottler) {
+		mp.peerThrottler.EndProcessing(fromConnectedPeer)
+	}
 }
 
 // parseReceivedMessage will transform the received p2p.Message in a RequestData object.
 func (mp *messageProcessor) parseReceivedMessage(message p2p.MessageP2P, fromConnectedPeer core.PeerID) (*dataRetriever.RequestData, error) {
+	messageSize := uint64(len(message.Data()))
+	if mp.maxMessageSize > 0 && messageSize > mp.maxMessageSize {
+		mp.countParseFailure(parseFailureReasonTooLarge)
+		return nil, fmt.Errorf("%w, size %d, max size %d", mp.wrapParseError(dataRetriever.ErrMessageTooLarge, message, fromConnectedPeer),
+			messageSize, mp.maxMessageSize)
+	}
+
 	rd := &dataRetriever.RequestData{}
 	err := rd.UnmarshalWith(mp.marshalizer, message)
 	if err != nil {
@@ -47,12 +129,61 @@ func (mp *messageProcessor) parseReceivedMessage(message p2p.MessageP2P, fromCon
 		reason := "unmarshalable data got on request topic " + mp.topic
 		mp.antifloodHandler.BlacklistPeer(message.Peer(), reason, common.InvalidMessageBlacklistDuration)
 		mp.antifloodHandler.BlacklistPeer(fromConnectedPeer, reason, common.InvalidMessageBlacklistDuration)
+		mp.countParseFailure(parseFailureReasonUnmarshal)
 
-		return nil, err
+		return nil, mp.wrapParseError(err, message, fromConnectedPeer)
 	}
 	if rd.Value == nil {
-		return nil, dataRetriever.ErrNilValue
+		mp.countParseFailure(parseFailureReasonNilValue)
+		return nil, mp.wrapParseError(dataRetriever.ErrNilValue, message, fromConnectedPeer)
+	}
+	err = mp.checkRequestVersion(rd)
+	if err != nil {
+		mp.countParseFailure(parseFailureReasonVersion)
+		return nil, mp.wrapParseError(err, message, fromConnectedPeer)
 	}
 
 	return rd, nil
 }
+
+// checkRequestVersion sets the version of the requests not having one to dataRetriever.RequestDataVersion1, as they
+// were sent by the peers not aware of the versioning, and rejects the versions higher than the supported one
+func (mp *messageProcessor) checkRequestVersion(rd *dataRetriever.RequestData) error {
+	if rd.Version == 0 {
+		rd.Version = dataRetriever.RequestDataVersion1
+	}
+
+	maxSupportedVersion := mp.maxSupportedVersion
+	if maxSupportedVersion == 0 {
+		maxSupportedVersion = dataRetriever.RequestDataVersion1
+	}
+	if rd.Version > maxSupportedVersion {
+		return fmt.Errorf("%w: version %d, max supported version %d",
+			dataRetriever.ErrUnsupportedRequestVersion, rd.Version, maxSupportedVersion)
+	}
+
+	return nil
+}
+
+// wrapParseError adds the originator, the connected peer and the topic of a malformed request to its error, so that
+// the peers sending it can be traced
+func (mp *messageProcessor) wrapParseError(err error, message p2p.MessageP2P, fromConnectedPeer core.PeerID) error {
+	return fmt.Errorf("%w from peer %s, connected peer %s, on topic %s",
+		err, message.Peer().Pretty(), fromConnectedPeer.Pretty(), mp.topic)
+}
+
+func (mp *messageProcessor) countParseFailure(reason string) {
+	if check.IfNil(mp.metricsHandler) {
+		return
+	}
+
+	mp.metricsHandler.IncrementParseFailure(mp.topic, reason)
+}
+
+func (mp *messageProcessor) countRejection(reason string) {
+	if check.IfNil(mp.rejectionCounter) {
+		return
+	}
+
+	mp.rejectionCounter.IncRejected(reason)
+}
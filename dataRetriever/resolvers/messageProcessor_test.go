@@ -2,6 +2,7 @@ package resolvers
 
 import (
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -29,7 +30,7 @@ func TestMessageProcessor_CanProcessErrorsShouldErr(t *testing.T) {
 		},
 	}
 
-	err := mp.canProcessMessage(&mock.P2PMessageMock{}, "")
+	_, err := mp.canProcessMessage(&mock.P2PMessageMock{}, "")
 
 	assert.True(t, errors.Is(err, expectedErr))
 }
@@ -49,7 +50,7 @@ func TestMessageProcessor_CanProcessOnTopicErrorsShouldErr(t *testing.T) {
 		},
 	}
 
-	err := mp.canProcessMessage(&mock.P2PMessageMock{}, "")
+	_, err := mp.canProcessMessage(&mock.P2PMessageMock{}, "")
 
 	assert.True(t, errors.Is(err, expectedErr))
 }
@@ -75,7 +76,7 @@ func TestMessageProcessor_CanProcessThrottlerNotAllowingShouldErr(t *testing.T)
 		},
 	}
 
-	err := mp.canProcessMessage(&mock.P2PMessageMock{}, "")
+	_, err := mp.canProcessMessage(&mock.P2PMessageMock{}, "")
 
 	assert.True(t, errors.Is(err, dataRetriever.ErrSystemBusy))
 	assert.True(t, canProcessWasCalled)
@@ -102,12 +103,235 @@ func TestMessageProcessor_CanProcessShouldWork(t *testing.T) {
 		},
 	}
 
-	err := mp.canProcessMessage(&mock.P2PMessageMock{}, "")
+	_, err := mp.canProcessMessage(&mock.P2PMessageMock{}, "")
 
 	assert.Nil(t, err)
 	assert.True(t, canProcessWasCalled)
 }
 
+func TestMessageProcessor_CanProcessPeerThrottlerNotAllowingShouldErr(t *testing.T) {
+	t.Parallel()
+
+	var checkedPeer core.PeerID
+	mp := &messageProcessor{
+		antifloodHandler: &mock.P2PAntifloodHandlerStub{
+			CanProcessMessageCalled: func(message p2p.MessageP2P, fromConnectedPeer core.PeerID) error {
+				return nil
+			},
+			CanProcessMessagesOnTopicCalled: func(peer core.PeerID, topic string, numMessages uint32, totalSize uint64, sequence []byte) error {
+				return nil
+			},
+		},
+		throttler: &mock.ThrottlerStub{},
+		peerThrottler: &mock.PeerThrottlerStub{
+			CanProcessCalled: func(pid core.PeerID) bool {
+				checkedPeer = pid
+				return false
+			},
+		},
+	}
+
+	_, err := mp.canProcessMessage(&mock.P2PMessageMock{}, fromConnectedPeer)
+
+	assert.True(t, errors.Is(err, dataRetriever.ErrSystemBusy))
+	assert.Equal(t, fromConnectedPeer, checkedPeer)
+}
+
+func TestMessageProcessor_CanProcessShouldCountTheRejectionReasons(t *testing.T) {
+	t.Parallel()
+
+	expectedErr := errors.New("expected error")
+	createMessageProcessor := func(reasons map[string]int) *messageProcessor {
+		return &messageProcessor{
+			antifloodHandler: &mock.P2PAntifloodHandlerStub{},
+			throttler:        &mock.ThrottlerStub{},
+			rejectionCounter: &mock.ResolverRejectionCounterStub{
+				IncRejectedCalled: func(reason string) {
+					reasons[reason]++
+				},
+			},
+		}
+	}
+
+	t.Run("antiflood", func(t *testing.T) {
+		t.Parallel()
+
+		reasons := make(map[string]int)
+		mp := createMessageProcessor(reasons)
+		mp.antifloodHandler = &mock.P2PAntifloodHandlerStub{
+			CanProcessMessageCalled: func(message p2p.MessageP2P, fromConnectedPeer core.PeerID) error {
+				return expectedErr
+			},
+		}
+
+		_, err := mp.canProcessMessage(&mock.P2PMessageMock{}, fromConnectedPeer)
+		assert.True(t, errors.Is(err, expectedErr))
+		assert.Equal(t, map[string]int{rejectionReasonAntiflood: 1}, reasons)
+	})
+	t.Run("topic", func(t *testing.T) {
+		t.Parallel()
+
+		reasons := make(map[string]int)
+		mp := createMessageProcessor(reasons)
+		mp.antifloodHandler = &mock.P2PAntifloodHandlerStub{
+			CanProcessMessagesOnTopicCalled: func(peer core.PeerID, topic string, numMessages uint32, totalSize uint64, sequence []byte) error {
+				return expectedErr
+			},
+		}
+
+		_, err := mp.canProcessMessage(&mock.P2PMessageMock{}, fromConnectedPeer)
+		assert.True(t, errors.Is(err, expectedErr))
+		assert.Equal(t, map[string]int{rejectionReasonTopic: 1}, reasons)
+	})
+	t.Run("throttler", func(t *testing.T) {
+		t.Parallel()
+
+		reasons := make(map[string]int)
+		mp := createMessageProcessor(reasons)
+		mp.throttler = &mock.ThrottlerStub{
+			CanProcessCalled: func() bool {
+				return false
+			},
+		}
+
+		_, err := mp.canProcessMessage(&mock.P2PMessageMock{}, fromConnectedPeer)
+		assert.True(t, errors.Is(err, dataRetriever.ErrSystemBusy))
+		assert.Equal(t, map[string]int{rejectionReasonThrottler: 1}, reasons)
+	})
+	t.Run("peer throttler", func(t *testing.T) {
+		t.Parallel()
+
+		reasons := make(map[string]int)
+		mp := createMessageProcessor(reasons)
+		mp.peerThrottler = &mock.PeerThrottlerStub{
+			CanProcessCalled: func(pid core.PeerID) bool {
+				return false
+			},
+		}
+
+		_, err := mp.canProcessMessage(&mock.P2PMessageMock{}, fromConnectedPeer)
+		assert.True(t, errors.Is(err, dataRetriever.ErrSystemBusy))
+		assert.Equal(t, map[string]int{rejectionReasonPeerThrottler: 1}, reasons)
+	})
+	t.Run("accepted message should not count", func(t *testing.T) {
+		t.Parallel()
+
+		reasons := make(map[string]int)
+		mp := createMessageProcessor(reasons)
+
+		_, err := mp.canProcessMessage(&mock.P2PMessageMock{}, fromConnectedPeer)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(reasons))
+	})
+}
+
+func TestMessageProcessor_SetTopicThrottlerNilThrottlerShouldErr(t *testing.T) {
+	t.Parallel()
+
+	mp := &messageProcessor{}
+
+	err := mp.SetTopicThrottler("topic", nil)
+	assert.Equal(t, dataRetriever.ErrNilThrottler, err)
+}
+
+func TestMessageProcessor_SetTopicThrottlerShouldOverrideTheDefaultThrottler(t *testing.T) {
+	t.Parallel()
+
+	denyAllThrottler := &mock.ThrottlerStub{
+		CanProcessCalled: func() bool {
+			return false
+		},
+	}
+	createMessageProcessor := func(topic string) *messageProcessor {
+		mp := &messageProcessor{
+			antifloodHandler: &mock.P2PAntifloodHandlerStub{},
+			throttler:        &mock.ThrottlerStub{},
+			topic:            topic,
+		}
+		err := mp.SetTopicThrottler("trieNodes", denyAllThrottler)
+		require.Nil(t, err)
+
+		return mp
+	}
+
+	trieNodesProcessor := createMessageProcessor("trieNodes")
+	throttler, err := trieNodesProcessor.canProcessMessage(&mock.P2PMessageMock{}, fromConnectedPeer)
+	assert.True(t, errors.Is(err, dataRetriever.ErrSystemBusy))
+	assert.Nil(t, throttler)
+
+	transactionsProcessor := createMessageProcessor("transactions")
+	throttler, err = transactionsProcessor.canProcessMessage(&mock.P2PMessageMock{}, fromConnectedPeer)
+	assert.Nil(t, err)
+	assert.True(t, throttler == transactionsProcessor.throttler)
+}
+
+func TestMessageProcessor_StartEndProcessingShouldUseTheThrottlerTheMessageWasCheckedAgainst(t *testing.T) {
+	t.Parallel()
+
+	defaultThrottler := &mock.ThrottlerStub{}
+	topicThrottler := &mock.ThrottlerStub{}
+	mp := &messageProcessor{
+		antifloodHandler: &mock.P2PAntifloodHandlerStub{},
+		throttler:        defaultThrottler,
+		topic:            "trieNodes",
+	}
+
+	throttler, err := mp.canProcessMessage(&mock.P2PMessageMock{}, fromConnectedPeer)
+	require.Nil(t, err)
+	mp.startProcessing(throttler, fromConnectedPeer)
+
+	// the topic throttler set while the message is processed is used only for the next messages
+	err = mp.SetTopicThrottler("trieNodes", topicThrottler)
+	require.Nil(t, err)
+	mp.endProcessing(throttler, fromConnectedPeer)
+
+	assert.True(t, defaultThrottler.StartWasCalled)
+	assert.True(t, defaultThrottler.EndWasCalled)
+	assert.False(t, topicThrottler.EndWasCalled)
+}
+
+func TestMessageProcessor_StartEndProcessingShouldCallBothThrottlers(t *testing.T) {
+	t.Parallel()
+
+	throttler := &mock.ThrottlerStub{}
+	startedPeers := make([]core.PeerID, 0)
+	endedPeers := make([]core.PeerID, 0)
+	mp := &messageProcessor{
+		throttler: throttler,
+		peerThrottler: &mock.PeerThrottlerStub{
+			StartProcessingCalled: func(pid core.PeerID) {
+				startedPeers = append(startedPeers, pid)
+			},
+			EndProcessingCalled: func(pid core.PeerID) {
+				endedPeers = append(endedPeers, pid)
+			},
+		},
+	}
+
+	mp.startProcessing(throttler, fromConnectedPeer)
+	mp.endProcessing(throttler, fromConnectedPeer)
+
+	assert.True(t, throttler.StartWasCalled)
+	assert.True(t, throttler.EndWasCalled)
+	assert.Equal(t, []core.PeerID{fromConnectedPeer}, startedPeers)
+	assert.Equal(t, []core.PeerID{fromConnectedPeer}, endedPeers)
+}
+
+func TestMessageProcessor_StartEndProcessingWithoutPeerThrottlerShouldWork(t *testing.T) {
+	t.Parallel()
+
+	throttler := &mock.ThrottlerStub{}
+	mp := &messageProcessor{
+		throttler: throttler,
+	}
+
+	mp.startProcessing(throttler, fromConnectedPeer)
+	mp.endProcessing(throttler, fromConnectedPeer)
+
+	assert.True(t, throttler.StartWasCalled)
+	assert.True(t, throttler.EndWasCalled)
+}
+
 //------- parseReceivedMessage
 
 func TestMessageProcessor_ParseReceivedMessageMarshalizerFailsShouldErr(t *testing.T) {
@@ -133,6 +357,7 @@ func TestMessageProcessor_ParseReceivedMessageMarshalizerFailsShouldErr(t *testi
 				}
 			},
 		},
+		topic: "requests_topic",
 	}
 	msg := &mock.P2PMessageMock{
 		DataField: make([]byte, 0),
@@ -142,27 +367,36 @@ func TestMessageProcessor_ParseReceivedMessageMarshalizerFailsShouldErr(t *testi
 
 	assert.True(t, originatorBlackListed)
 	assert.True(t, fromConnectedPeerBlackListed)
-	assert.Equal(t, err, expectedErr)
+	assert.True(t, errors.Is(err, expectedErr))
+	assert.True(t, strings.Contains(err.Error(), originatorPid.Pretty()))
+	assert.True(t, strings.Contains(err.Error(), fromConnectedPeer.Pretty()))
+	assert.True(t, strings.Contains(err.Error(), "requests_topic"))
 	assert.Nil(t, rd)
 }
 
 func TestMessageProcessor_ParseReceivedMessageNilValueFieldShouldErr(t *testing.T) {
 	t.Parallel()
 
+	originatorPid := core.PeerID("originator")
 	mp := &messageProcessor{
 		marshalizer: &mock.MarshalizerStub{
 			UnmarshalCalled: func(obj interface{}, buff []byte) error {
 				return nil
 			},
 		},
+		topic: "requests_topic",
 	}
 
 	msg := &mock.P2PMessageMock{
 		DataField: make([]byte, 0),
+		PeerField: originatorPid,
 	}
 	rd, err := mp.parseReceivedMessage(msg, fromConnectedPeer)
 
-	assert.Equal(t, err, dataRetriever.ErrNilValue)
+	assert.True(t, errors.Is(err, dataRetriever.ErrNilValue))
+	assert.True(t, strings.Contains(err.Error(), originatorPid.Pretty()))
+	assert.True(t, strings.Contains(err.Error(), fromConnectedPeer.Pretty()))
+	assert.True(t, strings.Contains(err.Error(), "requests_topic"))
 	assert.Nil(t, rd)
 }
 
@@ -190,3 +424,167 @@ func TestMessageProcessor_ParseReceivedMessageShouldWork(t *testing.T) {
 	require.NotNil(t, rd)
 	assert.Equal(t, expectedValue, rd.Value)
 }
+
+func TestMessageProcessor_ParseReceivedMessageWithMaxMessageSize(t *testing.T) {
+	t.Parallel()
+
+	createMessageProcessor := func(numUnmarshalCalls *int, reasons map[string]int) *messageProcessor {
+		return &messageProcessor{
+			marshalizer: &mock.MarshalizerStub{
+				UnmarshalCalled: func(obj interface{}, buff []byte) error {
+					*numUnmarshalCalls++
+					rd := obj.(*dataRetriever.RequestData)
+					rd.Value = []byte("value")
+
+					return nil
+				},
+			},
+			antifloodHandler: &mock.P2PAntifloodHandlerStub{},
+			metricsHandler: &mock.ResolverMetricsHandlerStub{
+				IncrementParseFailureCalled: func(topic string, reason string) {
+					reasons[reason]++
+				},
+			},
+			topic:          "topic",
+			maxMessageSize: 4,
+		}
+	}
+
+	t.Run("under the limit should unmarshal", func(t *testing.T) {
+		t.Parallel()
+
+		numUnmarshalCalls := 0
+		reasons := make(map[string]int)
+		mp := createMessageProcessor(&numUnmarshalCalls, reasons)
+		msg := &mock.P2PMessageMock{
+			DataField: make([]byte, 4),
+		}
+		rd, err := mp.parseReceivedMessage(msg, fromConnectedPeer)
+
+		assert.Nil(t, err)
+		require.NotNil(t, rd)
+		assert.Equal(t, 1, numUnmarshalCalls)
+		assert.Equal(t, 0, len(reasons))
+	})
+	t.Run("over the limit should error before unmarshalling", func(t *testing.T) {
+		t.Parallel()
+
+		numUnmarshalCalls := 0
+		reasons := make(map[string]int)
+		mp := createMessageProcessor(&numUnmarshalCalls, reasons)
+		msg := &mock.P2PMessageMock{
+			DataField: make([]byte, 5),
+		}
+		rd, err := mp.parseReceivedMessage(msg, fromConnectedPeer)
+
+		assert.True(t, errors.Is(err, dataRetriever.ErrMessageTooLarge))
+		assert.Nil(t, rd)
+		assert.Equal(t, 0, numUnmarshalCalls)
+		assert.Equal(t, map[string]int{parseFailureReasonTooLarge: 1}, reasons)
+	})
+}
+
+func TestMessageProcessor_ParseReceivedMessageWithVersion(t *testing.T) {
+	t.Parallel()
+
+	createMessageProcessor := func(version uint32, maxSupportedVersion uint32, reasons map[string]int) *messageProcessor {
+		return &messageProcessor{
+			marshalizer: &mock.MarshalizerStub{
+				UnmarshalCalled: func(obj interface{}, buff []byte) error {
+					rd := obj.(*dataRetriever.RequestData)
+					rd.Value = []byte("value")
+					rd.Version = version
+
+					return nil
+				},
+			},
+			antifloodHandler: &mock.P2PAntifloodHandlerStub{},
+			metricsHandler: &mock.ResolverMetricsHandlerStub{
+				IncrementParseFailureCalled: func(topic string, reason string) {
+					reasons[reason]++
+				},
+			},
+			topic:               "topic",
+			maxSupportedVersion: maxSupportedVersion,
+		}
+	}
+	msg := &mock.P2PMessageMock{
+		DataField: make([]byte, 0),
+	}
+
+	t.Run("unset version should be accepted as version 1", func(t *testing.T) {
+		t.Parallel()
+
+		reasons := make(map[string]int)
+		mp := createMessageProcessor(0, 0, reasons)
+		rd, err := mp.parseReceivedMessage(msg, fromConnectedPeer)
+
+		assert.Nil(t, err)
+		require.NotNil(t, rd)
+		assert.Equal(t, dataRetriever.RequestDataVersion1, rd.Version)
+		assert.Equal(t, 0, len(reasons))
+	})
+	t.Run("version over the max supported version should error", func(t *testing.T) {
+		t.Parallel()
+
+		reasons := make(map[string]int)
+		mp := createMessageProcessor(2, 0, reasons)
+		rd, err := mp.parseReceivedMessage(msg, fromConnectedPeer)
+
+		assert.True(t, errors.Is(err, dataRetriever.ErrUnsupportedRequestVersion))
+		assert.Nil(t, rd)
+		assert.Equal(t, map[string]int{parseFailureReasonVersion: 1}, reasons)
+	})
+	t.Run("version up to the configured max supported version should work", func(t *testing.T) {
+		t.Parallel()
+
+		reasons := make(map[string]int)
+		mp := createMessageProcessor(2, 2, reasons)
+		rd, err := mp.parseReceivedMessage(msg, fromConnectedPeer)
+
+		assert.Nil(t, err)
+		require.NotNil(t, rd)
+		assert.Equal(t, uint32(2), rd.Version)
+		assert.Equal(t, 0, len(reasons))
+	})
+}
+
+func TestMessageProcessor_ParseReceivedMessageShouldCountParseFailures(t *testing.T) {
+	t.Parallel()
+
+	unmarshalShouldFail := true
+	counters := make(map[string]int)
+	mp := &messageProcessor{
+		marshalizer: &mock.MarshalizerStub{
+			UnmarshalCalled: func(obj interface{}, buff []byte) error {
+				if unmarshalShouldFail {
+					return errors.New("expected error")
+				}
+
+				return nil
+			},
+		},
+		antifloodHandler: &mock.P2PAntifloodHandlerStub{},
+		metricsHandler: &mock.ResolverMetricsHandlerStub{
+			IncrementParseFailureCalled: func(topic string, reason string) {
+				assert.Equal(t, "topic", topic)
+				counters[reason]++
+			},
+		},
+		topic: "topic",
+	}
+
+	msg := &mock.P2PMessageMock{
+		DataField: make([]byte, 0),
+	}
+	_, _ = mp.parseReceivedMessage(msg, fromConnectedPeer)
+	_, _ = mp.parseReceivedMessage(msg, fromConnectedPeer)
+	unmarshalShouldFail = false
+	_, _ = mp.parseReceivedMessage(msg, fromConnectedPeer)
+
+	expectedCounters := map[string]int{
+		parseFailureReasonUnmarshal: 2,
+		parseFailureReasonNilValue:  1,
+	}
+	assert.Equal(t, expectedCounters, counters)
+}
@@ -50,10 +50,15 @@ func NewMiniblockResolver(arg ArgMiniblockResolver) (*miniblockResolver, error)
 		baseStorageResolver: createBaseStorageResolver(arg.MiniBlockStorage, arg.IsFullHistoryNode),
 		dataPacker:          arg.DataPacker,
 		messageProcessor: messageProcessor{
-			marshalizer:      arg.Marshaller,
-			antifloodHandler: arg.AntifloodHandler,
-			topic:            arg.SenderResolver.RequestTopic(),
-			throttler:        arg.Throttler,
+			marshalizer:         arg.Marshaller,
+			antifloodHandler:    arg.AntifloodHandler,
+			topic:               arg.SenderResolver.RequestTopic(),
+			throttler:           arg.Throttler,
+			metricsHandler:      arg.MetricsHandler,
+			peerThrottler:       arg.PeerThrottler,
+			rejectionCounter:    arg.RejectionCounter,
+			maxMessageSize:      arg.MaxMessageSize,
+			maxSupportedVersion: arg.MaxSupportedVersion,
 		},
 	}
 
@@ -80,13 +85,13 @@ func checkArgMiniblockResolver(arg ArgMiniblockResolver) error {
 // ProcessReceivedMessage will be the callback func from the p2p.Messenger and will be called each time a new message was received
 // (for the topic this validator was registered to, usually a request topic)
 func (mbRes *miniblockResolver) ProcessReceivedMessage(message p2p.MessageP2P, fromConnectedPeer core.PeerID) error {
-	err := mbRes.canProcessMessage(message, fromConnectedPeer)
+	throttler, err := mbRes.canProcessMessage(message, fromConnectedPeer)
 	if err != nil {
 		return err
 	}
 
-	mbRes.throttler.StartProcessing()
-	defer mbRes.throttler.EndProcessing()
+	mbRes.startProcessing(throttler, fromConnectedPeer)
+	defer mbRes.endProcessing(throttler, fromConnectedPeer)
 
 	rd, err := mbRes.parseReceivedMessage(message, fromConnectedPeer)
 	if err != nil {
@@ -166,7 +166,7 @@ func TestMiniblockResolver_ProcessReceivedMessageNilValueShouldErr(t *testing.T)
 	mbRes, _ := resolvers.NewMiniblockResolver(arg)
 
 	err := mbRes.ProcessReceivedMessage(createRequestMsg(dataRetriever.HashType, nil), fromConnectedPeerId)
-	assert.Equal(t, dataRetriever.ErrNilValue, err)
+	assert.True(t, errors.Is(err, dataRetriever.ErrNilValue))
 	assert.True(t, arg.Throttler.(*mock.ThrottlerStub).StartWasCalled)
 	assert.True(t, arg.Throttler.(*mock.ThrottlerStub).EndWasCalled)
 }
@@ -45,10 +45,15 @@ func NewPeerAuthenticationResolver(arg ArgPeerAuthenticationResolver) (*peerAuth
 			TopicResolverSender: arg.SenderResolver,
 		},
 		messageProcessor: messageProcessor{
-			marshalizer:      arg.Marshaller,
-			antifloodHandler: arg.AntifloodHandler,
-			throttler:        arg.Throttler,
-			topic:            arg.SenderResolver.RequestTopic(),
+			marshalizer:         arg.Marshaller,
+			antifloodHandler:    arg.AntifloodHandler,
+			throttler:           arg.Throttler,
+			metricsHandler:      arg.MetricsHandler,
+			peerThrottler:       arg.PeerThrottler,
+			rejectionCounter:    arg.RejectionCounter,
+			maxMessageSize:      arg.MaxMessageSize,
+			maxSupportedVersion: arg.MaxSupportedVersion,
+			topic:               arg.SenderResolver.RequestTopic(),
 		},
 		peerAuthenticationPool: arg.PeerAuthenticationPool,
 		dataPacker:             arg.DataPacker,
@@ -108,13 +113,13 @@ func (res *peerAuthenticationResolver) RequestDataFromHashArray(hashes [][]byte,
 // ProcessReceivedMessage represents the callback func from the p2p.Messenger that is called each time a new message is received
 // (for the topic this validator was registered to, usually a request topic)
 func (res *peerAuthenticationResolver) ProcessReceivedMessage(message p2p.MessageP2P, fromConnectedPeer core.PeerID) error {
-	err := res.canProcessMessage(message, fromConnectedPeer)
+	throttler, err := res.canProcessMessage(message, fromConnectedPeer)
 	if err != nil {
 		return err
 	}
 
-	res.throttler.StartProcessing()
-	defer res.throttler.EndProcessing()
+	res.startProcessing(throttler, fromConnectedPeer)
+	defer res.endProcessing(throttler, fromConnectedPeer)
 
 	rd, err := res.parseReceivedMessage(message, fromConnectedPeer)
 	if err != nil {
@@ -55,10 +55,15 @@ func NewTxResolver(arg ArgTxResolver) (*TxResolver, error) {
 		baseStorageResolver: createBaseStorageResolver(arg.TxStorage, arg.IsFullHistoryNode),
 		dataPacker:          arg.DataPacker,
 		messageProcessor: messageProcessor{
-			marshalizer:      arg.Marshaller,
-			antifloodHandler: arg.AntifloodHandler,
-			topic:            arg.SenderResolver.RequestTopic(),
-			throttler:        arg.Throttler,
+			marshalizer:         arg.Marshaller,
+			antifloodHandler:    arg.AntifloodHandler,
+			topic:               arg.SenderResolver.RequestTopic(),
+			throttler:           arg.Throttler,
+			metricsHandler:      arg.MetricsHandler,
+			peerThrottler:       arg.PeerThrottler,
+			rejectionCounter:    arg.RejectionCounter,
+			maxMessageSize:      arg.MaxMessageSize,
+			maxSupportedVersion: arg.MaxSupportedVersion,
 		},
 	}
 
@@ -85,13 +90,13 @@ func checkArgTxResolver(arg ArgTxResolver) error {
 // ProcessReceivedMessage will be the callback func from the p2p.Messenger and will be called each time a new message was received
 // (for the topic this validator was registered to, usually a request topic)
 func (txRes *TxResolver) ProcessReceivedMessage(message p2p.MessageP2P, fromConnectedPeer core.PeerID) error {
-	err := txRes.canProcessMessage(message, fromConnectedPeer)
+	throttler, err := txRes.canProcessMessage(message, fromConnectedPeer)
 	if err != nil {
 		return err
 	}
 
-	txRes.throttler.StartProcessing()
-	defer txRes.throttler.EndProcessing()
+	txRes.startProcessing(throttler, fromConnectedPeer)
+	defer txRes.endProcessing(throttler, fromConnectedPeer)
 
 	rd, err := txRes.parseReceivedMessage(message, fromConnectedPeer)
 	if err != nil {
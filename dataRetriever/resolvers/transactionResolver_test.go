@@ -185,7 +185,7 @@ func TestTxResolver_ProcessReceivedMessageNilValueShouldErr(t *testing.T) {
 
 	err := txRes.ProcessReceivedMessage(msg, connectedPeerId)
 
-	assert.Equal(t, dataRetriever.ErrNilValue, err)
+	assert.True(t, errors.Is(err, dataRetriever.ErrNilValue))
 	assert.True(t, arg.Throttler.(*mock.ThrottlerStub).StartWasCalled)
 	assert.True(t, arg.Throttler.(*mock.ThrottlerStub).EndWasCalled)
 }
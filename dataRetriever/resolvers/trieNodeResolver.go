@@ -38,10 +38,15 @@ func NewTrieNodeResolver(arg ArgTrieNodeResolver) (*TrieNodeResolver, error) {
 		},
 		trieDataGetter: arg.TrieDataGetter,
 		messageProcessor: messageProcessor{
-			marshalizer:      arg.Marshaller,
-			antifloodHandler: arg.AntifloodHandler,
-			topic:            arg.SenderResolver.RequestTopic(),
-			throttler:        arg.Throttler,
+			marshalizer:         arg.Marshaller,
+			antifloodHandler:    arg.AntifloodHandler,
+			topic:               arg.SenderResolver.RequestTopic(),
+			throttler:           arg.Throttler,
+			metricsHandler:      arg.MetricsHandler,
+			peerThrottler:       arg.PeerThrottler,
+			rejectionCounter:    arg.RejectionCounter,
+			maxMessageSize:      arg.MaxMessageSize,
+			maxSupportedVersion: arg.MaxSupportedVersion,
 		},
 	}, nil
 }
@@ -60,13 +65,13 @@ func checkArgTrieNodeResolver(arg ArgTrieNodeResolver) error {
 // ProcessReceivedMessage will be the callback func from the p2p.Messenger and will be called each time a new message was received
 // (for the topic this validator was registered to, usually a request topic)
 func (tnRes *TrieNodeResolver) ProcessReceivedMessage(message p2p.MessageP2P, fromConnectedPeer core.PeerID) error {
-	err := tnRes.canProcessMessage(message, fromConnectedPeer)
+	throttler, err := tnRes.canProcessMessage(message, fromConnectedPeer)
 	if err != nil {
 		return err
 	}
 
-	tnRes.throttler.StartProcessing()
-	defer tnRes.throttler.EndProcessing()
+	tnRes.startProcessing(throttler, fromConnectedPeer)
+	defer tnRes.endProcessing(throttler, fromConnectedPeer)
 
 	rd, err := tnRes.parseReceivedMessage(message, fromConnectedPeer)
 	if err != nil {
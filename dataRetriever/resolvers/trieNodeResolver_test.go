@@ -157,7 +157,7 @@ func TestTrieNodeResolver_ProcessReceivedMessageNilValueShouldErr(t *testing.T)
 	msg := &mock.P2PMessageMock{DataField: data}
 
 	err := tnRes.ProcessReceivedMessage(msg, fromConnectedPeer)
-	assert.Equal(t, dataRetriever.ErrNilValue, err)
+	assert.True(t, errors.Is(err, dataRetriever.ErrNilValue))
 	assert.True(t, arg.Throttler.(*mock.ThrottlerStub).StartWasCalled)
 	assert.True(t, arg.Throttler.(*mock.ThrottlerStub).EndWasCalled)
 }
@@ -0,0 +1,18 @@
+package throttler
+
+import "time"
+
+// SetGetTimeHandler -
+func (pt *peerThrottler) SetGetTimeHandler(handler func() time.Time) {
+	pt.mut.Lock()
+	pt.getTimeHandler = handler
+	pt.mut.Unlock()
+}
+
+// NumPeers -
+func (pt *peerThrottler) NumPeers() int {
+	pt.mut.Lock()
+	defer pt.mut.Unlock()
+
+	return len(pt.peerThrottlers)
+}
@@ -0,0 +1,118 @@
+package throttler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ElrondNetwork/elrond-go-core/core"
+	"github.com/ElrondNetwork/elrond-go/dataRetriever"
+)
+
+const minIdleTimeout = time.Second
+
+// ArgPeerThrottler is the argument structure used to create a new peerThrottler instance
+type ArgPeerThrottler struct {
+	MaxNumRequestsPerPeer int32
+	IdleTimeout           time.Duration
+}
+
+type peerThrottlerEntry struct {
+	numRequestsInProcess int32
+	lastActivity         time.Time
+}
+
+type peerThrottler struct {
+	mut                   sync.Mutex
+	peerThrottlers        map[core.PeerID]*peerThrottlerEntry
+	maxNumRequestsPerPeer int32
+	idleTimeout           time.Duration
+	lastEviction          time.Time
+	getTimeHandler        func() time.Time
+}
+
+// NewPeerThrottler creates a throttler which limits separately the number of requests processed in parallel for each
+// peer, so that a single peer can not consume the whole processing capacity. The throttler of a peer is created when
+// the peer first sends a request and is removed after the peer was idle for the configured timeout
+func NewPeerThrottler(arg ArgPeerThrottler) (*peerThrottler, error) {
+	if arg.MaxNumRequestsPerPeer <= 0 {
+		return nil, fmt.Errorf("%w for MaxNumRequestsPerPeer", dataRetriever.ErrInvalidValue)
+	}
+	if arg.IdleTimeout < minIdleTimeout {
+		return nil, fmt.Errorf("%w for IdleTimeout, minimum is %v", dataRetriever.ErrInvalidValue, minIdleTimeout)
+	}
+
+	return &peerThrottler{
+		peerThrottlers:        make(map[core.PeerID]*peerThrottlerEntry),
+		maxNumRequestsPerPeer: arg.MaxNumRequestsPerPeer,
+		idleTimeout:           arg.IdleTimeout,
+		lastEviction:          time.Now(),
+		getTimeHandler:        time.Now,
+	}, nil
+}
+
+// CanProcess returns true if the given peer did not use its whole processing share
+func (pt *peerThrottler) CanProcess(pid core.PeerID) bool {
+	pt.mut.Lock()
+	defer pt.mut.Unlock()
+
+	pt.evictIdlePeers()
+
+	entry, found := pt.peerThrottlers[pid]
+	if !found {
+		return true
+	}
+
+	return entry.numRequestsInProcess < pt.maxNumRequestsPerPeer
+}
+
+// StartProcessing marks the start of processing a request of the given peer
+func (pt *peerThrottler) StartProcessing(pid core.PeerID) {
+	pt.mut.Lock()
+	defer pt.mut.Unlock()
+
+	entry, found := pt.peerThrottlers[pid]
+	if !found {
+		entry = &peerThrottlerEntry{}
+		pt.peerThrottlers[pid] = entry
+	}
+
+	entry.numRequestsInProcess++
+	entry.lastActivity = pt.getTimeHandler()
+}
+
+// EndProcessing marks the end of processing a request of the given peer
+func (pt *peerThrottler) EndProcessing(pid core.PeerID) {
+	pt.mut.Lock()
+	defer pt.mut.Unlock()
+
+	entry, found := pt.peerThrottlers[pid]
+	if !found || entry.numRequestsInProcess == 0 {
+		return
+	}
+
+	entry.numRequestsInProcess--
+	entry.lastActivity = pt.getTimeHandler()
+}
+
+// evictIdlePeers removes the throttlers of the peers which have no request in process and were not active for the
+// idle timeout. The check is done at most once for each idle timeout
+func (pt *peerThrottler) evictIdlePeers() {
+	now := pt.getTimeHandler()
+	if now.Sub(pt.lastEviction) < pt.idleTimeout {
+		return
+	}
+	pt.lastEviction = now
+
+	for pid, entry := range pt.peerThrottlers {
+		isIdle := entry.numRequestsInProcess == 0 && now.Sub(entry.lastActivity) >= pt.idleTimeout
+		if isIdle {
+			delete(pt.peerThrottlers, pid)
+		}
+	}
+}
+
+// IsInterfaceNil returns true if there is no value under the interface
+func (pt *peerThrottler) IsInterfaceNil() bool {
+	return pt == nil
+}
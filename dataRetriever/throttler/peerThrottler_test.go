@@ -0,0 +1,99 @@
+package throttler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ElrondNetwork/elrond-go-core/core"
+	"github.com/ElrondNetwork/elrond-go-core/core/check"
+	"github.com/ElrondNetwork/elrond-go/dataRetriever"
+	"github.com/stretchr/testify/assert"
+)
+
+func createMockArgPeerThrottler() ArgPeerThrottler {
+	return ArgPeerThrottler{
+		MaxNumRequestsPerPeer: 2,
+		IdleTimeout:           time.Minute,
+	}
+}
+
+func TestNewPeerThrottler(t *testing.T) {
+	t.Parallel()
+
+	t.Run("invalid max num requests per peer should err", func(t *testing.T) {
+		t.Parallel()
+
+		arg := createMockArgPeerThrottler()
+		arg.MaxNumRequestsPerPeer = 0
+		pt, err := NewPeerThrottler(arg)
+		assert.True(t, errors.Is(err, dataRetriever.ErrInvalidValue))
+		assert.True(t, check.IfNil(pt))
+	})
+	t.Run("invalid idle timeout should err", func(t *testing.T) {
+		t.Parallel()
+
+		arg := createMockArgPeerThrottler()
+		arg.IdleTimeout = minIdleTimeout - time.Millisecond
+		pt, err := NewPeerThrottler(arg)
+		assert.True(t, errors.Is(err, dataRetriever.ErrInvalidValue))
+		assert.True(t, check.IfNil(pt))
+	})
+	t.Run("should work", func(t *testing.T) {
+		t.Parallel()
+
+		pt, err := NewPeerThrottler(createMockArgPeerThrottler())
+		assert.Nil(t, err)
+		assert.False(t, check.IfNil(pt))
+	})
+}
+
+func TestPeerThrottler_CanProcessShouldLimitEachPeer(t *testing.T) {
+	t.Parallel()
+
+	pt, _ := NewPeerThrottler(createMockArgPeerThrottler())
+	pid1 := core.PeerID("pid1")
+	pid2 := core.PeerID("pid2")
+
+	assert.True(t, pt.CanProcess(pid1))
+	pt.StartProcessing(pid1)
+	assert.True(t, pt.CanProcess(pid1))
+	pt.StartProcessing(pid1)
+	assert.False(t, pt.CanProcess(pid1))
+	assert.True(t, pt.CanProcess(pid2))
+
+	pt.EndProcessing(pid1)
+	assert.True(t, pt.CanProcess(pid1))
+}
+
+func TestPeerThrottler_EndProcessingUnknownPeerShouldNotPanic(t *testing.T) {
+	t.Parallel()
+
+	pt, _ := NewPeerThrottler(createMockArgPeerThrottler())
+	pt.EndProcessing("pid")
+	assert.Equal(t, 0, pt.NumPeers())
+}
+
+func TestPeerThrottler_ShouldEvictIdlePeers(t *testing.T) {
+	t.Parallel()
+
+	currentTime := time.Now()
+	pt, _ := NewPeerThrottler(createMockArgPeerThrottler())
+	pt.SetGetTimeHandler(func() time.Time {
+		return currentTime
+	})
+
+	pt.StartProcessing("idle pid")
+	pt.EndProcessing("idle pid")
+	pt.StartProcessing("busy pid")
+	assert.Equal(t, 2, pt.NumPeers())
+
+	currentTime = currentTime.Add(time.Second)
+	assert.True(t, pt.CanProcess("pid"))
+	assert.Equal(t, 2, pt.NumPeers())
+
+	currentTime = currentTime.Add(time.Minute)
+	assert.True(t, pt.CanProcess("pid"))
+	assert.Equal(t, 1, pt.NumPeers())
+	assert.True(t, pt.CanProcess("busy pid"))
+}
@@ -989,14 +989,14 @@ func (e *epochStartBootstrap) updateDataForScheduled(
 	shardNotarizedHeader data.ShardHeaderHandler,
 ) (*dataToSync, error) {
 
-	scheduledTxsHandler, err := preprocess.NewScheduledTxsExecution(
-		&factoryDisabled.TxProcessor{},
-		&factoryDisabled.TxCoordinator{},
-		e.storerScheduledSCRs,
-		e.coreComponentsHolder.InternalMarshalizer(),
-		e.coreComponentsHolder.Hasher(),
-		e.shardCoordinator,
-	)
+	scheduledTxsHandler, err := preprocess.NewScheduledTxsExecution(preprocess.ArgsScheduledTxsExecution{
+		TxProcessor:      &factoryDisabled.TxProcessor{},
+		TxCoordinator:    &factoryDisabled.TxCoordinator{},
+		Storer:           e.storerScheduledSCRs,
+		Marshaller:       e.coreComponentsHolder.InternalMarshalizer(),
+		Hasher:           e.coreComponentsHolder.Hasher(),
+		ShardCoordinator: e.shardCoordinator,
+	})
 	if err != nil {
 		return nil, err
 	}
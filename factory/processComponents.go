@@ -515,14 +515,14 @@ func (pcf *processComponentsFactory) Create() (*processComponents, error) {
 		Marshalizer:            pcf.coreData.InternalMarshalizer(),
 	}
 
-	scheduledTxsExecutionHandler, err := preprocess.NewScheduledTxsExecution(
-		&disabled.TxProcessor{},
-		&disabled.TxCoordinator{},
-		pcf.data.StorageService().GetStorer(dataRetriever.ScheduledSCRsUnit),
-		pcf.coreData.InternalMarshalizer(),
-		pcf.coreData.Hasher(),
-		pcf.bootstrapComponents.ShardCoordinator(),
-	)
+	scheduledTxsExecutionHandler, err := preprocess.NewScheduledTxsExecution(preprocess.ArgsScheduledTxsExecution{
+		TxProcessor:      &disabled.TxProcessor{},
+		TxCoordinator:    &disabled.TxCoordinator{},
+		Storer:           pcf.data.StorageService().GetStorer(dataRetriever.ScheduledSCRsUnit),
+		Marshaller:       pcf.coreData.InternalMarshalizer(),
+		Hasher:           pcf.coreData.Hasher(),
+		ShardCoordinator: pcf.bootstrapComponents.ShardCoordinator(),
+	})
 	if err != nil {
 		return nil, err
 	}
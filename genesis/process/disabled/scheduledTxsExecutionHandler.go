@@ -23,6 +23,11 @@ func (steh *ScheduledTxsExecutionHandler) AddScheduledTx(_ []byte, _ data.Transa
 	return true
 }
 
+// AddScheduledTxs does nothing as it is a disabled component
+func (steh *ScheduledTxsExecutionHandler) AddScheduledTxs(txHashes [][]byte, _ []data.TransactionHandler) int {
+	return len(txHashes)
+}
+
 // AddScheduledMiniBlocks does nothing as it is a disabled component
 func (steh *ScheduledTxsExecutionHandler) AddScheduledMiniBlocks(_ block.MiniBlockSlice) {
 }
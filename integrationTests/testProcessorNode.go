@@ -1617,14 +1617,14 @@ func (tpn *TestProcessorNode) initInnerProcessors(gasMap map[string]map[string]u
 		PenalizedTooMuchGasEnableEpoch: tpn.EnableEpochs.PenalizedTooMuchGasEnableEpoch,
 	}
 	tpn.TxProcessor, _ = transaction.NewTxProcessor(argsNewTxProcessor)
-	scheduledTxsExecutionHandler, _ := preprocess.NewScheduledTxsExecution(
-		tpn.TxProcessor,
-		&mock.TransactionCoordinatorMock{},
-		tpn.Storage.GetStorer(dataRetriever.ScheduledSCRsUnit),
-		TestMarshalizer,
-		TestHasher,
-		tpn.ShardCoordinator,
-	)
+	scheduledTxsExecutionHandler, _ := preprocess.NewScheduledTxsExecution(preprocess.ArgsScheduledTxsExecution{
+		TxProcessor:      tpn.TxProcessor,
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           tpn.Storage.GetStorer(dataRetriever.ScheduledSCRsUnit),
+		Marshaller:       TestMarshalizer,
+		Hasher:           TestHasher,
+		ShardCoordinator: tpn.ShardCoordinator,
+	})
 	processedMiniBlocksTracker := processedMb.NewProcessedMiniBlocksTracker()
 
 	fact, _ := shard.NewPreProcessorsContainerFactory(
@@ -1866,13 +1866,14 @@ func (tpn *TestProcessorNode) initMetaInnerProcessors() {
 		BuiltInFunctionOnMetachainEnableEpoch: tpn.EnableEpochs.BuiltInFunctionOnMetaEnableEpoch,
 	}
 	tpn.TxProcessor, _ = transaction.NewMetaTxProcessor(argsNewMetaTxProc)
-	scheduledTxsExecutionHandler, _ := preprocess.NewScheduledTxsExecution(
-		tpn.TxProcessor,
-		&mock.TransactionCoordinatorMock{},
-		tpn.Storage.GetStorer(dataRetriever.ScheduledSCRsUnit),
-		TestMarshalizer,
-		TestHasher,
-		tpn.ShardCoordinator)
+	scheduledTxsExecutionHandler, _ := preprocess.NewScheduledTxsExecution(preprocess.ArgsScheduledTxsExecution{
+		TxProcessor:      tpn.TxProcessor,
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           tpn.Storage.GetStorer(dataRetriever.ScheduledSCRsUnit),
+		Marshaller:       TestMarshalizer,
+		Hasher:           TestHasher,
+		ShardCoordinator: tpn.ShardCoordinator,
+	})
 	processedMiniBlocksTracker := processedMb.NewProcessedMiniBlocksTracker()
 
 	fact, _ := metaProcess.NewPreProcessorsContainerFactory(
@@ -0,0 +1,76 @@
+package preprocess
+
+import (
+	"time"
+
+	"github.com/ElrondNetwork/elrond-go/process"
+)
+
+type timeExecutionBudget struct {
+	maxDuration time.Duration
+}
+
+// NewTimeExecutionBudget creates an execution budget which stops the execution after the given duration
+func NewTimeExecutionBudget(maxDuration time.Duration) *timeExecutionBudget {
+	return &timeExecutionBudget{
+		maxDuration: maxDuration,
+	}
+}
+
+// ShouldContinue returns true while the elapsed time is lower than the max duration
+func (budget *timeExecutionBudget) ShouldContinue(_ uint64, elapsed time.Duration) bool {
+	return elapsed < budget.maxDuration
+}
+
+// IsInterfaceNil returns true if there is no value under the interface
+func (budget *timeExecutionBudget) IsInterfaceNil() bool {
+	return budget == nil
+}
+
+type gasExecutionBudget struct {
+	maxGas uint64
+}
+
+// NewGasExecutionBudget creates an execution budget which stops the execution after the given gas is consumed. The
+// consumed gas is known only if a gas handler was set on the scheduled txs execution
+func NewGasExecutionBudget(maxGas uint64) *gasExecutionBudget {
+	return &gasExecutionBudget{
+		maxGas: maxGas,
+	}
+}
+
+// ShouldContinue returns true while the consumed gas is lower than the max gas
+func (budget *gasExecutionBudget) ShouldContinue(consumedGas uint64, _ time.Duration) bool {
+	return consumedGas < budget.maxGas
+}
+
+// IsInterfaceNil returns true if there is no value under the interface
+func (budget *gasExecutionBudget) IsInterfaceNil() bool {
+	return budget == nil
+}
+
+type haveTimeExecutionBudget struct {
+	haveTime func() time.Duration
+}
+
+// NewHaveTimeExecutionBudget creates an execution budget which stops the execution when the given have time handler
+// reports that there is no time left
+func NewHaveTimeExecutionBudget(haveTime func() time.Duration) (*haveTimeExecutionBudget, error) {
+	if haveTime == nil {
+		return nil, process.ErrNilHaveTimeHandler
+	}
+
+	return &haveTimeExecutionBudget{
+		haveTime: haveTime,
+	}, nil
+}
+
+// ShouldContinue returns true while the have time handler reports time left
+func (budget *haveTimeExecutionBudget) ShouldContinue(_ uint64, _ time.Duration) bool {
+	return budget.haveTime() > 0
+}
+
+// IsInterfaceNil returns true if there is no value under the interface
+func (budget *haveTimeExecutionBudget) IsInterfaceNil() bool {
+	return budget == nil
+}
@@ -0,0 +1,56 @@
+package preprocess
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ElrondNetwork/elrond-go-core/core/check"
+	"github.com/ElrondNetwork/elrond-go/process"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeExecutionBudget_ShouldContinue(t *testing.T) {
+	t.Parallel()
+
+	budget := NewTimeExecutionBudget(time.Second)
+	assert.False(t, check.IfNil(budget))
+	assert.True(t, budget.ShouldContinue(1000, 0))
+	assert.True(t, budget.ShouldContinue(1000, time.Second-1))
+	assert.False(t, budget.ShouldContinue(0, time.Second))
+}
+
+func TestGasExecutionBudget_ShouldContinue(t *testing.T) {
+	t.Parallel()
+
+	budget := NewGasExecutionBudget(100)
+	assert.False(t, check.IfNil(budget))
+	assert.True(t, budget.ShouldContinue(0, time.Hour))
+	assert.True(t, budget.ShouldContinue(99, time.Hour))
+	assert.False(t, budget.ShouldContinue(100, 0))
+}
+
+func TestNewHaveTimeExecutionBudget(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil have time handler should error", func(t *testing.T) {
+		t.Parallel()
+
+		budget, err := NewHaveTimeExecutionBudget(nil)
+		assert.Equal(t, process.ErrNilHaveTimeHandler, err)
+		assert.True(t, check.IfNil(budget))
+	})
+	t.Run("should work", func(t *testing.T) {
+		t.Parallel()
+
+		timeLeft := time.Second
+		budget, err := NewHaveTimeExecutionBudget(func() time.Duration {
+			return timeLeft
+		})
+		assert.Nil(t, err)
+		assert.False(t, check.IfNil(budget))
+		assert.True(t, budget.ShouldContinue(0, 0))
+
+		timeLeft = 0
+		assert.False(t, budget.ShouldContinue(0, 0))
+	})
+}
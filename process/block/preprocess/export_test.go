@@ -168,10 +168,11 @@ func (gc *gasComputation) GetTxHashesWithGasPenalizedSinceLastReset(key []byte)
 func (ste *scheduledTxsExecution) ComputeScheduledIntermediateTxs(
 	mapAllIntermediateTxsBeforeScheduledExecution map[block.Type]map[string]data.TransactionHandler,
 	mapAllIntermediateTxsAfterScheduledExecution map[block.Type]map[string]data.TransactionHandler,
-) {
+) error {
 	ste.mutScheduledTxs.Lock()
-	ste.computeScheduledIntermediateTxs(mapAllIntermediateTxsBeforeScheduledExecution, mapAllIntermediateTxsAfterScheduledExecution)
-	ste.mutScheduledTxs.Unlock()
+	defer ste.mutScheduledTxs.Unlock()
+
+	return ste.computeScheduledIntermediateTxs(mapAllIntermediateTxsBeforeScheduledExecution, mapAllIntermediateTxsAfterScheduledExecution)
 }
 
 func (ste *scheduledTxsExecution) GetMapScheduledIntermediateTxs() map[block.Type][]data.TransactionHandler {
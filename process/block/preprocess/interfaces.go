@@ -2,6 +2,7 @@ package preprocess
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/ElrondNetwork/elrond-go/storage/txcache"
 )
@@ -55,3 +56,10 @@ type BalanceComputationHandler interface {
 	AddressHasEnoughBalance(address []byte, value *big.Int) bool
 	IsInterfaceNil() bool
 }
+
+// ExecutionBudget defines the stop condition of the scheduled txs execution. It is checked before each scheduled tx,
+// with the gas consumed and the time elapsed since the execution started
+type ExecutionBudget interface {
+	ShouldContinue(consumedGas uint64, elapsed time.Duration) bool
+	IsInterfaceNil() bool
+}
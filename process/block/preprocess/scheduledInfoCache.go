@@ -0,0 +1,65 @@
+package preprocess
+
+import (
+	"math/big"
+
+	"github.com/ElrondNetwork/elrond-go-core/data"
+	"github.com/ElrondNetwork/elrond-go-core/data/block"
+	"github.com/ElrondNetwork/elrond-go/process"
+)
+
+// cacheScheduledInfo keeps a copy of the given scheduled info in the scheduled info cache, if enabled, so that a
+// following read for the same header does not need to get and decode it from storage
+func (ste *scheduledTxsExecution) cacheScheduledInfo(headerHash []byte, scheduledInfo *process.ScheduledInfo) {
+	if ste.scheduledInfoCache == nil {
+		return
+	}
+
+	ste.scheduledInfoCache.Put(headerHash, cloneScheduledInfo(scheduledInfo), 0)
+}
+
+func (ste *scheduledTxsExecution) getCachedScheduledInfo(headerHash []byte) (*process.ScheduledInfo, bool) {
+	if ste.scheduledInfoCache == nil {
+		return nil, false
+	}
+
+	value, ok := ste.scheduledInfoCache.Get(headerHash)
+	if !ok {
+		return nil, false
+	}
+	scheduledInfo, ok := value.(*process.ScheduledInfo)
+	if !ok {
+		return nil, false
+	}
+
+	return cloneScheduledInfo(scheduledInfo), true
+}
+
+// cloneScheduledInfo copies the given scheduled info, so that the cached one can not be changed through the returned or
+// the saved scheduled info. The intermediate txs themselves are shared, as they are not changed once created
+func cloneScheduledInfo(scheduledInfo *process.ScheduledInfo) *process.ScheduledInfo {
+	clonedScheduledInfo := &process.ScheduledInfo{
+		RootHash:        append([]byte(nil), scheduledInfo.RootHash...),
+		IntermediateTxs: make(map[block.Type][]data.TransactionHandler, len(scheduledInfo.IntermediateTxs)),
+		GasAndFees:      scheduledInfo.GasAndFees,
+	}
+
+	for blockType, intermediateTxs := range scheduledInfo.IntermediateTxs {
+		clonedScheduledInfo.IntermediateTxs[blockType] = make([]data.TransactionHandler, len(intermediateTxs))
+		copy(clonedScheduledInfo.IntermediateTxs[blockType], intermediateTxs)
+	}
+	if scheduledInfo.MiniBlocks != nil {
+		clonedScheduledInfo.MiniBlocks = make(block.MiniBlockSlice, len(scheduledInfo.MiniBlocks))
+		for index, miniBlock := range scheduledInfo.MiniBlocks {
+			clonedScheduledInfo.MiniBlocks[index] = miniBlock.Clone()
+		}
+	}
+	if scheduledInfo.GasAndFees.AccumulatedFees != nil {
+		clonedScheduledInfo.GasAndFees.AccumulatedFees = big.NewInt(0).Set(scheduledInfo.GasAndFees.AccumulatedFees)
+	}
+	if scheduledInfo.GasAndFees.DeveloperFees != nil {
+		clonedScheduledInfo.GasAndFees.DeveloperFees = big.NewInt(0).Set(scheduledInfo.GasAndFees.DeveloperFees)
+	}
+
+	return clonedScheduledInfo
+}
@@ -0,0 +1,246 @@
+package preprocess
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/ElrondNetwork/elrond-go-core/core"
+	"github.com/ElrondNetwork/elrond-go-core/data"
+	"github.com/ElrondNetwork/elrond-go-core/data/block"
+	"github.com/ElrondNetwork/elrond-go/process"
+)
+
+// scheduledInfoDeltaFormat marks a stored scheduled info which holds only the intermediate txs block types changed
+// against the scheduled info of a base header. It is laid out as: the base header hash, length prefixed, the removed
+// block types, count prefixed, and the scheduled info with the changed block types, encoded as a full scheduled info
+const scheduledInfoDeltaFormat = byte(2)
+
+// maxScheduledInfoDeltaChainLength is the maximum number of deltas read to reconstruct a scheduled info. A full
+// scheduled info is saved instead of a delta which would exceed it
+const maxScheduledInfoDeltaChainLength = 16
+
+// SaveStateDelta saves the scheduled SC execution state of a header from the given epoch, writing only the intermediate
+// txs block types changed against the previously saved state. The full state is saved when the previously saved state
+// has a different root hash or was saved in another epoch, as a delta is read together with its base from the epoch
+// storage of the delta
+func (ste *scheduledTxsExecution) SaveStateDelta(headerHash []byte, epoch uint32, scheduledInfo *process.ScheduledInfo) {
+	ste.mutScheduledTxs.RLock()
+	baseHeaderHash := ste.lastSavedHeaderHash
+	baseEpoch := ste.lastSavedEpoch
+	baseScheduledInfo := ste.lastSavedScheduledInfo
+	baseDeltaChainLength := ste.lastSavedDeltaChainLength
+	ste.mutScheduledTxs.RUnlock()
+
+	canSaveDelta := baseScheduledInfo != nil &&
+		baseEpoch.HasValue && baseEpoch.Value == epoch &&
+		bytes.Equal(baseScheduledInfo.RootHash, scheduledInfo.RootHash) &&
+		baseDeltaChainLength < maxScheduledInfoDeltaChainLength
+	if !canSaveDelta {
+		ste.saveState(headerHash, core.OptionalUint32{Value: epoch, HasValue: true}, scheduledInfo)
+		return
+	}
+
+	changedScheduledInfo, removedBlockTypes, err := ste.computeScheduledInfoDelta(baseScheduledInfo, scheduledInfo)
+	if err != nil {
+		log.Warn("scheduledTxsExecution.SaveStateDelta: computeScheduledInfoDelta", "error", err.Error())
+		return
+	}
+
+	marshalledScheduledInfo, err := ste.getMarshalledScheduledInfo(changedScheduledInfo)
+	if err != nil {
+		log.Warn("scheduledTxsExecution.SaveStateDelta: getMarshalledScheduledInfo", "error", err.Error())
+		return
+	}
+
+	deltaData := encodeScheduledInfoDelta(baseHeaderHash, removedBlockTypes, marshalledScheduledInfo)
+	log.Debug("scheduledTxsExecution.SaveStateDelta: Put",
+		"header hash", headerHash,
+		"base header hash", baseHeaderHash,
+		"num of changed block types", len(changedScheduledInfo.IntermediateTxs),
+		"num of removed block types", len(removedBlockTypes),
+		"length of delta", len(deltaData))
+	err = ste.storer.Put(headerHash, deltaData)
+	if err != nil {
+		log.Warn("scheduledTxsExecution.SaveStateDelta Put -> ScheduledIntermediateTxsUnit", "error", err.Error())
+		return
+	}
+
+	ste.setLastSavedScheduledInfo(headerHash, baseEpoch, scheduledInfo, baseDeltaChainLength+1)
+	ste.cacheScheduledInfo(headerHash, scheduledInfo)
+}
+
+func (ste *scheduledTxsExecution) setLastSavedScheduledInfo(
+	headerHash []byte,
+	epoch core.OptionalUint32,
+	scheduledInfo *process.ScheduledInfo,
+	deltaChainLength int,
+) {
+	ste.mutScheduledTxs.Lock()
+	ste.lastSavedHeaderHash = headerHash
+	ste.lastSavedEpoch = epoch
+	ste.lastSavedScheduledInfo = scheduledInfo
+	ste.lastSavedDeltaChainLength = deltaChainLength
+	ste.mutScheduledTxs.Unlock()
+}
+
+// computeScheduledInfoDelta returns the scheduled info holding only the changed intermediate txs block types, together
+// with the block types which are no longer present
+func (ste *scheduledTxsExecution) computeScheduledInfoDelta(
+	baseScheduledInfo *process.ScheduledInfo,
+	scheduledInfo *process.ScheduledInfo,
+) (*process.ScheduledInfo, []block.Type, error) {
+	changedIntermediateTxs := make(map[block.Type][]data.TransactionHandler)
+	for blockType, intermediateTxs := range scheduledInfo.IntermediateTxs {
+		isChanged, err := ste.areIntermediateTxsChanged(baseScheduledInfo.IntermediateTxs[blockType], intermediateTxs)
+		if err != nil {
+			return nil, nil, err
+		}
+		if isChanged {
+			changedIntermediateTxs[blockType] = intermediateTxs
+		}
+	}
+
+	removedBlockTypes := make([]block.Type, 0)
+	for blockType := range baseScheduledInfo.IntermediateTxs {
+		_, found := scheduledInfo.IntermediateTxs[blockType]
+		if !found {
+			removedBlockTypes = append(removedBlockTypes, blockType)
+		}
+	}
+	sort.Slice(removedBlockTypes, func(a, b int) bool {
+		return removedBlockTypes[a] < removedBlockTypes[b]
+	})
+
+	changedScheduledInfo := &process.ScheduledInfo{
+		RootHash:        scheduledInfo.RootHash,
+		IntermediateTxs: changedIntermediateTxs,
+		GasAndFees:      scheduledInfo.GasAndFees,
+		MiniBlocks:      scheduledInfo.MiniBlocks,
+	}
+
+	return changedScheduledInfo, removedBlockTypes, nil
+}
+
+func (ste *scheduledTxsExecution) areIntermediateTxsChanged(baseTxs []data.TransactionHandler, txs []data.TransactionHandler) (bool, error) {
+	if len(baseTxs) != len(txs) {
+		return true, nil
+	}
+
+	for index := range txs {
+		marshalledBaseTx, err := ste.marshaller.Marshal(baseTxs[index])
+		if err != nil {
+			return false, err
+		}
+		marshalledTx, err := ste.marshaller.Marshal(txs[index])
+		if err != nil {
+			return false, err
+		}
+		if !bytes.Equal(marshalledBaseTx, marshalledTx) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func encodeScheduledInfoDelta(baseHeaderHash []byte, removedBlockTypes []block.Type, marshalledScheduledInfo []byte) []byte {
+	deltaData := []byte{scheduledInfoFormatMarker, scheduledInfoDeltaFormat}
+	deltaData = appendUint32(deltaData, uint32(len(baseHeaderHash)))
+	deltaData = append(deltaData, baseHeaderHash...)
+	deltaData = appendUint32(deltaData, uint32(len(removedBlockTypes)))
+	for _, blockType := range removedBlockTypes {
+		deltaData = appendUint32(deltaData, uint32(blockType))
+	}
+
+	return append(deltaData, marshalledScheduledInfo...)
+}
+
+func appendUint32(buff []byte, value uint32) []byte {
+	var encoded [4]byte
+	binary.BigEndian.PutUint32(encoded[:], value)
+	return append(buff, encoded[:]...)
+}
+
+func isScheduledInfoDelta(data []byte) bool {
+	return len(data) >= 2 && data[0] == scheduledInfoFormatMarker && data[1] == scheduledInfoDeltaFormat
+}
+
+// decodeScheduledInfoDelta returns the base header hash, the removed block types and the encoded scheduled info with
+// the changed block types
+func decodeScheduledInfoDelta(deltaData []byte) ([]byte, []block.Type, []byte, error) {
+	buff := deltaData[2:]
+	readUint32 := func() (uint32, bool) {
+		if len(buff) < 4 {
+			return 0, false
+		}
+		value := binary.BigEndian.Uint32(buff[:4])
+		buff = buff[4:]
+		return value, true
+	}
+
+	baseHeaderHashLength, ok := readUint32()
+	if !ok || uint64(len(buff)) < uint64(baseHeaderHashLength) {
+		return nil, nil, nil, fmt.Errorf("%w: truncated scheduled info delta", process.ErrUnknownScheduledInfoFormat)
+	}
+	baseHeaderHash := buff[:baseHeaderHashLength]
+	buff = buff[baseHeaderHashLength:]
+
+	numRemovedBlockTypes, ok := readUint32()
+	if !ok || uint64(len(buff)) < uint64(numRemovedBlockTypes)*4 {
+		return nil, nil, nil, fmt.Errorf("%w: truncated scheduled info delta", process.ErrUnknownScheduledInfoFormat)
+	}
+	removedBlockTypes := make([]block.Type, numRemovedBlockTypes)
+	for index := range removedBlockTypes {
+		blockType, _ := readUint32()
+		removedBlockTypes[index] = block.Type(blockType)
+	}
+
+	return baseHeaderHash, removedBlockTypes, buff, nil
+}
+
+// getScheduledInfoFromDelta reconstructs the full scheduled info by merging the changed block types over the scheduled
+// info of the base header, which can be a delta as well
+func (ste *scheduledTxsExecution) getScheduledInfoFromDelta(
+	deltaData []byte,
+	epoch core.OptionalUint32,
+	deltaChainLength int,
+) (*process.ScheduledInfo, error) {
+	if deltaChainLength > maxScheduledInfoDeltaChainLength {
+		return nil, fmt.Errorf("%w: scheduled info delta chain longer than %d",
+			process.ErrUnknownScheduledInfoFormat, maxScheduledInfoDeltaChainLength)
+	}
+
+	baseHeaderHash, removedBlockTypes, encodedScheduledInfo, err := decodeScheduledInfoDelta(deltaData)
+	if err != nil {
+		return nil, err
+	}
+
+	baseData, err := ste.getStoredScheduledInfo(baseHeaderHash, epoch)
+	if err != nil {
+		return nil, fmt.Errorf("%w for base header hash %x: %s", process.ErrMissingScheduledInfoDeltaBase, baseHeaderHash, err.Error())
+	}
+	baseScheduledInfo, err := ste.getScheduledInfoFromStoredDataWithDepth(baseData, epoch, deltaChainLength+1)
+	if err != nil {
+		return nil, err
+	}
+	changedScheduledInfo, err := ste.getScheduledInfoFromStoredDataWithDepth(encodedScheduledInfo, epoch, deltaChainLength+1)
+	if err != nil {
+		return nil, err
+	}
+
+	intermediateTxs := make(map[block.Type][]data.TransactionHandler, len(baseScheduledInfo.IntermediateTxs))
+	for blockType, baseTxs := range baseScheduledInfo.IntermediateTxs {
+		intermediateTxs[blockType] = baseTxs
+	}
+	for _, blockType := range removedBlockTypes {
+		delete(intermediateTxs, blockType)
+	}
+	for blockType, changedTxs := range changedScheduledInfo.IntermediateTxs {
+		intermediateTxs[blockType] = changedTxs
+	}
+	changedScheduledInfo.IntermediateTxs = intermediateTxs
+
+	return changedScheduledInfo, nil
+}
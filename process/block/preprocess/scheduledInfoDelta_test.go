@@ -0,0 +1,207 @@
+package preprocess
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ElrondNetwork/elrond-go-core/core"
+	"github.com/ElrondNetwork/elrond-go-core/data"
+	"github.com/ElrondNetwork/elrond-go-core/data/block"
+	"github.com/ElrondNetwork/elrond-go-core/data/scheduled"
+	"github.com/ElrondNetwork/elrond-go-core/data/smartContractResult"
+	"github.com/ElrondNetwork/elrond-go-core/data/transaction"
+	"github.com/ElrondNetwork/elrond-go-core/marshal"
+	"github.com/ElrondNetwork/elrond-go/process"
+	"github.com/ElrondNetwork/elrond-go/process/mock"
+	"github.com/ElrondNetwork/elrond-go/testscommon"
+	"github.com/ElrondNetwork/elrond-go/testscommon/genericMocks"
+	"github.com/ElrondNetwork/elrond-go/testscommon/hashingMocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createScheduledInfoForDelta(rootHash []byte, intermediateTxs map[block.Type][]data.TransactionHandler) *process.ScheduledInfo {
+	return &process.ScheduledInfo{
+		RootHash:        rootHash,
+		IntermediateTxs: intermediateTxs,
+		GasAndFees: scheduled.GasAndFees{
+			AccumulatedFees: big.NewInt(100),
+			DeveloperFees:   big.NewInt(10),
+		},
+		MiniBlocks: block.MiniBlockSlice{},
+	}
+}
+
+func getNonces(txs []data.TransactionHandler) []uint64 {
+	nonces := make([]uint64, 0, len(txs))
+	for _, tx := range txs {
+		nonces = append(nonces, tx.GetNonce())
+	}
+
+	return nonces
+}
+
+func TestScheduledTxsExecution_SaveStateDelta(t *testing.T) {
+	t.Parallel()
+
+	storer := genericMocks.NewStorerMock()
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           storer,
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	scrs := []data.TransactionHandler{
+		&smartContractResult.SmartContractResult{Nonce: 1, Data: []byte("scr data 1")},
+		&smartContractResult.SmartContractResult{Nonce: 2, Data: []byte("scr data 2")},
+	}
+	invalidTxs := []data.TransactionHandler{
+		&transaction.Transaction{Nonce: 3, Data: []byte("invalid tx data")},
+	}
+	receipts := []data.TransactionHandler{
+		&smartContractResult.SmartContractResult{Nonce: 4},
+	}
+	rootHash := []byte("root hash")
+
+	baseScheduledInfo := createScheduledInfoForDelta(rootHash, map[block.Type][]data.TransactionHandler{
+		block.SmartContractResultBlock: scrs,
+		block.InvalidBlock:             invalidTxs,
+		block.ReceiptBlock:             receipts,
+	})
+	scheduledTxsExec.SaveStateDelta([]byte("header hash 1"), 0, baseScheduledInfo)
+	baseData, err := storer.Get([]byte("header hash 1"))
+	require.Nil(t, err)
+	assert.False(t, isScheduledInfoDelta(baseData))
+
+	changedSCRs := append(scrs, &smartContractResult.SmartContractResult{Nonce: 5, Data: []byte("scr data 5")})
+	deltaScheduledInfo := createScheduledInfoForDelta(rootHash, map[block.Type][]data.TransactionHandler{
+		block.SmartContractResultBlock: changedSCRs,
+		block.InvalidBlock:             invalidTxs,
+	})
+	scheduledTxsExec.SaveStateDelta([]byte("header hash 2"), 0, deltaScheduledInfo)
+	deltaData, err := storer.Get([]byte("header hash 2"))
+	require.Nil(t, err)
+	require.True(t, isScheduledInfoDelta(deltaData))
+
+	baseHeaderHash, removedBlockTypes, encodedScheduledInfo, err := decodeScheduledInfoDelta(deltaData)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("header hash 1"), baseHeaderHash)
+	assert.Equal(t, []block.Type{block.ReceiptBlock}, removedBlockTypes)
+	changedScheduledInfo, err := scheduledTxsExec.getScheduledInfoFromStoredData(encodedScheduledInfo, core.OptionalUint32{})
+	require.Nil(t, err)
+	assert.Equal(t, 1, len(changedScheduledInfo.IntermediateTxs))
+	assert.Equal(t, 3, len(changedScheduledInfo.IntermediateTxs[block.SmartContractResultBlock]))
+
+	t.Run("delta should be merged over its base", func(t *testing.T) {
+		readScheduledInfo, errGet := scheduledTxsExec.getScheduledInfoForHeader([]byte("header hash 2"), core.OptionalUint32{})
+		require.Nil(t, errGet)
+		assert.Equal(t, rootHash, readScheduledInfo.RootHash)
+		assert.Equal(t, deltaScheduledInfo.GasAndFees, readScheduledInfo.GasAndFees)
+		assert.Equal(t, 2, len(readScheduledInfo.IntermediateTxs))
+		assert.Equal(t, []uint64{1, 2, 5}, getNonces(readScheduledInfo.IntermediateTxs[block.SmartContractResultBlock]))
+		assert.Equal(t, []uint64{3}, getNonces(readScheduledInfo.IntermediateTxs[block.InvalidBlock]))
+	})
+	t.Run("delta over a delta should be merged", func(t *testing.T) {
+		scheduledTxsExec.SaveStateDelta([]byte("header hash 3"), 0, baseScheduledInfo)
+		data3, errGet := storer.Get([]byte("header hash 3"))
+		require.Nil(t, errGet)
+		require.True(t, isScheduledInfoDelta(data3))
+
+		readScheduledInfo, errGet := scheduledTxsExec.getScheduledInfoForHeader([]byte("header hash 3"), core.OptionalUint32{})
+		require.Nil(t, errGet)
+		assert.Equal(t, 3, len(readScheduledInfo.IntermediateTxs))
+		assert.Equal(t, []uint64{1, 2}, getNonces(readScheduledInfo.IntermediateTxs[block.SmartContractResultBlock]))
+		assert.Equal(t, []uint64{3}, getNonces(readScheduledInfo.IntermediateTxs[block.InvalidBlock]))
+		assert.Equal(t, []uint64{4}, getNonces(readScheduledInfo.IntermediateTxs[block.ReceiptBlock]))
+	})
+	t.Run("different root hash should save the full state", func(t *testing.T) {
+		scheduledTxsExec.SaveStateDelta([]byte("header hash 4"), 0, createScheduledInfoForDelta([]byte("other root hash"), nil))
+		data4, errGet := storer.Get([]byte("header hash 4"))
+		require.Nil(t, errGet)
+		assert.False(t, isScheduledInfoDelta(data4))
+	})
+	t.Run("missing base should error", func(t *testing.T) {
+		storerWithoutBase := genericMocks.NewStorerMock()
+		errPut := storerWithoutBase.Put([]byte("header hash 2"), deltaData)
+		require.Nil(t, errPut)
+		scheduledTxsExecWithoutBase, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:      &testscommon.TxProcessorMock{},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           storerWithoutBase,
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+		})
+
+		readScheduledInfo, errGet := scheduledTxsExecWithoutBase.getScheduledInfoForHeader([]byte("header hash 2"), core.OptionalUint32{})
+		assert.NotNil(t, errGet)
+		assert.Nil(t, readScheduledInfo)
+	})
+}
+
+func TestScheduledTxsExecution_SaveStateDeltaInAnotherEpochShouldSaveTheFullState(t *testing.T) {
+	t.Parallel()
+
+	storer := genericMocks.NewStorerMockWithEpoch(1)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           storer,
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	rootHash := []byte("root hash")
+	scrs := []data.TransactionHandler{
+		&smartContractResult.SmartContractResult{Nonce: 1, Data: []byte("scr data 1")},
+	}
+	invalidTxs := []data.TransactionHandler{
+		&transaction.Transaction{Nonce: 2, Data: []byte("invalid tx data")},
+	}
+	scheduledTxsExec.SaveStateDelta([]byte("header hash 1"), 1, createScheduledInfoForDelta(rootHash, map[block.Type][]data.TransactionHandler{
+		block.SmartContractResultBlock: scrs,
+		block.InvalidBlock:             invalidTxs,
+	}))
+
+	storer.SetCurrentEpoch(2)
+	changedSCRs := append(scrs, &smartContractResult.SmartContractResult{Nonce: 3, Data: []byte("scr data 3")})
+	scheduledTxsExec.SaveStateDelta([]byte("header hash 2"), 2, createScheduledInfoForDelta(rootHash, map[block.Type][]data.TransactionHandler{
+		block.SmartContractResultBlock: changedSCRs,
+		block.InvalidBlock:             invalidTxs,
+	}))
+	data2, err := storer.GetFromEpoch([]byte("header hash 2"), 2)
+	require.Nil(t, err)
+	assert.False(t, isScheduledInfoDelta(data2))
+
+	scheduledTxsExec.SaveStateDelta([]byte("header hash 3"), 2, createScheduledInfoForDelta(rootHash, map[block.Type][]data.TransactionHandler{
+		block.SmartContractResultBlock: changedSCRs,
+	}))
+	data3, err := storer.GetFromEpoch([]byte("header hash 3"), 2)
+	require.Nil(t, err)
+	assert.True(t, isScheduledInfoDelta(data3))
+
+	readScheduledInfo, err := scheduledTxsExec.getScheduledInfoForHeader([]byte("header hash 2"), core.OptionalUint32{Value: 2, HasValue: true})
+	require.Nil(t, err)
+	assert.Equal(t, []uint64{1, 3}, getNonces(readScheduledInfo.IntermediateTxs[block.SmartContractResultBlock]))
+	assert.Equal(t, []uint64{2}, getNonces(readScheduledInfo.IntermediateTxs[block.InvalidBlock]))
+
+	readScheduledInfo, err = scheduledTxsExec.getScheduledInfoForHeader([]byte("header hash 3"), core.OptionalUint32{Value: 2, HasValue: true})
+	require.Nil(t, err)
+	assert.Equal(t, 1, len(readScheduledInfo.IntermediateTxs))
+	assert.Equal(t, []uint64{1, 3}, getNonces(readScheduledInfo.IntermediateTxs[block.SmartContractResultBlock]))
+}
+
+func TestDecodeScheduledInfoDelta_TruncatedDataShouldErr(t *testing.T) {
+	t.Parallel()
+
+	deltaData := encodeScheduledInfoDelta([]byte("base header hash"), []block.Type{block.ReceiptBlock}, []byte("scheduled info"))
+	for _, length := range []int{3, 10, 22, 25} {
+		_, _, _, err := decodeScheduledInfoDelta(deltaData[:length])
+		assert.True(t, errors.Is(err, process.ErrUnknownScheduledInfoFormat))
+	}
+}
@@ -0,0 +1,166 @@
+package preprocess
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ElrondNetwork/elrond-go-core/core/check"
+	"github.com/ElrondNetwork/elrond-go-core/data"
+	"github.com/ElrondNetwork/elrond-go-core/data/block"
+	"github.com/ElrondNetwork/elrond-go-core/data/scheduled"
+	"github.com/ElrondNetwork/elrond-go/process"
+)
+
+// dryRunSnapshot holds, besides the pre-execution snapshot, the results of the last execution which a dry run
+// overwrites
+type dryRunSnapshot struct {
+	preExecution                *preExecutionSnapshot
+	mapScheduledIntermediateTxs map[block.Type][]data.TransactionHandler
+	mapScheduledInterTxsByHash  map[string]data.TransactionHandler
+	mapScheduledMbHashes        map[string]struct{}
+	mapScheduledTxFees          map[string]*big.Int
+	mapScheduledTxGasAndFees    map[string]scheduled.GasAndFees
+	mapStorageAccessStats       map[string]process.StorageAccessStat
+	failedScheduledTxHashes     [][]byte
+	mapScheduledTxsByBlockType  map[block.Type][][]byte
+	mapScheduledGasPerShard     map[uint32]uint64
+	noOpScheduledTxHashes       [][]byte
+	projectedMiniBlockSize      uint64
+	scheduledReceipts           []data.TransactionHandler
+	mapScheduledReceipts        map[string][]byte
+	mapSeenInterTxHashes        map[string]struct{}
+	duplicateInterTxHashes      [][]byte
+	interTxsMarshalledSize      uint64
+	numAttemptedScheduledTxs    uint32
+	fingerprintData             []byte
+	executionFingerprint        []byte
+	mapExecutionResults         map[string]error
+	computedScheduledRootHash   []byte
+	lastRolledBackHeaderHash    []byte
+	resumableExecution          *resumableScheduledExecution
+}
+
+// DryRunAll executes all the scheduled transactions, as ExecuteAll does, and returns the resulted scheduled info: the
+// computed scheduled root hash, the scheduled intermediate txs, the gas and fees and the scheduled mini blocks. Nothing
+// is kept afterwards: the accounts are reverted to the snapshot taken before the execution and the scheduled txs, their
+// mini blocks and the results of the last execution are restored. The intermediate txs created meanwhile are only
+// dropped from the tx coordinator when its block processing is started again.
+// The dry run needs the accounts adapter and it is not supported with streamed intermediate txs, as those can not be
+// taken back
+func (ste *scheduledTxsExecution) DryRunAll(haveTime func() time.Duration) (*process.ScheduledInfo, error) {
+	ste.mutScheduledTxs.Lock()
+	defer ste.mutScheduledTxs.Unlock()
+
+	if check.IfNil(ste.accounts) {
+		return nil, fmt.Errorf("%w in scheduledTxsExecution.DryRunAll", process.ErrNilAccountsAdapter)
+	}
+	if ste.streamIntermediateTxs {
+		return nil, fmt.Errorf("%w in scheduledTxsExecution.DryRunAll, streaming intermediate txs is not supported",
+			process.ErrInvalidValue)
+	}
+
+	snapshot := ste.createDryRunSnapshot()
+	defer func() {
+		err := ste.restoreDryRunSnapshot(snapshot)
+		if err != nil {
+			log.Warn("scheduledTxsExecution.DryRunAll: restoreDryRunSnapshot", "error", err)
+		}
+	}()
+
+	err := ste.executeAll(context.Background(), haveTime)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduledInfo := &process.ScheduledInfo{
+		RootHash:        ste.computedScheduledRootHash,
+		IntermediateTxs: make(map[block.Type][]data.TransactionHandler),
+		GasAndFees:      ste.gasAndFees,
+		MiniBlocks:      make(block.MiniBlockSlice, len(ste.scheduledMbs)),
+	}
+	for blockType, scheduledIntermediateTxs := range ste.mapScheduledIntermediateTxs {
+		if len(scheduledIntermediateTxs) == 0 {
+			continue
+		}
+
+		scheduledInfo.IntermediateTxs[blockType] = make([]data.TransactionHandler, len(scheduledIntermediateTxs))
+		copy(scheduledInfo.IntermediateTxs[blockType], scheduledIntermediateTxs)
+	}
+	for index, scheduledMb := range ste.scheduledMbs {
+		scheduledInfo.MiniBlocks[index] = scheduledMb.Clone()
+	}
+
+	log.Debug("scheduledTxsExecution.DryRunAll",
+		"num of executed txs", len(ste.mapExecutionResults),
+		"root hash", scheduledInfo.RootHash)
+
+	return scheduledInfo, nil
+}
+
+func (ste *scheduledTxsExecution) createDryRunSnapshot() *dryRunSnapshot {
+	snapshot := &dryRunSnapshot{
+		preExecution:                ste.createPreExecutionSnapshot(),
+		mapScheduledIntermediateTxs: ste.mapScheduledIntermediateTxs,
+		mapScheduledInterTxsByHash:  ste.mapScheduledInterTxsByHash,
+		mapScheduledMbHashes:        ste.mapScheduledMbHashes,
+		mapScheduledTxFees:          ste.mapScheduledTxFees,
+		mapScheduledTxGasAndFees:    ste.mapScheduledTxGasAndFees,
+		mapStorageAccessStats:       make(map[string]process.StorageAccessStat, len(ste.mapStorageAccessStats)),
+		failedScheduledTxHashes:     ste.failedScheduledTxHashes,
+		mapScheduledTxsByBlockType:  ste.mapScheduledTxsByBlockType,
+		mapScheduledGasPerShard:     ste.mapScheduledGasPerShard,
+		noOpScheduledTxHashes:       ste.noOpScheduledTxHashes,
+		projectedMiniBlockSize:      ste.projectedMiniBlockSize,
+		scheduledReceipts:           ste.scheduledReceipts,
+		mapScheduledReceipts:        ste.mapScheduledReceipts,
+		mapSeenInterTxHashes:        ste.mapSeenInterTxHashes,
+		duplicateInterTxHashes:      ste.duplicateInterTxHashes,
+		interTxsMarshalledSize:      ste.interTxsMarshalledSize,
+		numAttemptedScheduledTxs:    ste.numAttemptedScheduledTxs,
+		fingerprintData:             ste.fingerprintData,
+		executionFingerprint:        ste.executionFingerprint,
+		mapExecutionResults:         ste.mapExecutionResults,
+		computedScheduledRootHash:   ste.computedScheduledRootHash,
+		lastRolledBackHeaderHash:    ste.lastRolledBackHeaderHash,
+		resumableExecution:          ste.resumableExecution,
+	}
+
+	// the storage access stats are updated in place by the execution, while the other results are replaced
+	for txHash, stat := range ste.mapStorageAccessStats {
+		snapshot.mapStorageAccessStats[txHash] = stat
+	}
+
+	return snapshot
+}
+
+func (ste *scheduledTxsExecution) restoreDryRunSnapshot(snapshot *dryRunSnapshot) error {
+	err := ste.restorePreExecutionSnapshot(snapshot.preExecution)
+
+	ste.mapScheduledIntermediateTxs = snapshot.mapScheduledIntermediateTxs
+	ste.mapScheduledInterTxsByHash = snapshot.mapScheduledInterTxsByHash
+	ste.mapScheduledMbHashes = snapshot.mapScheduledMbHashes
+	ste.mapScheduledTxFees = snapshot.mapScheduledTxFees
+	ste.mapScheduledTxGasAndFees = snapshot.mapScheduledTxGasAndFees
+	ste.mapStorageAccessStats = snapshot.mapStorageAccessStats
+	ste.failedScheduledTxHashes = snapshot.failedScheduledTxHashes
+	ste.mapScheduledTxsByBlockType = snapshot.mapScheduledTxsByBlockType
+	ste.mapScheduledGasPerShard = snapshot.mapScheduledGasPerShard
+	ste.noOpScheduledTxHashes = snapshot.noOpScheduledTxHashes
+	ste.projectedMiniBlockSize = snapshot.projectedMiniBlockSize
+	ste.scheduledReceipts = snapshot.scheduledReceipts
+	ste.mapScheduledReceipts = snapshot.mapScheduledReceipts
+	ste.mapSeenInterTxHashes = snapshot.mapSeenInterTxHashes
+	ste.duplicateInterTxHashes = snapshot.duplicateInterTxHashes
+	ste.interTxsMarshalledSize = snapshot.interTxsMarshalledSize
+	ste.numAttemptedScheduledTxs = snapshot.numAttemptedScheduledTxs
+	ste.fingerprintData = snapshot.fingerprintData
+	ste.executionFingerprint = snapshot.executionFingerprint
+	ste.mapExecutionResults = snapshot.mapExecutionResults
+	ste.computedScheduledRootHash = snapshot.computedScheduledRootHash
+	ste.lastRolledBackHeaderHash = snapshot.lastRolledBackHeaderHash
+	ste.resumableExecution = snapshot.resumableExecution
+
+	return err
+}
@@ -0,0 +1,154 @@
+package preprocess
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ElrondNetwork/elrond-go-core/data"
+	"github.com/ElrondNetwork/elrond-go-core/data/block"
+	"github.com/ElrondNetwork/elrond-go-core/data/smartContractResult"
+	"github.com/ElrondNetwork/elrond-go-core/data/transaction"
+	"github.com/ElrondNetwork/elrond-go-core/marshal"
+	"github.com/ElrondNetwork/elrond-go/process"
+	"github.com/ElrondNetwork/elrond-go/process/mock"
+	"github.com/ElrondNetwork/elrond-go/testscommon"
+	"github.com/ElrondNetwork/elrond-go/testscommon/genericMocks"
+	"github.com/ElrondNetwork/elrond-go/testscommon/hashingMocks"
+	stateMock "github.com/ElrondNetwork/elrond-go/testscommon/state"
+	vmcommon "github.com/ElrondNetwork/elrond-vm-common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduledTxsExecution_DryRunAllShouldErr(t *testing.T) {
+	t.Parallel()
+
+	createArgs := func() ArgsScheduledTxsExecution {
+		return ArgsScheduledTxsExecution{
+			TxProcessor:      &testscommon.TxProcessorMock{},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+			Accounts:         &stateMock.AccountsStub{},
+		}
+	}
+
+	t.Run("nil accounts", func(t *testing.T) {
+		t.Parallel()
+
+		args := createArgs()
+		args.Accounts = nil
+		scheduledTxsExec, _ := NewScheduledTxsExecution(args)
+
+		scheduledInfo, err := scheduledTxsExec.DryRunAll(func() time.Duration { return time.Second })
+		assert.True(t, errors.Is(err, process.ErrNilAccountsAdapter))
+		assert.Nil(t, scheduledInfo)
+	})
+	t.Run("execution error should restore the state", func(t *testing.T) {
+		t.Parallel()
+
+		expectedErr := errors.New("expected error")
+		journalLen := 5
+		args := createArgs()
+		args.TxProcessor = &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(_ *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				journalLen++
+				return vmcommon.ExecutionFailed, expectedErr
+			},
+		}
+		args.Accounts = &stateMock.AccountsStub{
+			JournalLenCalled: func() int {
+				return journalLen
+			},
+			RevertToSnapshotCalled: func(snapshot int) error {
+				journalLen = snapshot
+				return nil
+			},
+		}
+		scheduledTxsExec, _ := NewScheduledTxsExecution(args)
+		scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+
+		scheduledInfo, err := scheduledTxsExec.DryRunAll(func() time.Duration { return time.Second })
+		assert.True(t, errors.Is(err, expectedErr))
+		assert.Nil(t, scheduledInfo)
+		assert.Equal(t, 5, journalLen)
+		assert.Equal(t, 1, len(scheduledTxsExec.GetScheduledTxs()))
+	})
+}
+
+func TestScheduledTxsExecution_DryRunAllShouldMatchExecuteAll(t *testing.T) {
+	t.Parallel()
+
+	mapIntermediateTxs := map[block.Type]map[string]data.TransactionHandler{
+		block.SmartContractResultBlock: {},
+	}
+	journalLen := 0
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(tx *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				journalLen++
+				scrHash := fmt.Sprintf("scrHash%d", tx.Nonce)
+				mapIntermediateTxs[block.SmartContractResultBlock][scrHash] = &smartContractResult.SmartContractResult{Nonce: tx.Nonce}
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator: &mock.TransactionCoordinatorMock{
+			GetAllIntermediateTxsCalled: func() map[block.Type]map[string]data.TransactionHandler {
+				mapCopy := make(map[block.Type]map[string]data.TransactionHandler)
+				for blockType, txs := range mapIntermediateTxs {
+					mapCopy[blockType] = make(map[string]data.TransactionHandler)
+					for txHash, tx := range txs {
+						mapCopy[blockType][txHash] = tx
+					}
+				}
+				return mapCopy
+			},
+		},
+		Storer:     genericMocks.NewStorerMock(),
+		Marshaller: &marshal.GogoProtoMarshalizer{},
+		Hasher:     &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{
+			SameShardCalled: func(_, _ []byte) bool {
+				return false
+			},
+		},
+		Accounts: &stateMock.AccountsStub{
+			JournalLenCalled: func() int {
+				return journalLen
+			},
+			RevertToSnapshotCalled: func(snapshot int) error {
+				journalLen = snapshot
+				return nil
+			},
+			RootHashCalled: func() ([]byte, error) {
+				return []byte(fmt.Sprintf("rootHash%d", journalLen)), nil
+			},
+		},
+	})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 1})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 2})
+	haveTime := func() time.Duration { return time.Second }
+
+	scheduledInfo, err := scheduledTxsExec.DryRunAll(haveTime)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("rootHash2"), scheduledInfo.RootHash)
+	assert.Equal(t, 2, len(scheduledInfo.IntermediateTxs[block.SmartContractResultBlock]))
+
+	assert.Equal(t, 0, journalLen)
+	assert.Nil(t, scheduledTxsExec.GetComputedScheduledRootHash())
+	assert.Equal(t, 0, len(scheduledTxsExec.GetScheduledIntermediateTxs()))
+	assert.Equal(t, 2, len(scheduledTxsExec.GetScheduledTxs()))
+
+	// the block processing drops the intermediate txs created by the dry run from the tx coordinator
+	mapIntermediateTxs[block.SmartContractResultBlock] = make(map[string]data.TransactionHandler)
+
+	err = scheduledTxsExec.ExecuteAll(haveTime)
+	require.Nil(t, err)
+	assert.Equal(t, scheduledTxsExec.GetComputedScheduledRootHash(), scheduledInfo.RootHash)
+	assert.Equal(t, scheduledTxsExec.GetScheduledIntermediateTxs(), scheduledInfo.IntermediateTxs)
+	assert.Equal(t, scheduledTxsExec.GetScheduledGasAndFees(), scheduledInfo.GasAndFees)
+}
@@ -2,8 +2,14 @@ package preprocess
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math/big"
 	"sort"
 	"sync"
 	"time"
@@ -12,26 +18,90 @@ import (
 	"github.com/ElrondNetwork/elrond-go-core/core/check"
 	"github.com/ElrondNetwork/elrond-go-core/data"
 	"github.com/ElrondNetwork/elrond-go-core/data/block"
+	"github.com/ElrondNetwork/elrond-go-core/data/receipt"
 	"github.com/ElrondNetwork/elrond-go-core/data/scheduled"
+	"github.com/ElrondNetwork/elrond-go-core/data/smartContractResult"
 	"github.com/ElrondNetwork/elrond-go-core/data/transaction"
 	"github.com/ElrondNetwork/elrond-go-core/hashing"
 	"github.com/ElrondNetwork/elrond-go-core/marshal"
+	"github.com/ElrondNetwork/elrond-go/common"
 	"github.com/ElrondNetwork/elrond-go/process"
 	"github.com/ElrondNetwork/elrond-go/sharding"
+	"github.com/ElrondNetwork/elrond-go/state"
 	"github.com/ElrondNetwork/elrond-go/storage"
+	"github.com/ElrondNetwork/elrond-go/storage/lrucache"
+	vmcommon "github.com/ElrondNetwork/elrond-vm-common"
 )
 
+// scheduledInfoFormatMarker prefixes the stored scheduled info which is not a plain marshalled object. A marshalled
+// object never starts with a zero byte, so the old plain blobs are still read correctly
+const scheduledInfoFormatMarker = byte(0)
+
+const scheduledInfoCompressedFormat = byte(1)
+
+// FailedScheduledTxsMode defines what happens with a scheduled tx whose execution ends with ErrFailedTransaction
+type FailedScheduledTxsMode uint8
+
+const (
+	// KeepFailedScheduledTxs keeps the failed tx in the scheduled set, together with its resulted invalid tx
+	KeepFailedScheduledTxs FailedScheduledTxsMode = iota
+	// DropFailedScheduledTxs removes the failed tx from the scheduled set and from the scheduled intermediate txs
+	DropFailedScheduledTxs
+)
+
+// ScheduledInfoField is a bitmask selecting the fields of a scheduled info
+type ScheduledInfoField uint8
+
+const (
+	// ScheduledInfoRootHash selects the scheduled root hash
+	ScheduledInfoRootHash ScheduledInfoField = 1 << iota
+	// ScheduledInfoIntermediateTxs selects the scheduled intermediate txs
+	ScheduledInfoIntermediateTxs
+	// ScheduledInfoGasAndFees selects the scheduled gas and fees
+	ScheduledInfoGasAndFees
+	// ScheduledInfoMiniBlocks selects the scheduled mini blocks
+	ScheduledInfoMiniBlocks
+)
+
+// AllScheduledInfoFields selects all the fields of a scheduled info
+const AllScheduledInfoFields = ScheduledInfoRootHash | ScheduledInfoIntermediateTxs | ScheduledInfoGasAndFees | ScheduledInfoMiniBlocks
+
+func (fields ScheduledInfoField) has(field ScheduledInfoField) bool {
+	return fields&field != 0
+}
+
 type intermediateTxInfo struct {
 	txHash    []byte
 	txHandler data.TransactionHandler
 }
 
+const (
+	traceLineTypeTx             = "tx"
+	traceLineTypeIntermediateTx = "intermediateTx"
+)
+
+type executionTraceLine struct {
+	Type        string `json:"type"`
+	Index       int    `json:"index"`
+	Hash        string `json:"hash"`
+	ReturnCode  string `json:"returnCode,omitempty"`
+	GasConsumed uint64 `json:"gasConsumed,omitempty"`
+	BlockType   string `json:"blockType,omitempty"`
+}
+
+type scheduledTxInfo struct {
+	txHash    []byte
+	txHandler data.TransactionHandler
+}
+
 type scheduledTxsExecution struct {
 	txProcessor                 process.TransactionProcessor
 	txCoordinator               process.TransactionCoordinator
 	mapScheduledTxs             map[string]data.TransactionHandler
 	mapScheduledIntermediateTxs map[block.Type][]data.TransactionHandler
+	mapScheduledInterTxsByHash  map[string]data.TransactionHandler
 	scheduledTxs                []data.TransactionHandler
+	scheduledTxHashes           [][]byte
 	scheduledMbs                block.MiniBlockSlice
 	mapScheduledMbHashes        map[string]struct{}
 	scheduledRootHash           []byte
@@ -41,51 +111,262 @@ type scheduledTxsExecution struct {
 	hasher                      hashing.Hasher
 	mutScheduledTxs             sync.RWMutex
 	shardCoordinator            sharding.Coordinator
+	onInitHandler               func()
+	postRollbackVerify          func(restoredRootHash []byte) error
+	feeHandler                  process.TransactionFeeHandler
+	mapDeveloperFeesPerContract map[string]*big.Int
+	mapScheduledTxFees          map[string]*big.Int
+	mapScheduledTxGasAndFees    map[string]scheduled.GasAndFees
+	lastRolledBackHeaderHash    []byte
+	accountsWarmer              process.AccountsWarmer
+	maxIntermediateTxs          uint32
+	compressor                  process.DataCompressor
+	executionOrderComparator    func(first data.TransactionHandler, second data.TransactionHandler) int
+	storageAccessMeter          process.StorageAccessMeter
+	mapStorageAccessStats       map[string]process.StorageAccessStat
+	executionGracePeriod        time.Duration
+	failedScheduledTxsMode      FailedScheduledTxsMode
+	failedScheduledTxHashes     [][]byte
+	mapScheduledTxsByBlockType  map[block.Type][][]byte
+	mapScheduledGasPerShard     map[uint32]uint64
+	accounts                    state.AccountsAdapter
+	simulation                  *ScheduledTxsSimulationComponents
+	mutSimulation               sync.Mutex
+	interTxsSizeEstimator       func(tx data.TransactionHandler) uint64
+	maxProjectedMiniBlockSize   uint64
+	projectedMiniBlockSize      uint64
+	retryOnRootHashMismatch     bool
+	parallelMiniBlocksHashing   bool
+	generateReceipts            bool
+	scheduledReceipts           []data.TransactionHandler
+	mapScheduledReceipts        map[string][]byte
+	streamIntermediateTxs       bool
+	orderBySenderNonceHash      bool
+	rejectDuplicateInterTxs     bool
+	mapSeenInterTxHashes        map[string]struct{}
+	duplicateInterTxHashes      [][]byte
+	intermediateTxsConsumer     func(txHash []byte, intermediateTxs map[block.Type][]data.TransactionHandler) error
+	rootHashVerifier            func(rootHash []byte) error
+	computedScheduledRootHash   []byte
+	noOpScheduledTxHashes       [][]byte
+	traceWriter                 io.Writer
+	gasHandler                  process.GasHandler
+	executionBudget             ExecutionBudget
+	maxInterTxsMarshalledSize   uint64
+	interTxsMarshalledSize      uint64
+	usePriorityQueue            bool
+	executionPriorityHandler    func(tx data.TransactionHandler) uint64
+	mutPendingScheduledTxs      sync.Mutex
+	pendingScheduledTxs         []*scheduledTxInfo
+	mapQueueableTxHashes        map[string]struct{}
+	acceptPendingScheduledTxs   bool
+	progressUpdateInterval      uint32
+	statusHandler               core.AppStatusHandler
+	maxFailureRatio             float64
+	minFailureSample            uint32
+	numAttemptedScheduledTxs    uint32
+	canonicalExecutionOrder     [][]byte
+	computeFingerprint          bool
+	fingerprintData             []byte
+	executionFingerprint        []byte
+	mapExecutionResults         map[string]error
+	lastSavedHeaderHash         []byte
+	lastSavedEpoch              core.OptionalUint32
+	lastSavedScheduledInfo      *process.ScheduledInfo
+	lastSavedDeltaChainLength   int
+	scheduledSCRsMarshaller     marshal.Marshalizer
+	scheduledInfoCache          storage.Cacher
+	gasBudget                   *scheduledGasBudget
+	resumableExecution          *resumableScheduledExecution
 }
 
-// NewScheduledTxsExecution creates a new object which handles the execution of scheduled transactions
-func NewScheduledTxsExecution(
-	txProcessor process.TransactionProcessor,
-	txCoordinator process.TransactionCoordinator,
-	storer storage.Storer,
-	marshaller marshal.Marshalizer,
-	hasher hashing.Hasher,
-	shardCoordinator sharding.Coordinator,
-) (*scheduledTxsExecution, error) {
+// ArgsScheduledTxsExecution holds the arguments needed to create a new scheduledTxsExecution instance
+type ArgsScheduledTxsExecution struct {
+	TxProcessor      process.TransactionProcessor
+	TxCoordinator    process.TransactionCoordinator
+	Storer           storage.Storer
+	Marshaller       marshal.Marshalizer
+	Hasher           hashing.Hasher
+	ShardCoordinator sharding.Coordinator
+	// MaxIntermediateTxs limits the number of resulted intermediate txs. Zero means no limit
+	MaxIntermediateTxs uint32
+	// Compressor is used when saving the scheduled info. Nil means the scheduled info is stored uncompressed
+	Compressor process.DataCompressor
+	// ScheduledSCRsMarshaller is the codec of the stored scheduled info, which can differ from the one used for the
+	// blocks. Nil means the marshaller is used
+	ScheduledSCRsMarshaller marshal.Marshalizer
+	// ScheduledInfoCacheSize is the number of scheduled infos, saved or read from storage, kept in memory by header hash.
+	// Zero means the scheduled info is always read from storage
+	ScheduledInfoCacheSize uint32
+	// ExecutionGracePeriod is the time a scheduled tx which started before the deadline is allowed to run after it. The
+	// execution only stops between txs, so the tx is never abandoned, but if it finishes later the execution fails with
+	// ErrTimeIsOut. Zero means the tx already started always runs to completion without failing the execution
+	ExecutionGracePeriod time.Duration
+	// FailedScheduledTxsMode defines what happens with the scheduled txs which end with ErrFailedTransaction
+	FailedScheduledTxsMode FailedScheduledTxsMode
+	// Accounts is used to compute the root hash resulted after the execution of the scheduled txs. Nil means that the
+	// scheduled root hash is only set from outside
+	Accounts state.AccountsAdapter
+	// Simulation holds the components used by SimulateAll. Nil means the simulation is not enabled
+	Simulation *ScheduledTxsSimulationComponents
+	// RetryOnRootHashMismatch enables a second execution of all the scheduled txs, from the pre-execution snapshot, when
+	// the root hash verifier reports a mismatch. It needs the accounts adapter
+	RetryOnRootHashMismatch bool
+	// ParallelMiniBlocksHashing enables marshalling and hashing the scheduled mini blocks in parallel, one go routine for
+	// each mini block type
+	ParallelMiniBlocksHashing bool
+	// MaxProjectedMiniBlockSize is the space budget for the intermediate txs projected by the intermediate txs size
+	// estimator. The execution stops before the scheduled tx which would exceed it and the not executed txs are removed
+	// from the scheduled txs and mini blocks. Zero means no limit
+	MaxProjectedMiniBlockSize uint64
+	// GenerateReceipts enables the generation of a receipt for each executed scheduled tx, added to the scheduled
+	// intermediate txs as a receipt block. The receipts of the dropped failed txs are not generated
+	GenerateReceipts bool
+	// StreamIntermediateTxs enables handing the intermediate txs resulted from each scheduled tx to the intermediate txs
+	// consumer, right after its execution, instead of accumulating them. The scheduled intermediate txs are then empty.
+	// It can not be used together with RetryOnRootHashMismatch, as the streamed txs can not be taken back
+	StreamIntermediateTxs bool
+	// OrderBySenderNonceHash enables executing the scheduled txs ordered by sender, nonce and hash, so that the execution
+	// order does not depend on the arrival order of the txs and is the same after a node restart. When enabled, the
+	// execution order comparator is not used
+	OrderBySenderNonceHash bool
+	// RejectDuplicateIntermediateTxs enables failing the execution with ErrDuplicateIntermediateTx when an intermediate tx
+	// hash is produced more than once. Otherwise the duplicates are only recorded and the last produced tx is kept
+	RejectDuplicateIntermediateTxs bool
+	// MaxIntermediateTxsMarshalledSize is the byte budget for the marshalled intermediate txs. The execution stops, with
+	// the results of the already executed txs, once the running estimate of their marshalled size exceeds it, and the
+	// not executed txs are removed from the scheduled txs and mini blocks. Zero means no limit
+	MaxIntermediateTxsMarshalledSize uint64
+	// UsePriorityQueue enables executing the scheduled txs by the priority given by the execution priority handler. The
+	// txs added during the execution are then queued as well, so a higher priority tx can still be executed before the
+	// lower priority txs not started yet
+	UsePriorityQueue bool
+	// AppStatusHandler is used to publish the metrics of each scheduled txs execution. It can also be set later with
+	// SetAppStatusHandler. Nil means no metrics are published
+	AppStatusHandler core.AppStatusHandler
+	// ProgressUpdateInterval is the number of executed scheduled txs after which the execution progress is published on
+	// the status handler. Zero means the progress is not published
+	ProgressUpdateInterval uint32
+	// MaxFailureRatio is the maximum ratio of failed to attempted scheduled txs. The execution is aborted with
+	// ErrScheduledFailureThresholdExceeded once it is exceeded, after at least MinFailureSample txs were attempted.
+	// Zero means the failure ratio is not checked
+	MaxFailureRatio float64
+	// MinFailureSample is the number of attempted scheduled txs after which the failure ratio is checked
+	MinFailureSample uint32
+	// ComputeExecutionFingerprint enables computing a hash over the tx hash, return code, gas consumed and intermediate
+	// tx hashes of each executed scheduled tx, in the execution order. Nodes executing the scheduled txs identically get
+	// the same fingerprint
+	ComputeExecutionFingerprint bool
+}
 
-	if check.IfNil(txProcessor) {
-		return nil, process.ErrNilTxProcessor
+// Validate checks the arguments and returns the first violation found
+func (args ArgsScheduledTxsExecution) Validate() error {
+	if check.IfNil(args.TxProcessor) {
+		return fmt.Errorf("%w in NewScheduledTxsExecution", process.ErrNilTxProcessor)
+	}
+	if check.IfNil(args.TxCoordinator) {
+		return fmt.Errorf("%w in NewScheduledTxsExecution", process.ErrNilTransactionCoordinator)
+	}
+	if check.IfNil(args.Storer) {
+		return fmt.Errorf("%w in NewScheduledTxsExecution", process.ErrNilStorage)
+	}
+	if check.IfNil(args.Marshaller) {
+		return fmt.Errorf("%w in NewScheduledTxsExecution", process.ErrNilMarshalizer)
+	}
+	if check.IfNil(args.Hasher) {
+		return fmt.Errorf("%w in NewScheduledTxsExecution", process.ErrNilHasher)
 	}
-	if check.IfNil(txCoordinator) {
-		return nil, process.ErrNilTransactionCoordinator
+	if check.IfNil(args.ShardCoordinator) {
+		return fmt.Errorf("%w in NewScheduledTxsExecution", process.ErrNilShardCoordinator)
 	}
-	if check.IfNil(storer) {
-		return nil, process.ErrNilStorage
+	if args.ExecutionGracePeriod < 0 {
+		return fmt.Errorf("%w in NewScheduledTxsExecution for ExecutionGracePeriod", process.ErrInvalidValue)
 	}
-	if check.IfNil(marshaller) {
-		return nil, process.ErrNilMarshalizer
+	if args.FailedScheduledTxsMode > DropFailedScheduledTxs {
+		return fmt.Errorf("%w in NewScheduledTxsExecution for FailedScheduledTxsMode", process.ErrInvalidValue)
 	}
-	if check.IfNil(hasher) {
-		return nil, process.ErrNilHasher
+	if args.RetryOnRootHashMismatch && check.IfNil(args.Accounts) {
+		return fmt.Errorf("%w in NewScheduledTxsExecution for RetryOnRootHashMismatch", process.ErrNilAccountsAdapter)
 	}
-	if check.IfNil(shardCoordinator) {
-		return nil, process.ErrNilShardCoordinator
+	if args.RetryOnRootHashMismatch && args.StreamIntermediateTxs {
+		return fmt.Errorf("%w in NewScheduledTxsExecution for RetryOnRootHashMismatch, streaming intermediate txs is not supported",
+			process.ErrInvalidValue)
+	}
+
+	if args.MaxFailureRatio < 0 || args.MaxFailureRatio > 1 {
+		return fmt.Errorf("%w in NewScheduledTxsExecution for MaxFailureRatio", process.ErrInvalidValue)
+	}
+
+	return checkScheduledTxsSimulationComponents(args.Simulation)
+}
+
+// NewScheduledTxsExecution creates a new object which handles the execution of scheduled transactions
+func NewScheduledTxsExecution(args ArgsScheduledTxsExecution) (*scheduledTxsExecution, error) {
+	err := args.Validate()
+	if err != nil {
+		return nil, err
 	}
 
 	ste := &scheduledTxsExecution{
-		txProcessor:                 txProcessor,
-		txCoordinator:               txCoordinator,
+		txProcessor:                 args.TxProcessor,
+		txCoordinator:               args.TxCoordinator,
 		mapScheduledTxs:             make(map[string]data.TransactionHandler),
 		mapScheduledIntermediateTxs: make(map[block.Type][]data.TransactionHandler),
+		mapScheduledInterTxsByHash:  make(map[string]data.TransactionHandler),
 		scheduledTxs:                make([]data.TransactionHandler, 0),
+		scheduledTxHashes:           make([][]byte, 0),
 		scheduledMbs:                make(block.MiniBlockSlice, 0),
 		mapScheduledMbHashes:        make(map[string]struct{}),
 		gasAndFees:                  process.GetZeroGasAndFees(),
-		storer:                      storer,
-		marshaller:                  marshaller,
-		hasher:                      hasher,
+		storer:                      args.Storer,
+		marshaller:                  args.Marshaller,
+		hasher:                      args.Hasher,
 		scheduledRootHash:           nil,
-		shardCoordinator:            shardCoordinator,
+		shardCoordinator:            args.ShardCoordinator,
+		mapDeveloperFeesPerContract: make(map[string]*big.Int),
+		mapScheduledTxFees:          make(map[string]*big.Int),
+		mapScheduledTxGasAndFees:    make(map[string]scheduled.GasAndFees),
+		mapStorageAccessStats:       make(map[string]process.StorageAccessStat),
+		maxIntermediateTxs:          args.MaxIntermediateTxs,
+		compressor:                  args.Compressor,
+		scheduledSCRsMarshaller:     args.ScheduledSCRsMarshaller,
+		executionGracePeriod:        args.ExecutionGracePeriod,
+		failedScheduledTxsMode:      args.FailedScheduledTxsMode,
+		accounts:                    args.Accounts,
+		simulation:                  args.Simulation,
+		maxProjectedMiniBlockSize:   args.MaxProjectedMiniBlockSize,
+		retryOnRootHashMismatch:     args.RetryOnRootHashMismatch,
+		parallelMiniBlocksHashing:   args.ParallelMiniBlocksHashing,
+		generateReceipts:            args.GenerateReceipts,
+		streamIntermediateTxs:       args.StreamIntermediateTxs,
+		orderBySenderNonceHash:      args.OrderBySenderNonceHash,
+		rejectDuplicateInterTxs:     args.RejectDuplicateIntermediateTxs,
+		maxInterTxsMarshalledSize:   args.MaxIntermediateTxsMarshalledSize,
+		usePriorityQueue:            args.UsePriorityQueue,
+		progressUpdateInterval:      args.ProgressUpdateInterval,
+		statusHandler:               args.AppStatusHandler,
+		maxFailureRatio:             args.MaxFailureRatio,
+		minFailureSample:            args.MinFailureSample,
+		computeFingerprint:          args.ComputeExecutionFingerprint,
+		mapExecutionResults:         make(map[string]error),
+		pendingScheduledTxs:         make([]*scheduledTxInfo, 0),
+		mapSeenInterTxHashes:        make(map[string]struct{}),
+		duplicateInterTxHashes:      make([][]byte, 0),
+		scheduledReceipts:           make([]data.TransactionHandler, 0),
+		mapScheduledReceipts:        make(map[string][]byte),
+		failedScheduledTxHashes:     make([][]byte, 0),
+		mapScheduledTxsByBlockType:  make(map[block.Type][][]byte),
+		mapScheduledGasPerShard:     make(map[uint32]uint64),
+		noOpScheduledTxHashes:       make([][]byte, 0),
+	}
+	if check.IfNil(ste.scheduledSCRsMarshaller) {
+		ste.scheduledSCRsMarshaller = args.Marshaller
+	}
+	if args.ScheduledInfoCacheSize > 0 {
+		ste.scheduledInfoCache, err = lrucache.NewCache(int(args.ScheduledInfoCacheSize))
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return ste, nil
@@ -97,21 +378,124 @@ func (ste *scheduledTxsExecution) Init() {
 	log.Debug("scheduledTxsExecution.Init", "num of last scheduled txs", len(ste.scheduledTxs))
 	ste.mapScheduledTxs = make(map[string]data.TransactionHandler)
 	ste.scheduledTxs = make([]data.TransactionHandler, 0)
+	ste.scheduledTxHashes = make([][]byte, 0)
+	ste.failedScheduledTxHashes = make([][]byte, 0)
+	ste.mapScheduledTxsByBlockType = make(map[block.Type][][]byte)
+	ste.mapScheduledGasPerShard = make(map[uint32]uint64)
+	ste.mapDeveloperFeesPerContract = make(map[string]*big.Int)
+	ste.mapScheduledTxFees = make(map[string]*big.Int)
+	ste.mapScheduledTxGasAndFees = make(map[string]scheduled.GasAndFees)
+	ste.mapStorageAccessStats = make(map[string]process.StorageAccessStat)
+	ste.computedScheduledRootHash = nil
+	ste.noOpScheduledTxHashes = make([][]byte, 0)
+	ste.projectedMiniBlockSize = 0
+	ste.scheduledReceipts = make([]data.TransactionHandler, 0)
+	ste.mapScheduledReceipts = make(map[string][]byte)
+	ste.mapSeenInterTxHashes = make(map[string]struct{})
+	ste.duplicateInterTxHashes = make([][]byte, 0)
+	ste.interTxsMarshalledSize = 0
+	ste.numAttemptedScheduledTxs = 0
+	ste.canonicalExecutionOrder = nil
+	ste.resumableExecution = nil
+	ste.fingerprintData = nil
+	ste.executionFingerprint = nil
+	ste.mapExecutionResults = make(map[string]error)
+	onInitHandler := ste.onInitHandler
 	ste.mutScheduledTxs.Unlock()
+
+	if onInitHandler != nil {
+		onInitHandler()
+	}
 }
 
-// AddScheduledTx method adds a scheduled transaction to be executed
+// AddScheduledTx method adds a scheduled transaction to be executed. While the scheduled txs are executed by priority,
+// the tx is queued for the execution in progress, unless it is already scheduled or queued
 func (ste *scheduledTxsExecution) AddScheduledTx(txHash []byte, tx data.TransactionHandler) bool {
+	numQueuedTxs, isExecuting := ste.addPendingScheduledTxsIfExecuting([][]byte{txHash}, []data.TransactionHandler{tx})
+	if isExecuting {
+		return numQueuedTxs == 1
+	}
+
+	ste.mutScheduledTxs.Lock()
+	defer ste.mutScheduledTxs.Unlock()
+
+	isAdded := ste.addScheduledTx(txHash, tx)
+	if isAdded {
+		ste.dropResumableExecution("scheduled tx added")
+	}
+
+	return isAdded
+}
+
+// AddScheduledTxs adds the given txs, in order, taking the lock only once. The already scheduled txs are skipped, as
+// AddScheduledTx does, and the number of added txs is returned
+func (ste *scheduledTxsExecution) AddScheduledTxs(txHashes [][]byte, txs []data.TransactionHandler) int {
+	if len(txHashes) != len(txs) {
+		log.Warn("scheduledTxsExecution.AddScheduledTxs: tx hashes and txs length mismatch",
+			"num tx hashes", len(txHashes), "num txs", len(txs))
+		return 0
+	}
+	numQueuedTxs, isExecuting := ste.addPendingScheduledTxsIfExecuting(txHashes, txs)
+	if isExecuting {
+		return numQueuedTxs
+	}
+
 	ste.mutScheduledTxs.Lock()
 	defer ste.mutScheduledTxs.Unlock()
 
+	numAddedTxs := 0
+	for index, txHash := range txHashes {
+		if ste.addScheduledTx(txHash, txs[index]) {
+			numAddedTxs++
+		}
+	}
+	if numAddedTxs > 0 {
+		ste.dropResumableExecution("scheduled txs added")
+	}
+
+	return numAddedTxs
+}
+
+// addPendingScheduledTxsIfExecuting queues the given txs for the execution in progress, if any, skipping the txs
+// already scheduled or queued. It returns the number of queued txs and true if an execution is in progress
+func (ste *scheduledTxsExecution) addPendingScheduledTxsIfExecuting(txHashes [][]byte, txs []data.TransactionHandler) (int, bool) {
+	ste.mutPendingScheduledTxs.Lock()
+	defer ste.mutPendingScheduledTxs.Unlock()
+
+	if !ste.acceptPendingScheduledTxs {
+		return 0, false
+	}
+
+	numQueuedTxs := 0
+	for index, txHash := range txHashes {
+		_, isQueued := ste.mapQueueableTxHashes[string(txHash)]
+		if isQueued {
+			ste.countDuplicateScheduledTx(txHash)
+			continue
+		}
+
+		ste.mapQueueableTxHashes[string(txHash)] = struct{}{}
+		ste.pendingScheduledTxs = append(ste.pendingScheduledTxs, &scheduledTxInfo{
+			txHash:    txHash,
+			txHandler: txs[index],
+		})
+		numQueuedTxs++
+		log.Trace("scheduledTxsExecution.Add: queued during execution", "tx hash", txHash)
+	}
+
+	return numQueuedTxs, true
+}
+
+func (ste *scheduledTxsExecution) addScheduledTx(txHash []byte, tx data.TransactionHandler) bool {
 	_, exist := ste.mapScheduledTxs[string(txHash)]
 	if exist {
+		ste.countDuplicateScheduledTx(txHash)
 		return false
 	}
 
 	ste.mapScheduledTxs[string(txHash)] = tx
 	ste.scheduledTxs = append(ste.scheduledTxs, tx)
+	ste.scheduledTxHashes = append(ste.scheduledTxHashes, txHash)
 
 	log.Trace("scheduledTxsExecution.Add", "tx hash", txHash, "num of scheduled txs", len(ste.scheduledTxs))
 	return true
@@ -126,6 +510,8 @@ func (ste *scheduledTxsExecution) AddScheduledMiniBlocks(miniBlocks block.MiniBl
 	for index, miniBlock := range miniBlocks {
 		ste.scheduledMbs[index] = miniBlock.Clone()
 	}
+	ste.lastRolledBackHeaderHash = nil
+	ste.dropResumableExecution("scheduled mini blocks added")
 
 	log.Debug("scheduledTxsExecution.AddMiniBlocks", "num of scheduled mbs", len(ste.scheduledMbs))
 }
@@ -140,7 +526,7 @@ func (ste *scheduledTxsExecution) Execute(txHash []byte) error {
 		return fmt.Errorf("%w: in scheduledTxsExecution.Execute", process.ErrMissingTransaction)
 	}
 
-	err := ste.execute(txHandler)
+	_, err := ste.execute(txHandler)
 	if err != nil && !errors.Is(err, process.ErrFailedTransaction) {
 		return err
 	}
@@ -150,202 +536,1526 @@ func (ste *scheduledTxsExecution) Execute(txHash []byte) error {
 
 // ExecuteAll method executes all the scheduled transactions
 func (ste *scheduledTxsExecution) ExecuteAll(haveTime func() time.Duration) error {
+	return ste.ExecuteAllWithContext(context.Background(), haveTime)
+}
+
+// ExecuteAllWithContext executes all the scheduled transactions, as ExecuteAll does, and also stops before the next
+// scheduled tx, returning the context error, once the given context is done
+func (ste *scheduledTxsExecution) ExecuteAllWithContext(ctx context.Context, haveTime func() time.Duration) error {
+	if ctx == nil {
+		return process.ErrNilContext
+	}
+
 	ste.mutScheduledTxs.Lock()
 	defer ste.mutScheduledTxs.Unlock()
 
+	return ste.executeAll(ctx, haveTime)
+}
+
+// ExecuteAllWithResults executes all the scheduled transactions, as ExecuteAll does, and also returns the result of each
+// executed transaction, keyed by tx hash. When the execution is aborted, the results hold the transactions executed up
+// to, and including, the one which caused the abort
+func (ste *scheduledTxsExecution) ExecuteAllWithResults(haveTime func() time.Duration) (map[string]error, error) {
+	ste.mutScheduledTxs.Lock()
+	defer ste.mutScheduledTxs.Unlock()
+
+	err := ste.executeAll(context.Background(), haveTime)
+
+	mapExecutionResults := make(map[string]error, len(ste.mapExecutionResults))
+	for txHash, txErr := range ste.mapExecutionResults {
+		mapExecutionResults[txHash] = txErr
+	}
+
+	return mapExecutionResults, err
+}
+
+func (ste *scheduledTxsExecution) executeAll(ctx context.Context, haveTime func() time.Duration) error {
+	ste.dropResumableExecution("new execution started")
+	ste.mapExecutionResults = make(map[string]error)
 	if haveTime == nil {
 		return process.ErrNilHaveTimeHandler
 	}
+	if ste.streamIntermediateTxs && ste.intermediateTxsConsumer == nil {
+		return process.ErrNilIntermediateTxsConsumer
+	}
+	defer ste.publishExecutionMetrics(time.Now())
+
+	ste.computedScheduledRootHash = nil
+	ste.executionFingerprint = nil
+	ste.lastRolledBackHeaderHash = nil
+	if len(ste.scheduledTxs) == 0 {
+		ste.resetScheduledIntermediateTxs()
+		if len(ste.canonicalExecutionOrder) > 0 {
+			return fmt.Errorf("%w: tx hash %x", process.ErrUnexpectedScheduledTx, ste.canonicalExecutionOrder[0])
+		}
+		return ste.setScheduledMiniBlockHashes()
+	}
 
 	log.Debug("scheduledTxsExecution.ExecuteAll", "num of scheduled txs to be executed", len(ste.scheduledTxs))
 
-	mapAllIntermediateTxsBeforeScheduledExecution := ste.txCoordinator.GetAllIntermediateTxs()
-
-	for _, txHandler := range ste.scheduledTxs {
-		if haveTime() <= 0 {
-			return process.ErrTimeIsOut
-		}
+	stopAccountsWarming := ste.startAccountsWarming(haveTime)
+	defer stopAccountsWarming()
 
-		err := ste.execute(txHandler)
-		if err != nil {
-			log.Debug("scheduledTxsExecution.ExecuteAll: execute(txHandler)",
-				"nonce", txHandler.GetNonce(),
-				"value", txHandler.GetValue(),
-				"gas limit", txHandler.GetGasLimit(),
-				"gas price", txHandler.GetGasPrice(),
-				"sender address", txHandler.GetSndAddr(),
-				"receiver address", txHandler.GetRcvAddr(),
-				"data", string(txHandler.GetData()),
-				"error", err.Error())
-			if !errors.Is(err, process.ErrFailedTransaction) {
-				return err
-			}
-		}
+	mapAllIntermediateTxsBeforeScheduledExecution := ste.txCoordinator.GetAllIntermediateTxs()
+	if ste.rootHashVerifier == nil {
+		return ste.executeAllOnce(ctx, haveTime, mapAllIntermediateTxsBeforeScheduledExecution)
 	}
 
-	mapAllIntermediateTxsAfterScheduledExecution := ste.txCoordinator.GetAllIntermediateTxs()
-	ste.computeScheduledIntermediateTxs(mapAllIntermediateTxsBeforeScheduledExecution, mapAllIntermediateTxsAfterScheduledExecution)
-	err := ste.setScheduledMiniBlockHashes()
+	var snapshot *preExecutionSnapshot
+	if ste.retryOnRootHashMismatch {
+		snapshot = ste.createPreExecutionSnapshot()
+	}
+	err := ste.executeAllOnce(ctx, haveTime, mapAllIntermediateTxsBeforeScheduledExecution)
 	if err != nil {
 		return err
 	}
+	err = ste.rootHashVerifier(ste.computedScheduledRootHash)
+	if err == nil || !ste.retryOnRootHashMismatch {
+		return err
+	}
 
-	return nil
-}
+	firstRootHash := ste.computedScheduledRootHash
+	log.Warn("scheduledTxsExecution.ExecuteAll: scheduled root hash mismatch, retrying from the pre-execution snapshot",
+		"root hash", firstRootHash,
+		"error", err)
 
-func (ste *scheduledTxsExecution) setScheduledMiniBlockHashes() error {
-	ste.mapScheduledMbHashes = make(map[string]struct{})
-	for index := range ste.scheduledMbs {
-		mbHash, err := core.CalculateHash(ste.marshaller, ste.hasher, ste.scheduledMbs[index])
-		if err != nil {
-			return err
-		}
-		ste.mapScheduledMbHashes[string(mbHash)] = struct{}{}
+	err = ste.restorePreExecutionSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+	err = ste.executeAllOnce(ctx, haveTime, mapAllIntermediateTxsBeforeScheduledExecution)
+	if err != nil {
+		return err
 	}
+	err = ste.rootHashVerifier(ste.computedScheduledRootHash)
 
-	return nil
+	log.Debug("scheduledTxsExecution.ExecuteAll: retried after scheduled root hash mismatch",
+		"first root hash", firstRootHash,
+		"second root hash", ste.computedScheduledRootHash,
+		"error", err)
+
+	return err
 }
 
-func (ste *scheduledTxsExecution) execute(txHandler data.TransactionHandler) error {
-	tx, ok := txHandler.(*transaction.Transaction)
-	if !ok {
-		return fmt.Errorf("%w: in scheduledTxsExecution.execute", process.ErrWrongTypeAssertion)
+func isContextDone(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	default:
+		return false
 	}
-
-	_, err := ste.txProcessor.ProcessTransaction(tx)
-	return err
 }
 
-func (ste *scheduledTxsExecution) computeScheduledIntermediateTxs(
-	mapAllIntermediateTxsBeforeScheduledExecution map[block.Type]map[string]data.TransactionHandler,
-	mapAllIntermediateTxsAfterScheduledExecution map[block.Type]map[string]data.TransactionHandler,
-) {
-	numScheduledIntermediateTxs := 0
-	ste.mapScheduledIntermediateTxs = make(map[block.Type][]data.TransactionHandler)
-	for blockType, allIntermediateTxsAfterScheduledExecution := range mapAllIntermediateTxsAfterScheduledExecution {
-		intermediateTxsInfo := ste.getAllIntermediateTxsAfterScheduledExecution(
-			mapAllIntermediateTxsBeforeScheduledExecution[blockType],
-			allIntermediateTxsAfterScheduledExecution,
-			blockType,
-		)
-		if len(intermediateTxsInfo) == 0 {
-			continue
-		}
+// countDuplicateScheduledTx counts a tx scheduled again, which helps detecting the proposers including the same tx twice
+func (ste *scheduledTxsExecution) countDuplicateScheduledTx(txHash []byte) {
+	log.Trace("scheduledTxsExecution.Add: duplicate", "tx hash", txHash)
+	if check.IfNil(ste.statusHandler) {
+		return
+	}
 
-		sort.Slice(intermediateTxsInfo, func(a, b int) bool {
-			return bytes.Compare(intermediateTxsInfo[a].txHash, intermediateTxsInfo[b].txHash) < 0
-		})
+	ste.statusHandler.Increment(common.MetricScheduledTxsDuplicates)
+}
 
-		if blockType == block.InvalidBlock {
-			ste.removeInvalidTxsFromScheduledMiniBlocks(intermediateTxsInfo)
-		}
+func (ste *scheduledTxsExecution) publishExecutionMetrics(startTime time.Time) {
+	if check.IfNil(ste.statusHandler) {
+		return
+	}
 
-		ste.mapScheduledIntermediateTxs[blockType] = make([]data.TransactionHandler, len(intermediateTxsInfo))
-		for index, interTxInfo := range intermediateTxsInfo {
-			ste.mapScheduledIntermediateTxs[blockType][index] = interTxInfo.txHandler
-			log.Trace("scheduledTxsExecution.computeScheduledIntermediateTxs", "blockType", blockType, "sender", ste.mapScheduledIntermediateTxs[blockType][index].GetSndAddr(), "receiver", ste.mapScheduledIntermediateTxs[blockType][index].GetRcvAddr())
+	numFailed := 0
+	for _, txErr := range ste.mapExecutionResults {
+		if txErr != nil {
+			numFailed++
 		}
-
-		numScheduledIntermediateTxs += len(intermediateTxsInfo)
 	}
 
-	log.Debug("scheduledTxsExecution.computeScheduledIntermediateTxs", "num of scheduled intermediate txs created", numScheduledIntermediateTxs)
+	ste.statusHandler.SetUInt64Value(common.MetricScheduledTxsExecuted, uint64(len(ste.mapExecutionResults)))
+	ste.statusHandler.SetUInt64Value(common.MetricScheduledTxsFailed, uint64(numFailed))
+	ste.statusHandler.SetUInt64Value(common.MetricScheduledTxsExecutionTimeMs, uint64(time.Since(startTime).Milliseconds()))
 }
 
-func (ste *scheduledTxsExecution) removeInvalidTxsFromScheduledMiniBlocks(intermediateTxsInfo []*intermediateTxInfo) {
-	log.Debug("scheduledTxsExecution.removeInvalidTxsFromScheduledMiniBlocks", "num of invalid txs", len(intermediateTxsInfo))
+func (ste *scheduledTxsExecution) executeAllOnce(
+	ctx context.Context,
+	haveTime func() time.Duration,
+	mapAllIntermediateTxsBeforeScheduledExecution map[block.Type]map[string]data.TransactionHandler,
+) error {
+	ste.resetExecutionState()
 
-	numInvalidTxsRemoved := 0
-	for _, interTxInfo := range intermediateTxsInfo {
-		for index, miniBlock := range ste.scheduledMbs {
-			indexOfTxHashInMiniBlock := getIndexOfTxHashInMiniBlock(interTxInfo.txHash, miniBlock)
-			if indexOfTxHashInMiniBlock >= 0 {
-				log.Trace("scheduledTxsExecution.removeInvalidTxsFromScheduledMiniBlocks", "tx hash", interTxInfo.txHash)
-				ste.scheduledMbs[index].TxHashes = append(miniBlock.TxHashes[:indexOfTxHashInMiniBlock], miniBlock.TxHashes[indexOfTxHashInMiniBlock+1:]...)
-				numInvalidTxsRemoved++
-				break
-			}
-		}
+	_, err := ste.executeScheduledTxs(ctx, haveTime, mapAllIntermediateTxsBeforeScheduledExecution, false)
+	if err != nil {
+		return err
 	}
 
-	resultedScheduledMbs := make(block.MiniBlockSlice, 0)
-	for _, miniBlock := range ste.scheduledMbs {
-		if len(miniBlock.TxHashes) == 0 {
-			continue
-		}
-		resultedScheduledMbs = append(resultedScheduledMbs, miniBlock)
-	}
-	ste.scheduledMbs = resultedScheduledMbs
+	return ste.completeExecution(mapAllIntermediateTxsBeforeScheduledExecution)
+}
 
-	log.Debug("scheduledTxsExecution.removeInvalidTxsFromScheduledMiniBlocks", "num of invalid txs removed", numInvalidTxsRemoved)
+// resetExecutionState drops the state computed by a previous execution of the scheduled txs
+func (ste *scheduledTxsExecution) resetExecutionState() {
+	ste.computedScheduledRootHash = nil
+	ste.failedScheduledTxHashes = make([][]byte, 0)
+	ste.mapScheduledTxsByBlockType = make(map[block.Type][][]byte)
+	ste.mapScheduledGasPerShard = make(map[uint32]uint64)
+	ste.noOpScheduledTxHashes = make([][]byte, 0)
+	ste.projectedMiniBlockSize = 0
+	ste.scheduledReceipts = make([]data.TransactionHandler, 0)
+	ste.mapScheduledReceipts = make(map[string][]byte)
+	ste.mapSeenInterTxHashes = make(map[string]struct{})
+	ste.duplicateInterTxHashes = make([][]byte, 0)
+	ste.mapScheduledTxFees = make(map[string]*big.Int)
+	ste.mapScheduledTxGasAndFees = make(map[string]scheduled.GasAndFees)
+	ste.interTxsMarshalledSize = 0
+	ste.numAttemptedScheduledTxs = 0
+	ste.fingerprintData = nil
+	ste.executionFingerprint = nil
+	ste.mapExecutionResults = make(map[string]error)
 }
 
-func getIndexOfTxHashInMiniBlock(txHash []byte, miniBlock *block.MiniBlock) int {
-	indexOfTxHashInMiniBlock := -1
-	for index, hash := range miniBlock.TxHashes {
-		if bytes.Equal(txHash, hash) {
-			indexOfTxHashInMiniBlock = index
-			break
+// completeExecution computes, after all the scheduled txs were executed, the scheduled intermediate txs, mini blocks
+// and root hash
+func (ste *scheduledTxsExecution) completeExecution(
+	mapAllIntermediateTxsBeforeScheduledExecution map[block.Type]map[string]data.TransactionHandler,
+) error {
+	mapAllIntermediateTxsAfterScheduledExecution := ste.txCoordinator.GetAllIntermediateTxs()
+	ste.computeScheduledGasPerShard(
+		mapAllIntermediateTxsBeforeScheduledExecution[block.SmartContractResultBlock],
+		mapAllIntermediateTxsAfterScheduledExecution[block.SmartContractResultBlock],
+	)
+	var err error
+	if ste.streamIntermediateTxs {
+		ste.resetScheduledIntermediateTxs()
+	} else {
+		err = ste.computeScheduledIntermediateTxs(mapAllIntermediateTxsBeforeScheduledExecution, mapAllIntermediateTxsAfterScheduledExecution)
+		if err != nil {
+			return err
 		}
+		ste.addScheduledReceiptsToIntermediateTxs()
+	}
+	ste.removeNotExecutedScheduledTxs()
+	err = ste.setScheduledMiniBlockHashes()
+	if err != nil {
+		return err
 	}
 
-	return indexOfTxHashInMiniBlock
+	ste.removeFailedScheduledTxs()
+	ste.writeIntermediateTxsTrace()
+	ste.computeExecutionFingerprint()
+
+	return ste.computeScheduledRootHash()
 }
 
-func (ste *scheduledTxsExecution) getAllIntermediateTxsAfterScheduledExecution(
-	allIntermediateTxsBeforeScheduledExecution map[string]data.TransactionHandler,
-	allIntermediateTxsAfterScheduledExecution map[string]data.TransactionHandler,
-	blockType block.Type,
-) []*intermediateTxInfo {
-	intermediateTxsInfo := make([]*intermediateTxInfo, 0)
-	for txHash, txHandler := range allIntermediateTxsAfterScheduledExecution {
-		_, txExists := allIntermediateTxsBeforeScheduledExecution[txHash]
-		if txExists {
-			continue
-		}
+type preExecutionSnapshot struct {
+	accountsSnapshot            int
+	mapScheduledTxs             map[string]data.TransactionHandler
+	scheduledTxs                []data.TransactionHandler
+	scheduledTxHashes           [][]byte
+	scheduledMbs                block.MiniBlockSlice
+	mapDeveloperFeesPerContract map[string]*big.Int
+}
 
-		isInShardUnsignedTx := ste.shardCoordinator.SameShard(txHandler.GetSndAddr(), txHandler.GetRcvAddr()) &&
-			(blockType == block.ReceiptBlock || blockType == block.SmartContractResultBlock)
-		if isInShardUnsignedTx {
-			log.Trace("scheduledTxsExecution.getAllIntermediateTxsAfterScheduledExecution: intra shard unsigned tx skipped", "hash", []byte(txHash))
-			continue
-		}
+// createPreExecutionSnapshot saves the state changed by the execution of the scheduled txs, so that the execution can
+// be retried from scratch
+func (ste *scheduledTxsExecution) createPreExecutionSnapshot() *preExecutionSnapshot {
+	snapshot := &preExecutionSnapshot{
+		accountsSnapshot:            ste.accounts.JournalLen(),
+		mapScheduledTxs:             make(map[string]data.TransactionHandler, len(ste.mapScheduledTxs)),
+		scheduledTxs:                make([]data.TransactionHandler, len(ste.scheduledTxs)),
+		scheduledTxHashes:           make([][]byte, len(ste.scheduledTxHashes)),
+		scheduledMbs:                make(block.MiniBlockSlice, len(ste.scheduledMbs)),
+		mapDeveloperFeesPerContract: make(map[string]*big.Int, len(ste.mapDeveloperFeesPerContract)),
+	}
 
-		intermediateTxsInfo = append(intermediateTxsInfo, &intermediateTxInfo{
-			txHash:    []byte(txHash),
-			txHandler: txHandler,
-		})
+	for txHash, txHandler := range ste.mapScheduledTxs {
+		snapshot.mapScheduledTxs[txHash] = txHandler
+	}
+	copy(snapshot.scheduledTxs, ste.scheduledTxs)
+	copy(snapshot.scheduledTxHashes, ste.scheduledTxHashes)
+	for index, miniBlock := range ste.scheduledMbs {
+		snapshot.scheduledMbs[index] = miniBlock.Clone()
+	}
+	for contractAddress, developerFees := range ste.mapDeveloperFeesPerContract {
+		snapshot.mapDeveloperFeesPerContract[contractAddress] = big.NewInt(0).Set(developerFees)
 	}
 
-	return intermediateTxsInfo
+	return snapshot
 }
 
-// GetScheduledTxs gets all the scheduled txs to be executed
-func (ste *scheduledTxsExecution) GetScheduledTxs() []data.TransactionHandler {
-	ste.mutScheduledTxs.RLock()
-	defer ste.mutScheduledTxs.RUnlock()
+func (ste *scheduledTxsExecution) restorePreExecutionSnapshot(snapshot *preExecutionSnapshot) error {
+	err := ste.accounts.RevertToSnapshot(snapshot.accountsSnapshot)
+	if err != nil {
+		return err
+	}
 
-	scheduledTxs := make([]data.TransactionHandler, len(ste.scheduledTxs))
-	for index, scheduledTx := range ste.scheduledTxs {
-		scheduledTxs[index] = scheduledTx
-		log.Trace("scheduledTxsExecution.GetScheduledTxs", "sender", scheduledTxs[index].GetSndAddr(), "receiver", scheduledTxs[index].GetRcvAddr())
+	if !check.IfNil(ste.feeHandler) {
+		ste.feeHandler.RevertFees(snapshot.scheduledTxHashes)
 	}
 
-	log.Debug("scheduledTxsExecution.GetScheduledTxs", "num of scheduled txs", len(scheduledTxs))
+	ste.mapScheduledTxs = snapshot.mapScheduledTxs
+	ste.scheduledTxs = snapshot.scheduledTxs
+	ste.scheduledTxHashes = snapshot.scheduledTxHashes
+	ste.scheduledMbs = snapshot.scheduledMbs
+	ste.mapDeveloperFeesPerContract = snapshot.mapDeveloperFeesPerContract
 
-	return scheduledTxs
+	return nil
 }
 
-// GetScheduledIntermediateTxs gets the resulted intermediate txs after the execution of scheduled transactions
-func (ste *scheduledTxsExecution) GetScheduledIntermediateTxs() map[block.Type][]data.TransactionHandler {
-	ste.mutScheduledTxs.RLock()
-	defer ste.mutScheduledTxs.RUnlock()
+// executeScheduledTxs executes the scheduled txs not executed yet. When canPause is set, the execution is paused instead
+// of aborted if the time is out, in which case true is returned
+func (ste *scheduledTxsExecution) executeScheduledTxs(
+	ctx context.Context,
+	haveTime func() time.Duration,
+	mapAllIntermediateTxsBeforeScheduledExecution map[block.Type]map[string]data.TransactionHandler,
+	canPause bool,
+) (bool, error) {
+	mapAllIntermediateTxsBeforeTx := mapAllIntermediateTxsBeforeScheduledExecution
+	numStreamedIntermediateTxs := 0
+	consumedGas := uint64(0)
+	startTime := time.Now()
+	hardDeadline := ste.computeHardDeadline(startTime, haveTime)
+	iterator, err := ste.createScheduledTxsIterator()
+	if err != nil {
+		return false, err
+	}
+	defer ste.stopAcceptingPendingScheduledTxs()
+	progressReporter := ste.createProgressReporter()
+	defer progressReporter.close()
 
-	numScheduledIntermediateTxs := 0
-	mapScheduledIntermediateTxs := make(map[block.Type][]data.TransactionHandler)
-	for blockType, scheduledIntermediateTxs := range ste.mapScheduledIntermediateTxs {
-		if len(scheduledIntermediateTxs) == 0 {
-			continue
+	for index := 0; ; index++ {
+		txInfo, ok := iterator.next()
+		if !ok {
+			break
+		}
+
+		if ste.isExecutedBeforeResume(txInfo.txHash) {
+			continue
+		}
+
+		txHandler := txInfo.txHandler
+		if isContextDone(ctx) {
+			log.Debug("scheduledTxsExecution.ExecuteAll: execution canceled",
+				"num of not executed txs", iterator.numRemaining()+1)
+			return false, ctx.Err()
+		}
+		if haveTime() <= 0 {
+			if canPause {
+				log.Debug("scheduledTxsExecution.ExecuteAllResumable: execution paused",
+					"num of not executed txs", iterator.numRemaining()+1)
+				return true, nil
+			}
+			return false, process.ErrTimeIsOut
+		}
+		if !ste.isWithinExecutionBudget(consumedGas, time.Since(startTime)) {
+			log.Debug("scheduledTxsExecution.ExecuteAll: execution budget exhausted",
+				"consumed gas", consumedGas,
+				"intermediate txs marshalled size", ste.interTxsMarshalledSize,
+				"num of not executed txs", iterator.numRemaining()+1)
+			return false, nil
+		}
+		if !ste.fitsGasBudget(consumedGas, txHandler) {
+			log.Debug("scheduledTxsExecution.ExecuteAll: gas budget exhausted",
+				"consumed gas", consumedGas,
+				"max gas", ste.gasBudget.maxGas,
+				"num of not executed txs", iterator.numRemaining()+1)
+			return false, nil
+		}
+		if !ste.reserveProjectedMiniBlockSpace(txHandler) {
+			log.Debug("scheduledTxsExecution.ExecuteAll: projected mini block space exhausted",
+				"projected mini block size", ste.projectedMiniBlockSize,
+				"max projected mini block size", ste.maxProjectedMiniBlockSize,
+				"num of not executed txs", iterator.numRemaining()+1)
+			return false, nil
+		}
+
+		developerFeesBeforeExecution := ste.getCurrentDeveloperFees()
+		accumulatedFeesBeforeExecution := ste.getCurrentAccumulatedFees()
+		storageAccessStatBeforeExecution := ste.getCurrentStorageAccessStat()
+		numIntermediateTxsBeforeExecution := ste.getCurrentNumIntermediateTxs()
+		returnCode, err := ste.executeWithinDeadline(txHandler, hardDeadline)
+		ste.mapExecutionResults[string(txInfo.txHash)] = err
+		if errors.Is(err, process.ErrTimeIsOut) {
+			log.Debug("scheduledTxsExecution.ExecuteAll: execution aborted",
+				"tx hash", txInfo.txHash,
+				"grace period", ste.executionGracePeriod)
+			return false, err
+		}
+		ste.numAttemptedScheduledTxs++
+		gasConsumed, errGasConsumed := ste.getGasConsumed(txInfo.txHash)
+		if errGasConsumed != nil {
+			log.Warn("scheduledTxsExecution.ExecuteAll: halted on gas refund anomaly", "error", errGasConsumed)
+			return false, errGasConsumed
+		}
+		consumedGas += gasConsumed
+		developerFees := ste.computeDeveloperFeesForTx(developerFeesBeforeExecution)
+		ste.addDeveloperFeesForContract(txHandler.GetRcvAddr(), developerFees)
+		ste.setFeeForTx(txInfo.txHash, accumulatedFeesBeforeExecution)
+		ste.setGasAndFeesForTx(txInfo.txHash, developerFees)
+		ste.setStorageAccessStatForTx(txInfo.txHash, storageAccessStatBeforeExecution)
+		ste.writeTxTrace(index, txInfo.txHash, returnCode, gasConsumed)
+		ste.classifyNoOpTx(txInfo.txHash, numIntermediateTxsBeforeExecution, gasConsumed)
+		if err != nil {
+			log.Debug("scheduledTxsExecution.ExecuteAll: execute(txHandler)",
+				"nonce", txHandler.GetNonce(),
+				"value", txHandler.GetValue(),
+				"gas limit", txHandler.GetGasLimit(),
+				"gas price", txHandler.GetGasPrice(),
+				"sender address", txHandler.GetSndAddr(),
+				"receiver address", txHandler.GetRcvAddr(),
+				"data", string(txHandler.GetData()),
+				"error", err.Error())
+			if !errors.Is(err, process.ErrFailedTransaction) {
+				return false, err
+			}
+
+			ste.failedScheduledTxHashes = append(ste.failedScheduledTxHashes, txInfo.txHash)
+			ste.mapScheduledTxsByBlockType[block.InvalidBlock] = append(ste.mapScheduledTxsByBlockType[block.InvalidBlock], txInfo.txHash)
+		} else {
+			ste.mapScheduledTxsByBlockType[block.TxBlock] = append(ste.mapScheduledTxsByBlockType[block.TxBlock], txInfo.txHash)
+		}
+		errFailureThreshold := ste.checkFailureThreshold()
+		if errFailureThreshold != nil {
+			log.Debug("scheduledTxsExecution.ExecuteAll: execution aborted", "error", errFailureThreshold)
+			return false, errFailureThreshold
+		}
+
+		scheduledReceipt, err := ste.createScheduledReceiptIfNeeded(txInfo, returnCode, err != nil)
+		if err != nil {
+			return false, err
+		}
+
+		if ste.isPerTxIntermediateTxsTrackingNeeded() {
+			mapAllIntermediateTxsAfterTx := ste.txCoordinator.GetAllIntermediateTxs()
+			if ste.streamIntermediateTxs {
+				numStreamedIntermediateTxs, err = ste.streamIntermediateTxsOfTx(
+					txInfo.txHash,
+					mapAllIntermediateTxsBeforeTx,
+					mapAllIntermediateTxsAfterTx,
+					scheduledReceipt,
+					numStreamedIntermediateTxs,
+				)
+				if err != nil {
+					return false, err
+				}
+			}
+			err = ste.addIntermediateTxsMarshalledSize(mapAllIntermediateTxsBeforeTx, mapAllIntermediateTxsAfterTx)
+			if err != nil {
+				return false, err
+			}
+			ste.addToExecutionFingerprint(txInfo.txHash, returnCode, gasConsumed, mapAllIntermediateTxsBeforeTx, mapAllIntermediateTxsAfterTx)
+			mapAllIntermediateTxsBeforeTx = mapAllIntermediateTxsAfterTx
+		}
+
+		progressReporter.report(index+1, index+1+iterator.numRemaining(), haveTime())
+	}
+
+	return false, nil
+}
+
+// createScheduledReceiptIfNeeded creates the receipt of an executed scheduled tx, holding the fee charged for it and
+// the return code of its execution. The receipt is accumulated, unless the intermediate txs are streamed
+func (ste *scheduledTxsExecution) createScheduledReceiptIfNeeded(
+	txInfo *scheduledTxInfo,
+	returnCode vmcommon.ReturnCode,
+	failed bool,
+) (data.TransactionHandler, error) {
+	if !ste.generateReceipts {
+		return nil, nil
+	}
+	if failed && ste.failedScheduledTxsMode == DropFailedScheduledTxs {
+		return nil, nil
+	}
+
+	fee := big.NewInt(0)
+	txFee, ok := ste.mapScheduledTxFees[string(txInfo.txHash)]
+	if ok {
+		fee.Set(txFee)
+	}
+
+	rpt := &receipt.Receipt{
+		Value:   fee,
+		SndAddr: txInfo.txHandler.GetSndAddr(),
+		Data:    []byte(returnCode.String()),
+		TxHash:  txInfo.txHash,
+	}
+	marshalledReceipt, err := ste.marshaller.Marshal(rpt)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ste.streamIntermediateTxs {
+		ste.scheduledReceipts = append(ste.scheduledReceipts, rpt)
+	}
+	ste.mapScheduledReceipts[string(txInfo.txHash)] = marshalledReceipt
+
+	return rpt, nil
+}
+
+// streamIntermediateTxsOfTx hands the intermediate txs resulted from the execution of the given scheduled tx, together
+// with its receipt, if any, to the intermediate txs consumer. It returns the number of intermediate txs streamed so far
+func (ste *scheduledTxsExecution) streamIntermediateTxsOfTx(
+	txHash []byte,
+	mapAllIntermediateTxsBeforeTx map[block.Type]map[string]data.TransactionHandler,
+	mapAllIntermediateTxsAfterTx map[block.Type]map[string]data.TransactionHandler,
+	scheduledReceipt data.TransactionHandler,
+	numStreamedIntermediateTxs int,
+) (int, error) {
+	numIntermediateTxs := 0
+	intermediateTxs := make(map[block.Type][]data.TransactionHandler)
+	for blockType, allIntermediateTxsAfterTx := range mapAllIntermediateTxsAfterTx {
+		intermediateTxsInfo := ste.getSortedScheduledIntermediateTxsInfo(
+			mapAllIntermediateTxsBeforeTx[blockType],
+			allIntermediateTxsAfterTx,
+			blockType,
+		)
+		err := ste.checkDuplicateIntermediateTxs(mapAllIntermediateTxsBeforeTx[blockType], allIntermediateTxsAfterTx, intermediateTxsInfo)
+		if err != nil {
+			return numStreamedIntermediateTxs, err
+		}
+		if len(intermediateTxsInfo) == 0 {
+			continue
+		}
+
+		intermediateTxs[blockType] = make([]data.TransactionHandler, len(intermediateTxsInfo))
+		for index, interTxInfo := range intermediateTxsInfo {
+			intermediateTxs[blockType][index] = interTxInfo.txHandler
+		}
+		numIntermediateTxs += len(intermediateTxsInfo)
+	}
+	if !check.IfNil(scheduledReceipt) {
+		intermediateTxs[block.ReceiptBlock] = append(intermediateTxs[block.ReceiptBlock], scheduledReceipt)
+	}
+
+	isMaxIntermediateTxsExceeded := ste.maxIntermediateTxs > 0 &&
+		numStreamedIntermediateTxs+numIntermediateTxs > int(ste.maxIntermediateTxs)
+	if isMaxIntermediateTxsExceeded {
+		return numStreamedIntermediateTxs, fmt.Errorf("%w: more than %d intermediate txs", process.ErrTooManyScheduledIntermediateTxs, ste.maxIntermediateTxs)
+	}
+	if len(intermediateTxs) == 0 {
+		return numStreamedIntermediateTxs, nil
+	}
+
+	log.Trace("scheduledTxsExecution.streamIntermediateTxsOfTx", "tx hash", txHash, "num of intermediate txs", numIntermediateTxs)
+
+	return numStreamedIntermediateTxs + numIntermediateTxs, ste.intermediateTxsConsumer(txHash, intermediateTxs)
+}
+
+func (ste *scheduledTxsExecution) addScheduledReceiptsToIntermediateTxs() {
+	if len(ste.scheduledReceipts) == 0 {
+		return
+	}
+
+	ste.mapScheduledIntermediateTxs[block.ReceiptBlock] = append(ste.mapScheduledIntermediateTxs[block.ReceiptBlock], ste.scheduledReceipts...)
+	for _, scheduledReceipt := range ste.scheduledReceipts {
+		ste.indexScheduledIntermediateTx(scheduledReceipt)
+	}
+
+	log.Debug("scheduledTxsExecution.addScheduledReceiptsToIntermediateTxs", "num of scheduled receipts", len(ste.scheduledReceipts))
+}
+
+func (ste *scheduledTxsExecution) isWithinExecutionBudget(consumedGas uint64, elapsed time.Duration) bool {
+	isMaxMarshalledSizeExceeded := ste.maxInterTxsMarshalledSize > 0 &&
+		ste.interTxsMarshalledSize > ste.maxInterTxsMarshalledSize
+	if isMaxMarshalledSizeExceeded {
+		return false
+	}
+	if check.IfNil(ste.executionBudget) {
+		return true
+	}
+
+	return ste.executionBudget.ShouldContinue(consumedGas, elapsed)
+}
+
+func (ste *scheduledTxsExecution) isPerTxIntermediateTxsTrackingNeeded() bool {
+	return ste.streamIntermediateTxs || ste.maxInterTxsMarshalledSize > 0 || ste.computeFingerprint
+}
+
+// addIntermediateTxsMarshalledSize adds the marshalled size of the new intermediate txs to the running estimate of the
+// marshalled size of the scheduled intermediate txs
+func (ste *scheduledTxsExecution) addIntermediateTxsMarshalledSize(
+	mapAllIntermediateTxsBefore map[block.Type]map[string]data.TransactionHandler,
+	mapAllIntermediateTxsAfter map[block.Type]map[string]data.TransactionHandler,
+) error {
+	if ste.maxInterTxsMarshalledSize == 0 {
+		return nil
+	}
+
+	for blockType, allIntermediateTxsAfter := range mapAllIntermediateTxsAfter {
+		intermediateTxsInfo := ste.getAllIntermediateTxsAfterScheduledExecution(mapAllIntermediateTxsBefore[blockType], allIntermediateTxsAfter, blockType)
+		for _, interTxInfo := range intermediateTxsInfo {
+			marshalledTx, err := ste.marshaller.Marshal(interTxInfo.txHandler)
+			if err != nil {
+				return err
+			}
+
+			ste.interTxsMarshalledSize += uint64(len(marshalledTx))
+		}
+	}
+
+	return nil
+}
+
+// addToExecutionFingerprint appends the outcome of an executed scheduled tx to the fingerprint data. The hashes are length
+// prefixed and the intermediate tx hashes are sorted, so that the data is the same for identical executions
+func (ste *scheduledTxsExecution) addToExecutionFingerprint(
+	txHash []byte,
+	returnCode vmcommon.ReturnCode,
+	gasConsumed uint64,
+	mapAllIntermediateTxsBefore map[block.Type]map[string]data.TransactionHandler,
+	mapAllIntermediateTxsAfter map[block.Type]map[string]data.TransactionHandler,
+) {
+	if !ste.computeFingerprint {
+		return
+	}
+
+	intermediateTxHashes := make([][]byte, 0)
+	for blockType, allIntermediateTxsAfter := range mapAllIntermediateTxsAfter {
+		for interTxHash := range allIntermediateTxsAfter {
+			_, existedBefore := mapAllIntermediateTxsBefore[blockType][interTxHash]
+			if !existedBefore {
+				intermediateTxHashes = append(intermediateTxHashes, []byte(interTxHash))
+			}
+		}
+	}
+	sort.Slice(intermediateTxHashes, func(a, b int) bool {
+		return bytes.Compare(intermediateTxHashes[a], intermediateTxHashes[b]) < 0
+	})
+
+	ste.fingerprintData = appendLengthPrefixed(ste.fingerprintData, txHash)
+	ste.fingerprintData = appendUint64(ste.fingerprintData, uint64(returnCode))
+	ste.fingerprintData = appendUint64(ste.fingerprintData, gasConsumed)
+	ste.fingerprintData = appendUint64(ste.fingerprintData, uint64(len(intermediateTxHashes)))
+	for _, interTxHash := range intermediateTxHashes {
+		ste.fingerprintData = appendLengthPrefixed(ste.fingerprintData, interTxHash)
+	}
+}
+
+func (ste *scheduledTxsExecution) computeExecutionFingerprint() {
+	if !ste.computeFingerprint {
+		return
+	}
+
+	ste.executionFingerprint = ste.hasher.Compute(string(ste.fingerprintData))
+	ste.fingerprintData = nil
+	log.Debug("scheduledTxsExecution.ExecuteAll", "execution fingerprint", ste.executionFingerprint)
+}
+
+func appendLengthPrefixed(buff []byte, value []byte) []byte {
+	buff = appendUint64(buff, uint64(len(value)))
+	return append(buff, value...)
+}
+
+func appendUint64(buff []byte, value uint64) []byte {
+	var encoded [8]byte
+	binary.BigEndian.PutUint64(encoded[:], value)
+	return append(buff, encoded[:]...)
+}
+
+// checkFailureThreshold returns ErrScheduledFailureThresholdExceeded when the failure ratio of the attempted scheduled
+// txs exceeds the max failure ratio, as a mostly failing execution points to a systemic problem
+func (ste *scheduledTxsExecution) checkFailureThreshold() error {
+	if ste.maxFailureRatio == 0 || ste.numAttemptedScheduledTxs < ste.minFailureSample {
+		return nil
+	}
+
+	failureRatio := ste.getScheduledFailureRatio()
+	if failureRatio <= ste.maxFailureRatio {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %d failed txs out of %d attempted txs, failure ratio %v, max failure ratio %v",
+		process.ErrScheduledFailureThresholdExceeded, len(ste.failedScheduledTxHashes), ste.numAttemptedScheduledTxs,
+		failureRatio, ste.maxFailureRatio)
+}
+
+func (ste *scheduledTxsExecution) getScheduledFailureRatio() float64 {
+	if ste.numAttemptedScheduledTxs == 0 {
+		return 0
+	}
+
+	return float64(len(ste.failedScheduledTxHashes)) / float64(ste.numAttemptedScheduledTxs)
+}
+
+func (ste *scheduledTxsExecution) createProgressReporter() *scheduledTxsProgressReporter {
+	if ste.progressUpdateInterval == 0 || check.IfNil(ste.statusHandler) {
+		return nil
+	}
+
+	return newScheduledTxsProgressReporter(ste.statusHandler, int(ste.progressUpdateInterval))
+}
+
+func (ste *scheduledTxsExecution) createScheduledTxsIterator() (scheduledTxsIterator, error) {
+	if ste.canonicalExecutionOrder != nil {
+		scheduledTxsInfo, err := ste.getScheduledTxsInCanonicalOrder()
+		if err != nil {
+			return nil, err
+		}
+
+		return newSliceScheduledTxsIterator(scheduledTxsInfo), nil
+	}
+
+	scheduledTxsInfo := ste.getScheduledTxsInExecutionOrder()
+	if !ste.usePriorityQueue {
+		return newSliceScheduledTxsIterator(scheduledTxsInfo), nil
+	}
+
+	// the scheduled txs can not be read while queueing, as the execution holds their lock, so their hashes are copied
+	mapQueueableTxHashes := make(map[string]struct{}, len(ste.mapScheduledTxs))
+	for txHash := range ste.mapScheduledTxs {
+		mapQueueableTxHashes[txHash] = struct{}{}
+	}
+
+	ste.mutPendingScheduledTxs.Lock()
+	ste.mapQueueableTxHashes = mapQueueableTxHashes
+	ste.acceptPendingScheduledTxs = true
+	ste.mutPendingScheduledTxs.Unlock()
+
+	return newPriorityScheduledTxsIterator(scheduledTxsInfo, ste.executionPriorityHandler, ste.pullPendingScheduledTxs), nil
+}
+
+// getScheduledTxsInCanonicalOrder returns the scheduled txs in the order set from the block. The scheduled txs which are
+// not referenced by the block are not executed
+func (ste *scheduledTxsExecution) getScheduledTxsInCanonicalOrder() ([]*scheduledTxInfo, error) {
+	scheduledTxsInfo := make([]*scheduledTxInfo, 0, len(ste.canonicalExecutionOrder))
+	mapOrderedTxHashes := make(map[string]struct{}, len(ste.canonicalExecutionOrder))
+	for _, txHash := range ste.canonicalExecutionOrder {
+		_, isDuplicate := mapOrderedTxHashes[string(txHash)]
+		if isDuplicate {
+			return nil, fmt.Errorf("%w: duplicate tx hash %x in the execution order", process.ErrUnexpectedScheduledTx, txHash)
+		}
+		mapOrderedTxHashes[string(txHash)] = struct{}{}
+
+		txHandler, found := ste.mapScheduledTxs[string(txHash)]
+		if !found {
+			return nil, fmt.Errorf("%w: tx hash %x", process.ErrUnexpectedScheduledTx, txHash)
+		}
+
+		scheduledTxsInfo = append(scheduledTxsInfo, &scheduledTxInfo{
+			txHash:    txHash,
+			txHandler: txHandler,
+		})
+	}
+
+	numNotOrderedTxs := len(ste.scheduledTxs) - len(scheduledTxsInfo)
+	if numNotOrderedTxs > 0 {
+		log.Debug("scheduledTxsExecution.ExecuteAll: scheduled txs not referenced by the execution order",
+			"num of not executed txs", numNotOrderedTxs)
+	}
+
+	return scheduledTxsInfo, nil
+}
+
+// pullPendingScheduledTxs adds the txs queued during the execution to the scheduled txs and returns the ones which were
+// not already scheduled
+func (ste *scheduledTxsExecution) pullPendingScheduledTxs() []*scheduledTxInfo {
+	ste.mutPendingScheduledTxs.Lock()
+	pendingScheduledTxs := ste.pendingScheduledTxs
+	ste.pendingScheduledTxs = make([]*scheduledTxInfo, 0)
+	ste.mutPendingScheduledTxs.Unlock()
+
+	addedScheduledTxs := make([]*scheduledTxInfo, 0, len(pendingScheduledTxs))
+	for _, txInfo := range pendingScheduledTxs {
+		if ste.addScheduledTx(txInfo.txHash, txInfo.txHandler) {
+			addedScheduledTxs = append(addedScheduledTxs, txInfo)
+		}
+	}
+
+	return addedScheduledTxs
+}
+
+// stopAcceptingPendingScheduledTxs ends the queueing of the added txs. The txs queued after the last pull are added to
+// the scheduled txs without being executed, so they are removed with the other not executed txs once the execution
+// completes
+func (ste *scheduledTxsExecution) stopAcceptingPendingScheduledTxs() {
+	if !ste.usePriorityQueue {
+		return
+	}
+
+	ste.mutPendingScheduledTxs.Lock()
+	ste.acceptPendingScheduledTxs = false
+	ste.mapQueueableTxHashes = nil
+	ste.mutPendingScheduledTxs.Unlock()
+
+	_ = ste.pullPendingScheduledTxs()
+}
+
+// reserveProjectedMiniBlockSpace adds the estimated size of the intermediate txs of the given tx to the projected mini
+// block size and returns true, unless this would exceed the space budget
+func (ste *scheduledTxsExecution) reserveProjectedMiniBlockSpace(txHandler data.TransactionHandler) bool {
+	if ste.interTxsSizeEstimator == nil {
+		return true
+	}
+
+	projectedMiniBlockSize := ste.projectedMiniBlockSize + ste.interTxsSizeEstimator(txHandler)
+	if ste.maxProjectedMiniBlockSize > 0 && projectedMiniBlockSize > ste.maxProjectedMiniBlockSize {
+		return false
+	}
+
+	ste.projectedMiniBlockSize = projectedMiniBlockSize
+	return true
+}
+
+// computeScheduledRootHash stores the root hash of the accounts after the execution of the scheduled txs, if an
+// accounts adapter was provided
+func (ste *scheduledTxsExecution) computeScheduledRootHash() error {
+	if check.IfNil(ste.accounts) {
+		return nil
+	}
+
+	rootHash, err := ste.accounts.RootHash()
+	if err != nil {
+		return err
+	}
+
+	ste.computedScheduledRootHash = rootHash
+	log.Debug("scheduledTxsExecution.computeScheduledRootHash", "computed scheduled root hash", rootHash)
+
+	return nil
+}
+
+// computeHardDeadline returns the deadline of the execution plus the grace period, computed once for the whole
+// execution. The zero time is returned if no grace period is set
+func (ste *scheduledTxsExecution) computeHardDeadline(startTime time.Time, haveTime func() time.Duration) time.Time {
+	if ste.executionGracePeriod == 0 {
+		return time.Time{}
+	}
+
+	return startTime.Add(haveTime() + ste.executionGracePeriod)
+}
+
+// executeWithinDeadline executes the given tx, which always runs to completion, as the tx processor can not be stopped
+// while it changes the accounts, so the execution only stops between txs. When a hard deadline is set and the tx
+// finishes after it, ErrTimeIsOut is returned, so that the execution is aborted
+func (ste *scheduledTxsExecution) executeWithinDeadline(
+	txHandler data.TransactionHandler,
+	hardDeadline time.Time,
+) (vmcommon.ReturnCode, error) {
+	if hardDeadline.IsZero() {
+		return ste.execute(txHandler)
+	}
+
+	returnCode, err := ste.execute(txHandler)
+	if time.Now().After(hardDeadline) {
+		return returnCode, fmt.Errorf("%w: scheduled tx did not finish in the grace period", process.ErrTimeIsOut)
+	}
+
+	return returnCode, err
+}
+
+// getScheduledTxsInExecutionOrder returns the scheduled txs in the order they were added, unless an execution order
+// comparator is set. In this case, the txs which are equal for the comparator are ordered by their hashes, so that all
+// the nodes execute the scheduled txs in the same order
+func (ste *scheduledTxsExecution) getScheduledTxsInExecutionOrder() []*scheduledTxInfo {
+	scheduledTxsInfo := make([]*scheduledTxInfo, len(ste.scheduledTxs))
+	for index, txHandler := range ste.scheduledTxs {
+		scheduledTxsInfo[index] = &scheduledTxInfo{
+			txHash:    ste.scheduledTxHashes[index],
+			txHandler: txHandler,
+		}
+	}
+
+	if ste.orderBySenderNonceHash {
+		sort.Slice(scheduledTxsInfo, func(a, b int) bool {
+			return isBeforeBySenderNonceHash(scheduledTxsInfo[a], scheduledTxsInfo[b])
+		})
+
+		return scheduledTxsInfo
+	}
+	if ste.executionOrderComparator == nil {
+		return scheduledTxsInfo
+	}
+
+	sort.SliceStable(scheduledTxsInfo, func(a, b int) bool {
+		result := ste.executionOrderComparator(scheduledTxsInfo[a].txHandler, scheduledTxsInfo[b].txHandler)
+		if result != 0 {
+			return result < 0
+		}
+
+		return bytes.Compare(scheduledTxsInfo[a].txHash, scheduledTxsInfo[b].txHash) < 0
+	})
+
+	return scheduledTxsInfo
+}
+
+func isBeforeBySenderNonceHash(first *scheduledTxInfo, second *scheduledTxInfo) bool {
+	result := bytes.Compare(first.txHandler.GetSndAddr(), second.txHandler.GetSndAddr())
+	if result != 0 {
+		return result < 0
+	}
+	if first.txHandler.GetNonce() != second.txHandler.GetNonce() {
+		return first.txHandler.GetNonce() < second.txHandler.GetNonce()
+	}
+
+	return bytes.Compare(first.txHash, second.txHash) < 0
+}
+
+// startAccountsWarming loads in background the accounts used by the scheduled txs, in their execution order, while there
+// is still time left. The returned function stops the warming, if it is not already finished, and waits for the account
+// being warmed, so that the accounts warmer is no longer used after it returns
+func (ste *scheduledTxsExecution) startAccountsWarming(haveTime func() time.Duration) func() {
+	if check.IfNil(ste.accountsWarmer) {
+		return func() {}
+	}
+
+	addresses := make([][]byte, 0, 2*len(ste.scheduledTxs))
+	mapAddresses := make(map[string]struct{})
+	for _, txHandler := range ste.scheduledTxs {
+		for _, address := range [][]byte{txHandler.GetSndAddr(), txHandler.GetRcvAddr()} {
+			_, exists := mapAddresses[string(address)]
+			if exists || len(address) == 0 {
+				continue
+			}
+
+			mapAddresses[string(address)] = struct{}{}
+			addresses = append(addresses, address)
+		}
+	}
+
+	chanStop := make(chan struct{})
+	chanDone := make(chan struct{})
+	accountsWarmer := ste.accountsWarmer
+	go func() {
+		defer close(chanDone)
+
+		for _, address := range addresses {
+			select {
+			case <-chanStop:
+				return
+			default:
+			}
+
+			if haveTime() <= 0 {
+				return
+			}
+
+			accountsWarmer.WarmAccount(address)
+		}
+	}()
+
+	return func() {
+		close(chanStop)
+		<-chanDone
+	}
+}
+
+func (ste *scheduledTxsExecution) setScheduledMiniBlockHashes() error {
+	if ste.parallelMiniBlocksHashing {
+		return ste.setScheduledMiniBlockHashesInParallel()
+	}
+
+	ste.mapScheduledMbHashes = make(map[string]struct{})
+	for index := range ste.scheduledMbs {
+		mbHash, err := core.CalculateHash(ste.marshaller, ste.hasher, ste.scheduledMbs[index])
+		if err != nil {
+			return err
+		}
+		ste.mapScheduledMbHashes[string(mbHash)] = struct{}{}
+	}
+
+	return nil
+}
+
+type miniBlockHashesResult struct {
+	hashes [][]byte
+	err    error
+}
+
+// setScheduledMiniBlockHashesInParallel marshals and hashes the scheduled mini blocks in one go routine for each mini
+// block type. The results are merged in the order of the mini block types, so the first error returned is the same on
+// all the nodes
+func (ste *scheduledTxsExecution) setScheduledMiniBlockHashesInParallel() error {
+	mapMiniBlocksPerType := make(map[block.Type]block.MiniBlockSlice)
+	for _, miniBlock := range ste.scheduledMbs {
+		mapMiniBlocksPerType[miniBlock.Type] = append(mapMiniBlocksPerType[miniBlock.Type], miniBlock)
+	}
+
+	miniBlockTypes := make([]block.Type, 0, len(mapMiniBlocksPerType))
+	for miniBlockType := range mapMiniBlocksPerType {
+		miniBlockTypes = append(miniBlockTypes, miniBlockType)
+	}
+	sort.Slice(miniBlockTypes, func(a, b int) bool {
+		return miniBlockTypes[a] < miniBlockTypes[b]
+	})
+
+	results := make([]miniBlockHashesResult, len(miniBlockTypes))
+	wg := sync.WaitGroup{}
+	wg.Add(len(miniBlockTypes))
+	for index, miniBlockType := range miniBlockTypes {
+		go func(result *miniBlockHashesResult, miniBlocks block.MiniBlockSlice) {
+			defer wg.Done()
+
+			result.hashes = make([][]byte, 0, len(miniBlocks))
+			for _, miniBlock := range miniBlocks {
+				mbHash, err := core.CalculateHash(ste.marshaller, ste.hasher, miniBlock)
+				if err != nil {
+					result.err = err
+					return
+				}
+				result.hashes = append(result.hashes, mbHash)
+			}
+		}(&results[index], mapMiniBlocksPerType[miniBlockType])
+	}
+	wg.Wait()
+
+	ste.mapScheduledMbHashes = make(map[string]struct{})
+	for _, result := range results {
+		if result.err != nil {
+			ste.mapScheduledMbHashes = make(map[string]struct{})
+			return result.err
+		}
+		for _, mbHash := range result.hashes {
+			ste.mapScheduledMbHashes[string(mbHash)] = struct{}{}
+		}
+	}
+
+	return nil
+}
+
+func (ste *scheduledTxsExecution) execute(txHandler data.TransactionHandler) (vmcommon.ReturnCode, error) {
+	tx, ok := txHandler.(*transaction.Transaction)
+	if !ok {
+		return vmcommon.ExecutionFailed, fmt.Errorf("%w: in scheduledTxsExecution.execute", process.ErrWrongTypeAssertion)
+	}
+
+	return ste.txProcessor.ProcessTransaction(tx)
+}
+
+func (ste *scheduledTxsExecution) getCurrentDeveloperFees() *big.Int {
+	if check.IfNil(ste.feeHandler) {
+		return nil
+	}
+
+	return ste.feeHandler.GetDeveloperFees()
+}
+
+func (ste *scheduledTxsExecution) computeDeveloperFeesForTx(developerFeesBeforeExecution *big.Int) *big.Int {
+	if check.IfNil(ste.feeHandler) || developerFeesBeforeExecution == nil {
+		return nil
+	}
+
+	return big.NewInt(0).Sub(ste.feeHandler.GetDeveloperFees(), developerFeesBeforeExecution)
+}
+
+func (ste *scheduledTxsExecution) addDeveloperFeesForContract(contractAddress []byte, developerFees *big.Int) {
+	if developerFees == nil || developerFees.Sign() <= 0 {
+		return
+	}
+
+	accumulatedDeveloperFees, ok := ste.mapDeveloperFeesPerContract[string(contractAddress)]
+	if !ok {
+		accumulatedDeveloperFees = big.NewInt(0)
+		ste.mapDeveloperFeesPerContract[string(contractAddress)] = accumulatedDeveloperFees
+	}
+
+	accumulatedDeveloperFees.Add(accumulatedDeveloperFees, developerFees)
+}
+
+func (ste *scheduledTxsExecution) getCurrentAccumulatedFees() *big.Int {
+	if check.IfNil(ste.feeHandler) {
+		return nil
+	}
+
+	return ste.feeHandler.GetAccumulatedFees()
+}
+
+func (ste *scheduledTxsExecution) setFeeForTx(txHash []byte, accumulatedFeesBeforeExecution *big.Int) {
+	if check.IfNil(ste.feeHandler) || accumulatedFeesBeforeExecution == nil {
+		return
+	}
+
+	ste.mapScheduledTxFees[string(txHash)] = big.NewInt(0).Sub(ste.feeHandler.GetAccumulatedFees(), accumulatedFeesBeforeExecution)
+}
+
+func (ste *scheduledTxsExecution) getCurrentStorageAccessStat() process.StorageAccessStat {
+	if check.IfNil(ste.storageAccessMeter) {
+		return process.StorageAccessStat{}
+	}
+
+	return ste.storageAccessMeter.GetStorageAccessStat()
+}
+
+func (ste *scheduledTxsExecution) setStorageAccessStatForTx(txHash []byte, storageAccessStatBeforeExecution process.StorageAccessStat) {
+	if check.IfNil(ste.storageAccessMeter) {
+		return
+	}
+
+	storageAccessStat := ste.storageAccessMeter.GetStorageAccessStat()
+	ste.mapStorageAccessStats[string(txHash)] = process.StorageAccessStat{
+		NumReads:  storageAccessStat.NumReads - storageAccessStatBeforeExecution.NumReads,
+		NumWrites: storageAccessStat.NumWrites - storageAccessStatBeforeExecution.NumWrites,
+	}
+}
+
+// resetScheduledIntermediateTxs drops the scheduled intermediate txs, together with their index by hash
+func (ste *scheduledTxsExecution) resetScheduledIntermediateTxs() {
+	ste.mapScheduledIntermediateTxs = make(map[block.Type][]data.TransactionHandler)
+	ste.mapScheduledInterTxsByHash = make(map[string]data.TransactionHandler)
+}
+
+// indexScheduledIntermediateTx adds a scheduled intermediate tx to the index by hash, computing its hash
+func (ste *scheduledTxsExecution) indexScheduledIntermediateTx(intermediateTx data.TransactionHandler) {
+	intermediateTxHash, err := core.CalculateHash(ste.marshaller, ste.hasher, intermediateTx)
+	if err != nil {
+		log.Debug("scheduledTxsExecution.indexScheduledIntermediateTx: CalculateHash", "error", err.Error())
+		return
+	}
+
+	ste.mapScheduledInterTxsByHash[string(intermediateTxHash)] = intermediateTx
+}
+
+func (ste *scheduledTxsExecution) computeScheduledIntermediateTxs(
+	mapAllIntermediateTxsBeforeScheduledExecution map[block.Type]map[string]data.TransactionHandler,
+	mapAllIntermediateTxsAfterScheduledExecution map[block.Type]map[string]data.TransactionHandler,
+) error {
+	numScheduledIntermediateTxs := 0
+	ste.resetScheduledIntermediateTxs()
+	ste.mapSeenInterTxHashes = make(map[string]struct{})
+	ste.duplicateInterTxHashes = make([][]byte, 0)
+	for blockType, allIntermediateTxsAfterScheduledExecution := range mapAllIntermediateTxsAfterScheduledExecution {
+		intermediateTxsInfo := ste.getSortedScheduledIntermediateTxsInfo(
+			mapAllIntermediateTxsBeforeScheduledExecution[blockType],
+			allIntermediateTxsAfterScheduledExecution,
+			blockType,
+		)
+		err := ste.checkDuplicateIntermediateTxs(
+			mapAllIntermediateTxsBeforeScheduledExecution[blockType],
+			allIntermediateTxsAfterScheduledExecution,
+			intermediateTxsInfo,
+		)
+		if err != nil {
+			ste.resetScheduledIntermediateTxs()
+			return err
+		}
+		if len(intermediateTxsInfo) == 0 {
+			continue
+		}
+
+		isMaxIntermediateTxsExceeded := ste.maxIntermediateTxs > 0 &&
+			numScheduledIntermediateTxs+len(intermediateTxsInfo) > int(ste.maxIntermediateTxs)
+		if isMaxIntermediateTxsExceeded {
+			ste.resetScheduledIntermediateTxs()
+			return fmt.Errorf("%w: more than %d intermediate txs", process.ErrTooManyScheduledIntermediateTxs, ste.maxIntermediateTxs)
+		}
+
+		ste.mapScheduledIntermediateTxs[blockType] = make([]data.TransactionHandler, len(intermediateTxsInfo))
+		for index, interTxInfo := range intermediateTxsInfo {
+			ste.mapScheduledIntermediateTxs[blockType][index] = interTxInfo.txHandler
+			ste.mapScheduledInterTxsByHash[string(interTxInfo.txHash)] = interTxInfo.txHandler
+			log.Trace("scheduledTxsExecution.computeScheduledIntermediateTxs", "blockType", blockType, "sender", ste.mapScheduledIntermediateTxs[blockType][index].GetSndAddr(), "receiver", ste.mapScheduledIntermediateTxs[blockType][index].GetRcvAddr())
+		}
+
+		numScheduledIntermediateTxs += len(intermediateTxsInfo)
+	}
+
+	log.Debug("scheduledTxsExecution.computeScheduledIntermediateTxs", "num of scheduled intermediate txs created", numScheduledIntermediateTxs)
+
+	return nil
+}
+
+// checkDuplicateIntermediateTxs records the intermediate tx hashes produced more than once: the hashes already produced
+// for another block type, and the hashes of the existing intermediate txs replaced by a different tx. An error is
+// returned for the first duplicate, if the duplicates are rejected
+func (ste *scheduledTxsExecution) checkDuplicateIntermediateTxs(
+	allIntermediateTxsBefore map[string]data.TransactionHandler,
+	allIntermediateTxsAfter map[string]data.TransactionHandler,
+	intermediateTxsInfo []*intermediateTxInfo,
+) error {
+	duplicateHashes := make([][]byte, 0)
+	for txHash, txHandlerBefore := range allIntermediateTxsBefore {
+		txHandlerAfter, found := allIntermediateTxsAfter[txHash]
+		if found && txHandlerAfter != txHandlerBefore {
+			duplicateHashes = append(duplicateHashes, []byte(txHash))
+		}
+	}
+	for _, interTxInfo := range intermediateTxsInfo {
+		_, isSeen := ste.mapSeenInterTxHashes[string(interTxInfo.txHash)]
+		if isSeen {
+			duplicateHashes = append(duplicateHashes, interTxInfo.txHash)
+			continue
+		}
+		ste.mapSeenInterTxHashes[string(interTxInfo.txHash)] = struct{}{}
+	}
+	if len(duplicateHashes) == 0 {
+		return nil
+	}
+
+	log.Debug("scheduledTxsExecution.checkDuplicateIntermediateTxs", "num of duplicate intermediate txs", len(duplicateHashes))
+	ste.duplicateInterTxHashes = append(ste.duplicateInterTxHashes, duplicateHashes...)
+	if ste.rejectDuplicateInterTxs {
+		return fmt.Errorf("%w: hash %x", process.ErrDuplicateIntermediateTx, duplicateHashes[0])
+	}
+
+	return nil
+}
+
+// getSortedScheduledIntermediateTxsInfo returns the new intermediate txs of the given block type, sorted by hash. The
+// invalid txs are also removed from the scheduled mini blocks
+func (ste *scheduledTxsExecution) getSortedScheduledIntermediateTxsInfo(
+	allIntermediateTxsBefore map[string]data.TransactionHandler,
+	allIntermediateTxsAfter map[string]data.TransactionHandler,
+	blockType block.Type,
+) []*intermediateTxInfo {
+	intermediateTxsInfo := ste.getAllIntermediateTxsAfterScheduledExecution(allIntermediateTxsBefore, allIntermediateTxsAfter, blockType)
+	if blockType == block.InvalidBlock && len(intermediateTxsInfo) > 0 {
+		ste.removeInvalidTxsFromScheduledMiniBlocks(intermediateTxsInfo)
+		intermediateTxsInfo = ste.removeDroppedFailedTxs(intermediateTxsInfo)
+	}
+
+	sort.Slice(intermediateTxsInfo, func(a, b int) bool {
+		return bytes.Compare(intermediateTxsInfo[a].txHash, intermediateTxsInfo[b].txHash) < 0
+	})
+
+	return intermediateTxsInfo
+}
+
+// writeTxTrace writes a trace line for an executed scheduled tx. The trace lines hold only values which are the same on
+// all the nodes executing the same scheduled txs, so that the traces can be compared line by line
+func (ste *scheduledTxsExecution) writeTxTrace(index int, txHash []byte, returnCode vmcommon.ReturnCode, gasConsumed uint64) {
+	if ste.traceWriter == nil {
+		return
+	}
+
+	ste.writeTraceLine(&executionTraceLine{
+		Type:        traceLineTypeTx,
+		Index:       index,
+		Hash:        hex.EncodeToString(txHash),
+		ReturnCode:  returnCode.String(),
+		GasConsumed: gasConsumed,
+	})
+}
+
+// writeIntermediateTxsTrace writes a trace line for each resulted scheduled intermediate tx, ordered by block type
+// and then by hash
+func (ste *scheduledTxsExecution) writeIntermediateTxsTrace() {
+	if ste.traceWriter == nil {
+		return
+	}
+
+	blockTypes := make([]block.Type, 0, len(ste.mapScheduledIntermediateTxs))
+	for blockType := range ste.mapScheduledIntermediateTxs {
+		blockTypes = append(blockTypes, blockType)
+	}
+	sort.Slice(blockTypes, func(i, j int) bool {
+		return blockTypes[i] < blockTypes[j]
+	})
+
+	for _, blockType := range blockTypes {
+		for index, txHandler := range ste.mapScheduledIntermediateTxs[blockType] {
+			txHash, err := core.CalculateHash(ste.marshaller, ste.hasher, txHandler)
+			if err != nil {
+				log.Warn("scheduledTxsExecution.writeIntermediateTxsTrace: CalculateHash", "error", err.Error())
+				continue
+			}
+
+			ste.writeTraceLine(&executionTraceLine{
+				Type:      traceLineTypeIntermediateTx,
+				Index:     index,
+				Hash:      hex.EncodeToString(txHash),
+				BlockType: blockType.String(),
+			})
+		}
+	}
+}
+
+func (ste *scheduledTxsExecution) writeTraceLine(traceLine *executionTraceLine) {
+	buff, err := json.Marshal(traceLine)
+	if err != nil {
+		log.Warn("scheduledTxsExecution.writeTraceLine: Marshal", "error", err.Error())
+		return
+	}
+
+	_, err = ste.traceWriter.Write(append(buff, '\n'))
+	if err != nil {
+		log.Warn("scheduledTxsExecution.writeTraceLine: Write", "error", err.Error())
+	}
+}
+
+func (ste *scheduledTxsExecution) getGasConsumed(txHash []byte) (uint64, error) {
+	return computeGasConsumed(ste.gasHandler, txHash)
+}
+
+// computeGasConsumed returns the gas provided for the given tx minus the gas refunded to it. A gas refund larger than
+// the gas provided means a gas accounting bug, so an error is returned instead
+func computeGasConsumed(gasHandler process.GasHandler, txHash []byte) (uint64, error) {
+	if check.IfNil(gasHandler) {
+		return 0, nil
+	}
+
+	gasProvided := gasHandler.GasProvidedAsScheduled(txHash)
+	gasRefunded := gasHandler.GasRefunded(txHash)
+	if gasRefunded > gasProvided {
+		return 0, fmt.Errorf("%w for tx hash %x: gas refunded %d, gas provided %d",
+			process.ErrInvalidGasRefund, txHash, gasRefunded, gasProvided)
+	}
+
+	return gasProvided - gasRefunded, nil
+}
+
+// getCurrentNumIntermediateTxs returns the number of intermediate txs currently held by the tx coordinator, or -1 if
+// the no-op txs are not classified. The classification needs the gas handler, as the no-op txs must not consume gas
+func (ste *scheduledTxsExecution) getCurrentNumIntermediateTxs() int {
+	if check.IfNil(ste.gasHandler) {
+		return -1
+	}
+
+	numIntermediateTxs := 0
+	for _, intermediateTxs := range ste.txCoordinator.GetAllIntermediateTxs() {
+		numIntermediateTxs += len(intermediateTxs)
+	}
+
+	return numIntermediateTxs
+}
+
+// classifyNoOpTx records the given executed tx as a no-op tx if it did not produce intermediate txs and did not
+// consume gas
+func (ste *scheduledTxsExecution) classifyNoOpTx(txHash []byte, numIntermediateTxsBeforeExecution int, gasConsumed uint64) {
+	if numIntermediateTxsBeforeExecution < 0 {
+		return
+	}
+
+	hasIntermediateTxs := ste.getCurrentNumIntermediateTxs() != numIntermediateTxsBeforeExecution
+	if hasIntermediateTxs || gasConsumed > 0 {
+		return
+	}
+
+	ste.noOpScheduledTxHashes = append(ste.noOpScheduledTxHashes, txHash)
+}
+
+// computeScheduledGasPerShard accumulates the gas carried by the smart contract results produced by the scheduled txs,
+// split by their destination shard. The intra shard results are counted as well, under the self shard
+func (ste *scheduledTxsExecution) computeScheduledGasPerShard(
+	allSCRsBeforeScheduledExecution map[string]data.TransactionHandler,
+	allSCRsAfterScheduledExecution map[string]data.TransactionHandler,
+) {
+	for txHash, txHandler := range allSCRsAfterScheduledExecution {
+		_, txExists := allSCRsBeforeScheduledExecution[txHash]
+		if txExists {
+			continue
+		}
+
+		destShardID := ste.shardCoordinator.ComputeId(txHandler.GetRcvAddr())
+		ste.mapScheduledGasPerShard[destShardID] += txHandler.GetGasLimit()
+	}
+}
+
+// removeDroppedFailedTxs removes the invalid txs resulted from the failed scheduled txs, if these are dropped
+func (ste *scheduledTxsExecution) removeDroppedFailedTxs(intermediateTxsInfo []*intermediateTxInfo) []*intermediateTxInfo {
+	if ste.failedScheduledTxsMode != DropFailedScheduledTxs || len(ste.failedScheduledTxHashes) == 0 {
+		return intermediateTxsInfo
+	}
+
+	mapFailedTxHashes := make(map[string]struct{}, len(ste.failedScheduledTxHashes))
+	for _, txHash := range ste.failedScheduledTxHashes {
+		mapFailedTxHashes[string(txHash)] = struct{}{}
+	}
+
+	resultedIntermediateTxsInfo := make([]*intermediateTxInfo, 0, len(intermediateTxsInfo))
+	for _, interTxInfo := range intermediateTxsInfo {
+		_, isFailed := mapFailedTxHashes[string(interTxInfo.txHash)]
+		if isFailed {
+			continue
+		}
+		resultedIntermediateTxsInfo = append(resultedIntermediateTxsInfo, interTxInfo)
+	}
+
+	return resultedIntermediateTxsInfo
+}
+
+// removeFailedScheduledTxs removes the failed txs from the scheduled set
+// removeNotExecutedScheduledTxs removes the scheduled txs left out when the execution stopped early because a budget was
+// exhausted, together with their hashes from the scheduled mini blocks, so that the scheduled txs and mini blocks hold
+// only the txs the results were computed for
+func (ste *scheduledTxsExecution) removeNotExecutedScheduledTxs() {
+	if len(ste.mapExecutionResults) == len(ste.scheduledTxHashes) {
+		return
+	}
+
+	mapNotExecutedTxHashes := make(map[string]struct{})
+	scheduledTxs := make([]data.TransactionHandler, 0, len(ste.mapExecutionResults))
+	scheduledTxHashes := make([][]byte, 0, len(ste.mapExecutionResults))
+	for index, txHash := range ste.scheduledTxHashes {
+		_, isExecuted := ste.mapExecutionResults[string(txHash)]
+		if !isExecuted {
+			mapNotExecutedTxHashes[string(txHash)] = struct{}{}
+			delete(ste.mapScheduledTxs, string(txHash))
+			continue
+		}
+		scheduledTxs = append(scheduledTxs, ste.scheduledTxs[index])
+		scheduledTxHashes = append(scheduledTxHashes, txHash)
+	}
+	ste.scheduledTxs = scheduledTxs
+	ste.scheduledTxHashes = scheduledTxHashes
+
+	scheduledMbs := make(block.MiniBlockSlice, 0, len(ste.scheduledMbs))
+	for _, miniBlock := range ste.scheduledMbs {
+		txHashes := make([][]byte, 0, len(miniBlock.TxHashes))
+		for _, txHash := range miniBlock.TxHashes {
+			_, isNotExecuted := mapNotExecutedTxHashes[string(txHash)]
+			if !isNotExecuted {
+				txHashes = append(txHashes, txHash)
+			}
+		}
+		if len(txHashes) == 0 {
+			continue
+		}
+		miniBlock.TxHashes = txHashes
+		scheduledMbs = append(scheduledMbs, miniBlock)
+	}
+	ste.scheduledMbs = scheduledMbs
+
+	log.Debug("scheduledTxsExecution.removeNotExecutedScheduledTxs",
+		"num of not executed txs removed", len(mapNotExecutedTxHashes),
+		"num of scheduled txs", len(ste.scheduledTxs),
+		"num of scheduled mbs", len(ste.scheduledMbs))
+}
+
+func (ste *scheduledTxsExecution) removeFailedScheduledTxs() {
+	if ste.failedScheduledTxsMode != DropFailedScheduledTxs || len(ste.failedScheduledTxHashes) == 0 {
+		return
+	}
+
+	for _, txHash := range ste.failedScheduledTxHashes {
+		delete(ste.mapScheduledTxs, string(txHash))
+	}
+
+	scheduledTxs := make([]data.TransactionHandler, 0, len(ste.mapScheduledTxs))
+	scheduledTxHashes := make([][]byte, 0, len(ste.mapScheduledTxs))
+	for index, txHash := range ste.scheduledTxHashes {
+		_, exists := ste.mapScheduledTxs[string(txHash)]
+		if !exists {
+			continue
+		}
+		scheduledTxs = append(scheduledTxs, ste.scheduledTxs[index])
+		scheduledTxHashes = append(scheduledTxHashes, txHash)
+	}
+
+	log.Debug("scheduledTxsExecution.removeFailedScheduledTxs", "num of failed txs removed", len(ste.scheduledTxs)-len(scheduledTxs))
+
+	delete(ste.mapScheduledTxsByBlockType, block.InvalidBlock)
+
+	ste.scheduledTxs = scheduledTxs
+	ste.scheduledTxHashes = scheduledTxHashes
+}
+
+func (ste *scheduledTxsExecution) removeInvalidTxsFromScheduledMiniBlocks(intermediateTxsInfo []*intermediateTxInfo) {
+	log.Debug("scheduledTxsExecution.removeInvalidTxsFromScheduledMiniBlocks", "num of invalid txs", len(intermediateTxsInfo))
+
+	numInvalidTxsRemoved := 0
+	for _, interTxInfo := range intermediateTxsInfo {
+		for index, miniBlock := range ste.scheduledMbs {
+			indexOfTxHashInMiniBlock := getIndexOfTxHashInMiniBlock(interTxInfo.txHash, miniBlock)
+			if indexOfTxHashInMiniBlock >= 0 {
+				log.Trace("scheduledTxsExecution.removeInvalidTxsFromScheduledMiniBlocks", "tx hash", interTxInfo.txHash)
+				ste.scheduledMbs[index].TxHashes = append(miniBlock.TxHashes[:indexOfTxHashInMiniBlock], miniBlock.TxHashes[indexOfTxHashInMiniBlock+1:]...)
+				numInvalidTxsRemoved++
+				break
+			}
+		}
+	}
+
+	resultedScheduledMbs := make(block.MiniBlockSlice, 0)
+	for _, miniBlock := range ste.scheduledMbs {
+		if len(miniBlock.TxHashes) == 0 {
+			continue
+		}
+		resultedScheduledMbs = append(resultedScheduledMbs, miniBlock)
+	}
+	ste.scheduledMbs = resultedScheduledMbs
+
+	log.Debug("scheduledTxsExecution.removeInvalidTxsFromScheduledMiniBlocks", "num of invalid txs removed", numInvalidTxsRemoved)
+}
+
+func getIndexOfTxHashInMiniBlock(txHash []byte, miniBlock *block.MiniBlock) int {
+	indexOfTxHashInMiniBlock := -1
+	for index, hash := range miniBlock.TxHashes {
+		if bytes.Equal(txHash, hash) {
+			indexOfTxHashInMiniBlock = index
+			break
+		}
+	}
+
+	return indexOfTxHashInMiniBlock
+}
+
+func (ste *scheduledTxsExecution) getAllIntermediateTxsAfterScheduledExecution(
+	allIntermediateTxsBeforeScheduledExecution map[string]data.TransactionHandler,
+	allIntermediateTxsAfterScheduledExecution map[string]data.TransactionHandler,
+	blockType block.Type,
+) []*intermediateTxInfo {
+	intermediateTxsInfo := make([]*intermediateTxInfo, 0)
+	for txHash, txHandler := range allIntermediateTxsAfterScheduledExecution {
+		_, txExists := allIntermediateTxsBeforeScheduledExecution[txHash]
+		if txExists {
+			continue
+		}
+
+		if ste.isInShardUnsignedTx(txHandler, blockType) {
+			log.Trace("scheduledTxsExecution.getAllIntermediateTxsAfterScheduledExecution: intra shard unsigned tx skipped", "hash", []byte(txHash))
+			continue
+		}
+
+		intermediateTxsInfo = append(intermediateTxsInfo, &intermediateTxInfo{
+			txHash:    []byte(txHash),
+			txHandler: txHandler,
+		})
+	}
+
+	return intermediateTxsInfo
+}
+
+// isInShardUnsignedTx returns true if the given intermediate tx is a receipt or a smart contract result which is
+// applied in this shard, so it is not kept as a scheduled intermediate tx. A smart contract result addressed back to
+// the original sender of the tx is decided only by the shard of its receiver: it is dropped if the original sender is
+// in this shard, as it was already applied inline, and kept otherwise, no matter the shard of its sender, so that its
+// fees are accounted only once, in the destination shard
+func (ste *scheduledTxsExecution) isInShardUnsignedTx(txHandler data.TransactionHandler, blockType block.Type) bool {
+	isUnsignedTx := blockType == block.ReceiptBlock || blockType == block.SmartContractResultBlock
+	if !isUnsignedTx {
+		return false
+	}
+
+	scr, ok := txHandler.(*smartContractResult.SmartContractResult)
+	isSelfSCR := ok && len(scr.OriginalSender) > 0 && bytes.Equal(scr.RcvAddr, scr.OriginalSender)
+	if isSelfSCR {
+		return ste.shardCoordinator.ComputeId(scr.RcvAddr) == ste.shardCoordinator.SelfId()
+	}
+
+	return ste.shardCoordinator.SameShard(txHandler.GetSndAddr(), txHandler.GetRcvAddr())
+}
+
+// GetScheduledTxs gets all the scheduled txs to be executed
+func (ste *scheduledTxsExecution) GetScheduledTxs() []data.TransactionHandler {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	scheduledTxs := make([]data.TransactionHandler, len(ste.scheduledTxs))
+	for index, scheduledTx := range ste.scheduledTxs {
+		scheduledTxs[index] = scheduledTx
+		log.Trace("scheduledTxsExecution.GetScheduledTxs", "sender", scheduledTxs[index].GetSndAddr(), "receiver", scheduledTxs[index].GetRcvAddr())
+	}
+
+	log.Debug("scheduledTxsExecution.GetScheduledTxs", "num of scheduled txs", len(scheduledTxs))
+
+	return scheduledTxs
+}
+
+// GetScheduledTxHashes gets the hashes of all the scheduled txs to be executed, in the order they were added
+func (ste *scheduledTxsExecution) GetScheduledTxHashes() [][]byte {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	scheduledTxHashes := make([][]byte, len(ste.scheduledTxHashes))
+	copy(scheduledTxHashes, ste.scheduledTxHashes)
+
+	return scheduledTxHashes
+}
+
+// GetScheduledIntermediateTxs gets the resulted intermediate txs after the execution of scheduled transactions
+func (ste *scheduledTxsExecution) GetScheduledIntermediateTxs() map[block.Type][]data.TransactionHandler {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	numScheduledIntermediateTxs := 0
+	mapScheduledIntermediateTxs := make(map[block.Type][]data.TransactionHandler)
+	for blockType, scheduledIntermediateTxs := range ste.mapScheduledIntermediateTxs {
+		if len(scheduledIntermediateTxs) == 0 {
+			continue
 		}
 
 		mapScheduledIntermediateTxs[blockType] = make([]data.TransactionHandler, len(scheduledIntermediateTxs))
@@ -361,6 +2071,140 @@ func (ste *scheduledTxsExecution) GetScheduledIntermediateTxs() map[block.Type][
 	return mapScheduledIntermediateTxs
 }
 
+// GetScheduledIntermediateTxsForType gets the resulted intermediate txs of the given block type after the execution of
+// scheduled transactions. It returns an empty slice if there are no such txs
+func (ste *scheduledTxsExecution) GetScheduledIntermediateTxsForType(blockType block.Type) []data.TransactionHandler {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	scheduledIntermediateTxs := make([]data.TransactionHandler, len(ste.mapScheduledIntermediateTxs[blockType]))
+	copy(scheduledIntermediateTxs, ste.mapScheduledIntermediateTxs[blockType])
+
+	return scheduledIntermediateTxs
+}
+
+// IsScheduledIntermediateTx returns true if the given txHash is the hash of an intermediate tx resulted after the
+// execution of scheduled transactions
+func (ste *scheduledTxsExecution) IsScheduledIntermediateTx(txHash []byte) bool {
+	_, ok := ste.GetScheduledIntermediateTx(txHash)
+	return ok
+}
+
+// GetScheduledIntermediateTx gets the intermediate tx with the given txHash, resulted after the execution of scheduled
+// transactions
+func (ste *scheduledTxsExecution) GetScheduledIntermediateTx(txHash []byte) (data.TransactionHandler, bool) {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	scheduledIntermediateTx, ok := ste.mapScheduledInterTxsByHash[string(txHash)]
+	return scheduledIntermediateTx, ok
+}
+
+// GetScheduledIntermediateTxsByShard gets the resulted intermediate txs after the execution of scheduled transactions,
+// grouped by the shard of their receiver and then by block type. The in shard unsigned txs are already left out when
+// the scheduled intermediate txs are computed
+func (ste *scheduledTxsExecution) GetScheduledIntermediateTxsByShard() map[uint32]map[block.Type][]data.TransactionHandler {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	mapScheduledIntermediateTxsByShard := make(map[uint32]map[block.Type][]data.TransactionHandler)
+	for blockType, scheduledIntermediateTxs := range ste.mapScheduledIntermediateTxs {
+		for _, scheduledIntermediateTx := range scheduledIntermediateTxs {
+			destShard := ste.shardCoordinator.ComputeId(scheduledIntermediateTx.GetRcvAddr())
+			mapScheduledIntermediateTxs, ok := mapScheduledIntermediateTxsByShard[destShard]
+			if !ok {
+				mapScheduledIntermediateTxs = make(map[block.Type][]data.TransactionHandler)
+				mapScheduledIntermediateTxsByShard[destShard] = mapScheduledIntermediateTxs
+			}
+
+			mapScheduledIntermediateTxs[blockType] = append(mapScheduledIntermediateTxs[blockType], scheduledIntermediateTx)
+		}
+	}
+
+	log.Debug("scheduledTxsExecution.GetScheduledIntermediateTxsByShard", "num of destination shards", len(mapScheduledIntermediateTxsByShard))
+
+	return mapScheduledIntermediateTxsByShard
+}
+
+type packedMiniBlockKey struct {
+	blockType block.Type
+	destShard uint32
+}
+
+// PackScheduledMiniBlocks packs the scheduled intermediate txs into mini blocks, one group for each block type and
+// destination shard, split in mini blocks of at most maxTxsPerMiniBlock txs. The groups are ordered by block type and
+// destination shard and the txs by hash, so all the nodes pack the same intermediate txs into the same mini blocks.
+// A non-positive maxTxsPerMiniBlock means that the groups are not split
+func (ste *scheduledTxsExecution) PackScheduledMiniBlocks(maxTxsPerMiniBlock int) block.MiniBlockSlice {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	mapTxHashesPerKey := make(map[packedMiniBlockKey][][]byte)
+	for blockType, intermediateTxs := range ste.mapScheduledIntermediateTxs {
+		for _, txHandler := range intermediateTxs {
+			txHash, err := core.CalculateHash(ste.marshaller, ste.hasher, txHandler)
+			if err != nil {
+				log.Warn("scheduledTxsExecution.PackScheduledMiniBlocks: CalculateHash", "block type", blockType, "error", err)
+				continue
+			}
+
+			key := packedMiniBlockKey{
+				blockType: blockType,
+				destShard: ste.getPackedMiniBlockDestShard(blockType, txHandler),
+			}
+			mapTxHashesPerKey[key] = append(mapTxHashesPerKey[key], txHash)
+		}
+	}
+
+	keys := make([]packedMiniBlockKey, 0, len(mapTxHashesPerKey))
+	for key := range mapTxHashesPerKey {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		if keys[a].blockType != keys[b].blockType {
+			return keys[a].blockType < keys[b].blockType
+		}
+		return keys[a].destShard < keys[b].destShard
+	})
+
+	miniBlocks := make(block.MiniBlockSlice, 0)
+	for _, key := range keys {
+		txHashes := mapTxHashesPerKey[key]
+		sort.Slice(txHashes, func(a, b int) bool {
+			return bytes.Compare(txHashes[a], txHashes[b]) < 0
+		})
+
+		for len(txHashes) > 0 {
+			numTxs := len(txHashes)
+			if maxTxsPerMiniBlock > 0 && numTxs > maxTxsPerMiniBlock {
+				numTxs = maxTxsPerMiniBlock
+			}
+
+			miniBlocks = append(miniBlocks, &block.MiniBlock{
+				TxHashes:        txHashes[:numTxs],
+				SenderShardID:   ste.shardCoordinator.SelfId(),
+				ReceiverShardID: key.destShard,
+				Type:            key.blockType,
+			})
+			txHashes = txHashes[numTxs:]
+		}
+	}
+
+	log.Debug("scheduledTxsExecution.PackScheduledMiniBlocks", "num of packed mini blocks", len(miniBlocks))
+
+	return miniBlocks
+}
+
+// getPackedMiniBlockDestShard returns the destination shard of the given intermediate tx. The invalid txs and the
+// receipts are kept in the self shard
+func (ste *scheduledTxsExecution) getPackedMiniBlockDestShard(blockType block.Type, txHandler data.TransactionHandler) uint32 {
+	if blockType == block.InvalidBlock || blockType == block.ReceiptBlock {
+		return ste.shardCoordinator.SelfId()
+	}
+
+	return ste.shardCoordinator.ComputeId(txHandler.GetRcvAddr())
+}
+
 // GetScheduledMiniBlocks gets the resulted mini blocks after the execution of scheduled transactions
 func (ste *scheduledTxsExecution) GetScheduledMiniBlocks() block.MiniBlockSlice {
 	ste.mutScheduledTxs.RLock()
@@ -381,112 +2225,408 @@ func (ste *scheduledTxsExecution) GetScheduledMiniBlocks() block.MiniBlockSlice
 	return miniBlocks
 }
 
+// GetScheduledMBsFiltered gets the resulted mini blocks after the execution of scheduled transactions, which have one
+// of the given types and the given destination shard. An empty types slice matches mini blocks of any type
+func (ste *scheduledTxsExecution) GetScheduledMBsFiltered(types []block.Type, destShard uint32) block.MiniBlockSlice {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	miniBlocks := make(block.MiniBlockSlice, 0)
+	for _, scheduledMb := range ste.scheduledMbs {
+		if scheduledMb.ReceiverShardID != destShard {
+			continue
+		}
+		if !isMiniBlockTypeIncluded(scheduledMb.Type, types) {
+			continue
+		}
+
+		miniBlocks = append(miniBlocks, scheduledMb.Clone())
+	}
+
+	log.Debug("scheduledTxsExecution.GetScheduledMBsFiltered", "dest shard", destShard, "num of scheduled mbs", len(miniBlocks))
+
+	return miniBlocks
+}
+
+func isMiniBlockTypeIncluded(mbType block.Type, types []block.Type) bool {
+	if len(types) == 0 {
+		return true
+	}
+
+	for _, t := range types {
+		if t == mbType {
+			return true
+		}
+	}
+
+	return false
+}
+
 // SetScheduledInfo sets the resulted scheduled mini blocks, root hash, intermediate txs, gas and fees after the execution of scheduled transactions
 func (ste *scheduledTxsExecution) SetScheduledInfo(scheduledInfo *process.ScheduledInfo) {
 	ste.mutScheduledTxs.Lock()
 	defer ste.mutScheduledTxs.Unlock()
 
-	ste.scheduledRootHash = scheduledInfo.RootHash
+	ste.setScheduledInfo(scheduledInfo)
+	ste.lastRolledBackHeaderHash = nil
+}
+
+// SetScheduledInfoPartial sets only the selected fields of the given scheduled info, keeping the current values of the
+// other ones
+func (ste *scheduledTxsExecution) SetScheduledInfoPartial(scheduledInfo *process.ScheduledInfo, fields ScheduledInfoField) {
+	ste.mutScheduledTxs.Lock()
+	defer ste.mutScheduledTxs.Unlock()
+
+	ste.setScheduledInfoFields(scheduledInfo, fields)
+	ste.lastRolledBackHeaderHash = nil
+}
+
+func (ste *scheduledTxsExecution) setScheduledInfo(scheduledInfo *process.ScheduledInfo) {
+	ste.setScheduledInfoFields(scheduledInfo, AllScheduledInfoFields)
+}
+
+func (ste *scheduledTxsExecution) setScheduledInfoFields(scheduledInfo *process.ScheduledInfo, fields ScheduledInfoField) {
+	ste.dropResumableExecution("scheduled info set")
+	if fields.has(ScheduledInfoRootHash) {
+		ste.scheduledRootHash = scheduledInfo.RootHash
+	}
+
+	if fields.has(ScheduledInfoIntermediateTxs) {
+		ste.resetScheduledIntermediateTxs()
+		for blockType, intermediateTxs := range scheduledInfo.IntermediateTxs {
+			if len(intermediateTxs) == 0 {
+				continue
+			}
+
+			ste.mapScheduledIntermediateTxs[blockType] = make([]data.TransactionHandler, len(intermediateTxs))
+			for index, intermediateTx := range intermediateTxs {
+				ste.mapScheduledIntermediateTxs[blockType][index] = intermediateTx
+				ste.indexScheduledIntermediateTx(intermediateTx)
+				log.Trace("scheduledTxsExecution.SetScheduledInfo", "blockType", blockType, "sender", ste.mapScheduledIntermediateTxs[blockType][index].GetSndAddr(), "receiver", ste.mapScheduledIntermediateTxs[blockType][index].GetRcvAddr())
+			}
+		}
+	}
+
+	if fields.has(ScheduledInfoGasAndFees) {
+		ste.setAggregateGasAndFeesFromScheduledInfo(scheduledInfo.GasAndFees)
+	}
+
+	if fields.has(ScheduledInfoMiniBlocks) {
+		ste.scheduledMbs = make(block.MiniBlockSlice, len(scheduledInfo.MiniBlocks))
+		for index, scheduledMiniBlock := range scheduledInfo.MiniBlocks {
+			miniBlock := scheduledMiniBlock.Clone()
+			ste.scheduledMbs[index] = miniBlock
+		}
+
+		err := ste.setScheduledMiniBlockHashes()
+		if err != nil {
+			log.Error("scheduledTxsExecution.SetScheduledInfo: setScheduledMiniBlockHashes", "error", err.Error())
+		}
+	}
+
+	log.Debug("scheduledTxsExecution.SetScheduledInfo",
+		"fields", fields,
+		"scheduled root hash", ste.scheduledRootHash,
+		"num of scheduled mbs", len(ste.scheduledMbs),
+		"num of scheduled intermediate txs", getNumScheduledIntermediateTxs(ste.mapScheduledIntermediateTxs),
+		"accumulatedFees", ste.gasAndFees.AccumulatedFees.String(),
+		"developerFees", ste.gasAndFees.DeveloperFees.String(),
+		"gasProvided", ste.gasAndFees.GasProvided,
+		"gasPenalized", ste.gasAndFees.GasPenalized,
+		"gasRefunded", ste.gasAndFees.GasRefunded)
+}
+
+// GetScheduledRootHash gets the resulted root hash after the execution of scheduled transactions
+func (ste *scheduledTxsExecution) GetScheduledRootHash() []byte {
+	ste.mutScheduledTxs.RLock()
+	rootHash := ste.scheduledRootHash
+	ste.mutScheduledTxs.RUnlock()
+
+	log.Debug("scheduledTxsExecution.GetScheduledRootHash", "scheduled root hash", rootHash)
+
+	return rootHash
+}
+
+// GetScheduledGasAndFees returns the gas and fees for the scheduled transactions in last processed block
+// if there are no scheduled transactions in the last processed block, the returned struct has zero values
+func (ste *scheduledTxsExecution) GetScheduledGasAndFees() scheduled.GasAndFees {
+	ste.mutScheduledTxs.RLock()
+	gasAndFees := ste.gasAndFees
+	ste.mutScheduledTxs.RUnlock()
+
+	log.Debug("scheduledTxsExecution.GetScheduledGasAndFees",
+		"accumulatedFees", gasAndFees.AccumulatedFees.String(),
+		"developerFees", gasAndFees.DeveloperFees.String(),
+		"gasProvided", gasAndFees.GasProvided,
+		"gasPenalized", gasAndFees.GasPenalized,
+		"gasRefunded", gasAndFees.GasRefunded)
+
+	return gasAndFees
+}
+
+// SetScheduledRootHash sets the resulted root hash after the execution of scheduled transactions
+func (ste *scheduledTxsExecution) SetScheduledRootHash(rootHash []byte) {
+	ste.mutScheduledTxs.Lock()
+	defer ste.mutScheduledTxs.Unlock()
+
+	ste.scheduledRootHash = rootHash
+	ste.lastRolledBackHeaderHash = nil
+	ste.dropResumableExecution("scheduled root hash set")
+	log.Debug("scheduledTxsExecution.SetScheduledRootHash", "scheduled root hash", ste.scheduledRootHash)
+}
+
+// SetScheduledGasAndFees sets the gas and fees for the scheduled transactions
+func (ste *scheduledTxsExecution) SetScheduledGasAndFees(gasAndFees scheduled.GasAndFees) {
+	ste.mutScheduledTxs.Lock()
+	defer ste.mutScheduledTxs.Unlock()
+
+	err := ste.setAggregateGasAndFeesOfLastExecution(gasAndFees)
+	if err != nil {
+		log.Error("scheduledTxsExecution.SetScheduledGasAndFees: the per tx gas and fees were dropped", "error", err.Error())
+	}
+	ste.lastRolledBackHeaderHash = nil
+	ste.dropResumableExecution("scheduled gas and fees set")
+	log.Debug("scheduledTxsExecution.SetScheduledGasAndFees",
+		"accumulatedFees", ste.gasAndFees.AccumulatedFees.String(),
+		"developerFees", ste.gasAndFees.DeveloperFees.String(),
+		"gasProvided", ste.gasAndFees.GasProvided,
+		"gasPenalized", ste.gasAndFees.GasPenalized,
+		"gasRefunded", ste.gasAndFees.GasRefunded)
+}
+
+// SetTransactionProcessor sets the transaction processor needed by scheduled txs execution component
+func (ste *scheduledTxsExecution) SetTransactionProcessor(txProcessor process.TransactionProcessor) {
+	ste.txProcessor = txProcessor
+}
+
+// SetTransactionCoordinator sets the transaction coordinator needed by scheduled txs execution component
+func (ste *scheduledTxsExecution) SetTransactionCoordinator(txCoordinator process.TransactionCoordinator) {
+	ste.txCoordinator = txCoordinator
+}
+
+// SetOnInitHandler sets an optional handler called at the end of each Init call, so that components holding data
+// derived from the scheduled state can invalidate it
+func (ste *scheduledTxsExecution) SetOnInitHandler(handler func()) {
+	ste.mutScheduledTxs.Lock()
+	ste.onInitHandler = handler
+	ste.mutScheduledTxs.Unlock()
+}
+
+// SetPostRollbackVerify sets an optional handler called with the restored scheduled root hash at the end of each roll
+// back. An error returned by the handler is propagated to the roll back caller, after the scheduled state was restored
+func (ste *scheduledTxsExecution) SetPostRollbackVerify(handler func(restoredRootHash []byte) error) {
+	ste.mutScheduledTxs.Lock()
+	ste.postRollbackVerify = handler
+	ste.mutScheduledTxs.Unlock()
+}
+
+// SetIntermediateTxsSizeEstimator sets an optional estimator of the size of the intermediate txs a scheduled tx will
+// produce, used to stop the execution once the projected mini block space is exhausted
+func (ste *scheduledTxsExecution) SetIntermediateTxsSizeEstimator(estimator func(tx data.TransactionHandler) uint64) {
+	ste.mutScheduledTxs.Lock()
+	ste.interTxsSizeEstimator = estimator
+	ste.mutScheduledTxs.Unlock()
+}
+
+// SetIntermediateTxsConsumer sets the handler which receives the intermediate txs resulted from each scheduled tx, when
+// streaming the intermediate txs is enabled. The handler is called while the scheduled txs are locked, so it should not
+// call back into this component
+func (ste *scheduledTxsExecution) SetIntermediateTxsConsumer(consumer func(txHash []byte, intermediateTxs map[block.Type][]data.TransactionHandler) error) {
+	ste.mutScheduledTxs.Lock()
+	ste.intermediateTxsConsumer = consumer
+	ste.mutScheduledTxs.Unlock()
+}
+
+// SetExecutionBudget sets the budget checked before each scheduled tx. When the budget is exhausted, the remaining
+// scheduled txs are not executed and are removed from the scheduled txs and mini blocks. A nil budget disables the
+// check, the execution being bounded only by the have time handler
+func (ste *scheduledTxsExecution) SetExecutionBudget(executionBudget ExecutionBudget) {
+	ste.mutScheduledTxs.Lock()
+	ste.executionBudget = executionBudget
+	ste.mutScheduledTxs.Unlock()
+}
+
+// GetIntermediateTxsMarshalledSize returns the running estimate of the marshalled size of the intermediate txs resulted
+// from the last execution. It is computed only if a max marshalled size is set in the arguments
+func (ste *scheduledTxsExecution) GetIntermediateTxsMarshalledSize() uint64 {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	return ste.interTxsMarshalledSize
+}
+
+// SetExecutionPriorityHandler sets the handler giving the priority of a scheduled tx, used when the scheduled txs are
+// executed by priority. The higher priority txs are executed first. A nil handler gives all the txs the same priority
+func (ste *scheduledTxsExecution) SetExecutionPriorityHandler(handler func(tx data.TransactionHandler) uint64) {
+	ste.mutScheduledTxs.Lock()
+	ste.executionPriorityHandler = handler
+	ste.mutScheduledTxs.Unlock()
+}
+
+// GetProjectedMiniBlockSize returns the size of the intermediate txs projected for the scheduled txs executed since the
+// last Init call
+func (ste *scheduledTxsExecution) GetProjectedMiniBlockSize() uint64 {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	return ste.projectedMiniBlockSize
+}
+
+// SetRootHashVerifier sets the verifier of the root hash computed after the execution of the scheduled txs. Its error is
+// returned by ExecuteAll, after retrying the execution once if the retry on root hash mismatch is enabled
+func (ste *scheduledTxsExecution) SetRootHashVerifier(verifier func(rootHash []byte) error) {
+	ste.mutScheduledTxs.Lock()
+	ste.rootHashVerifier = verifier
+	ste.mutScheduledTxs.Unlock()
+}
+
+// SetExecutionOrderComparator sets the comparator used to prioritize the execution of scheduled txs. The comparator
+// should return a negative value if the first tx should be executed before the second one, a positive value if it
+// should be executed after and zero if the txs have the same priority
+func (ste *scheduledTxsExecution) SetExecutionOrderComparator(comparator func(first data.TransactionHandler, second data.TransactionHandler) int) {
+	ste.mutScheduledTxs.Lock()
+	ste.executionOrderComparator = comparator
+	ste.mutScheduledTxs.Unlock()
+}
+
+// SetAccountsWarmer sets the component used to load the accounts of the scheduled txs before their execution
+func (ste *scheduledTxsExecution) SetAccountsWarmer(accountsWarmer process.AccountsWarmer) {
+	ste.mutScheduledTxs.Lock()
+	ste.accountsWarmer = accountsWarmer
+	ste.mutScheduledTxs.Unlock()
+}
+
+// SetFeeHandler sets the fee handler used to attribute the developer fees to the contracts called by scheduled txs
+func (ste *scheduledTxsExecution) SetFeeHandler(feeHandler process.TransactionFeeHandler) {
+	ste.mutScheduledTxs.Lock()
+	ste.feeHandler = feeHandler
+	ste.mutScheduledTxs.Unlock()
+}
+
+// GetDeveloperFeesPerContract returns the developer fees accumulated by each contract during the execution of scheduled
+// transactions since the last Init call
+func (ste *scheduledTxsExecution) GetDeveloperFeesPerContract() map[string]*big.Int {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	mapDeveloperFeesPerContract := make(map[string]*big.Int, len(ste.mapDeveloperFeesPerContract))
+	for contractAddress, developerFees := range ste.mapDeveloperFeesPerContract {
+		mapDeveloperFeesPerContract[contractAddress] = big.NewInt(0).Set(developerFees)
+	}
+
+	return mapDeveloperFeesPerContract
+}
+
+// GetScheduledTxFee returns the fee charged for the given scheduled tx, executed since the last Init call. The fees are
+// recorded only if a fee handler was set
+func (ste *scheduledTxsExecution) GetScheduledTxFee(txHash []byte) (*big.Int, bool) {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	fee, ok := ste.mapScheduledTxFees[string(txHash)]
+	if !ok {
+		return nil, false
+	}
 
-	numScheduledIntermediateTxs := 0
-	ste.mapScheduledIntermediateTxs = make(map[block.Type][]data.TransactionHandler)
-	for blockType, intermediateTxs := range scheduledInfo.IntermediateTxs {
-		if len(intermediateTxs) == 0 {
-			continue
-		}
+	return big.NewInt(0).Set(fee), true
+}
 
-		ste.mapScheduledIntermediateTxs[blockType] = make([]data.TransactionHandler, len(intermediateTxs))
-		for index, intermediateTx := range intermediateTxs {
-			ste.mapScheduledIntermediateTxs[blockType][index] = intermediateTx
-			log.Trace("scheduledTxsExecution.SetScheduledInfo", "blockType", blockType, "sender", ste.mapScheduledIntermediateTxs[blockType][index].GetSndAddr(), "receiver", ste.mapScheduledIntermediateTxs[blockType][index].GetRcvAddr())
-		}
+// GetScheduledReceiptFor returns the marshalled receipt generated for the given scheduled tx by the last execution. The
+// receipts are generated only if enabled in the arguments
+func (ste *scheduledTxsExecution) GetScheduledReceiptFor(txHash []byte) ([]byte, bool) {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
 
-		numScheduledIntermediateTxs += len(intermediateTxs)
+	marshalledReceipt, ok := ste.mapScheduledReceipts[string(txHash)]
+	if !ok {
+		return nil, false
 	}
 
-	ste.gasAndFees = scheduledInfo.GasAndFees
+	return append([]byte(nil), marshalledReceipt...), true
+}
 
-	ste.scheduledMbs = make(block.MiniBlockSlice, len(scheduledInfo.MiniBlocks))
-	for index, scheduledMiniBlock := range scheduledInfo.MiniBlocks {
-		miniBlock := scheduledMiniBlock.Clone()
-		ste.scheduledMbs[index] = miniBlock
-	}
+// SetTraceWriter sets the writer on which the execution trace of the scheduled txs is streamed. A nil writer disables
+// the trace
+func (ste *scheduledTxsExecution) SetTraceWriter(traceWriter io.Writer) {
+	ste.mutScheduledTxs.Lock()
+	ste.traceWriter = traceWriter
+	ste.mutScheduledTxs.Unlock()
+}
 
-	err := ste.setScheduledMiniBlockHashes()
-	if err != nil {
-		log.Error("scheduledTxsExecution.SetScheduledInfo: setScheduledMiniBlockHashes", "error", err.Error())
+// SetGasHandler sets the gas handler used to get the gas consumed by each scheduled tx, written in the execution trace
+func (ste *scheduledTxsExecution) SetGasHandler(gasHandler process.GasHandler) {
+	ste.mutScheduledTxs.Lock()
+	ste.gasHandler = gasHandler
+	ste.mutScheduledTxs.Unlock()
+}
+
+// SetCanonicalExecutionOrder sets the order, given by tx hashes, in which the scheduled txs are executed, so that the
+// execution of a received block reproduces the one of the proposer. The order is used until the next Init call and
+// takes precedence over all the other ordering options. Nil means the scheduled txs are executed in their own order
+func (ste *scheduledTxsExecution) SetCanonicalExecutionOrder(txHashes [][]byte) {
+	ste.mutScheduledTxs.Lock()
+	defer ste.mutScheduledTxs.Unlock()
+
+	if txHashes == nil {
+		ste.canonicalExecutionOrder = nil
+		return
 	}
 
-	log.Debug("scheduledTxsExecution.SetScheduledInfo",
-		"scheduled root hash", ste.scheduledRootHash,
-		"num of scheduled mbs", len(ste.scheduledMbs),
-		"num of scheduled intermediate txs", numScheduledIntermediateTxs,
-		"accumulatedFees", ste.gasAndFees.AccumulatedFees.String(),
-		"developerFees", ste.gasAndFees.DeveloperFees.String(),
-		"gasProvided", ste.gasAndFees.GasProvided,
-		"gasPenalized", ste.gasAndFees.GasPenalized,
-		"gasRefunded", ste.gasAndFees.GasRefunded)
+	ste.canonicalExecutionOrder = make([][]byte, len(txHashes))
+	copy(ste.canonicalExecutionOrder, txHashes)
 }
 
-// GetScheduledRootHash gets the resulted root hash after the execution of scheduled transactions
-func (ste *scheduledTxsExecution) GetScheduledRootHash() []byte {
+// GetExecutionFingerprint returns the fingerprint of the last successful scheduled txs execution. It is nil when the
+// fingerprint is not enabled, or there was no such execution since the last Init call
+func (ste *scheduledTxsExecution) GetExecutionFingerprint() []byte {
 	ste.mutScheduledTxs.RLock()
-	rootHash := ste.scheduledRootHash
-	ste.mutScheduledTxs.RUnlock()
+	defer ste.mutScheduledTxs.RUnlock()
 
-	log.Debug("scheduledTxsExecution.GetScheduledRootHash", "scheduled root hash", rootHash)
+	if ste.executionFingerprint == nil {
+		return nil
+	}
 
-	return rootHash
+	fingerprint := make([]byte, len(ste.executionFingerprint))
+	copy(fingerprint, ste.executionFingerprint)
+
+	return fingerprint
 }
 
-// GetScheduledGasAndFees returns the gas and fees for the scheduled transactions in last processed block
-// if there are no scheduled transactions in the last processed block, the returned struct has zero values
-func (ste *scheduledTxsExecution) GetScheduledGasAndFees() scheduled.GasAndFees {
+// GetScheduledFailureRatio returns the ratio of failed to attempted scheduled txs in the last execution
+func (ste *scheduledTxsExecution) GetScheduledFailureRatio() float64 {
 	ste.mutScheduledTxs.RLock()
-	gasAndFees := ste.gasAndFees
-	ste.mutScheduledTxs.RUnlock()
-
-	log.Debug("scheduledTxsExecution.GetScheduledGasAndFees",
-		"accumulatedFees", gasAndFees.AccumulatedFees.String(),
-		"developerFees", gasAndFees.DeveloperFees.String(),
-		"gasProvided", gasAndFees.GasProvided,
-		"gasPenalized", gasAndFees.GasPenalized,
-		"gasRefunded", gasAndFees.GasRefunded)
+	defer ste.mutScheduledTxs.RUnlock()
 
-	return gasAndFees
+	return ste.getScheduledFailureRatio()
 }
 
-// SetScheduledRootHash sets the resulted root hash after the execution of scheduled transactions
-func (ste *scheduledTxsExecution) SetScheduledRootHash(rootHash []byte) {
+// SetAppStatusHandler sets the status handler on which the progress and the metrics of the scheduled txs execution are
+// published
+func (ste *scheduledTxsExecution) SetAppStatusHandler(statusHandler core.AppStatusHandler) {
 	ste.mutScheduledTxs.Lock()
-	defer ste.mutScheduledTxs.Unlock()
-
-	ste.scheduledRootHash = rootHash
-	log.Debug("scheduledTxsExecution.SetScheduledRootHash", "scheduled root hash", ste.scheduledRootHash)
+	ste.statusHandler = statusHandler
+	ste.mutScheduledTxs.Unlock()
 }
 
-// SetScheduledGasAndFees sets the gas and fees for the scheduled transactions
-func (ste *scheduledTxsExecution) SetScheduledGasAndFees(gasAndFees scheduled.GasAndFees) {
+// SetStorageAccessMeter sets the component used to count the storage reads and writes of each executed scheduled tx
+func (ste *scheduledTxsExecution) SetStorageAccessMeter(storageAccessMeter process.StorageAccessMeter) {
 	ste.mutScheduledTxs.Lock()
-	defer ste.mutScheduledTxs.Unlock()
-
-	ste.gasAndFees = gasAndFees
-	log.Debug("scheduledTxsExecution.SetScheduledGasAndFees",
-		"accumulatedFees", ste.gasAndFees.AccumulatedFees.String(),
-		"developerFees", ste.gasAndFees.DeveloperFees.String(),
-		"gasProvided", ste.gasAndFees.GasProvided,
-		"gasPenalized", ste.gasAndFees.GasPenalized,
-		"gasRefunded", ste.gasAndFees.GasRefunded)
+	ste.storageAccessMeter = storageAccessMeter
+	ste.mutScheduledTxs.Unlock()
 }
 
-// SetTransactionProcessor sets the transaction processor needed by scheduled txs execution component
-func (ste *scheduledTxsExecution) SetTransactionProcessor(txProcessor process.TransactionProcessor) {
-	ste.txProcessor = txProcessor
-}
+// GetStorageAccessStats returns the storage reads and writes done by each scheduled tx executed since the last Init
+// call, keyed by tx hash
+func (ste *scheduledTxsExecution) GetStorageAccessStats() map[string]process.StorageAccessStat {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
 
-// SetTransactionCoordinator sets the transaction coordinator needed by scheduled txs execution component
-func (ste *scheduledTxsExecution) SetTransactionCoordinator(txCoordinator process.TransactionCoordinator) {
-	ste.txCoordinator = txCoordinator
+	mapStorageAccessStats := make(map[string]process.StorageAccessStat, len(ste.mapStorageAccessStats))
+	for txHash, storageAccessStat := range ste.mapStorageAccessStats {
+		mapStorageAccessStats[txHash] = storageAccessStat
+	}
+
+	return mapStorageAccessStats
 }
 
 // GetScheduledRootHashForHeader gets scheduled root hash of the given header from storage
@@ -518,11 +2658,57 @@ func (ste *scheduledTxsExecution) GetScheduledRootHashForHeaderWithEpoch(
 	return scheduledInfo.RootHash, nil
 }
 
-// RollBackToBlock rolls back the scheduled txs execution handler to the given header
+// GetAllScheduledStateKeys returns the keys of all the scheduled info saved in the storer, which are the hashes of the
+// headers the scheduled info was saved for. The storer is dedicated to the scheduled info, so all its keys are returned.
+// It returns process.ErrStorerIterationNotSupported for the storers which can not iterate over their keys, as the
+// pruning storer used by the production nodes, the nil storer and the disabled storer, so production nodes can not
+// enumerate the scheduled info keys
+func (ste *scheduledTxsExecution) GetAllScheduledStateKeys() ([][]byte, error) {
+	rangeKeysSupportChecker, ok := ste.storer.(process.RangeKeysSupportChecker)
+	if ok && !rangeKeysSupportChecker.IsRangeKeysSupported() {
+		return nil, process.ErrStorerIterationNotSupported
+	}
+
+	keys := make([][]byte, 0)
+	ste.storer.RangeKeys(func(key []byte, _ []byte) bool {
+		keys = append(keys, append([]byte{}, key...))
+		return true
+	})
+
+	log.Debug("scheduledTxsExecution.GetAllScheduledStateKeys", "num of keys", len(keys))
+
+	return keys, nil
+}
+
+// RollBackToBlock rolls back the scheduled txs execution handler to the given header. Consecutive calls for the same
+// header are no-ops, as the scheduled info was already restored
 func (ste *scheduledTxsExecution) RollBackToBlock(headerHash []byte) error {
-	scheduledInfo, err := ste.getScheduledInfoForHeader(headerHash, core.OptionalUint32{})
+	_, err := ste.rollBackToBlock(headerHash, false)
+	return err
+}
+
+// RollBackToBlockWithInfo rolls back the scheduled txs execution handler to the given header, as RollBackToBlock does,
+// and returns the restored scheduled info. When the roll back was already done for the same header, the scheduled info
+// currently held is returned
+func (ste *scheduledTxsExecution) RollBackToBlockWithInfo(headerHash []byte) (*process.ScheduledInfo, error) {
+	return ste.rollBackToBlock(headerHash, false)
+}
+
+// ForceRollBackToBlock rolls back the scheduled txs execution handler to the given header, even if the last roll back
+// was done for the same header
+func (ste *scheduledTxsExecution) ForceRollBackToBlock(headerHash []byte) error {
+	_, err := ste.rollBackToBlock(headerHash, true)
+	return err
+}
+
+func (ste *scheduledTxsExecution) rollBackToBlock(headerHash []byte, force bool) (*process.ScheduledInfo, error) {
+	scheduledInfo, isRestored, err := ste.restoreScheduledInfoForHeader(headerHash, force)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if !isRestored {
+		log.Debug("scheduledTxsExecution.RollBackToBlock: already rolled back to this header", "header hash", headerHash)
+		return scheduledInfo, nil
 	}
 
 	log.Debug("scheduledTxsExecution.RollBackToBlock",
@@ -536,21 +2722,86 @@ func (ste *scheduledTxsExecution) RollBackToBlock(headerHash []byte) error {
 		"gasPenalized", scheduledInfo.GasAndFees.GasPenalized,
 		"gasRefunded", scheduledInfo.GasAndFees.GasRefunded)
 
-	ste.SetScheduledInfo(scheduledInfo)
+	ste.mutScheduledTxs.RLock()
+	postRollbackVerify := ste.postRollbackVerify
+	ste.mutScheduledTxs.RUnlock()
+	if postRollbackVerify == nil {
+		return scheduledInfo, nil
+	}
+
+	err = postRollbackVerify(scheduledInfo.RootHash)
+	if err != nil {
+		log.Warn("scheduledTxsExecution.RollBackToBlock: post roll back verification failed",
+			"header hash", headerHash,
+			"scheduled root hash", scheduledInfo.RootHash,
+			"error", err)
+
+		// a roll back done meanwhile to another header is kept
+		ste.mutScheduledTxs.Lock()
+		if bytes.Equal(ste.lastRolledBackHeaderHash, headerHash) {
+			ste.lastRolledBackHeaderHash = nil
+		}
+		ste.mutScheduledTxs.Unlock()
 
-	return nil
+		return nil, err
+	}
+
+	return scheduledInfo, nil
 }
 
-// SaveStateIfNeeded saves the scheduled SC execution state for the given header hash, if there are scheduled txs
-func (ste *scheduledTxsExecution) SaveStateIfNeeded(headerHash []byte) {
+// restoreScheduledInfoForHeader loads and sets the scheduled info of the given header under the same lock as the check
+// of the last roll back, so that concurrent roll backs can not interleave. It returns false, together with the scheduled
+// info currently held, if the roll back was already done for the same header and it is not forced
+func (ste *scheduledTxsExecution) restoreScheduledInfoForHeader(headerHash []byte, force bool) (*process.ScheduledInfo, bool, error) {
+	ste.mutScheduledTxs.Lock()
+	defer ste.mutScheduledTxs.Unlock()
+
+	isAlreadyRolledBack := len(ste.lastRolledBackHeaderHash) > 0 && bytes.Equal(ste.lastRolledBackHeaderHash, headerHash)
+	if isAlreadyRolledBack && !force {
+		return ste.getCurrentScheduledInfo(), false, nil
+	}
+
+	scheduledInfo, err := ste.getScheduledInfoForHeader(headerHash, core.OptionalUint32{})
+	if err != nil {
+		return nil, false, err
+	}
+
+	ste.setScheduledInfo(scheduledInfo)
+	ste.lastRolledBackHeaderHash = append([]byte(nil), headerHash...)
+
+	return scheduledInfo, true, nil
+}
+
+// getCurrentScheduledInfo returns a copy of the scheduled info currently held. The caller has to hold the scheduled txs
+// lock, so that all the fields belong to the same state
+func (ste *scheduledTxsExecution) getCurrentScheduledInfo() *process.ScheduledInfo {
 	scheduledInfo := &process.ScheduledInfo{
-		RootHash:        ste.GetScheduledRootHash(),
-		IntermediateTxs: ste.GetScheduledIntermediateTxs(),
-		GasAndFees:      ste.GetScheduledGasAndFees(),
-		MiniBlocks:      ste.GetScheduledMiniBlocks(),
+		RootHash:        ste.scheduledRootHash,
+		IntermediateTxs: make(map[block.Type][]data.TransactionHandler),
+		GasAndFees:      ste.gasAndFees,
+	}
+	for blockType, scheduledIntermediateTxs := range ste.mapScheduledIntermediateTxs {
+		if len(scheduledIntermediateTxs) == 0 {
+			continue
+		}
+
+		scheduledInfo.IntermediateTxs[blockType] = make([]data.TransactionHandler, len(scheduledIntermediateTxs))
+		copy(scheduledInfo.IntermediateTxs[blockType], scheduledIntermediateTxs)
 	}
+	if len(ste.scheduledMbs) > 0 {
+		scheduledInfo.MiniBlocks = make(block.MiniBlockSlice, len(ste.scheduledMbs))
+		for index, scheduledMb := range ste.scheduledMbs {
+			scheduledInfo.MiniBlocks[index] = scheduledMb.Clone()
+		}
+	}
+
+	return scheduledInfo
+}
 
+// SaveStateIfNeeded saves the scheduled SC execution state for the given header hash, if there are scheduled txs
+func (ste *scheduledTxsExecution) SaveStateIfNeeded(headerHash []byte) {
 	ste.mutScheduledTxs.RLock()
+	scheduledInfo := ste.getCurrentScheduledInfo()
 	numScheduledTxs := len(ste.scheduledTxs)
 	ste.mutScheduledTxs.RUnlock()
 
@@ -572,6 +2823,12 @@ func (ste *scheduledTxsExecution) SaveStateIfNeeded(headerHash []byte) {
 
 // SaveState saves the scheduled SC execution state
 func (ste *scheduledTxsExecution) SaveState(headerHash []byte, scheduledInfo *process.ScheduledInfo) {
+	ste.saveState(headerHash, core.OptionalUint32{}, scheduledInfo)
+}
+
+// saveState saves the full scheduled info of the given header, recording the epoch it was saved in, if known, so that
+// the next delta is saved over it only from the same epoch
+func (ste *scheduledTxsExecution) saveState(headerHash []byte, epoch core.OptionalUint32, scheduledInfo *process.ScheduledInfo) {
 	marshalledScheduledInfo, err := ste.getMarshalledScheduledInfo(scheduledInfo)
 	if err != nil {
 		log.Warn("scheduledTxsExecution.SaveState: getMarshalledScheduledInfo", "error", err.Error())
@@ -591,10 +2848,15 @@ func (ste *scheduledTxsExecution) SaveState(headerHash []byte, scheduledInfo *pr
 	err = ste.storer.Put(headerHash, marshalledScheduledInfo)
 	if err != nil {
 		log.Warn("scheduledTxsExecution.SaveState Put -> ScheduledIntermediateTxsUnit", "error", err.Error())
+		return
 	}
+
+	ste.setLastSavedScheduledInfo(headerHash, epoch, scheduledInfo, 0)
+	ste.cacheScheduledInfo(headerHash, scheduledInfo)
 }
 
-// getScheduledInfoForHeader gets scheduled mini blocks, root hash, intermediate txs, gas and fees of the given header from storage
+// getScheduledInfoForHeader gets scheduled mini blocks, root hash, intermediate txs, gas and fees of the given header from
+// the scheduled info cache or, on a miss, from storage
 func (ste *scheduledTxsExecution) getScheduledInfoForHeader(headerHash []byte, epoch core.OptionalUint32) (*process.ScheduledInfo, error) {
 	var data []byte
 	var err error
@@ -607,19 +2869,111 @@ func (ste *scheduledTxsExecution) getScheduledInfoForHeader(headerHash []byte, e
 		}
 	}()
 
+	scheduledInfo, ok := ste.getCachedScheduledInfo(headerHash)
+	if ok {
+		return scheduledInfo, nil
+	}
+
+	data, err = ste.getStoredScheduledInfo(headerHash, epoch)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduledInfo, err = ste.getScheduledInfoFromStoredData(data, epoch)
+	if err != nil {
+		return nil, err
+	}
+
+	ste.cacheScheduledInfo(headerHash, scheduledInfo)
+
+	return scheduledInfo, nil
+}
+
+func (ste *scheduledTxsExecution) getStoredScheduledInfo(headerHash []byte, epoch core.OptionalUint32) ([]byte, error) {
 	if epoch.HasValue {
-		data, err = ste.storer.GetFromEpoch(headerHash, epoch.Value)
-	} else {
-		data, err = ste.storer.Get(headerHash)
+		return ste.storer.GetFromEpoch(headerHash, epoch.Value)
+	}
+
+	return ste.storer.Get(headerHash)
+}
+
+// GetScheduledInfoForHeaders gets the scheduled info of the given headers, from the scheduled info cache or from storage,
+// one header at a time. The headers which do not have scheduled info saved are not included in the returned map, while
+// any other read error is returned. Use GetScheduledInfoForHeadersWithEpoch for a single bulk read from storage
+func (ste *scheduledTxsExecution) GetScheduledInfoForHeaders(headerHashes [][]byte) (map[string]*process.ScheduledInfo, error) {
+	mapScheduledInfo := make(map[string]*process.ScheduledInfo, len(headerHashes))
+	for _, headerHash := range headerHashes {
+		scheduledInfo, err := ste.getScheduledInfoForHeader(headerHash, core.OptionalUint32{})
+		if isScheduledInfoNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w for header hash %x", err, headerHash)
+		}
+
+		mapScheduledInfo[string(headerHash)] = scheduledInfo
+	}
+
+	return mapScheduledInfo, nil
+}
+
+// isScheduledInfoNotFound returns true if the error signals that no scheduled info was saved for the header. A missing
+// base of a saved delta means corrupted storage, so it is not considered a miss
+func isScheduledInfoNotFound(err error) bool {
+	return storage.IsNotFoundInStorageErr(err) && !errors.Is(err, process.ErrMissingScheduledInfoDeltaBase)
+}
+
+// GetScheduledInfoForHeadersWithEpoch gets the scheduled info of the given headers from the given epoch storage,
+// using a single bulk read. The headers which do not have scheduled info saved are not included in the returned map
+func (ste *scheduledTxsExecution) GetScheduledInfoForHeadersWithEpoch(
+	headerHashes [][]byte,
+	epoch uint32,
+) (map[string]*process.ScheduledInfo, error) {
+	keyValuePairs, err := ste.storer.GetBulkFromEpoch(headerHashes, epoch)
+	if err != nil {
+		return nil, err
+	}
+
+	mapScheduledInfo := make(map[string]*process.ScheduledInfo, len(keyValuePairs))
+	for _, keyValuePair := range keyValuePairs {
+		scheduledInfo, errGet := ste.getScheduledInfoFromStoredData(keyValuePair.Value, core.OptionalUint32{Value: epoch, HasValue: true})
+		if errGet != nil {
+			return nil, errGet
+		}
+
+		mapScheduledInfo[string(keyValuePair.Key)] = scheduledInfo
+	}
+
+	return mapScheduledInfo, nil
+}
+
+// getScheduledInfoFromStoredData decodes the stored scheduled info. The base scheduled info of a delta is read from the
+// given epoch, if provided
+func (ste *scheduledTxsExecution) getScheduledInfoFromStoredData(data []byte, epoch core.OptionalUint32) (*process.ScheduledInfo, error) {
+	return ste.getScheduledInfoFromStoredDataWithDepth(data, epoch, 0)
+}
+
+func (ste *scheduledTxsExecution) getScheduledInfoFromStoredDataWithDepth(
+	data []byte,
+	epoch core.OptionalUint32,
+	deltaChainLength int,
+) (*process.ScheduledInfo, error) {
+	if isScheduledInfoDelta(data) {
+		return ste.getScheduledInfoFromDelta(data, epoch, deltaChainLength)
 	}
+
+	data, err := ste.decodeStoredScheduledInfo(data)
 	if err != nil {
 		return nil, err
 	}
 
 	scheduledSCRs := &scheduled.ScheduledSCRs{}
-	err = ste.marshaller.Unmarshal(scheduledSCRs, data)
+	err = ste.scheduledSCRsMarshaller.Unmarshal(scheduledSCRs, data)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %s", process.ErrUnmarshalWithWrongVersion, err.Error())
+	}
+	if scheduledSCRs.GasAndFees == nil {
+		return nil, fmt.Errorf("%w: missing gas and fees", process.ErrUnmarshalWithWrongVersion)
 	}
 
 	scheduledInfo := &process.ScheduledInfo{
@@ -646,7 +3000,39 @@ func (ste *scheduledTxsExecution) getMarshalledScheduledInfo(
 		return nil, err
 	}
 
-	return ste.marshaller.Marshal(scheduledSCRs)
+	marshalledScheduledSCRs, err := ste.scheduledSCRsMarshaller.Marshal(scheduledSCRs)
+	if err != nil {
+		return nil, err
+	}
+
+	return ste.encodeScheduledInfoForStorage(marshalledScheduledSCRs)
+}
+
+func (ste *scheduledTxsExecution) encodeScheduledInfoForStorage(marshalledScheduledSCRs []byte) ([]byte, error) {
+	if check.IfNil(ste.compressor) {
+		return marshalledScheduledSCRs, nil
+	}
+
+	compressedScheduledSCRs, err := ste.compressor.Compress(marshalledScheduledSCRs)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{scheduledInfoFormatMarker, scheduledInfoCompressedFormat}, compressedScheduledSCRs...), nil
+}
+
+func (ste *scheduledTxsExecution) decodeStoredScheduledInfo(data []byte) ([]byte, error) {
+	if len(data) == 0 || data[0] != scheduledInfoFormatMarker {
+		return data, nil
+	}
+	if len(data) < 2 || data[1] != scheduledInfoCompressedFormat {
+		return nil, process.ErrUnknownScheduledInfoFormat
+	}
+	if check.IfNil(ste.compressor) {
+		return nil, fmt.Errorf("%w while reading compressed scheduled info", process.ErrNilDataCompressor)
+	}
+
+	return ste.compressor.Decompress(data[2:])
 }
 
 // IsScheduledTx returns true if the given txHash was scheduled for execution for the current block
@@ -658,6 +3044,20 @@ func (ste *scheduledTxsExecution) IsScheduledTx(txHash []byte) bool {
 	return ok
 }
 
+// AreScheduledTxs returns, in the given order, whether each of the given txHashes was scheduled for execution for the
+// current block
+func (ste *scheduledTxsExecution) AreScheduledTxs(txHashes [][]byte) []bool {
+	areScheduledTxs := make([]bool, len(txHashes))
+
+	ste.mutScheduledTxs.RLock()
+	for index, txHash := range txHashes {
+		_, areScheduledTxs[index] = ste.mapScheduledTxs[string(txHash)]
+	}
+	ste.mutScheduledTxs.RUnlock()
+
+	return areScheduledTxs
+}
+
 // IsMiniBlockExecuted returns true if the given mini block is already executed
 func (ste *scheduledTxsExecution) IsMiniBlockExecuted(mbHash []byte) bool {
 	// TODO: This method and also ste.mapScheduledMbHashes could be removed when we will have mini block header IsFinal method later,
@@ -679,6 +3079,88 @@ func getNumScheduledIntermediateTxs(mapScheduledIntermediateTxs map[block.Type][
 	return numScheduledIntermediateTxs
 }
 
+// GetFailedScheduledTxsMode returns the mode used for the scheduled txs which end with ErrFailedTransaction
+func (ste *scheduledTxsExecution) GetFailedScheduledTxsMode() FailedScheduledTxsMode {
+	return ste.failedScheduledTxsMode
+}
+
+// GetFailedScheduledTxHashes returns the hashes of the scheduled txs which ended with ErrFailedTransaction in the
+// last execution
+func (ste *scheduledTxsExecution) GetFailedScheduledTxHashes() [][]byte {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	failedScheduledTxHashes := make([][]byte, len(ste.failedScheduledTxHashes))
+	copy(failedScheduledTxHashes, ste.failedScheduledTxHashes)
+
+	return failedScheduledTxHashes
+}
+
+// GetScheduledTxsByBlockType returns the hashes of the scheduled txs executed in the last execution, grouped by the
+// type of the mini block they should be placed in: TxBlock for the successful txs and InvalidBlock for the failed ones
+func (ste *scheduledTxsExecution) GetScheduledTxsByBlockType() map[block.Type][][]byte {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	mapScheduledTxsByBlockType := make(map[block.Type][][]byte, len(ste.mapScheduledTxsByBlockType))
+	for blockType, txHashes := range ste.mapScheduledTxsByBlockType {
+		mapScheduledTxsByBlockType[blockType] = make([][]byte, len(txHashes))
+		copy(mapScheduledTxsByBlockType[blockType], txHashes)
+	}
+
+	return mapScheduledTxsByBlockType
+}
+
+// GetScheduledGasPerShard returns the gas carried by the smart contract results produced in the last execution, split
+// by their destination shard
+func (ste *scheduledTxsExecution) GetScheduledGasPerShard() map[uint32]uint64 {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	mapScheduledGasPerShard := make(map[uint32]uint64, len(ste.mapScheduledGasPerShard))
+	for shardID, gas := range ste.mapScheduledGasPerShard {
+		mapScheduledGasPerShard[shardID] = gas
+	}
+
+	return mapScheduledGasPerShard
+}
+
+// GetComputedScheduledRootHash returns the accounts root hash computed after the last execution of the scheduled txs.
+// It returns nil if no accounts adapter was provided or no scheduled txs were executed
+func (ste *scheduledTxsExecution) GetComputedScheduledRootHash() []byte {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	return ste.computedScheduledRootHash
+}
+
+// GetDuplicateIntermediateTxHashes returns the intermediate tx hashes produced more than once by the last execution,
+// sorted
+func (ste *scheduledTxsExecution) GetDuplicateIntermediateTxHashes() [][]byte {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	duplicateInterTxHashes := make([][]byte, len(ste.duplicateInterTxHashes))
+	copy(duplicateInterTxHashes, ste.duplicateInterTxHashes)
+	sort.Slice(duplicateInterTxHashes, func(a, b int) bool {
+		return bytes.Compare(duplicateInterTxHashes[a], duplicateInterTxHashes[b]) < 0
+	})
+
+	return duplicateInterTxHashes
+}
+
+// GetNoOpScheduledTxHashes returns the hashes of the scheduled txs executed since the last Init which did not produce
+// intermediate txs and did not consume gas. The txs are classified only if a gas handler was set
+func (ste *scheduledTxsExecution) GetNoOpScheduledTxHashes() [][]byte {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	noOpScheduledTxHashes := make([][]byte, len(ste.noOpScheduledTxHashes))
+	copy(noOpScheduledTxHashes, ste.noOpScheduledTxHashes)
+
+	return noOpScheduledTxHashes
+}
+
 // IsInterfaceNil returns true if there is no value under the interface
 func (ste *scheduledTxsExecution) IsInterfaceNil() bool {
 	return ste == nil
@@ -2,10 +2,17 @@ package preprocess
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"math/big"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -15,14 +22,22 @@ import (
 	"github.com/ElrondNetwork/elrond-go-core/core/check"
 	"github.com/ElrondNetwork/elrond-go-core/data"
 	"github.com/ElrondNetwork/elrond-go-core/data/block"
+	"github.com/ElrondNetwork/elrond-go-core/data/receipt"
 	"github.com/ElrondNetwork/elrond-go-core/data/scheduled"
 	"github.com/ElrondNetwork/elrond-go-core/data/smartContractResult"
 	"github.com/ElrondNetwork/elrond-go-core/data/transaction"
 	"github.com/ElrondNetwork/elrond-go-core/marshal"
+	"github.com/ElrondNetwork/elrond-go-core/storage"
+	"github.com/ElrondNetwork/elrond-go/common"
+	"github.com/ElrondNetwork/elrond-go/common/compression"
 	"github.com/ElrondNetwork/elrond-go/process"
 	"github.com/ElrondNetwork/elrond-go/process/mock"
+	"github.com/ElrondNetwork/elrond-go/state"
+	"github.com/ElrondNetwork/elrond-go/storage/storageUnit"
 	"github.com/ElrondNetwork/elrond-go/testscommon"
 	"github.com/ElrondNetwork/elrond-go/testscommon/genericMocks"
+	stateMock "github.com/ElrondNetwork/elrond-go/testscommon/state"
+	"github.com/ElrondNetwork/elrond-go/testscommon/statusHandler"
 	storageMocks "github.com/ElrondNetwork/elrond-go/testscommon/storage"
 	vmcommon "github.com/ElrondNetwork/elrond-vm-common"
 	"github.com/stretchr/testify/assert"
@@ -32,110 +47,210 @@ import (
 func TestScheduledTxsExecution_NewScheduledTxsExecutionNilTxProcessor(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, err := NewScheduledTxsExecution(
-		nil,
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+	scheduledTxsExec, err := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      nil,
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	assert.True(t, check.IfNil(scheduledTxsExec))
-	assert.Equal(t, process.ErrNilTxProcessor, err)
+	assert.True(t, errors.Is(err, process.ErrNilTxProcessor))
 }
 
 func TestScheduledTxsExecution_NewScheduledTxsExecutionNilTxCoordinator(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, err := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		nil,
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+	scheduledTxsExec, err := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    nil,
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	assert.True(t, check.IfNil(scheduledTxsExec))
-	assert.Equal(t, process.ErrNilTransactionCoordinator, err)
+	assert.True(t, errors.Is(err, process.ErrNilTransactionCoordinator))
 }
 
 func TestScheduledTxsExecution_NewScheduledTxsExecutionNilStorer(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, err := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		nil,
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+	scheduledTxsExec, err := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           nil,
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	assert.True(t, check.IfNil(scheduledTxsExec))
-	assert.Equal(t, process.ErrNilStorage, err)
+	assert.True(t, errors.Is(err, process.ErrNilStorage))
 }
 
 func TestScheduledTxsExecution_NewScheduledTxsExecutionNilMarshaller(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, err := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		nil,
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+	scheduledTxsExec, err := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       nil,
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	assert.True(t, check.IfNil(scheduledTxsExec))
-	assert.Equal(t, process.ErrNilMarshalizer, err)
+	assert.True(t, errors.Is(err, process.ErrNilMarshalizer))
 }
 
 func TestScheduledTxsExecution_NewScheduledTxsExecutionNilHasher(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, err := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		nil,
-		&mock.ShardCoordinatorStub{},
-	)
+	scheduledTxsExec, err := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           nil,
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	assert.True(t, check.IfNil(scheduledTxsExec))
-	assert.Equal(t, process.ErrNilHasher, err)
+	assert.True(t, errors.Is(err, process.ErrNilHasher))
 }
 
 func TestScheduledTxsExecution_NewScheduledTxsExecutionNilShardCoordinator(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, err := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		nil,
-	)
+	scheduledTxsExec, err := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: nil,
+	})
+
+	assert.True(t, check.IfNil(scheduledTxsExec))
+	assert.True(t, errors.Is(err, process.ErrNilShardCoordinator))
+}
+
+func TestScheduledTxsExecution_NewScheduledTxsExecutionNegativeGracePeriod(t *testing.T) {
+	t.Parallel()
+
+	scheduledTxsExec, err := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:          &testscommon.TxProcessorMock{},
+		TxCoordinator:        &mock.TransactionCoordinatorMock{},
+		Storer:               genericMocks.NewStorerMock(),
+		Marshaller:           &marshal.GogoProtoMarshalizer{},
+		Hasher:               &hashingMocks.HasherMock{},
+		ShardCoordinator:     &mock.ShardCoordinatorStub{},
+		ExecutionGracePeriod: -time.Second,
+	})
 
 	assert.True(t, check.IfNil(scheduledTxsExec))
-	assert.Equal(t, process.ErrNilShardCoordinator, err)
+	assert.True(t, errors.Is(err, process.ErrInvalidValue))
+}
+
+func TestScheduledTxsExecution_NewScheduledTxsExecutionInvalidRetryOnRootHashMismatch(t *testing.T) {
+	t.Parallel()
+
+	t.Run("without accounts adapter", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec, err := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:             &testscommon.TxProcessorMock{},
+			TxCoordinator:           &mock.TransactionCoordinatorMock{},
+			Storer:                  genericMocks.NewStorerMock(),
+			Marshaller:              &marshal.GogoProtoMarshalizer{},
+			Hasher:                  &hashingMocks.HasherMock{},
+			ShardCoordinator:        &mock.ShardCoordinatorStub{},
+			RetryOnRootHashMismatch: true,
+		})
+
+		assert.True(t, check.IfNil(scheduledTxsExec))
+		assert.True(t, errors.Is(err, process.ErrNilAccountsAdapter))
+	})
+}
+
+func TestArgsScheduledTxsExecution_Validate(t *testing.T) {
+	t.Parallel()
+
+	createArgs := func() ArgsScheduledTxsExecution {
+		return ArgsScheduledTxsExecution{
+			TxProcessor:      &testscommon.TxProcessorMock{},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+		}
+	}
+
+	t.Run("valid args should work", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Nil(t, createArgs().Validate())
+	})
+	t.Run("should return the first violation", func(t *testing.T) {
+		t.Parallel()
+
+		args := createArgs()
+		args.Hasher = nil
+		args.ExecutionGracePeriod = -time.Second
+		args.FailedScheduledTxsMode = DropFailedScheduledTxs + 1
+
+		err := args.Validate()
+		assert.True(t, errors.Is(err, process.ErrNilHasher))
+		assert.Contains(t, err.Error(), "NewScheduledTxsExecution")
+	})
+	t.Run("invalid simulation components should error", func(t *testing.T) {
+		t.Parallel()
+
+		args := createArgs()
+		args.Simulation = &ScheduledTxsSimulationComponents{}
+
+		err := args.Validate()
+		assert.True(t, errors.Is(err, process.ErrNilTxProcessor))
+	})
+	t.Run("streaming intermediate txs with retry on root hash mismatch should error", func(t *testing.T) {
+		t.Parallel()
+
+		args := createArgs()
+		args.Accounts = &stateMock.AccountsStub{}
+		args.RetryOnRootHashMismatch = true
+		args.StreamIntermediateTxs = true
+
+		err := args.Validate()
+		assert.True(t, errors.Is(err, process.ErrInvalidValue))
+	})
+	t.Run("invalid max failure ratio should error", func(t *testing.T) {
+		t.Parallel()
+
+		args := createArgs()
+		args.MaxFailureRatio = 1.5
+
+		err := args.Validate()
+		assert.True(t, errors.Is(err, process.ErrInvalidValue))
+	})
 }
 
 func TestScheduledTxsExecution_NewScheduledTxsExecutionOk(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, err := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+	scheduledTxsExec, err := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	assert.Nil(t, err)
 	assert.NotNil(t, scheduledTxsExec)
@@ -144,14 +259,14 @@ func TestScheduledTxsExecution_NewScheduledTxsExecutionOk(t *testing.T) {
 func TestScheduledTxsExecution_InitShouldWork(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
 	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1})
@@ -166,17 +281,45 @@ func TestScheduledTxsExecution_InitShouldWork(t *testing.T) {
 	assert.Equal(t, 0, len(scheduledTxsExec.scheduledTxs))
 }
 
+func TestScheduledTxsExecution_InitShouldCallOnInitHandler(t *testing.T) {
+	t.Parallel()
+
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	scheduledTxsExec.Init()
+
+	numCalls := 0
+	scheduledTxsExec.SetOnInitHandler(func() {
+		numCalls++
+		assert.Equal(t, 0, len(scheduledTxsExec.GetScheduledTxs()))
+	})
+
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+	scheduledTxsExec.Init()
+	assert.Equal(t, 1, numCalls)
+
+	scheduledTxsExec.Init()
+	assert.Equal(t, 2, numCalls)
+}
+
 func TestScheduledTxsExecution_AddShouldWork(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	res := scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
 	assert.True(t, res)
@@ -204,17 +347,110 @@ func TestScheduledTxsExecution_AddShouldWork(t *testing.T) {
 	assert.Equal(t, 3, len(scheduledTxsExec.scheduledTxs))
 }
 
+func TestScheduledTxsExecution_AddShouldCountTheDuplicates(t *testing.T) {
+	t.Parallel()
+
+	numDuplicates := 0
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+		AppStatusHandler: &statusHandler.AppStatusHandlerStub{
+			IncrementHandler: func(key string) {
+				assert.Equal(t, common.MetricScheduledTxsDuplicates, key)
+				numDuplicates++
+			},
+		},
+	})
+
+	res := scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+	assert.True(t, res)
+	assert.Equal(t, 0, numDuplicates)
+
+	res = scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+	assert.False(t, res)
+	assert.Equal(t, 1, numDuplicates)
+
+	res = scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1})
+	assert.True(t, res)
+	assert.Equal(t, 1, numDuplicates)
+}
+
+func TestScheduledTxsExecution_AddScheduledTxsShouldWorkAsSequentialAdds(t *testing.T) {
+	t.Parallel()
+
+	createScheduledTxsExecution := func() *scheduledTxsExecution {
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:      &testscommon.TxProcessorMock{},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+		})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 1})
+
+		return scheduledTxsExec
+	}
+
+	txHashes := [][]byte{[]byte("txHash2"), []byte("txHash1"), []byte("txHash3"), []byte("txHash2")}
+	txs := []data.TransactionHandler{
+		&transaction.Transaction{Nonce: 2},
+		&transaction.Transaction{Nonce: 10},
+		&transaction.Transaction{Nonce: 3},
+		&transaction.Transaction{Nonce: 20},
+	}
+
+	sequentialScheduledTxsExec := createScheduledTxsExecution()
+	numSequentialAddedTxs := 0
+	for index, txHash := range txHashes {
+		if sequentialScheduledTxsExec.AddScheduledTx(txHash, txs[index]) {
+			numSequentialAddedTxs++
+		}
+	}
+
+	bulkScheduledTxsExec := createScheduledTxsExecution()
+	numBulkAddedTxs := bulkScheduledTxsExec.AddScheduledTxs(txHashes, txs)
+
+	assert.Equal(t, 2, numBulkAddedTxs)
+	assert.Equal(t, numSequentialAddedTxs, numBulkAddedTxs)
+	assert.Equal(t, sequentialScheduledTxsExec.scheduledTxHashes, bulkScheduledTxsExec.scheduledTxHashes)
+	assert.Equal(t, sequentialScheduledTxsExec.scheduledTxs, bulkScheduledTxsExec.scheduledTxs)
+	assert.Equal(t, sequentialScheduledTxsExec.mapScheduledTxs, bulkScheduledTxsExec.mapScheduledTxs)
+	assert.Equal(t, [][]byte{[]byte("txHash1"), []byte("txHash2"), []byte("txHash3")}, bulkScheduledTxsExec.scheduledTxHashes)
+}
+
+func TestScheduledTxsExecution_AddScheduledTxsWithLengthMismatchShouldNotAdd(t *testing.T) {
+	t.Parallel()
+
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	numAddedTxs := scheduledTxsExec.AddScheduledTxs([][]byte{[]byte("txHash1")}, nil)
+	assert.Equal(t, 0, numAddedTxs)
+	assert.Equal(t, 0, len(scheduledTxsExec.scheduledTxs))
+}
+
 func TestScheduledTxsExecution_ExecuteShouldErrMissingTransaction(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	err := scheduledTxsExec.Execute([]byte("txHash1"))
 	assert.True(t, errors.Is(err, process.ErrMissingTransaction))
@@ -224,18 +460,18 @@ func TestScheduledTxsExecution_ExecuteShouldErr(t *testing.T) {
 	t.Parallel()
 
 	localError := errors.New("error")
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
 			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
 				return vmcommon.Ok, localError
 			},
 		},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
 	err := scheduledTxsExec.Execute([]byte("txHash1"))
@@ -245,18 +481,18 @@ func TestScheduledTxsExecution_ExecuteShouldErr(t *testing.T) {
 func TestScheduledTxsExecution_ExecuteShouldWorkOnErrFailedTransaction(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
 			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
 				return vmcommon.Ok, process.ErrFailedTransaction
 			},
 		},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
 	err := scheduledTxsExec.Execute([]byte("txHash1"))
@@ -266,18 +502,18 @@ func TestScheduledTxsExecution_ExecuteShouldWorkOnErrFailedTransaction(t *testin
 func TestScheduledTxsExecution_ExecuteShouldWork(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
 			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
 				return vmcommon.Ok, nil
 			},
 		},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
 	err := scheduledTxsExec.Execute([]byte("txHash1"))
@@ -287,157 +523,2254 @@ func TestScheduledTxsExecution_ExecuteShouldWork(t *testing.T) {
 func TestScheduledTxsExecution_ExecuteAllShouldErrNilHaveTimeHandler(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	err := scheduledTxsExec.ExecuteAll(nil)
 	assert.Equal(t, process.ErrNilHaveTimeHandler, err)
 }
 
-func TestScheduledTxsExecution_ExecuteAllShouldErrTimeIsOut(t *testing.T) {
+func TestScheduledTxsExecution_ExecuteAllEmptyQueueShouldNotComputeIntermediateTxs(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{
+			GetAllIntermediateTxsCalled: func() map[block.Type]map[string]data.TransactionHandler {
+				assert.Fail(t, "should have not been called")
+				return nil
+			},
+		},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	haveTimeFunction := func() time.Duration { return time.Duration(-1) }
-	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
 
 	err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
-	assert.Equal(t, process.ErrTimeIsOut, err)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(scheduledTxsExec.GetScheduledIntermediateTxs()))
 }
 
-func TestScheduledTxsExecution_ExecuteAllShouldErrFailedTransaction(t *testing.T) {
+func TestScheduledTxsExecution_ExecuteAllEmptyQueueShouldNotSkipTheNextRollBack(t *testing.T) {
 	t.Parallel()
 
-	localError := errors.New("error")
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{
-			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
-				return vmcommon.Ok, localError
+	headerHash := []byte("header hash")
+	scheduledSCRs := &scheduled.ScheduledSCRs{
+		RootHash: []byte("root hash"),
+		Scrs: []*smartContractResult.SmartContractResult{
+			{
+				Nonce: 0,
 			},
 		},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+		GasAndFees: &scheduled.GasAndFees{},
+	}
+	marshalledSCRsSavedData, _ := json.Marshal(scheduledSCRs)
 
-	haveTimeFunction := func() time.Duration { return time.Duration(100) }
+	numGetCalls := 0
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer: &storageMocks.StorerStub{
+			GetCalled: func(_ []byte) ([]byte, error) {
+				numGetCalls++
+				return marshalledSCRsSavedData, nil
+			},
+		},
+		Marshaller:       &testscommon.MarshalizerMock{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	err := scheduledTxsExec.RollBackToBlock(headerHash)
+	require.Nil(t, err)
+	assert.Equal(t, 1, getNumScheduledIntermediateTxs(scheduledTxsExec.GetScheduledIntermediateTxs()))
+
+	err = scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	require.Nil(t, err)
+	assert.Equal(t, 0, getNumScheduledIntermediateTxs(scheduledTxsExec.GetScheduledIntermediateTxs()))
+
+	err = scheduledTxsExec.RollBackToBlock(headerHash)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, numGetCalls)
+	assert.Equal(t, 1, getNumScheduledIntermediateTxs(scheduledTxsExec.GetScheduledIntermediateTxs()))
+}
+
+func TestScheduledTxsExecution_ExecuteAllEmptyQueueShouldResetTheMiniBlockHashes(t *testing.T) {
+	t.Parallel()
+
+	mbHash := []byte("mb hash")
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer:        genericMocks.NewStorerMock(),
+		Marshaller:    &marshal.GogoProtoMarshalizer{},
+		Hasher: &mock.HasherStub{
+			ComputeCalled: func(s string) []byte {
+				return mbHash
+			},
+		},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	scheduledTxsExec.AddScheduledMiniBlocks(block.MiniBlockSlice{&block.MiniBlock{
+		TxHashes: [][]byte{[]byte("dummyhash")},
+	}})
+	err := scheduledTxsExec.setScheduledMiniBlockHashes()
+	require.Nil(t, err)
+	require.True(t, scheduledTxsExec.IsMiniBlockExecuted(mbHash))
+
+	scheduledTxsExec.AddScheduledMiniBlocks(block.MiniBlockSlice{})
+	err = scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	assert.Nil(t, err)
+	assert.False(t, scheduledTxsExec.IsMiniBlockExecuted(mbHash))
+}
+
+func TestScheduledTxsExecution_ExecuteAllWithContext(t *testing.T) {
+	t.Parallel()
+
+	createScheduledTxsExecution := func(cancel func()) (*scheduledTxsExecution, *int32) {
+		numTxsExecuted := int32(0)
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor: &testscommon.TxProcessorMock{
+				ProcessTransactionCalled: func(_ *transaction.Transaction) (vmcommon.ReturnCode, error) {
+					if atomic.AddInt32(&numTxsExecuted, 1) == 2 {
+						cancel()
+					}
+					return vmcommon.Ok, nil
+				},
+			},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+		})
+		for i := 0; i < 5; i++ {
+			scheduledTxsExec.AddScheduledTx([]byte(fmt.Sprintf("txHash%d", i)), &transaction.Transaction{
+				Nonce:   uint64(i),
+				SndAddr: []byte("sender"),
+			})
+		}
+
+		return scheduledTxsExec, &numTxsExecuted
+	}
+	haveTime := func() time.Duration { return time.Second }
+
+	t.Run("nil context should error", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec, _ := createScheduledTxsExecution(func() {})
+		err := scheduledTxsExec.ExecuteAllWithContext(nil, haveTime)
+		assert.Equal(t, process.ErrNilContext, err)
+	})
+	t.Run("canceled context should stop before the next tx", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		scheduledTxsExec, numTxsExecuted := createScheduledTxsExecution(cancel)
+
+		err := scheduledTxsExec.ExecuteAllWithContext(ctx, haveTime)
+		assert.Equal(t, context.Canceled, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(numTxsExecuted))
+	})
+	t.Run("not canceled context should execute all txs", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec, numTxsExecuted := createScheduledTxsExecution(func() {})
+
+		err := scheduledTxsExec.ExecuteAllWithContext(context.Background(), haveTime)
+		assert.Nil(t, err)
+		assert.Equal(t, int32(5), atomic.LoadInt32(numTxsExecuted))
+	})
+}
+
+func TestScheduledTxsExecution_ExecuteAllShouldErrTimeIsOut(t *testing.T) {
+	t.Parallel()
+
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	haveTimeFunction := func() time.Duration { return time.Duration(-1) }
 	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
 
 	err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
-	assert.Equal(t, localError, err)
+	assert.Equal(t, process.ErrTimeIsOut, err)
 }
 
-func TestScheduledTxsExecution_ExecuteAllShouldWorkOnErrFailedTransaction(t *testing.T) {
+func TestScheduledTxsExecution_ExecuteAllWithGracePeriodShouldFailTxExceedingHardDeadline(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{
+	numTxsStarted := int32(0)
+	numTxsFinished := int32(0)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
 			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
-				return vmcommon.Ok, process.ErrFailedTransaction
+				atomic.AddInt32(&numTxsStarted, 1)
+				time.Sleep(time.Millisecond * 50)
+				atomic.AddInt32(&numTxsFinished, 1)
+				return vmcommon.Ok, nil
 			},
 		},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+		TxCoordinator:        &mock.TransactionCoordinatorMock{},
+		Storer:               genericMocks.NewStorerMock(),
+		Marshaller:           &marshal.GogoProtoMarshalizer{},
+		Hasher:               &hashingMocks.HasherMock{},
+		ShardCoordinator:     &mock.ShardCoordinatorStub{},
+		ExecutionGracePeriod: time.Millisecond * 10,
+	})
 
-	haveTimeFunction := func() time.Duration { return time.Duration(100) }
+	haveTimeFunction := func() time.Duration { return time.Millisecond * 10 }
 	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1})
 
 	err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
-	assert.Nil(t, err)
+	assert.True(t, errors.Is(err, process.ErrTimeIsOut))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&numTxsStarted))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&numTxsFinished))
 }
 
-func TestScheduledTxsExecution_ExecuteAllShouldWork(t *testing.T) {
+func TestScheduledTxsExecution_ExecuteAllWithGracePeriodShouldComputeTheHardDeadlineOnce(t *testing.T) {
 	t.Parallel()
 
-	numTxsExecuted := 0
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{
+	numTxsStarted := int32(0)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
 			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
-				numTxsExecuted++
+				atomic.AddInt32(&numTxsStarted, 1)
+				time.Sleep(time.Millisecond * 30)
 				return vmcommon.Ok, nil
 			},
 		},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+		TxCoordinator:        &mock.TransactionCoordinatorMock{},
+		Storer:               genericMocks.NewStorerMock(),
+		Marshaller:           &marshal.GogoProtoMarshalizer{},
+		Hasher:               &hashingMocks.HasherMock{},
+		ShardCoordinator:     &mock.ShardCoordinatorStub{},
+		ExecutionGracePeriod: time.Millisecond * 10,
+	})
 
-	haveTimeFunction := func() time.Duration { return time.Duration(100) }
+	// the remaining time never decreases, so only a hard deadline computed once stops the execution
+	haveTimeFunction := func() time.Duration { return time.Millisecond * 40 }
 	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
 	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1})
 	scheduledTxsExec.AddScheduledTx([]byte("txHash3"), &transaction.Transaction{Nonce: 2})
 
 	err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
-	assert.Nil(t, err)
-	assert.Equal(t, 3, numTxsExecuted)
+	assert.True(t, errors.Is(err, process.ErrTimeIsOut))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&numTxsStarted))
 }
 
-func TestScheduledTxsExecution_executeShouldErr(t *testing.T) {
+func TestScheduledTxsExecution_ExecuteAllWithGracePeriodShouldLetTxFinishAfterSoftDeadline(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+	numTxsExecuted := int32(0)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				time.Sleep(time.Millisecond * 20)
+				atomic.AddInt32(&numTxsExecuted, 1)
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator:        &mock.TransactionCoordinatorMock{},
+		Storer:               genericMocks.NewStorerMock(),
+		Marshaller:           &marshal.GogoProtoMarshalizer{},
+		Hasher:               &hashingMocks.HasherMock{},
+		ShardCoordinator:     &mock.ShardCoordinatorStub{},
+		ExecutionGracePeriod: time.Second,
+	})
 
-	err := scheduledTxsExec.execute(nil)
-	assert.True(t, errors.Is(err, process.ErrWrongTypeAssertion))
+	numCalls := 0
+	haveTimeFunction := func() time.Duration {
+		numCalls++
+		if numCalls > 2 {
+			return -1
+		}
+		return time.Millisecond
+	}
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1})
+
+	err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
+	assert.Equal(t, process.ErrTimeIsOut, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&numTxsExecuted))
 }
 
-func TestScheduledTxsExecution_executeShouldWork(t *testing.T) {
+func TestScheduledTxsExecution_ExecuteAllShouldErrFailedTransaction(t *testing.T) {
 	t.Parallel()
 
-	response := errors.New("response")
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{
+	localError := errors.New("error")
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
 			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
-				return vmcommon.Ok, response
+				return vmcommon.Ok, localError
 			},
 		},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
-	err := scheduledTxsExec.execute(&transaction.Transaction{Nonce: 0})
-	assert.Equal(t, response, err)
+	haveTimeFunction := func() time.Duration { return time.Duration(100) }
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+
+	err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
+	assert.Equal(t, localError, err)
 }
 
-func TestScheduledTxsExecution_computeScheduledSCRsShouldWork(t *testing.T) {
+func TestScheduledTxsExecution_ExecuteAllShouldWorkOnErrFailedTransaction(t *testing.T) {
 	t.Parallel()
 
-	shardCoordinator := &mock.ShardCoordinatorStub{
-		SameShardCalled: func(_, _ []byte) bool {
-			return false
-		},
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				return vmcommon.Ok, process.ErrFailedTransaction
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	haveTimeFunction := func() time.Duration { return time.Duration(100) }
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+
+	err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
+	assert.Nil(t, err)
+}
+
+func TestScheduledTxsExecution_NewScheduledTxsExecutionInvalidFailedScheduledTxsMode(t *testing.T) {
+	t.Parallel()
+
+	scheduledTxsExec, err := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:            &testscommon.TxProcessorMock{},
+		TxCoordinator:          &mock.TransactionCoordinatorMock{},
+		Storer:                 genericMocks.NewStorerMock(),
+		Marshaller:             &marshal.GogoProtoMarshalizer{},
+		Hasher:                 &hashingMocks.HasherMock{},
+		ShardCoordinator:       &mock.ShardCoordinatorStub{},
+		FailedScheduledTxsMode: DropFailedScheduledTxs + 1,
+	})
+
+	assert.True(t, check.IfNil(scheduledTxsExec))
+	assert.True(t, errors.Is(err, process.ErrInvalidValue))
+}
+
+func TestScheduledTxsExecution_ExecuteAllFailedScheduledTxsModes(t *testing.T) {
+	t.Parallel()
+
+	failedTxHash := []byte("txHash2")
+	createScheduledTxsExecution := func(mode FailedScheduledTxsMode) *scheduledTxsExecution {
+		mapIntermediateTxs := make(map[block.Type]map[string]data.TransactionHandler)
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor: &testscommon.TxProcessorMock{
+				ProcessTransactionCalled: func(tx *transaction.Transaction) (vmcommon.ReturnCode, error) {
+					if tx.Nonce != 1 {
+						return vmcommon.Ok, nil
+					}
+
+					mapIntermediateTxs[block.InvalidBlock] = map[string]data.TransactionHandler{
+						string(failedTxHash): tx,
+					}
+					return vmcommon.UserError, process.ErrFailedTransaction
+				},
+			},
+			TxCoordinator: &mock.TransactionCoordinatorMock{
+				GetAllIntermediateTxsCalled: func() map[block.Type]map[string]data.TransactionHandler {
+					mapCopy := make(map[block.Type]map[string]data.TransactionHandler)
+					for blockType, txs := range mapIntermediateTxs {
+						mapCopy[blockType] = txs
+					}
+					return mapCopy
+				},
+			},
+			Storer:                 genericMocks.NewStorerMock(),
+			Marshaller:             &marshal.GogoProtoMarshalizer{},
+			Hasher:                 &hashingMocks.HasherMock{},
+			ShardCoordinator:       &mock.ShardCoordinatorStub{},
+			FailedScheduledTxsMode: mode,
+		})
+
+		scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+		scheduledTxsExec.AddScheduledTx(failedTxHash, &transaction.Transaction{Nonce: 1})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash3"), &transaction.Transaction{Nonce: 2})
+
+		return scheduledTxsExec
+	}
+	haveTimeFunction := func() time.Duration { return time.Second }
+
+	t.Run("keep failed txs", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec := createScheduledTxsExecution(KeepFailedScheduledTxs)
+		assert.Equal(t, KeepFailedScheduledTxs, scheduledTxsExec.GetFailedScheduledTxsMode())
+
+		err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
+		require.Nil(t, err)
+		assert.Equal(t, [][]byte{failedTxHash}, scheduledTxsExec.GetFailedScheduledTxHashes())
+		assert.Equal(t, 3, len(scheduledTxsExec.GetScheduledTxs()))
+		assert.True(t, scheduledTxsExec.IsScheduledTx(failedTxHash))
+		assert.Equal(t, 1, len(scheduledTxsExec.GetScheduledIntermediateTxs()[block.InvalidBlock]))
+
+		expectedScheduledTxsByBlockType := map[block.Type][][]byte{
+			block.TxBlock:      {[]byte("txHash1"), []byte("txHash3")},
+			block.InvalidBlock: {failedTxHash},
+		}
+		assert.Equal(t, expectedScheduledTxsByBlockType, scheduledTxsExec.GetScheduledTxsByBlockType())
+
+		scheduledTxsExec.Init()
+		assert.Equal(t, 0, len(scheduledTxsExec.GetScheduledTxsByBlockType()))
+	})
+
+	t.Run("drop failed txs", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec := createScheduledTxsExecution(DropFailedScheduledTxs)
+		assert.Equal(t, DropFailedScheduledTxs, scheduledTxsExec.GetFailedScheduledTxsMode())
+
+		err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
+		require.Nil(t, err)
+		assert.Equal(t, [][]byte{failedTxHash}, scheduledTxsExec.GetFailedScheduledTxHashes())
+		assert.Equal(t, 2, len(scheduledTxsExec.GetScheduledTxs()))
+		assert.False(t, scheduledTxsExec.IsScheduledTx(failedTxHash))
+		assert.Equal(t, 0, len(scheduledTxsExec.GetScheduledIntermediateTxs()[block.InvalidBlock]))
+
+		expectedScheduledTxsByBlockType := map[block.Type][][]byte{
+			block.TxBlock: {[]byte("txHash1"), []byte("txHash3")},
+		}
+		assert.Equal(t, expectedScheduledTxsByBlockType, scheduledTxsExec.GetScheduledTxsByBlockType())
+
+		scheduledTxsExec.Init()
+		assert.Equal(t, 0, len(scheduledTxsExec.GetFailedScheduledTxHashes()))
+	})
+}
+
+func TestScheduledTxsExecution_ExecuteAllShouldWriteDeterministicTrace(t *testing.T) {
+	t.Parallel()
+
+	executeWithTrace := func() string {
+		mapIntermediateTxs := make(map[block.Type]map[string]data.TransactionHandler)
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor: &testscommon.TxProcessorMock{
+				ProcessTransactionCalled: func(tx *transaction.Transaction) (vmcommon.ReturnCode, error) {
+					if tx.Nonce == 1 {
+						return vmcommon.UserError, process.ErrFailedTransaction
+					}
+
+					mapIntermediateTxs[block.SmartContractResultBlock] = map[string]data.TransactionHandler{
+						"scrHash": &smartContractResult.SmartContractResult{Nonce: 7},
+					}
+					return vmcommon.Ok, nil
+				},
+			},
+			TxCoordinator: &mock.TransactionCoordinatorMock{
+				GetAllIntermediateTxsCalled: func() map[block.Type]map[string]data.TransactionHandler {
+					mapCopy := make(map[block.Type]map[string]data.TransactionHandler)
+					for blockType, txs := range mapIntermediateTxs {
+						mapCopy[blockType] = txs
+					}
+					return mapCopy
+				},
+			},
+			Storer:     genericMocks.NewStorerMock(),
+			Marshaller: &testscommon.MarshalizerMock{},
+			Hasher:     &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{
+				SameShardCalled: func(_, _ []byte) bool {
+					return false
+				},
+			},
+		})
+		scheduledTxsExec.SetGasHandler(&testscommon.GasHandlerStub{
+			GasProvidedAsScheduledCalled: func(hash []byte) uint64 {
+				return 100
+			},
+			GasRefundedCalled: func(hash []byte) uint64 {
+				return 30
+			},
+		})
+		traceBuffer := &bytes.Buffer{}
+		scheduledTxsExec.SetTraceWriter(traceBuffer)
+
+		scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1})
+
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		require.Nil(t, err)
+
+		return traceBuffer.String()
+	}
+
+	trace := executeWithTrace()
+	assert.Equal(t, trace, executeWithTrace())
+
+	scrHash, _ := core.CalculateHash(&testscommon.MarshalizerMock{}, &hashingMocks.HasherMock{}, &smartContractResult.SmartContractResult{Nonce: 7})
+	expectedTrace := fmt.Sprintf(`{"type":"tx","index":0,"hash":"%s","returnCode":"%s","gasConsumed":70}
+{"type":"tx","index":1,"hash":"%s","returnCode":"%s","gasConsumed":70}
+{"type":"intermediateTx","index":0,"hash":"%s","blockType":"%s"}
+`,
+		hex.EncodeToString([]byte("txHash1")),
+		vmcommon.Ok.String(),
+		hex.EncodeToString([]byte("txHash2")),
+		vmcommon.UserError.String(),
+		hex.EncodeToString(scrHash),
+		block.SmartContractResultBlock.String(),
+	)
+	assert.Equal(t, expectedTrace, trace)
+}
+
+func TestScheduledTxsExecution_ExecuteAllShouldComputeGasConsumedOncePerTx(t *testing.T) {
+	t.Parallel()
+
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+	numCalls := 0
+	scheduledTxsExec.SetGasHandler(&testscommon.GasHandlerStub{
+		GasProvidedAsScheduledCalled: func(hash []byte) uint64 {
+			numCalls++
+			return 0
+		},
+	})
+	scheduledTxsExec.SetTraceWriter(&bytes.Buffer{})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1})
+
+	err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	assert.Nil(t, err)
+	assert.Equal(t, 2, numCalls)
+}
+
+func TestScheduledTxsExecution_ExecuteAllShouldHaltOnInvalidGasRefund(t *testing.T) {
+	t.Parallel()
+
+	executedTxs := make([]uint64, 0)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				executedTxs = append(executedTxs, transaction.Nonce)
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+	scheduledTxsExec.SetGasHandler(&testscommon.GasHandlerStub{
+		GasProvidedAsScheduledCalled: func(hash []byte) uint64 {
+			return 100
+		},
+		GasRefundedCalled: func(hash []byte) uint64 {
+			if bytes.Equal(hash, []byte("txHash2")) {
+				return 101
+			}
+			return 40
+		},
+	})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash3"), &transaction.Transaction{Nonce: 2})
+
+	err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	assert.True(t, errors.Is(err, process.ErrInvalidGasRefund))
+	assert.Contains(t, err.Error(), hex.EncodeToString([]byte("txHash2")))
+	assert.Equal(t, []uint64{0, 1}, executedTxs)
+}
+
+func TestScheduledTxsExecution_ExecuteAllShouldClassifyNoOpTxs(t *testing.T) {
+	t.Parallel()
+
+	t.Run("without gas handler should not classify", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:      &testscommon.TxProcessorMock{},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+		})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(scheduledTxsExec.GetNoOpScheduledTxHashes()))
+	})
+	t.Run("should classify txs without intermediate txs and gas consumed", func(t *testing.T) {
+		t.Parallel()
+
+		mapIntermediateTxs := make(map[block.Type]map[string]data.TransactionHandler)
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor: &testscommon.TxProcessorMock{
+				ProcessTransactionCalled: func(tx *transaction.Transaction) (vmcommon.ReturnCode, error) {
+					if tx.Nonce == 1 {
+						mapIntermediateTxs[block.SmartContractResultBlock] = map[string]data.TransactionHandler{
+							"scrHash": &smartContractResult.SmartContractResult{},
+						}
+					}
+					return vmcommon.Ok, nil
+				},
+			},
+			TxCoordinator: &mock.TransactionCoordinatorMock{
+				GetAllIntermediateTxsCalled: func() map[block.Type]map[string]data.TransactionHandler {
+					return mapIntermediateTxs
+				},
+			},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+		})
+		scheduledTxsExec.SetGasHandler(&testscommon.GasHandlerStub{
+			GasProvidedAsScheduledCalled: func(hash []byte) uint64 {
+				if bytes.Equal(hash, []byte("txHash3")) {
+					return 100
+				}
+				return 0
+			},
+		})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash3"), &transaction.Transaction{Nonce: 2})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash4"), &transaction.Transaction{Nonce: 3})
+
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		assert.Nil(t, err)
+		assert.Equal(t, [][]byte{[]byte("txHash1"), []byte("txHash4")}, scheduledTxsExec.GetNoOpScheduledTxHashes())
+
+		scheduledTxsExec.Init()
+		assert.Equal(t, 0, len(scheduledTxsExec.GetNoOpScheduledTxHashes()))
+	})
+}
+
+func TestScheduledTxsExecution_ExecuteAllShouldAccumulateGasPerShard(t *testing.T) {
+	t.Parallel()
+
+	mapIntermediateTxs := map[block.Type]map[string]data.TransactionHandler{
+		block.SmartContractResultBlock: {
+			"previousScrHash": &smartContractResult.SmartContractResult{RcvAddr: []byte("shard0"), GasLimit: 1000},
+		},
+	}
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(tx *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				scrs := make(map[string]data.TransactionHandler)
+				for txHash, txHandler := range mapIntermediateTxs[block.SmartContractResultBlock] {
+					scrs[txHash] = txHandler
+				}
+				scrs[fmt.Sprintf("scrHash%d_0", tx.Nonce)] = &smartContractResult.SmartContractResult{RcvAddr: []byte("shard0"), GasLimit: 10}
+				scrs[fmt.Sprintf("scrHash%d_1", tx.Nonce)] = &smartContractResult.SmartContractResult{RcvAddr: []byte("shard1"), GasLimit: 20}
+				scrs[fmt.Sprintf("scrHash%d_2", tx.Nonce)] = &smartContractResult.SmartContractResult{RcvAddr: []byte("shard2"), GasLimit: 30}
+				mapIntermediateTxs[block.SmartContractResultBlock] = scrs
+
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator: &mock.TransactionCoordinatorMock{
+			GetAllIntermediateTxsCalled: func() map[block.Type]map[string]data.TransactionHandler {
+				mapCopy := make(map[block.Type]map[string]data.TransactionHandler)
+				for blockType, txs := range mapIntermediateTxs {
+					mapCopy[blockType] = txs
+				}
+				return mapCopy
+			},
+		},
+		Storer:     genericMocks.NewStorerMock(),
+		Marshaller: &marshal.GogoProtoMarshalizer{},
+		Hasher:     &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{
+			ComputeIdCalled: func(address []byte) uint32 {
+				switch string(address) {
+				case "shard1":
+					return 1
+				case "shard2":
+					return 2
+				default:
+					return 0
+				}
+			},
+			SameShardCalled: func(_, _ []byte) bool {
+				return false
+			},
+		},
+	})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 1})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 2})
+
+	err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	require.Nil(t, err)
+
+	expectedGasPerShard := map[uint32]uint64{
+		0: 20,
+		1: 40,
+		2: 60,
+	}
+	assert.Equal(t, expectedGasPerShard, scheduledTxsExec.GetScheduledGasPerShard())
+
+	scheduledTxsExec.Init()
+	assert.Equal(t, 0, len(scheduledTxsExec.GetScheduledGasPerShard()))
+}
+
+func TestScheduledTxsExecution_ExecuteAllShouldComputeScheduledRootHash(t *testing.T) {
+	t.Parallel()
+
+	createScheduledTxsExecution := func(accounts state.AccountsAdapter) *scheduledTxsExecution {
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:      &testscommon.TxProcessorMock{},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+			Accounts:         accounts,
+		})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+
+		return scheduledTxsExec
+	}
+	haveTimeFunction := func() time.Duration { return time.Second }
+
+	t.Run("without accounts adapter", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec := createScheduledTxsExecution(nil)
+		err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
+		assert.Nil(t, err)
+		assert.Nil(t, scheduledTxsExec.GetComputedScheduledRootHash())
+	})
+	t.Run("root hash error should err", func(t *testing.T) {
+		t.Parallel()
+
+		expectedErr := errors.New("expected error")
+		scheduledTxsExec := createScheduledTxsExecution(&stateMock.AccountsStub{
+			RootHashCalled: func() ([]byte, error) {
+				return nil, expectedErr
+			},
+		})
+		err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
+		assert.Equal(t, expectedErr, err)
+		assert.Nil(t, scheduledTxsExec.GetComputedScheduledRootHash())
+	})
+	t.Run("should work", func(t *testing.T) {
+		t.Parallel()
+
+		rootHash := []byte("computed root hash")
+		scheduledTxsExec := createScheduledTxsExecution(&stateMock.AccountsStub{
+			RootHashCalled: func() ([]byte, error) {
+				return rootHash, nil
+			},
+		})
+		err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
+		assert.Nil(t, err)
+		assert.Equal(t, rootHash, scheduledTxsExec.GetComputedScheduledRootHash())
+
+		scheduledTxsExec.Init()
+		assert.Nil(t, scheduledTxsExec.GetComputedScheduledRootHash())
+	})
+}
+
+func TestScheduledTxsExecution_ExecuteAllWithRootHashVerifier(t *testing.T) {
+	t.Parallel()
+
+	expectedErr := errors.New("root hash mismatch")
+	type executionState struct {
+		executedTxs       []uint64
+		journalLen        int
+		revertedSnapshots []int
+		revertedFees      [][]byte
+	}
+	createScheduledTxsExecution := func(state *executionState, retry bool) *scheduledTxsExecution {
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor: &testscommon.TxProcessorMock{
+				ProcessTransactionCalled: func(tx *transaction.Transaction) (vmcommon.ReturnCode, error) {
+					state.executedTxs = append(state.executedTxs, tx.Nonce)
+					state.journalLen++
+					if tx.Nonce == 1 {
+						return vmcommon.UserError, process.ErrFailedTransaction
+					}
+					return vmcommon.Ok, nil
+				},
+			},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+			Accounts: &stateMock.AccountsStub{
+				JournalLenCalled: func() int {
+					return state.journalLen
+				},
+				RevertToSnapshotCalled: func(snapshot int) error {
+					state.revertedSnapshots = append(state.revertedSnapshots, snapshot)
+					state.journalLen = snapshot
+					return nil
+				},
+				RootHashCalled: func() ([]byte, error) {
+					return []byte(fmt.Sprintf("root hash %d", len(state.executedTxs))), nil
+				},
+			},
+			FailedScheduledTxsMode:  DropFailedScheduledTxs,
+			RetryOnRootHashMismatch: retry,
+		})
+		scheduledTxsExec.SetFeeHandler(&mock.FeeAccumulatorStub{
+			RevertFeesCalled: func(txHashes [][]byte) {
+				state.revertedFees = append(state.revertedFees, txHashes...)
+			},
+		})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1})
+
+		return scheduledTxsExec
+	}
+	haveTimeFunction := func() time.Duration { return time.Second }
+
+	t.Run("without retry should return the verifier error", func(t *testing.T) {
+		t.Parallel()
+
+		state := &executionState{}
+		scheduledTxsExec := createScheduledTxsExecution(state, false)
+		scheduledTxsExec.SetRootHashVerifier(func(rootHash []byte) error {
+			return expectedErr
+		})
+
+		err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
+		assert.Equal(t, expectedErr, err)
+		assert.Equal(t, []uint64{0, 1}, state.executedTxs)
+		assert.Equal(t, 0, len(state.revertedSnapshots))
+	})
+	t.Run("with retry should execute again from the pre-execution snapshot", func(t *testing.T) {
+		t.Parallel()
+
+		state := &executionState{journalLen: 5}
+		scheduledTxsExec := createScheduledTxsExecution(state, true)
+		verifiedRootHashes := make([]string, 0)
+		scheduledTxsExec.SetRootHashVerifier(func(rootHash []byte) error {
+			verifiedRootHashes = append(verifiedRootHashes, string(rootHash))
+			if len(verifiedRootHashes) == 1 {
+				return expectedErr
+			}
+			return nil
+		})
+
+		err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
+		assert.Nil(t, err)
+		assert.Equal(t, []uint64{0, 1, 0, 1}, state.executedTxs)
+		assert.Equal(t, []int{5}, state.revertedSnapshots)
+		assert.Equal(t, [][]byte{[]byte("txHash1"), []byte("txHash2")}, state.revertedFees)
+		assert.Equal(t, []string{"root hash 2", "root hash 4"}, verifiedRootHashes)
+		assert.Equal(t, []byte("root hash 4"), scheduledTxsExec.GetComputedScheduledRootHash())
+		assert.Equal(t, 1, len(scheduledTxsExec.GetScheduledTxs()))
+		assert.False(t, scheduledTxsExec.IsScheduledTx([]byte("txHash2")))
+	})
+	t.Run("with retry should return the verifier error of the second execution", func(t *testing.T) {
+		t.Parallel()
+
+		state := &executionState{}
+		scheduledTxsExec := createScheduledTxsExecution(state, true)
+		scheduledTxsExec.SetRootHashVerifier(func(rootHash []byte) error {
+			return expectedErr
+		})
+
+		err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
+		assert.Equal(t, expectedErr, err)
+		assert.Equal(t, []uint64{0, 1, 0, 1}, state.executedTxs)
+		assert.Equal(t, 1, len(state.revertedSnapshots))
+	})
+}
+
+func TestScheduledTxsExecution_ExecuteAllShouldWork(t *testing.T) {
+	t.Parallel()
+
+	numTxsExecuted := 0
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				numTxsExecuted++
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	haveTimeFunction := func() time.Duration { return time.Duration(100) }
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash3"), &transaction.Transaction{Nonce: 2})
+
+	err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, numTxsExecuted)
+}
+
+func TestScheduledTxsExecution_ExecuteAllShouldAccumulateDeveloperFeesPerContract(t *testing.T) {
+	t.Parallel()
+
+	developerFees := big.NewInt(0)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				developerFees.Add(developerFees, big.NewInt(int64(transaction.Nonce)))
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+	scheduledTxsExec.SetFeeHandler(&mock.FeeAccumulatorStub{
+		GetDeveloperFeesCalled: func() *big.Int {
+			return big.NewInt(0).Set(developerFees)
+		},
+	})
+
+	haveTimeFunction := func() time.Duration { return time.Duration(100) }
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 1, RcvAddr: []byte("sc1")})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 0, RcvAddr: []byte("sc2")})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash3"), &transaction.Transaction{Nonce: 2, RcvAddr: []byte("sc1")})
+
+	err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
+	assert.Nil(t, err)
+
+	developerFeesPerContract := scheduledTxsExec.GetDeveloperFeesPerContract()
+	assert.Equal(t, map[string]*big.Int{"sc1": big.NewInt(3)}, developerFeesPerContract)
+
+	developerFeesPerContract["sc1"].SetInt64(100)
+	assert.Equal(t, big.NewInt(3), scheduledTxsExec.GetDeveloperFeesPerContract()["sc1"])
+
+	scheduledTxsExec.Init()
+	assert.Equal(t, 0, len(scheduledTxsExec.GetDeveloperFeesPerContract()))
+}
+
+func TestScheduledTxsExecution_ExecuteAllShouldStopWhenProjectedMiniBlockSpaceIsExhausted(t *testing.T) {
+	t.Parallel()
+
+	executedTxs := make([]uint64, 0)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(tx *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				executedTxs = append(executedTxs, tx.Nonce)
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator:             &mock.TransactionCoordinatorMock{},
+		Storer:                    genericMocks.NewStorerMock(),
+		Marshaller:                &marshal.GogoProtoMarshalizer{},
+		Hasher:                    &hashingMocks.HasherMock{},
+		ShardCoordinator:          &mock.ShardCoordinatorStub{},
+		MaxProjectedMiniBlockSize: 250,
+	})
+	scheduledTxsExec.SetIntermediateTxsSizeEstimator(func(tx data.TransactionHandler) uint64 {
+		return tx.GetGasLimit()
+	})
+
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0, GasLimit: 100})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1, GasLimit: 150})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash3"), &transaction.Transaction{Nonce: 2, GasLimit: 10})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash4"), &transaction.Transaction{Nonce: 3, GasLimit: 0})
+	scheduledTxsExec.AddScheduledMiniBlocks(block.MiniBlockSlice{
+		&block.MiniBlock{TxHashes: [][]byte{[]byte("txHash1"), []byte("txHash2"), []byte("txHash3")}},
+		&block.MiniBlock{TxHashes: [][]byte{[]byte("txHash4")}, ReceiverShardID: 1},
+	})
+
+	err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{0, 1}, executedTxs)
+	assert.Equal(t, uint64(250), scheduledTxsExec.GetProjectedMiniBlockSize())
+	assert.Equal(t, [][]byte{[]byte("txHash1"), []byte("txHash2")}, scheduledTxsExec.GetScheduledTxHashes())
+	assert.False(t, scheduledTxsExec.IsScheduledTx([]byte("txHash3")))
+	scheduledMbs := scheduledTxsExec.GetScheduledMiniBlocks()
+	require.Equal(t, 1, len(scheduledMbs))
+	assert.Equal(t, [][]byte{[]byte("txHash1"), []byte("txHash2")}, scheduledMbs[0].TxHashes)
+
+	scheduledTxsExec.Init()
+	assert.Equal(t, uint64(0), scheduledTxsExec.GetProjectedMiniBlockSize())
+}
+
+func TestScheduledTxsExecution_ExecuteAllWithoutSpaceBudgetShouldOnlyProjectMiniBlockSize(t *testing.T) {
+	t.Parallel()
+
+	numTxsExecuted := 0
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(tx *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				numTxsExecuted++
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+	scheduledTxsExec.SetIntermediateTxsSizeEstimator(func(tx data.TransactionHandler) uint64 {
+		return 1000
+	})
+
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1})
+
+	err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	assert.Nil(t, err)
+	assert.Equal(t, 2, numTxsExecuted)
+	assert.Equal(t, uint64(2000), scheduledTxsExec.GetProjectedMiniBlockSize())
+}
+
+func TestScheduledTxsExecution_ExecuteAllShouldRecordFeePerTx(t *testing.T) {
+	t.Parallel()
+
+	accumulatedFees := big.NewInt(0)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				accumulatedFees.Add(accumulatedFees, big.NewInt(int64(transaction.Nonce*10)))
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+	scheduledTxsExec.SetFeeHandler(&mock.FeeAccumulatorStub{
+		GetAccumulatedFeesCalled: func() *big.Int {
+			return big.NewInt(0).Set(accumulatedFees)
+		},
+	})
+
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 1})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 0})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash3"), &transaction.Transaction{Nonce: 2})
+
+	err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	assert.Nil(t, err)
+
+	fee, ok := scheduledTxsExec.GetScheduledTxFee([]byte("txHash1"))
+	assert.True(t, ok)
+	assert.Equal(t, big.NewInt(10), fee)
+	fee, ok = scheduledTxsExec.GetScheduledTxFee([]byte("txHash2"))
+	assert.True(t, ok)
+	assert.Equal(t, big.NewInt(0), fee)
+	fee, ok = scheduledTxsExec.GetScheduledTxFee([]byte("txHash3"))
+	assert.True(t, ok)
+	assert.Equal(t, big.NewInt(20), fee)
+
+	fee, ok = scheduledTxsExec.GetScheduledTxFee([]byte("txHash4"))
+	assert.False(t, ok)
+	assert.Nil(t, fee)
+
+	scheduledTxsExec.Init()
+	_, ok = scheduledTxsExec.GetScheduledTxFee([]byte("txHash1"))
+	assert.False(t, ok)
+}
+
+func TestScheduledTxsExecution_ExecuteAllShouldGenerateReceipts(t *testing.T) {
+	t.Parallel()
+
+	createScheduledTxsExecution := func(generateReceipts bool, failedScheduledTxsMode FailedScheduledTxsMode) *scheduledTxsExecution {
+		accumulatedFees := big.NewInt(0)
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor: &testscommon.TxProcessorMock{
+				ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
+					accumulatedFees.Add(accumulatedFees, big.NewInt(int64(transaction.Nonce*10)))
+					if transaction.Nonce == 2 {
+						return vmcommon.UserError, process.ErrFailedTransaction
+					}
+					return vmcommon.Ok, nil
+				},
+			},
+			TxCoordinator:          &mock.TransactionCoordinatorMock{},
+			Storer:                 genericMocks.NewStorerMock(),
+			Marshaller:             &marshal.GogoProtoMarshalizer{},
+			Hasher:                 &hashingMocks.HasherMock{},
+			ShardCoordinator:       &mock.ShardCoordinatorStub{},
+			FailedScheduledTxsMode: failedScheduledTxsMode,
+			GenerateReceipts:       generateReceipts,
+		})
+		scheduledTxsExec.SetFeeHandler(&mock.FeeAccumulatorStub{
+			GetAccumulatedFeesCalled: func() *big.Int {
+				return big.NewInt(0).Set(accumulatedFees)
+			},
+		})
+
+		scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 1, SndAddr: []byte("alice")})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 2, SndAddr: []byte("bob")})
+
+		return scheduledTxsExec
+	}
+
+	t.Run("receipts not enabled", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec := createScheduledTxsExecution(false, KeepFailedScheduledTxs)
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		require.Nil(t, err)
+
+		_, ok := scheduledTxsExec.GetScheduledReceiptFor([]byte("txHash1"))
+		assert.False(t, ok)
+		assert.Nil(t, scheduledTxsExec.GetScheduledIntermediateTxs()[block.ReceiptBlock])
+	})
+	t.Run("receipts enabled", func(t *testing.T) {
+		t.Parallel()
+
+		marshaller := &marshal.GogoProtoMarshalizer{}
+		scheduledTxsExec := createScheduledTxsExecution(true, KeepFailedScheduledTxs)
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		require.Nil(t, err)
+
+		expectedReceipt1 := &receipt.Receipt{
+			Value:   big.NewInt(10),
+			SndAddr: []byte("alice"),
+			Data:    []byte(vmcommon.Ok.String()),
+			TxHash:  []byte("txHash1"),
+		}
+		expectedReceipt2 := &receipt.Receipt{
+			Value:   big.NewInt(20),
+			SndAddr: []byte("bob"),
+			Data:    []byte(vmcommon.UserError.String()),
+			TxHash:  []byte("txHash2"),
+		}
+
+		marshalledReceipt, ok := scheduledTxsExec.GetScheduledReceiptFor([]byte("txHash1"))
+		require.True(t, ok)
+		rpt := &receipt.Receipt{}
+		require.Nil(t, marshaller.Unmarshal(rpt, marshalledReceipt))
+		assert.Equal(t, expectedReceipt1, rpt)
+
+		marshalledReceipt, ok = scheduledTxsExec.GetScheduledReceiptFor([]byte("txHash2"))
+		require.True(t, ok)
+		rpt = &receipt.Receipt{}
+		require.Nil(t, marshaller.Unmarshal(rpt, marshalledReceipt))
+		assert.Equal(t, expectedReceipt2, rpt)
+
+		assert.Equal(t, []data.TransactionHandler{expectedReceipt1, expectedReceipt2}, scheduledTxsExec.GetScheduledIntermediateTxs()[block.ReceiptBlock])
+
+		scheduledTxsExec.Init()
+		_, ok = scheduledTxsExec.GetScheduledReceiptFor([]byte("txHash1"))
+		assert.False(t, ok)
+	})
+	t.Run("receipts of the dropped failed txs should not be generated", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec := createScheduledTxsExecution(true, DropFailedScheduledTxs)
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		require.Nil(t, err)
+
+		_, ok := scheduledTxsExec.GetScheduledReceiptFor([]byte("txHash1"))
+		assert.True(t, ok)
+		_, ok = scheduledTxsExec.GetScheduledReceiptFor([]byte("txHash2"))
+		assert.False(t, ok)
+		assert.Equal(t, 1, len(scheduledTxsExec.GetScheduledIntermediateTxs()[block.ReceiptBlock]))
+	})
+}
+
+func TestScheduledTxsExecution_ExecuteAllShouldStreamIntermediateTxs(t *testing.T) {
+	t.Parallel()
+
+	createScheduledTxsExecution := func(mapAllIntermediateTxs map[block.Type]map[string]data.TransactionHandler) *scheduledTxsExecution {
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor: &testscommon.TxProcessorMock{
+				ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
+					if transaction.Nonce == 0 {
+						return vmcommon.Ok, nil
+					}
+
+					scrHash := fmt.Sprintf("scrHash%d", transaction.Nonce)
+					mapAllIntermediateTxs[block.SmartContractResultBlock][scrHash] = &smartContractResult.SmartContractResult{Nonce: transaction.Nonce}
+					return vmcommon.Ok, nil
+				},
+			},
+			TxCoordinator: &mock.TransactionCoordinatorMock{
+				GetAllIntermediateTxsCalled: func() map[block.Type]map[string]data.TransactionHandler {
+					mapCopy := make(map[block.Type]map[string]data.TransactionHandler)
+					for blockType, allIntermediateTxs := range mapAllIntermediateTxs {
+						mapCopy[blockType] = make(map[string]data.TransactionHandler)
+						for txHash, txHandler := range allIntermediateTxs {
+							mapCopy[blockType][txHash] = txHandler
+						}
+					}
+					return mapCopy
+				},
+			},
+			Storer:     genericMocks.NewStorerMock(),
+			Marshaller: &marshal.GogoProtoMarshalizer{},
+			Hasher:     &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{
+				SameShardCalled: func(_, _ []byte) bool {
+					return false
+				},
+			},
+			StreamIntermediateTxs: true,
+		})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 1})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 0})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash3"), &transaction.Transaction{Nonce: 2})
+
+		return scheduledTxsExec
+	}
+
+	t.Run("nil consumer should error", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec := createScheduledTxsExecution(map[block.Type]map[string]data.TransactionHandler{
+			block.SmartContractResultBlock: {},
+		})
+
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		assert.Equal(t, process.ErrNilIntermediateTxsConsumer, err)
+	})
+	t.Run("consumer error should stop the execution", func(t *testing.T) {
+		t.Parallel()
+
+		expectedErr := errors.New("expected error")
+		numCalls := 0
+		scheduledTxsExec := createScheduledTxsExecution(map[block.Type]map[string]data.TransactionHandler{
+			block.SmartContractResultBlock: {},
+		})
+		scheduledTxsExec.SetIntermediateTxsConsumer(func(_ []byte, _ map[block.Type][]data.TransactionHandler) error {
+			numCalls++
+			return expectedErr
+		})
+
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		assert.Equal(t, expectedErr, err)
+		assert.Equal(t, 1, numCalls)
+	})
+	t.Run("should hand the intermediate txs of each tx to the consumer", func(t *testing.T) {
+		t.Parallel()
+
+		previousScr := &smartContractResult.SmartContractResult{Nonce: 100}
+		scheduledTxsExec := createScheduledTxsExecution(map[block.Type]map[string]data.TransactionHandler{
+			block.SmartContractResultBlock: {
+				"previousScrHash": previousScr,
+			},
+		})
+		streamedTxHashes := make([][]byte, 0)
+		streamedIntermediateTxs := make([]map[block.Type][]data.TransactionHandler, 0)
+		scheduledTxsExec.SetIntermediateTxsConsumer(func(txHash []byte, intermediateTxs map[block.Type][]data.TransactionHandler) error {
+			streamedTxHashes = append(streamedTxHashes, txHash)
+			streamedIntermediateTxs = append(streamedIntermediateTxs, intermediateTxs)
+			return nil
+		})
+
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		require.Nil(t, err)
+
+		assert.Equal(t, [][]byte{[]byte("txHash1"), []byte("txHash3")}, streamedTxHashes)
+		assert.Equal(t, []map[block.Type][]data.TransactionHandler{
+			{block.SmartContractResultBlock: {&smartContractResult.SmartContractResult{Nonce: 1}}},
+			{block.SmartContractResultBlock: {&smartContractResult.SmartContractResult{Nonce: 2}}},
+		}, streamedIntermediateTxs)
+		assert.Equal(t, 0, len(scheduledTxsExec.GetScheduledIntermediateTxs()))
+	})
+}
+
+func TestScheduledTxsExecution_ExecuteAllOrderedBySenderNonceHashShouldKeepTheOrderAfterRestart(t *testing.T) {
+	t.Parallel()
+
+	type scheduledTx struct {
+		txHash []byte
+		tx     *transaction.Transaction
+	}
+	scheduledTxs := []scheduledTx{
+		{txHash: []byte("txHash1"), tx: &transaction.Transaction{SndAddr: []byte("bob"), Nonce: 1, Data: []byte("txHash1")}},
+		{txHash: []byte("txHash2"), tx: &transaction.Transaction{SndAddr: []byte("alice"), Nonce: 2, Data: []byte("txHash2")}},
+		{txHash: []byte("txHash3"), tx: &transaction.Transaction{SndAddr: []byte("bob"), Nonce: 0, Data: []byte("txHash3")}},
+		{txHash: []byte("txHash4"), tx: &transaction.Transaction{SndAddr: []byte("alice"), Nonce: 1, Data: []byte("txHash4")}},
+		{txHash: []byte("txHash5"), tx: &transaction.Transaction{SndAddr: []byte("alice"), Nonce: 1, Data: []byte("txHash5")}},
+	}
+
+	executeInArrivalOrder := func(arrivalOrder []int) []string {
+		executedTxs := make([]string, 0)
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor: &testscommon.TxProcessorMock{
+				ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
+					executedTxs = append(executedTxs, string(transaction.Data))
+					return vmcommon.Ok, nil
+				},
+			},
+			TxCoordinator:          &mock.TransactionCoordinatorMock{},
+			Storer:                 genericMocks.NewStorerMock(),
+			Marshaller:             &marshal.GogoProtoMarshalizer{},
+			Hasher:                 &hashingMocks.HasherMock{},
+			ShardCoordinator:       &mock.ShardCoordinatorStub{},
+			OrderBySenderNonceHash: true,
+		})
+		for _, index := range arrivalOrder {
+			scheduledTxsExec.AddScheduledTx(scheduledTxs[index].txHash, scheduledTxs[index].tx)
+		}
+
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		require.Nil(t, err)
+
+		return executedTxs
+	}
+
+	expectedExecutedTxs := []string{"txHash4", "txHash5", "txHash2", "txHash3", "txHash1"}
+	executedTxsBeforeRestart := executeInArrivalOrder([]int{0, 1, 2, 3, 4})
+	assert.Equal(t, expectedExecutedTxs, executedTxsBeforeRestart)
+
+	// after a restart, the same scheduled txs are added again, in a different order
+	executedTxsAfterRestart := executeInArrivalOrder([]int{4, 2, 0, 3, 1})
+	assert.Equal(t, executedTxsBeforeRestart, executedTxsAfterRestart)
+}
+
+func TestScheduledTxsExecution_ExecuteAllShouldStopWhenTheExecutionBudgetIsExhausted(t *testing.T) {
+	t.Parallel()
+
+	executedTxs := make([]uint64, 0)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				executedTxs = append(executedTxs, transaction.Nonce)
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+	scheduledTxsExec.SetGasHandler(&testscommon.GasHandlerStub{
+		GasProvidedAsScheduledCalled: func(hash []byte) uint64 {
+			return 60
+		},
+		GasRefundedCalled: func(hash []byte) uint64 {
+			return 10
+		},
+	})
+	scheduledTxsExec.SetExecutionBudget(NewGasExecutionBudget(100))
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash3"), &transaction.Transaction{Nonce: 2})
+	scheduledTxsExec.AddScheduledMiniBlocks(block.MiniBlockSlice{
+		&block.MiniBlock{TxHashes: [][]byte{[]byte("txHash1"), []byte("txHash2"), []byte("txHash3")}},
+	})
+
+	err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{0, 1}, executedTxs)
+	assert.Equal(t, [][]byte{[]byte("txHash1"), []byte("txHash2")}, scheduledTxsExec.GetScheduledTxHashes())
+	scheduledMbs := scheduledTxsExec.GetScheduledMiniBlocks()
+	require.Equal(t, 1, len(scheduledMbs))
+	assert.Equal(t, [][]byte{[]byte("txHash1"), []byte("txHash2")}, scheduledMbs[0].TxHashes)
+}
+
+func TestScheduledTxsExecution_ExecuteAllWithPriorityQueueShouldExecuteTheTxsAddedMidRunByPriority(t *testing.T) {
+	t.Parallel()
+
+	var scheduledTxsExec *scheduledTxsExecution
+	executedTxs := make([]uint64, 0)
+	scheduledTxsExec, _ = NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(tx *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				executedTxs = append(executedTxs, tx.Nonce)
+				if tx.Nonce == 0 {
+					assert.True(t, scheduledTxsExec.AddScheduledTx([]byte("txHash4"), &transaction.Transaction{Nonce: 3, GasPrice: 0}))
+					assert.True(t, scheduledTxsExec.AddScheduledTx([]byte("txHash5"), &transaction.Transaction{Nonce: 4, GasPrice: 5}))
+					assert.False(t, scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1, GasPrice: 1}))
+				}
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+		UsePriorityQueue: true,
+	})
+	scheduledTxsExec.SetExecutionPriorityHandler(func(tx data.TransactionHandler) uint64 {
+		return tx.GetGasPrice()
+	})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0, GasPrice: 1})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1, GasPrice: 1})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash3"), &transaction.Transaction{Nonce: 2, GasPrice: 1})
+
+	err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{0, 4, 1, 2, 3}, executedTxs)
+	assert.Equal(t, 5, len(scheduledTxsExec.GetScheduledTxs()))
+	assert.True(t, scheduledTxsExec.IsScheduledTx([]byte("txHash5")))
+}
+
+func TestScheduledTxsExecution_AddWhileExecutingByPriorityShouldSkipAndCountTheDuplicates(t *testing.T) {
+	t.Parallel()
+
+	var scheduledTxsExec *scheduledTxsExecution
+	numDuplicates := 0
+	executedTxs := make([]uint64, 0)
+	scheduledTxsExec, _ = NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(tx *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				executedTxs = append(executedTxs, tx.Nonce)
+				if tx.Nonce == 0 {
+					numAdded := scheduledTxsExec.AddScheduledTxs(
+						[][]byte{[]byte("txHash1"), []byte("txHash3"), []byte("txHash3")},
+						[]data.TransactionHandler{
+							&transaction.Transaction{Nonce: 0, GasPrice: 3},
+							&transaction.Transaction{Nonce: 2, GasPrice: 1},
+							&transaction.Transaction{Nonce: 2, GasPrice: 1},
+						},
+					)
+					assert.Equal(t, 1, numAdded)
+					assert.Equal(t, 2, numDuplicates)
+
+					assert.False(t, scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1, GasPrice: 2}))
+					assert.Equal(t, 3, numDuplicates)
+				}
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+		AppStatusHandler: &statusHandler.AppStatusHandlerStub{
+			IncrementHandler: func(key string) {
+				assert.Equal(t, common.MetricScheduledTxsDuplicates, key)
+				numDuplicates++
+			},
+		},
+		UsePriorityQueue: true,
+	})
+	scheduledTxsExec.SetExecutionPriorityHandler(func(tx data.TransactionHandler) uint64 {
+		return tx.GetGasPrice()
+	})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0, GasPrice: 3})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1, GasPrice: 2})
+
+	err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{0, 1, 2}, executedTxs)
+	assert.Equal(t, 3, len(scheduledTxsExec.GetScheduledTxs()))
+	assert.Equal(t, 3, numDuplicates)
+}
+
+func TestScheduledTxsExecution_ExecuteAllWithoutPriorityQueueShouldIgnoreThePriority(t *testing.T) {
+	t.Parallel()
+
+	executedTxs := make([]uint64, 0)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				executedTxs = append(executedTxs, transaction.Nonce)
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+	scheduledTxsExec.SetExecutionPriorityHandler(func(tx data.TransactionHandler) uint64 {
+		return tx.GetGasPrice()
+	})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0, GasPrice: 1})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1, GasPrice: 5})
+
+	err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{0, 1}, executedTxs)
+}
+
+func TestScheduledTxsExecution_ExecuteAllWithMaxFailureRatio(t *testing.T) {
+	t.Parallel()
+
+	createScheduledTxsExec := func(maxFailureRatio float64, executedTxs *[]uint64) *scheduledTxsExecution {
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor: &testscommon.TxProcessorMock{
+				ProcessTransactionCalled: func(tx *transaction.Transaction) (vmcommon.ReturnCode, error) {
+					*executedTxs = append(*executedTxs, tx.Nonce)
+					if tx.Nonce >= 1 && tx.Nonce <= 3 {
+						return vmcommon.UserError, process.ErrFailedTransaction
+					}
+					return vmcommon.Ok, nil
+				},
+			},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+			MaxFailureRatio:  maxFailureRatio,
+			MinFailureSample: 4,
+		})
+		for i := 0; i < 6; i++ {
+			scheduledTxsExec.AddScheduledTx([]byte(fmt.Sprintf("txHash%d", i)), &transaction.Transaction{Nonce: uint64(i)})
+		}
+
+		return scheduledTxsExec
+	}
+
+	t.Run("disabled check should execute all txs", func(t *testing.T) {
+		t.Parallel()
+
+		executedTxs := make([]uint64, 0)
+		scheduledTxsExec := createScheduledTxsExec(0, &executedTxs)
+
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		assert.Nil(t, err)
+		assert.Equal(t, []uint64{0, 1, 2, 3, 4, 5}, executedTxs)
+		assert.Equal(t, 0.5, scheduledTxsExec.GetScheduledFailureRatio())
+	})
+	t.Run("should abort after the minimum sample when the ratio is exceeded", func(t *testing.T) {
+		t.Parallel()
+
+		executedTxs := make([]uint64, 0)
+		scheduledTxsExec := createScheduledTxsExec(0.5, &executedTxs)
+
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		assert.True(t, errors.Is(err, process.ErrScheduledFailureThresholdExceeded))
+		assert.Equal(t, []uint64{0, 1, 2, 3}, executedTxs)
+		assert.Equal(t, 0.75, scheduledTxsExec.GetScheduledFailureRatio())
+	})
+	t.Run("ratio within the threshold should execute all txs", func(t *testing.T) {
+		t.Parallel()
+
+		executedTxs := make([]uint64, 0)
+		scheduledTxsExec := createScheduledTxsExec(0.8, &executedTxs)
+
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		assert.Nil(t, err)
+		assert.Equal(t, 6, len(executedTxs))
+	})
+}
+
+func TestScheduledTxsExecution_ExecuteAllWithCanonicalExecutionOrder(t *testing.T) {
+	t.Parallel()
+
+	createScheduledTxsExec := func(executedTxs *[]uint64) *scheduledTxsExecution {
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor: &testscommon.TxProcessorMock{
+				ProcessTransactionCalled: func(tx *transaction.Transaction) (vmcommon.ReturnCode, error) {
+					*executedTxs = append(*executedTxs, tx.Nonce)
+					return vmcommon.Ok, nil
+				},
+			},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+		})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash0"), &transaction.Transaction{Nonce: 0})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 1})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 2})
+
+		return scheduledTxsExec
+	}
+
+	t.Run("should execute in the supplied order", func(t *testing.T) {
+		t.Parallel()
+
+		executedTxs := make([]uint64, 0)
+		scheduledTxsExec := createScheduledTxsExec(&executedTxs)
+		scheduledTxsExec.SetCanonicalExecutionOrder([][]byte{[]byte("txHash2"), []byte("txHash0"), []byte("txHash1")})
+
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		assert.Nil(t, err)
+		assert.Equal(t, []uint64{2, 0, 1}, executedTxs)
+	})
+	t.Run("unknown tx hash should error", func(t *testing.T) {
+		t.Parallel()
+
+		executedTxs := make([]uint64, 0)
+		scheduledTxsExec := createScheduledTxsExec(&executedTxs)
+		scheduledTxsExec.SetCanonicalExecutionOrder([][]byte{[]byte("txHash2"), []byte("txHash3")})
+
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		assert.True(t, errors.Is(err, process.ErrUnexpectedScheduledTx))
+		assert.Equal(t, 0, len(executedTxs))
+	})
+	t.Run("duplicate tx hash should error", func(t *testing.T) {
+		t.Parallel()
+
+		executedTxs := make([]uint64, 0)
+		scheduledTxsExec := createScheduledTxsExec(&executedTxs)
+		scheduledTxsExec.SetCanonicalExecutionOrder([][]byte{[]byte("txHash1"), []byte("txHash1")})
+
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		assert.True(t, errors.Is(err, process.ErrUnexpectedScheduledTx))
+		assert.Equal(t, 0, len(executedTxs))
+	})
+	t.Run("init should reset the order", func(t *testing.T) {
+		t.Parallel()
+
+		executedTxs := make([]uint64, 0)
+		scheduledTxsExec := createScheduledTxsExec(&executedTxs)
+		scheduledTxsExec.SetCanonicalExecutionOrder([][]byte{[]byte("txHash2")})
+		scheduledTxsExec.Init()
+		scheduledTxsExec.AddScheduledTx([]byte("txHash0"), &transaction.Transaction{Nonce: 0})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 1})
+
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		assert.Nil(t, err)
+		assert.Equal(t, []uint64{0, 1}, executedTxs)
+	})
+}
+
+func TestScheduledTxsExecution_GetExecutionFingerprint(t *testing.T) {
+	t.Parallel()
+
+	type txOutcome struct {
+		returnCode vmcommon.ReturnCode
+		scrHash    string
+	}
+
+	computeFingerprint := func(outcomes map[uint64]txOutcome, gasRefunded uint64) []byte {
+		mapAllIntermediateTxs := map[block.Type]map[string]data.TransactionHandler{
+			block.SmartContractResultBlock: {},
+		}
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor: &testscommon.TxProcessorMock{
+				ProcessTransactionCalled: func(tx *transaction.Transaction) (vmcommon.ReturnCode, error) {
+					outcome := outcomes[tx.Nonce]
+					if len(outcome.scrHash) > 0 {
+						mapAllIntermediateTxs[block.SmartContractResultBlock][outcome.scrHash] = &smartContractResult.SmartContractResult{Nonce: tx.Nonce}
+					}
+					if outcome.returnCode != vmcommon.Ok {
+						return outcome.returnCode, process.ErrFailedTransaction
+					}
+					return vmcommon.Ok, nil
+				},
+			},
+			TxCoordinator: &mock.TransactionCoordinatorMock{
+				GetAllIntermediateTxsCalled: func() map[block.Type]map[string]data.TransactionHandler {
+					mapCopy := make(map[block.Type]map[string]data.TransactionHandler)
+					for blockType, txs := range mapAllIntermediateTxs {
+						mapCopy[blockType] = make(map[string]data.TransactionHandler)
+						for txHash, tx := range txs {
+							mapCopy[blockType][txHash] = tx
+						}
+					}
+					return mapCopy
+				},
+			},
+			Storer:     genericMocks.NewStorerMock(),
+			Marshaller: &marshal.GogoProtoMarshalizer{},
+			Hasher:     &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{
+				SameShardCalled: func(_, _ []byte) bool {
+					return false
+				},
+			},
+			ComputeExecutionFingerprint: true,
+		})
+		scheduledTxsExec.SetGasHandler(&testscommon.GasHandlerStub{
+			GasProvidedAsScheduledCalled: func(hash []byte) uint64 {
+				return 100
+			},
+			GasRefundedCalled: func(hash []byte) uint64 {
+				return gasRefunded
+			},
+		})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash0"), &transaction.Transaction{Nonce: 0})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 1})
+
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		require.Nil(t, err)
+
+		return scheduledTxsExec.GetExecutionFingerprint()
+	}
+
+	outcomes := map[uint64]txOutcome{
+		0: {returnCode: vmcommon.Ok, scrHash: "scrHash0"},
+		1: {returnCode: vmcommon.Ok},
+	}
+	fingerprint := computeFingerprint(outcomes, 10)
+	assert.Equal(t, 32, len(fingerprint))
+	assert.Equal(t, fingerprint, computeFingerprint(outcomes, 10))
+
+	assert.NotEqual(t, fingerprint, computeFingerprint(outcomes, 20))
+	assert.NotEqual(t, fingerprint, computeFingerprint(map[uint64]txOutcome{
+		0: {returnCode: vmcommon.Ok, scrHash: "scrHash0"},
+		1: {returnCode: vmcommon.UserError},
+	}, 10))
+	assert.NotEqual(t, fingerprint, computeFingerprint(map[uint64]txOutcome{
+		0: {returnCode: vmcommon.Ok, scrHash: "scrHash1"},
+		1: {returnCode: vmcommon.Ok},
+	}, 10))
+	assert.NotEqual(t, fingerprint, computeFingerprint(map[uint64]txOutcome{
+		0: {returnCode: vmcommon.Ok},
+		1: {returnCode: vmcommon.Ok, scrHash: "scrHash0"},
+	}, 10))
+}
+
+func TestScheduledTxsExecution_GetExecutionFingerprintNotEnabledShouldBeNil(t *testing.T) {
+	t.Parallel()
+
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(_ *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash0"), &transaction.Transaction{Nonce: 0})
+
+	err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	assert.Nil(t, err)
+	assert.Nil(t, scheduledTxsExec.GetExecutionFingerprint())
+}
+
+func TestScheduledTxsExecution_ExecuteAllWithResults(t *testing.T) {
+	t.Parallel()
+
+	expectedErr := errors.New("expected error")
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(tx *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				switch tx.Nonce {
+				case 1:
+					return vmcommon.UserError, process.ErrFailedTransaction
+				case 2:
+					return vmcommon.ExecutionFailed, expectedErr
+				default:
+					return vmcommon.Ok, nil
+				}
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+	for i := 0; i < 4; i++ {
+		scheduledTxsExec.AddScheduledTx([]byte(fmt.Sprintf("txHash%d", i)), &transaction.Transaction{Nonce: uint64(i)})
+	}
+
+	results, err := scheduledTxsExec.ExecuteAllWithResults(func() time.Duration { return time.Second })
+	assert.Equal(t, expectedErr, err)
+	assert.Equal(t, map[string]error{
+		"txHash0": nil,
+		"txHash1": process.ErrFailedTransaction,
+		"txHash2": expectedErr,
+	}, results)
+
+	results, err = scheduledTxsExec.ExecuteAllWithResults(nil)
+	assert.Equal(t, process.ErrNilHaveTimeHandler, err)
+	assert.Equal(t, 0, len(results))
+}
+
+func TestScheduledTxsExecution_ExecuteAllShouldPublishTheProgress(t *testing.T) {
+	t.Parallel()
+
+	mutMetrics := sync.Mutex{}
+	executedPercents := make([]uint64, 0)
+	remainingTimes := make([]uint64, 0)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(_ *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator:          &mock.TransactionCoordinatorMock{},
+		Storer:                 genericMocks.NewStorerMock(),
+		Marshaller:             &marshal.GogoProtoMarshalizer{},
+		Hasher:                 &hashingMocks.HasherMock{},
+		ShardCoordinator:       &mock.ShardCoordinatorStub{},
+		ProgressUpdateInterval: 2,
+	})
+	scheduledTxsExec.SetAppStatusHandler(&statusHandler.AppStatusHandlerStub{
+		SetUInt64ValueHandler: func(key string, value uint64) {
+			mutMetrics.Lock()
+			defer mutMetrics.Unlock()
+
+			switch key {
+			case common.MetricScheduledTxsExecutedPercent:
+				executedPercents = append(executedPercents, value)
+			case common.MetricScheduledTxsRemainingTimeMs:
+				remainingTimes = append(remainingTimes, value)
+			}
+		},
+	})
+	for i := 0; i < 5; i++ {
+		scheduledTxsExec.AddScheduledTx([]byte(fmt.Sprintf("txHash%d", i)), &transaction.Transaction{Nonce: uint64(i)})
+	}
+
+	err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	assert.Nil(t, err)
+
+	isFullyPublished := func() bool {
+		mutMetrics.Lock()
+		defer mutMetrics.Unlock()
+
+		isLastPublished := len(executedPercents) > 0 && executedPercents[len(executedPercents)-1] == 100
+		return isLastPublished && len(executedPercents) == len(remainingTimes)
+	}
+	assert.Eventually(t, isFullyPublished, time.Second, time.Millisecond*10)
+
+	mutMetrics.Lock()
+	defer mutMetrics.Unlock()
+
+	for index := 1; index < len(executedPercents); index++ {
+		assert.True(t, executedPercents[index-1] < executedPercents[index])
+	}
+	assert.Equal(t, uint64(1000), remainingTimes[len(remainingTimes)-1])
+}
+
+func TestScheduledTxsExecution_ExecuteAllShouldPublishTheExecutionMetrics(t *testing.T) {
+	t.Parallel()
+
+	createScheduledTxsExecution := func(failedNonce uint64, metrics map[string]uint64) *scheduledTxsExecution {
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor: &testscommon.TxProcessorMock{
+				ProcessTransactionCalled: func(tx *transaction.Transaction) (vmcommon.ReturnCode, error) {
+					if tx.Nonce == failedNonce {
+						return vmcommon.UserError, process.ErrFailedTransaction
+					}
+					return vmcommon.Ok, nil
+				},
+			},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+			AppStatusHandler: &statusHandler.AppStatusHandlerStub{
+				SetUInt64ValueHandler: func(key string, value uint64) {
+					metrics[key] = value
+				},
+			},
+		})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash3"), &transaction.Transaction{Nonce: 2})
+
+		return scheduledTxsExec
+	}
+	haveTimeFunction := func() time.Duration { return time.Second }
+
+	t.Run("all txs executed", func(t *testing.T) {
+		t.Parallel()
+
+		metrics := make(map[string]uint64)
+		scheduledTxsExec := createScheduledTxsExecution(math.MaxUint64, metrics)
+
+		err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(3), metrics[common.MetricScheduledTxsExecuted])
+		assert.Equal(t, uint64(0), metrics[common.MetricScheduledTxsFailed])
+		_, ok := metrics[common.MetricScheduledTxsExecutionTimeMs]
+		assert.True(t, ok)
+	})
+	t.Run("some txs failed", func(t *testing.T) {
+		t.Parallel()
+
+		metrics := make(map[string]uint64)
+		scheduledTxsExec := createScheduledTxsExecution(1, metrics)
+
+		err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(3), metrics[common.MetricScheduledTxsExecuted])
+		assert.Equal(t, uint64(1), metrics[common.MetricScheduledTxsFailed])
+		_, ok := metrics[common.MetricScheduledTxsExecutionTimeMs]
+		assert.True(t, ok)
+	})
+	t.Run("nil status handler should not publish", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec := createScheduledTxsExecution(1, make(map[string]uint64))
+		scheduledTxsExec.SetAppStatusHandler(nil)
+
+		err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
+		assert.Nil(t, err)
+	})
+}
+
+func TestScheduledTxsExecution_ExecuteAllShouldStopWhenTheMarshalledSizeIsExceeded(t *testing.T) {
+	t.Parallel()
+
+	marshaller := &marshal.GogoProtoMarshalizer{}
+	createScr := func(nonce uint64) *smartContractResult.SmartContractResult {
+		return &smartContractResult.SmartContractResult{Nonce: nonce, Data: []byte("scr data")}
+	}
+	marshalledScr, _ := marshaller.Marshal(createScr(1))
+	scrSize := uint64(len(marshalledScr))
+
+	mapAllIntermediateTxs := map[block.Type]map[string]data.TransactionHandler{
+		block.SmartContractResultBlock: {},
+	}
+	executedTxs := make([]uint64, 0)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				executedTxs = append(executedTxs, transaction.Nonce)
+				scrHash := fmt.Sprintf("scrHash%d", transaction.Nonce)
+				mapAllIntermediateTxs[block.SmartContractResultBlock][scrHash] = createScr(transaction.Nonce)
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator: &mock.TransactionCoordinatorMock{
+			GetAllIntermediateTxsCalled: func() map[block.Type]map[string]data.TransactionHandler {
+				mapCopy := make(map[block.Type]map[string]data.TransactionHandler)
+				for blockType, allIntermediateTxs := range mapAllIntermediateTxs {
+					mapCopy[blockType] = make(map[string]data.TransactionHandler)
+					for txHash, txHandler := range allIntermediateTxs {
+						mapCopy[blockType][txHash] = txHandler
+					}
+				}
+				return mapCopy
+			},
+		},
+		Storer:     genericMocks.NewStorerMock(),
+		Marshaller: marshaller,
+		Hasher:     &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{
+			SameShardCalled: func(_, _ []byte) bool {
+				return false
+			},
+		},
+		MaxIntermediateTxsMarshalledSize: scrSize + 1,
+	})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 1})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 2})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash3"), &transaction.Transaction{Nonce: 3})
+
+	err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	require.Nil(t, err)
+	assert.Equal(t, []uint64{1, 2}, executedTxs)
+	assert.Equal(t, 2*scrSize, scheduledTxsExec.GetIntermediateTxsMarshalledSize())
+	assert.Equal(t, 2, len(scheduledTxsExec.GetScheduledIntermediateTxsForType(block.SmartContractResultBlock)))
+	assert.Equal(t, [][]byte{[]byte("txHash1"), []byte("txHash2")}, scheduledTxsExec.GetScheduledTxHashes())
+}
+
+func TestScheduledTxsExecution_startAccountsWarmingShouldWarmUniqueAccounts(t *testing.T) {
+	t.Parallel()
+
+	mutWarmedAccounts := sync.Mutex{}
+	warmedAccounts := make([]string, 0)
+	wg := sync.WaitGroup{}
+	wg.Add(3)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+	scheduledTxsExec.SetAccountsWarmer(&testscommon.AccountsWarmerStub{
+		WarmAccountCalled: func(address []byte) {
+			mutWarmedAccounts.Lock()
+			warmedAccounts = append(warmedAccounts, string(address))
+			mutWarmedAccounts.Unlock()
+			wg.Done()
+		},
+	})
+
+	haveTimeFunction := func() time.Duration { return time.Duration(100) }
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{SndAddr: []byte("alice"), RcvAddr: []byte("sc")})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{SndAddr: []byte("bob"), RcvAddr: []byte("sc")})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash3"), &transaction.Transaction{SndAddr: []byte("alice"), RcvAddr: []byte("bob")})
+
+	scheduledTxsExec.mutScheduledTxs.Lock()
+	stopAccountsWarming := scheduledTxsExec.startAccountsWarming(haveTimeFunction)
+	scheduledTxsExec.mutScheduledTxs.Unlock()
+	wg.Wait()
+	stopAccountsWarming()
+
+	mutWarmedAccounts.Lock()
+	assert.Equal(t, []string{"alice", "sc", "bob"}, warmedAccounts)
+	mutWarmedAccounts.Unlock()
+}
+
+func TestScheduledTxsExecution_startAccountsWarmingStopShouldWaitForTheWarmedAccount(t *testing.T) {
+	t.Parallel()
+
+	chanWarmingStarted := make(chan struct{})
+	numWarmedAccounts := int32(0)
+	isStopped := int32(0)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+	scheduledTxsExec.SetAccountsWarmer(&testscommon.AccountsWarmerStub{
+		WarmAccountCalled: func(address []byte) {
+			if atomic.AddInt32(&numWarmedAccounts, 1) == 1 {
+				close(chanWarmingStarted)
+			}
+			time.Sleep(time.Millisecond * 50)
+			if atomic.LoadInt32(&isStopped) == 1 {
+				assert.Fail(t, "should have not warmed accounts after stop")
+			}
+		},
+	})
+
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{SndAddr: []byte("alice"), RcvAddr: []byte("sc")})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{SndAddr: []byte("bob"), RcvAddr: []byte("carol")})
+
+	scheduledTxsExec.mutScheduledTxs.Lock()
+	stopAccountsWarming := scheduledTxsExec.startAccountsWarming(func() time.Duration { return time.Hour })
+	scheduledTxsExec.mutScheduledTxs.Unlock()
+	<-chanWarmingStarted
+	stopAccountsWarming()
+	atomic.StoreInt32(&isStopped, 1)
+
+	time.Sleep(time.Millisecond * 100)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&numWarmedAccounts))
+}
+
+func TestScheduledTxsExecution_ExecuteAllWithoutTimeShouldNotWarmAccounts(t *testing.T) {
+	t.Parallel()
+
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+	scheduledTxsExec.SetAccountsWarmer(&testscommon.AccountsWarmerStub{
+		WarmAccountCalled: func(address []byte) {
+			assert.Fail(t, "should have not been called")
+		},
+	})
+
+	haveTimeFunction := func() time.Duration { return time.Duration(-1) }
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{SndAddr: []byte("alice"), RcvAddr: []byte("sc")})
+
+	err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
+	assert.Equal(t, process.ErrTimeIsOut, err)
+	time.Sleep(time.Millisecond * 10)
+}
+
+func BenchmarkScheduledTxsExecution_ExecuteAllWithColdAccounts(b *testing.B) {
+	benchmarkExecuteAllWithColdAccounts(b, false)
+}
+
+func BenchmarkScheduledTxsExecution_ExecuteAllWithAccountsWarmer(b *testing.B) {
+	benchmarkExecuteAllWithColdAccounts(b, true)
+}
+
+func benchmarkExecuteAllWithColdAccounts(b *testing.B, withAccountsWarmer bool) {
+	numTxs := 20
+	accountLoadDuration := time.Millisecond
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		warmAccounts := sync.Map{}
+		loadAccount := func(address []byte) {
+			_, loaded := warmAccounts.LoadOrStore(string(address), struct{}{})
+			if !loaded {
+				time.Sleep(accountLoadDuration)
+			}
+		}
+
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor: &testscommon.TxProcessorMock{
+				ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
+					loadAccount(transaction.SndAddr)
+					loadAccount(transaction.RcvAddr)
+					return vmcommon.Ok, nil
+				},
+			},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+		})
+		if withAccountsWarmer {
+			scheduledTxsExec.SetAccountsWarmer(&testscommon.AccountsWarmerStub{
+				WarmAccountCalled: loadAccount,
+			})
+		}
+		for j := 0; j < numTxs; j++ {
+			scheduledTxsExec.AddScheduledTx([]byte(fmt.Sprintf("txHash%d", j)), &transaction.Transaction{
+				SndAddr: []byte(fmt.Sprintf("sender%d", j)),
+				RcvAddr: []byte(fmt.Sprintf("receiver%d", j)),
+			})
+		}
+		b.StartTimer()
+
+		_ = scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	}
+}
+
+func TestScheduledTxsExecution_ExecuteAllWithComparatorShouldBreakTiesByHash(t *testing.T) {
+	t.Parallel()
+
+	executedTxs := make([]uint64, 0)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				executedTxs = append(executedTxs, transaction.Value.Uint64())
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+	scheduledTxsExec.SetExecutionOrderComparator(func(first data.TransactionHandler, second data.TransactionHandler) int {
+		if first.GetGasPrice() == second.GetGasPrice() {
+			return 0
+		}
+		if first.GetGasPrice() > second.GetGasPrice() {
+			return -1
+		}
+
+		return 1
+	})
+
+	haveTimeFunction := func() time.Duration { return time.Duration(100) }
+	scheduledTxsExec.AddScheduledTx([]byte("txHash4"), &transaction.Transaction{Nonce: 1, GasPrice: 10, Value: big.NewInt(4)})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1, GasPrice: 10, Value: big.NewInt(2)})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash5"), &transaction.Transaction{Nonce: 1, GasPrice: 20, Value: big.NewInt(5)})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 1, GasPrice: 10, Value: big.NewInt(1)})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash3"), &transaction.Transaction{Nonce: 1, GasPrice: 10, Value: big.NewInt(3)})
+
+	err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{5, 1, 2, 3, 4}, executedTxs)
+}
+
+func TestScheduledTxsExecution_ExecuteAllShouldRecordStorageAccessStats(t *testing.T) {
+	t.Parallel()
+
+	currentStat := process.StorageAccessStat{}
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				currentStat.NumReads += transaction.Nonce
+				currentStat.NumWrites += 2 * transaction.Nonce
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	haveTimeFunction := func() time.Duration { return time.Duration(100) }
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 1})
+	err := scheduledTxsExec.ExecuteAll(haveTimeFunction)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(scheduledTxsExec.GetStorageAccessStats()))
+
+	scheduledTxsExec.SetStorageAccessMeter(&testscommon.StorageAccessMeterStub{
+		GetStorageAccessStatCalled: func() process.StorageAccessStat {
+			return currentStat
+		},
+	})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 2})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash3"), &transaction.Transaction{Nonce: 3})
+	err = scheduledTxsExec.ExecuteAll(haveTimeFunction)
+	assert.Nil(t, err)
+
+	expectedStats := map[string]process.StorageAccessStat{
+		"txHash1": {NumReads: 1, NumWrites: 2},
+		"txHash2": {NumReads: 2, NumWrites: 4},
+		"txHash3": {NumReads: 3, NumWrites: 6},
+	}
+	assert.Equal(t, expectedStats, scheduledTxsExec.GetStorageAccessStats())
+
+	scheduledTxsExec.Init()
+	assert.Equal(t, 0, len(scheduledTxsExec.GetStorageAccessStats()))
+}
+
+func TestScheduledTxsExecution_executeShouldErr(t *testing.T) {
+	t.Parallel()
+
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	err := scheduledTxsExec.execute(nil)
+	assert.True(t, errors.Is(err, process.ErrWrongTypeAssertion))
+}
+
+func TestScheduledTxsExecution_executeShouldWork(t *testing.T) {
+	t.Parallel()
+
+	response := errors.New("response")
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(transaction *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				return vmcommon.Ok, response
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	err := scheduledTxsExec.execute(&transaction.Transaction{Nonce: 0})
+	assert.Equal(t, response, err)
+}
+
+func TestScheduledTxsExecution_computeScheduledSCRsShouldWork(t *testing.T) {
+	t.Parallel()
+
+	shardCoordinator := &mock.ShardCoordinatorStub{
+		SameShardCalled: func(_, _ []byte) bool {
+			return false
+		},
 	}
 
 	mapAllIntermediateTxsBeforeScheduledExecution := map[block.Type]map[string]data.TransactionHandler{
@@ -456,14 +2789,14 @@ func TestScheduledTxsExecution_computeScheduledSCRsShouldWork(t *testing.T) {
 	t.Run("nil maps, empty scheduled scrs", func(t *testing.T) {
 		t.Parallel()
 
-		scheduledTxsExec, _ := NewScheduledTxsExecution(
-			&testscommon.TxProcessorMock{},
-			&mock.TransactionCoordinatorMock{},
-			genericMocks.NewStorerMock(),
-			&marshal.GogoProtoMarshalizer{},
-			&hashingMocks.HasherMock{},
-			shardCoordinator,
-		)
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:      &testscommon.TxProcessorMock{},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: shardCoordinator,
+		})
 
 		scheduledTxsExec.ComputeScheduledIntermediateTxs(nil, nil)
 
@@ -472,14 +2805,14 @@ func TestScheduledTxsExecution_computeScheduledSCRsShouldWork(t *testing.T) {
 	t.Run("nil map after txs execution, empty scheduled scrs", func(t *testing.T) {
 		t.Parallel()
 
-		scheduledTxsExec, _ := NewScheduledTxsExecution(
-			&testscommon.TxProcessorMock{},
-			&mock.TransactionCoordinatorMock{},
-			genericMocks.NewStorerMock(),
-			&marshal.GogoProtoMarshalizer{},
-			&hashingMocks.HasherMock{},
-			shardCoordinator,
-		)
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:      &testscommon.TxProcessorMock{},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: shardCoordinator,
+		})
 
 		scheduledTxsExec.ComputeScheduledIntermediateTxs(mapAllIntermediateTxsBeforeScheduledExecution, nil)
 
@@ -488,14 +2821,14 @@ func TestScheduledTxsExecution_computeScheduledSCRsShouldWork(t *testing.T) {
 	t.Run("nil map after txs execution, empty scheduled scrs", func(t *testing.T) {
 		t.Parallel()
 
-		scheduledTxsExec, _ := NewScheduledTxsExecution(
-			&testscommon.TxProcessorMock{},
-			&mock.TransactionCoordinatorMock{},
-			genericMocks.NewStorerMock(),
-			&marshal.GogoProtoMarshalizer{},
-			&hashingMocks.HasherMock{},
-			shardCoordinator,
-		)
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:      &testscommon.TxProcessorMock{},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: shardCoordinator,
+		})
 
 		localMapAllIntermediateTxsAfterScheduledExecution := map[block.Type]map[string]data.TransactionHandler{
 			0: {
@@ -513,14 +2846,14 @@ func TestScheduledTxsExecution_computeScheduledSCRsShouldWork(t *testing.T) {
 	t.Run("should work", func(t *testing.T) {
 		t.Parallel()
 
-		scheduledTxsExec, _ := NewScheduledTxsExecution(
-			&testscommon.TxProcessorMock{},
-			&mock.TransactionCoordinatorMock{},
-			genericMocks.NewStorerMock(),
-			&marshal.GogoProtoMarshalizer{},
-			&hashingMocks.HasherMock{},
-			shardCoordinator,
-		)
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:      &testscommon.TxProcessorMock{},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: shardCoordinator,
+		})
 
 		scheduledTxsExec.ComputeScheduledIntermediateTxs(
 			mapAllIntermediateTxsBeforeScheduledExecution,
@@ -533,21 +2866,86 @@ func TestScheduledTxsExecution_computeScheduledSCRsShouldWork(t *testing.T) {
 	})
 }
 
+func TestScheduledTxsExecution_GetScheduledIntermediateTx(t *testing.T) {
+	t.Parallel()
+
+	shardCoordinator := &mock.ShardCoordinatorStub{
+		SameShardCalled: func(_, _ []byte) bool {
+			return false
+		},
+	}
+	marshaller := &marshal.GogoProtoMarshalizer{}
+	hasher := &hashingMocks.HasherMock{}
+
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       marshaller,
+		Hasher:           hasher,
+		ShardCoordinator: shardCoordinator,
+	})
+
+	tx1 := &transaction.Transaction{Nonce: 1}
+	tx3 := &transaction.Transaction{Nonce: 3}
+	mapAllIntermediateTxsBeforeScheduledExecution := map[block.Type]map[string]data.TransactionHandler{
+		0: {
+			"txHash1": tx1,
+			"txHash2": &transaction.Transaction{Nonce: 2},
+		},
+	}
+	mapAllIntermediateTxsAfterScheduledExecution := map[block.Type]map[string]data.TransactionHandler{
+		1: {
+			"txHash3": tx3,
+			"txHash4": &transaction.Transaction{Nonce: 4},
+		},
+	}
+	assert.False(t, scheduledTxsExec.IsScheduledIntermediateTx([]byte("txHash3")))
+
+	scheduledTxsExec.ComputeScheduledIntermediateTxs(
+		mapAllIntermediateTxsBeforeScheduledExecution,
+		mapAllIntermediateTxsAfterScheduledExecution,
+	)
+
+	assert.True(t, scheduledTxsExec.IsScheduledIntermediateTx([]byte("txHash3")))
+	scheduledIntermediateTx, ok := scheduledTxsExec.GetScheduledIntermediateTx([]byte("txHash3"))
+	assert.True(t, ok)
+	assert.Equal(t, tx3, scheduledIntermediateTx)
+
+	assert.False(t, scheduledTxsExec.IsScheduledIntermediateTx([]byte("txHash1")))
+	scheduledIntermediateTx, ok = scheduledTxsExec.GetScheduledIntermediateTx([]byte("txHash1"))
+	assert.False(t, ok)
+	assert.Nil(t, scheduledIntermediateTx)
+
+	tx1Hash, _ := core.CalculateHash(marshaller, hasher, tx1)
+	scheduledTxsExec.SetScheduledInfo(&process.ScheduledInfo{
+		IntermediateTxs: map[block.Type][]data.TransactionHandler{
+			block.SmartContractResultBlock: {tx1},
+		},
+		GasAndFees: scheduled.GasAndFees{},
+	})
+
+	assert.False(t, scheduledTxsExec.IsScheduledIntermediateTx([]byte("txHash3")))
+	scheduledIntermediateTx, ok = scheduledTxsExec.GetScheduledIntermediateTx(tx1Hash)
+	assert.True(t, ok)
+	assert.Equal(t, tx1, scheduledIntermediateTx)
+}
+
 func TestScheduledTxsExecution_computeScheduledSCRsShouldRemoveInvalidSCRs(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer:        genericMocks.NewStorerMock(),
+		Marshaller:    &marshal.GogoProtoMarshalizer{},
+		Hasher:        &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{
 			SameShardCalled: func(_, _ []byte) bool {
 				return false
 			},
 		},
-	)
+	})
 
 	txHash1 := "txHash1"
 	txHash2 := "txHash2"
@@ -621,18 +3019,18 @@ func TestScheduledTxsExecution_getAllIntermediateTxsAfterScheduledExecution(t *t
 	t.Run("not already existing txs, different shard", func(t *testing.T) {
 		t.Parallel()
 
-		scheduledTxsExec, _ := NewScheduledTxsExecution(
-			&testscommon.TxProcessorMock{},
-			&mock.TransactionCoordinatorMock{},
-			genericMocks.NewStorerMock(),
-			&marshal.GogoProtoMarshalizer{},
-			&hashingMocks.HasherMock{},
-			&mock.ShardCoordinatorStub{
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:   &testscommon.TxProcessorMock{},
+			TxCoordinator: &mock.TransactionCoordinatorMock{},
+			Storer:        genericMocks.NewStorerMock(),
+			Marshaller:    &marshal.GogoProtoMarshalizer{},
+			Hasher:        &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{
 				SameShardCalled: func(_, _ []byte) bool {
 					return false
 				},
 			},
-		)
+		})
 
 		scrsInfo := scheduledTxsExec.getAllIntermediateTxsAfterScheduledExecution(
 			allTxsBeforeExec[0],
@@ -645,18 +3043,18 @@ func TestScheduledTxsExecution_getAllIntermediateTxsAfterScheduledExecution(t *t
 	t.Run("not already existing txs, same shard, scr", func(t *testing.T) {
 		t.Parallel()
 
-		scheduledTxsExec, _ := NewScheduledTxsExecution(
-			&testscommon.TxProcessorMock{},
-			&mock.TransactionCoordinatorMock{},
-			genericMocks.NewStorerMock(),
-			&marshal.GogoProtoMarshalizer{},
-			&hashingMocks.HasherMock{},
-			&mock.ShardCoordinatorStub{
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:   &testscommon.TxProcessorMock{},
+			TxCoordinator: &mock.TransactionCoordinatorMock{},
+			Storer:        genericMocks.NewStorerMock(),
+			Marshaller:    &marshal.GogoProtoMarshalizer{},
+			Hasher:        &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{
 				SameShardCalled: func(_, _ []byte) bool {
 					return true
 				},
 			},
-		)
+		})
 
 		scrsInfo := scheduledTxsExec.getAllIntermediateTxsAfterScheduledExecution(
 			allTxsBeforeExec[0],
@@ -669,18 +3067,18 @@ func TestScheduledTxsExecution_getAllIntermediateTxsAfterScheduledExecution(t *t
 	t.Run("not already existing txs, same shard, receipt", func(t *testing.T) {
 		t.Parallel()
 
-		scheduledTxsExec, _ := NewScheduledTxsExecution(
-			&testscommon.TxProcessorMock{},
-			&mock.TransactionCoordinatorMock{},
-			genericMocks.NewStorerMock(),
-			&marshal.GogoProtoMarshalizer{},
-			&hashingMocks.HasherMock{},
-			&mock.ShardCoordinatorStub{
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:   &testscommon.TxProcessorMock{},
+			TxCoordinator: &mock.TransactionCoordinatorMock{},
+			Storer:        genericMocks.NewStorerMock(),
+			Marshaller:    &marshal.GogoProtoMarshalizer{},
+			Hasher:        &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{
 				SameShardCalled: func(_, _ []byte) bool {
 					return true
 				},
 			},
-		)
+		})
 
 		scrsInfo := scheduledTxsExec.getAllIntermediateTxsAfterScheduledExecution(
 			allTxsBeforeExec[0],
@@ -693,18 +3091,18 @@ func TestScheduledTxsExecution_getAllIntermediateTxsAfterScheduledExecution(t *t
 	t.Run("not already existing txs, same shard, transaction", func(t *testing.T) {
 		t.Parallel()
 
-		scheduledTxsExec, _ := NewScheduledTxsExecution(
-			&testscommon.TxProcessorMock{},
-			&mock.TransactionCoordinatorMock{},
-			genericMocks.NewStorerMock(),
-			&marshal.GogoProtoMarshalizer{},
-			&hashingMocks.HasherMock{},
-			&mock.ShardCoordinatorStub{
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:   &testscommon.TxProcessorMock{},
+			TxCoordinator: &mock.TransactionCoordinatorMock{},
+			Storer:        genericMocks.NewStorerMock(),
+			Marshaller:    &marshal.GogoProtoMarshalizer{},
+			Hasher:        &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{
 				SameShardCalled: func(_, _ []byte) bool {
 					return true
 				},
 			},
-		)
+		})
 
 		scrsInfo := scheduledTxsExec.getAllIntermediateTxsAfterScheduledExecution(
 			allTxsBeforeExec[0],
@@ -717,18 +3115,18 @@ func TestScheduledTxsExecution_getAllIntermediateTxsAfterScheduledExecution(t *t
 	t.Run("not already existing txs, same shard, invalid", func(t *testing.T) {
 		t.Parallel()
 
-		scheduledTxsExec, _ := NewScheduledTxsExecution(
-			&testscommon.TxProcessorMock{},
-			&mock.TransactionCoordinatorMock{},
-			genericMocks.NewStorerMock(),
-			&marshal.GogoProtoMarshalizer{},
-			&hashingMocks.HasherMock{},
-			&mock.ShardCoordinatorStub{
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:   &testscommon.TxProcessorMock{},
+			TxCoordinator: &mock.TransactionCoordinatorMock{},
+			Storer:        genericMocks.NewStorerMock(),
+			Marshaller:    &marshal.GogoProtoMarshalizer{},
+			Hasher:        &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{
 				SameShardCalled: func(_, _ []byte) bool {
 					return true
 				},
 			},
-		)
+		})
 
 		scrsInfo := scheduledTxsExec.getAllIntermediateTxsAfterScheduledExecution(
 			allTxsBeforeExec[0],
@@ -741,18 +3139,18 @@ func TestScheduledTxsExecution_getAllIntermediateTxsAfterScheduledExecution(t *t
 	t.Run("not existing block type, different shard", func(t *testing.T) {
 		t.Parallel()
 
-		scheduledTxsExec, _ := NewScheduledTxsExecution(
-			&testscommon.TxProcessorMock{},
-			&mock.TransactionCoordinatorMock{},
-			genericMocks.NewStorerMock(),
-			&marshal.GogoProtoMarshalizer{},
-			&hashingMocks.HasherMock{},
-			&mock.ShardCoordinatorStub{
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:   &testscommon.TxProcessorMock{},
+			TxCoordinator: &mock.TransactionCoordinatorMock{},
+			Storer:        genericMocks.NewStorerMock(),
+			Marshaller:    &marshal.GogoProtoMarshalizer{},
+			Hasher:        &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{
 				SameShardCalled: func(_, _ []byte) bool {
 					return false
 				},
 			},
-		)
+		})
 
 		scrsInfo := scheduledTxsExec.getAllIntermediateTxsAfterScheduledExecution(
 			allTxsBeforeExec[0],
@@ -765,35 +3163,299 @@ func TestScheduledTxsExecution_getAllIntermediateTxsAfterScheduledExecution(t *t
 	t.Run("already existing txs, different shard", func(t *testing.T) {
 		t.Parallel()
 
-		scheduledTxsExec, _ := NewScheduledTxsExecution(
-			&testscommon.TxProcessorMock{},
-			&mock.TransactionCoordinatorMock{},
-			genericMocks.NewStorerMock(),
-			&marshal.GogoProtoMarshalizer{},
-			&hashingMocks.HasherMock{},
-			&mock.ShardCoordinatorStub{
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:   &testscommon.TxProcessorMock{},
+			TxCoordinator: &mock.TransactionCoordinatorMock{},
+			Storer:        genericMocks.NewStorerMock(),
+			Marshaller:    &marshal.GogoProtoMarshalizer{},
+			Hasher:        &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{
 				SameShardCalled: func(_, _ []byte) bool {
 					return false
 				},
 			},
+		})
+
+		allTxsAfterExec := map[string]data.TransactionHandler{
+			"txHash1": &transaction.Transaction{Nonce: 1},
+			"txHash2": &transaction.Transaction{Nonce: 2},
+		}
+
+		scrsInfo := scheduledTxsExec.getAllIntermediateTxsAfterScheduledExecution(
+			allTxsBeforeExec[0],
+			allTxsAfterExec,
+			0,
 		)
 
+		assert.Equal(t, 0, len(scrsInfo))
+	})
+	t.Run("self scrs, same shard should be dropped and cross shard should be kept", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:   &testscommon.TxProcessorMock{},
+			TxCoordinator: &mock.TransactionCoordinatorMock{},
+			Storer:        genericMocks.NewStorerMock(),
+			Marshaller:    &marshal.GogoProtoMarshalizer{},
+			Hasher:        &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{
+				SameShardCalled: func(_, _ []byte) bool {
+					assert.Fail(t, "should not have been called for self scrs")
+					return false
+				},
+				ComputeIdCalled: func(address []byte) uint32 {
+					if bytes.Equal(address, []byte("cross shard sender")) {
+						return 1
+					}
+					return 0
+				},
+				SelfIdCalled: func() uint32 {
+					return 0
+				},
+			},
+		})
+
 		allTxsAfterExec := map[string]data.TransactionHandler{
+			"sameShardSelfScr": &smartContractResult.SmartContractResult{
+				SndAddr:        []byte("contract"),
+				RcvAddr:        []byte("same shard sender"),
+				OriginalSender: []byte("same shard sender"),
+			},
+			"crossShardSelfScr": &smartContractResult.SmartContractResult{
+				SndAddr:        []byte("contract"),
+				RcvAddr:        []byte("cross shard sender"),
+				OriginalSender: []byte("cross shard sender"),
+			},
+		}
+
+		scrsInfo := scheduledTxsExec.getAllIntermediateTxsAfterScheduledExecution(
+			allTxsBeforeExec[0],
+			allTxsAfterExec,
+			block.SmartContractResultBlock,
+		)
+
+		require.Equal(t, 1, len(scrsInfo))
+		assert.Equal(t, []byte("crossShardSelfScr"), scrsInfo[0].txHash)
+	})
+}
+
+func TestScheduledTxsExecution_GetScheduledIntermediateTxsNonEmptySCRsMap(t *testing.T) {
+	t.Parallel()
+
+	allTxsAfterExec := map[block.Type]map[string]data.TransactionHandler{
+		0: {
 			"txHash1": &transaction.Transaction{Nonce: 1},
 			"txHash2": &transaction.Transaction{Nonce: 2},
+		},
+		1: {
+			"txHash3": &transaction.Transaction{Nonce: 3},
+			"txHash4": &transaction.Transaction{Nonce: 4},
+		},
+	}
+
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer:        genericMocks.NewStorerMock(),
+		Marshaller:    &marshal.GogoProtoMarshalizer{},
+		Hasher:        &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{
+			SameShardCalled: func(_, _ []byte) bool {
+				return false
+			},
+		},
+	})
+
+	scheduledTxsExec.ComputeScheduledIntermediateTxs(
+		nil,
+		allTxsAfterExec,
+	)
+
+	scheduledIntermediateTxs := scheduledTxsExec.GetScheduledIntermediateTxs()
+
+	assert.Equal(t, 2, len(scheduledIntermediateTxs))
+	assert.Equal(t, 2, len(scheduledIntermediateTxs[0]))
+	assert.Equal(t, 2, len(scheduledIntermediateTxs[1]))
+}
+
+func TestScheduledTxsExecution_GetScheduledIntermediateTxsForType(t *testing.T) {
+	t.Parallel()
+
+	allTxsAfterExec := map[block.Type]map[string]data.TransactionHandler{
+		block.SmartContractResultBlock: {
+			"txHash2": &smartContractResult.SmartContractResult{Nonce: 2},
+			"txHash1": &smartContractResult.SmartContractResult{Nonce: 1},
+		},
+	}
+
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer:        genericMocks.NewStorerMock(),
+		Marshaller:    &marshal.GogoProtoMarshalizer{},
+		Hasher:        &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{
+			SameShardCalled: func(_, _ []byte) bool {
+				return false
+			},
+		},
+	})
+
+	scheduledTxsExec.ComputeScheduledIntermediateTxs(
+		nil,
+		allTxsAfterExec,
+	)
+
+	scrs := scheduledTxsExec.GetScheduledIntermediateTxsForType(block.SmartContractResultBlock)
+	assert.Equal(t, []data.TransactionHandler{
+		&smartContractResult.SmartContractResult{Nonce: 1},
+		&smartContractResult.SmartContractResult{Nonce: 2},
+	}, scrs)
+
+	scrs[0] = nil
+	assert.NotNil(t, scheduledTxsExec.GetScheduledIntermediateTxsForType(block.SmartContractResultBlock)[0])
+
+	receipts := scheduledTxsExec.GetScheduledIntermediateTxsForType(block.ReceiptBlock)
+	assert.NotNil(t, receipts)
+	assert.Equal(t, 0, len(receipts))
+}
+
+func TestScheduledTxsExecution_ComputeScheduledIntermediateTxsDuplicateHashes(t *testing.T) {
+	t.Parallel()
+
+	existingScr := &smartContractResult.SmartContractResult{Nonce: 1}
+	allTxsBeforeExec := map[block.Type]map[string]data.TransactionHandler{
+		block.SmartContractResultBlock: {
+			"scrHash1": existingScr,
+			"scrHash2": &smartContractResult.SmartContractResult{Nonce: 2},
+		},
+	}
+	createAllTxsAfterExec := func() map[block.Type]map[string]data.TransactionHandler {
+		return map[block.Type]map[string]data.TransactionHandler{
+			block.SmartContractResultBlock: {
+				"scrHash1": existingScr,
+				"scrHash2": &smartContractResult.SmartContractResult{Nonce: 3},
+				"scrHash3": &smartContractResult.SmartContractResult{Nonce: 4},
+			},
+			block.InvalidBlock: {
+				"scrHash3": &transaction.Transaction{Nonce: 5},
+			},
 		}
+	}
+	createScheduledTxsExecution := func(rejectDuplicates bool) *scheduledTxsExecution {
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:   &testscommon.TxProcessorMock{},
+			TxCoordinator: &mock.TransactionCoordinatorMock{},
+			Storer:        genericMocks.NewStorerMock(),
+			Marshaller:    &marshal.GogoProtoMarshalizer{},
+			Hasher:        &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{
+				SameShardCalled: func(_, _ []byte) bool {
+					return false
+				},
+			},
+			RejectDuplicateIntermediateTxs: rejectDuplicates,
+		})
+
+		return scheduledTxsExec
+	}
+
+	t.Run("duplicates should be recorded and the last tx kept", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec := createScheduledTxsExecution(false)
+		err := scheduledTxsExec.ComputeScheduledIntermediateTxs(allTxsBeforeExec, createAllTxsAfterExec())
+		require.Nil(t, err)
+
+		assert.Equal(t, [][]byte{[]byte("scrHash2"), []byte("scrHash3")}, scheduledTxsExec.GetDuplicateIntermediateTxHashes())
+		assert.Equal(t, 1, len(scheduledTxsExec.GetScheduledIntermediateTxsForType(block.SmartContractResultBlock)))
+		assert.Equal(t, 1, len(scheduledTxsExec.GetScheduledIntermediateTxsForType(block.InvalidBlock)))
+
+		scheduledTxsExec.Init()
+		assert.Equal(t, 0, len(scheduledTxsExec.GetDuplicateIntermediateTxHashes()))
+	})
+	t.Run("duplicates should be rejected in strict mode", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec := createScheduledTxsExecution(true)
+		err := scheduledTxsExec.ComputeScheduledIntermediateTxs(allTxsBeforeExec, createAllTxsAfterExec())
+		assert.True(t, errors.Is(err, process.ErrDuplicateIntermediateTx))
+		assert.Equal(t, 0, len(scheduledTxsExec.GetScheduledIntermediateTxs()))
+		assert.True(t, len(scheduledTxsExec.GetDuplicateIntermediateTxHashes()) > 0)
+	})
+	t.Run("no duplicates", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec := createScheduledTxsExecution(true)
+		err := scheduledTxsExec.ComputeScheduledIntermediateTxs(allTxsBeforeExec, allTxsBeforeExec)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(scheduledTxsExec.GetDuplicateIntermediateTxHashes()))
+	})
+}
+
+func TestScheduledTxsExecution_PackScheduledMiniBlocksShouldBeDeterministic(t *testing.T) {
+	t.Parallel()
+
+	scrs := make([]data.TransactionHandler, 0)
+	for nonce := uint64(0); nonce < 5; nonce++ {
+		scrs = append(scrs, &smartContractResult.SmartContractResult{Nonce: nonce, RcvAddr: []byte{byte(nonce % 2)}})
+	}
+	invalidTx := &transaction.Transaction{Nonce: 10, RcvAddr: []byte{1}}
 
-		scrsInfo := scheduledTxsExec.getAllIntermediateTxsAfterScheduledExecution(
-			allTxsBeforeExec[0],
-			allTxsAfterExec,
-			0,
-		)
+	packScheduledMiniBlocks := func(intermediateTxs map[block.Type][]data.TransactionHandler) block.MiniBlockSlice {
+		shardCoordinator := mock.NewMultiShardsCoordinatorMock(3)
+		shardCoordinator.CurrentShard = 2
+		shardCoordinator.ComputeIdCalled = func(address []byte) uint32 {
+			return uint32(address[0])
+		}
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:      &testscommon.TxProcessorMock{},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: shardCoordinator,
+		})
+		scheduledTxsExec.SetScheduledInfo(&process.ScheduledInfo{
+			IntermediateTxs: intermediateTxs,
+			GasAndFees:      scheduled.GasAndFees{},
+		})
+
+		return scheduledTxsExec.PackScheduledMiniBlocks(2)
+	}
 
-		assert.Equal(t, 0, len(scrsInfo))
+	miniBlocks := packScheduledMiniBlocks(map[block.Type][]data.TransactionHandler{
+		block.SmartContractResultBlock: scrs,
+		block.InvalidBlock:             {invalidTx},
 	})
+	reversedScrs := []data.TransactionHandler{scrs[4], scrs[3], scrs[2], scrs[1], scrs[0]}
+	miniBlocksOnOtherNode := packScheduledMiniBlocks(map[block.Type][]data.TransactionHandler{
+		block.InvalidBlock:             {invalidTx},
+		block.SmartContractResultBlock: reversedScrs,
+	})
+	assert.Equal(t, miniBlocks, miniBlocksOnOtherNode)
+
+	require.Equal(t, 4, len(miniBlocks))
+	expectedMiniBlocksInfo := []struct {
+		blockType block.Type
+		destShard uint32
+		numTxs    int
+	}{
+		{blockType: block.SmartContractResultBlock, destShard: 0, numTxs: 2},
+		{blockType: block.SmartContractResultBlock, destShard: 0, numTxs: 1},
+		{blockType: block.SmartContractResultBlock, destShard: 1, numTxs: 2},
+		{blockType: block.InvalidBlock, destShard: 2, numTxs: 1},
+	}
+	for index, miniBlock := range miniBlocks {
+		assert.Equal(t, expectedMiniBlocksInfo[index].blockType, miniBlock.Type)
+		assert.Equal(t, uint32(2), miniBlock.SenderShardID)
+		assert.Equal(t, expectedMiniBlocksInfo[index].destShard, miniBlock.ReceiverShardID)
+		assert.Equal(t, expectedMiniBlocksInfo[index].numTxs, len(miniBlock.TxHashes))
+	}
+	assert.True(t, bytes.Compare(miniBlocks[0].TxHashes[1], miniBlocks[1].TxHashes[0]) < 0)
 }
 
-func TestScheduledTxsExecution_GetScheduledIntermediateTxsNonEmptySCRsMap(t *testing.T) {
+func TestScheduledTxsExecution_ComputeScheduledIntermediateTxsMaxIntermediateTxs(t *testing.T) {
 	t.Parallel()
 
 	allTxsAfterExec := map[block.Type]map[string]data.TransactionHandler{
@@ -803,33 +3465,110 @@ func TestScheduledTxsExecution_GetScheduledIntermediateTxsNonEmptySCRsMap(t *tes
 		},
 		1: {
 			"txHash3": &transaction.Transaction{Nonce: 3},
-			"txHash4": &transaction.Transaction{Nonce: 4},
 		},
 	}
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{
-			SameShardCalled: func(_, _ []byte) bool {
-				return false
+	createScheduledTxsExecution := func(maxIntermediateTxs uint32) *scheduledTxsExecution {
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:   &testscommon.TxProcessorMock{},
+			TxCoordinator: &mock.TransactionCoordinatorMock{},
+			Storer:        genericMocks.NewStorerMock(),
+			Marshaller:    &marshal.GogoProtoMarshalizer{},
+			Hasher:        &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{
+				SameShardCalled: func(_, _ []byte) bool {
+					return false
+				},
 			},
+			MaxIntermediateTxs: maxIntermediateTxs,
+		})
+
+		return scheduledTxsExec
+	}
+
+	t.Run("at the cap should work", func(t *testing.T) {
+		scheduledTxsExec := createScheduledTxsExecution(3)
+		err := scheduledTxsExec.ComputeScheduledIntermediateTxs(nil, allTxsAfterExec)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, getNumScheduledIntermediateTxs(scheduledTxsExec.GetScheduledIntermediateTxs()))
+	})
+	t.Run("over the cap should error", func(t *testing.T) {
+		scheduledTxsExec := createScheduledTxsExecution(2)
+		err := scheduledTxsExec.ComputeScheduledIntermediateTxs(nil, allTxsAfterExec)
+		assert.True(t, errors.Is(err, process.ErrTooManyScheduledIntermediateTxs))
+		assert.Equal(t, 0, len(scheduledTxsExec.GetScheduledIntermediateTxs()))
+	})
+	t.Run("zero cap means unlimited", func(t *testing.T) {
+		scheduledTxsExec := createScheduledTxsExecution(0)
+		err := scheduledTxsExec.ComputeScheduledIntermediateTxs(nil, allTxsAfterExec)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, getNumScheduledIntermediateTxs(scheduledTxsExec.GetScheduledIntermediateTxs()))
+	})
+}
+
+func TestScheduledTxsExecution_GetScheduledIntermediateTxsByShard(t *testing.T) {
+	t.Parallel()
+
+	// the first byte of each address is its shard
+	tx1 := &transaction.Transaction{Nonce: 1, SndAddr: []byte{0}, RcvAddr: []byte{0}}
+	tx2 := &transaction.Transaction{Nonce: 2, SndAddr: []byte{0}, RcvAddr: []byte{1}}
+	scr1 := &smartContractResult.SmartContractResult{Nonce: 3, SndAddr: []byte{0}, RcvAddr: []byte{0}}
+	scr2 := &smartContractResult.SmartContractResult{Nonce: 4, SndAddr: []byte{0}, RcvAddr: []byte{2}}
+	receipt1 := &smartContractResult.SmartContractResult{Nonce: 5, SndAddr: []byte{0}, RcvAddr: []byte{0}}
+	invalidTx1 := &transaction.Transaction{Nonce: 6, SndAddr: []byte{0}, RcvAddr: []byte{0}}
+	allTxsAfterExec := map[block.Type]map[string]data.TransactionHandler{
+		block.TxBlock: {
+			"txHash1": tx1,
+			"txHash2": tx2,
 		},
-	)
+		block.SmartContractResultBlock: {
+			"scrHash1": scr1,
+			"scrHash2": scr2,
+		},
+		block.ReceiptBlock: {
+			"receiptHash1": receipt1,
+		},
+		block.InvalidBlock: {
+			"invalidTxHash1": invalidTx1,
+		},
+	}
+
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer:        genericMocks.NewStorerMock(),
+		Marshaller:    &marshal.GogoProtoMarshalizer{},
+		Hasher:        &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{
+			ComputeIdCalled: func(address []byte) uint32 {
+				return uint32(address[0])
+			},
+			SameShardCalled: func(firstAddress, secondAddress []byte) bool {
+				return firstAddress[0] == secondAddress[0]
+			},
+		},
+	})
+	assert.Equal(t, 0, len(scheduledTxsExec.GetScheduledIntermediateTxsByShard()))
 
 	scheduledTxsExec.ComputeScheduledIntermediateTxs(
 		nil,
 		allTxsAfterExec,
 	)
 
-	scheduledIntermediateTxs := scheduledTxsExec.GetScheduledIntermediateTxs()
-
-	assert.Equal(t, 2, len(scheduledIntermediateTxs))
-	assert.Equal(t, 2, len(scheduledIntermediateTxs[0]))
-	assert.Equal(t, 2, len(scheduledIntermediateTxs[1]))
+	// the same shard smart contract results and receipts are left out, while the same shard txs and invalid txs are kept
+	expectedTxsByShard := map[uint32]map[block.Type][]data.TransactionHandler{
+		0: {
+			block.TxBlock:      {tx1},
+			block.InvalidBlock: {invalidTx1},
+		},
+		1: {
+			block.TxBlock: {tx2},
+		},
+		2: {
+			block.SmartContractResultBlock: {scr2},
+		},
+	}
+	assert.Equal(t, expectedTxsByShard, scheduledTxsExec.GetScheduledIntermediateTxsByShard())
 }
 
 func TestScheduledTxsExecution_GetScheduledIntermediateTxsEmptySCRsMap(t *testing.T) {
@@ -837,18 +3576,18 @@ func TestScheduledTxsExecution_GetScheduledIntermediateTxsEmptySCRsMap(t *testin
 
 	allTxsAfterExec := make(map[block.Type]map[string]data.TransactionHandler)
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer:        genericMocks.NewStorerMock(),
+		Marshaller:    &marshal.GogoProtoMarshalizer{},
+		Hasher:        &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{
 			SameShardCalled: func(_, _ []byte) bool {
 				return false
 			},
 		},
-	)
+	})
 
 	scheduledTxsExec.ComputeScheduledIntermediateTxs(
 		nil,
@@ -863,14 +3602,14 @@ func TestScheduledTxsExecution_GetScheduledIntermediateTxsEmptySCRsMap(t *testin
 func TestScheduledTxsExecution_SetScheduledInfo(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	rootHash := []byte("root hash")
 	gasAndFees := scheduled.GasAndFees{}
@@ -904,20 +3643,83 @@ func TestScheduledTxsExecution_SetScheduledInfo(t *testing.T) {
 	assert.Equal(t, mbs, scheduledTxsExec.GetScheduledMiniBlocks())
 }
 
+func TestScheduledTxsExecution_SetScheduledInfoPartial(t *testing.T) {
+	t.Parallel()
+
+	initialScheduledInfo := &process.ScheduledInfo{
+		RootHash: []byte("initial root hash"),
+		IntermediateTxs: map[block.Type][]data.TransactionHandler{
+			block.SmartContractResultBlock: {&smartContractResult.SmartContractResult{Nonce: 1}},
+		},
+		GasAndFees: scheduled.GasAndFees{AccumulatedFees: big.NewInt(1), DeveloperFees: big.NewInt(2)},
+		MiniBlocks: block.MiniBlockSlice{{Type: block.TxBlock}},
+	}
+	newScheduledInfo := &process.ScheduledInfo{
+		RootHash: []byte("new root hash"),
+		IntermediateTxs: map[block.Type][]data.TransactionHandler{
+			block.InvalidBlock: {&transaction.Transaction{Nonce: 2}},
+		},
+		GasAndFees: scheduled.GasAndFees{AccumulatedFees: big.NewInt(3), DeveloperFees: big.NewInt(4)},
+		MiniBlocks: block.MiniBlockSlice{{Type: block.InvalidBlock}},
+	}
+
+	for fields := ScheduledInfoField(0); fields <= AllScheduledInfoFields; fields++ {
+		fields := fields
+		t.Run(fmt.Sprintf("fields %04b", fields), func(t *testing.T) {
+			t.Parallel()
+
+			scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+				TxProcessor:      &testscommon.TxProcessorMock{},
+				TxCoordinator:    &mock.TransactionCoordinatorMock{},
+				Storer:           genericMocks.NewStorerMock(),
+				Marshaller:       &marshal.GogoProtoMarshalizer{},
+				Hasher:           &hashingMocks.HasherMock{},
+				ShardCoordinator: &mock.ShardCoordinatorStub{},
+			})
+			scheduledTxsExec.SetScheduledInfo(initialScheduledInfo)
+			scheduledTxsExec.SetScheduledInfoPartial(newScheduledInfo, fields)
+
+			expectedScheduledInfo := initialScheduledInfo
+			if fields&ScheduledInfoRootHash != 0 {
+				expectedScheduledInfo = newScheduledInfo
+			}
+			assert.Equal(t, expectedScheduledInfo.RootHash, scheduledTxsExec.GetScheduledRootHash())
+
+			expectedScheduledInfo = initialScheduledInfo
+			if fields&ScheduledInfoIntermediateTxs != 0 {
+				expectedScheduledInfo = newScheduledInfo
+			}
+			assert.Equal(t, expectedScheduledInfo.IntermediateTxs, scheduledTxsExec.GetScheduledIntermediateTxs())
+
+			expectedScheduledInfo = initialScheduledInfo
+			if fields&ScheduledInfoGasAndFees != 0 {
+				expectedScheduledInfo = newScheduledInfo
+			}
+			assert.Equal(t, expectedScheduledInfo.GasAndFees, scheduledTxsExec.GetScheduledGasAndFees())
+
+			expectedScheduledInfo = initialScheduledInfo
+			if fields&ScheduledInfoMiniBlocks != 0 {
+				expectedScheduledInfo = newScheduledInfo
+			}
+			assert.Equal(t, expectedScheduledInfo.MiniBlocks, scheduledTxsExec.GetScheduledMiniBlocks())
+		})
+	}
+}
+
 func TestScheduledTxsExecution_Setters(t *testing.T) {
 	t.Parallel()
 
 	rootHash := []byte("root hash")
 	gasAndFees := scheduled.GasAndFees{}
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 	scheduledTxsExec.SetTransactionCoordinator(&mock.TransactionCoordinatorMock{})
 	scheduledTxsExec.SetTransactionProcessor(&testscommon.TxProcessorMock{})
 
@@ -937,18 +3739,18 @@ func TestScheduledTxsExecution_getScheduledInfoForHeaderShouldFail(t *testing.T)
 		t.Parallel()
 
 		expectedErr := errors.New("storer err")
-		scheduledTxsExec, _ := NewScheduledTxsExecution(
-			&testscommon.TxProcessorMock{},
-			&mock.TransactionCoordinatorMock{},
-			&storageMocks.StorerStub{
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:   &testscommon.TxProcessorMock{},
+			TxCoordinator: &mock.TransactionCoordinatorMock{},
+			Storer: &storageMocks.StorerStub{
 				GetCalled: func(_ []byte) ([]byte, error) {
 					return nil, expectedErr
 				},
 			},
-			&marshal.GogoProtoMarshalizer{},
-			&hashingMocks.HasherMock{},
-			&mock.ShardCoordinatorStub{},
-		)
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+		})
 
 		scheduledInfo, err := scheduledTxsExec.getScheduledInfoForHeader(rootHash, core.OptionalUint32{})
 		assert.Nil(t, scheduledInfo)
@@ -958,26 +3760,27 @@ func TestScheduledTxsExecution_getScheduledInfoForHeaderShouldFail(t *testing.T)
 		t.Parallel()
 
 		expectedErr := errors.New("marshaller err")
-		scheduledTxsExec, _ := NewScheduledTxsExecution(
-			&testscommon.TxProcessorMock{},
-			&mock.TransactionCoordinatorMock{},
-			&storageMocks.StorerStub{
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:   &testscommon.TxProcessorMock{},
+			TxCoordinator: &mock.TransactionCoordinatorMock{},
+			Storer: &storageMocks.StorerStub{
 				GetCalled: func(_ []byte) ([]byte, error) {
 					return nil, nil
 				},
 			},
-			&testscommon.MarshalizerStub{
+			Marshaller: &testscommon.MarshalizerStub{
 				UnmarshalCalled: func(_ interface{}, _ []byte) error {
 					return expectedErr
 				},
 			},
-			&hashingMocks.HasherMock{},
-			&mock.ShardCoordinatorStub{},
-		)
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+		})
 
 		scheduledInfo, err := scheduledTxsExec.getScheduledInfoForHeader(rootHash, core.OptionalUint32{})
 		assert.Nil(t, scheduledInfo)
-		assert.Equal(t, expectedErr, err)
+		assert.True(t, errors.Is(err, process.ErrUnmarshalWithWrongVersion))
+		assert.Contains(t, err.Error(), expectedErr.Error())
 	})
 }
 
@@ -1000,18 +3803,18 @@ func TestScheduledTxsExecution_getScheduledInfoForHeaderShouldWork(t *testing.T)
 	}
 	marshalledSCRsSavedData, _ := json.Marshal(scheduledSCRs)
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		&storageMocks.StorerStub{
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer: &storageMocks.StorerStub{
 			GetCalled: func(_ []byte) ([]byte, error) {
 				return marshalledSCRsSavedData, nil
 			},
 		},
-		&testscommon.MarshalizerMock{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+		Marshaller:       &testscommon.MarshalizerMock{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	scheduledInfo, _ := scheduledTxsExec.getScheduledInfoForHeader(headerHash, core.OptionalUint32{})
 
@@ -1047,14 +3850,14 @@ func TestScheduledTxsExecution_getMarshalledScheduledInfoShouldWork(t *testing.T
 	}
 	expectedScheduledSCRs, _ := json.Marshal(scheduledSCRs)
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&testscommon.MarshalizerMock{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &testscommon.MarshalizerMock{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	scheduledInfo := &process.ScheduledInfo{
 		RootHash:        scheduledRootHash,
@@ -1066,74 +3869,492 @@ func TestScheduledTxsExecution_getMarshalledScheduledInfoShouldWork(t *testing.T
 	assert.Equal(t, expectedScheduledSCRs, marshalledSCRs)
 }
 
-func TestScheduledTxsExecution_RollBackToBlockShouldFail(t *testing.T) {
-	t.Parallel()
+func TestScheduledTxsExecution_RollBackToBlockShouldFail(t *testing.T) {
+	t.Parallel()
+
+	rootHash := []byte("root hash")
+
+	expectedErr := errors.New("local err")
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer: &storageMocks.StorerStub{
+			GetCalled: func(_ []byte) ([]byte, error) {
+				return nil, expectedErr
+			},
+		},
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	err := scheduledTxsExec.RollBackToBlock(rootHash)
+	assert.Equal(t, expectedErr, err)
+}
+
+func TestScheduledTxsExecution_RollBackToBlockShouldWork(t *testing.T) {
+	t.Parallel()
+
+	headerHash := []byte("root hash")
+	expectedGasAndFees := scheduled.GasAndFees{
+		AccumulatedFees: big.NewInt(101),
+		DeveloperFees:   big.NewInt(102),
+		GasProvided:     103,
+		GasPenalized:    104,
+		GasRefunded:     105,
+	}
+
+	scheduledSCRs := &scheduled.ScheduledSCRs{
+		RootHash: headerHash,
+		Scrs: []*smartContractResult.SmartContractResult{
+			{
+				Nonce: 0,
+			},
+		},
+		GasAndFees: &expectedGasAndFees,
+	}
+	marshalledSCRsSavedData, _ := json.Marshal(scheduledSCRs)
+
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer: &storageMocks.StorerStub{
+			GetCalled: func(_ []byte) ([]byte, error) {
+				return marshalledSCRsSavedData, nil
+			},
+		},
+		Marshaller:       &testscommon.MarshalizerMock{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	err := scheduledTxsExec.RollBackToBlock(headerHash)
+	assert.Nil(t, err)
+
+	scheduledInfo, err := scheduledTxsExec.getScheduledInfoForHeader(headerHash, core.OptionalUint32{})
+	require.Nil(t, err)
+	assert.Equal(t, headerHash, scheduledInfo.RootHash)
+	assert.Equal(t, expectedGasAndFees, scheduledInfo.GasAndFees)
+	assert.NotNil(t, scheduledInfo.IntermediateTxs)
+	assert.Equal(t, block.MiniBlockSlice(nil), scheduledInfo.MiniBlocks)
+}
+
+func TestScheduledTxsExecution_RollBackToBlockSameHeaderShouldBeNoOp(t *testing.T) {
+	t.Parallel()
+
+	headerHash := []byte("header hash")
+	scheduledSCRs := &scheduled.ScheduledSCRs{
+		RootHash:   []byte("root hash"),
+		GasAndFees: &scheduled.GasAndFees{},
+	}
+	marshalledSCRsSavedData, _ := json.Marshal(scheduledSCRs)
+
+	numGetCalls := 0
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer: &storageMocks.StorerStub{
+			GetCalled: func(_ []byte) ([]byte, error) {
+				numGetCalls++
+				return marshalledSCRsSavedData, nil
+			},
+		},
+		Marshaller:       &testscommon.MarshalizerMock{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	err := scheduledTxsExec.RollBackToBlock(headerHash)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, numGetCalls)
+	assert.Equal(t, scheduledSCRs.RootHash, scheduledTxsExec.GetScheduledRootHash())
+
+	scheduledTxsExec.mutScheduledTxs.Lock()
+	scheduledTxsExec.scheduledRootHash = []byte("changed root hash")
+	scheduledTxsExec.mutScheduledTxs.Unlock()
+
+	err = scheduledTxsExec.RollBackToBlock(headerHash)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, numGetCalls)
+	assert.Equal(t, []byte("changed root hash"), scheduledTxsExec.GetScheduledRootHash())
+
+	scheduledTxsExec.SetScheduledRootHash([]byte("another root hash"))
+	err = scheduledTxsExec.RollBackToBlock(headerHash)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, numGetCalls)
+	assert.Equal(t, scheduledSCRs.RootHash, scheduledTxsExec.GetScheduledRootHash())
+}
+
+func TestScheduledTxsExecution_RollBackToBlockConcurrentlyOnSameHeaderShouldRestoreOnce(t *testing.T) {
+	t.Parallel()
+
+	headerHash := []byte("header hash")
+	scheduledSCRs := &scheduled.ScheduledSCRs{
+		RootHash:   []byte("root hash"),
+		GasAndFees: &scheduled.GasAndFees{},
+	}
+	marshalledSCRsSavedData, _ := json.Marshal(scheduledSCRs)
+
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer: &storageMocks.StorerStub{
+			GetCalled: func(_ []byte) ([]byte, error) {
+				return marshalledSCRsSavedData, nil
+			},
+		},
+		Marshaller:       &testscommon.MarshalizerMock{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	numVerifyCalls := int32(0)
+	scheduledTxsExec.SetPostRollbackVerify(func(_ []byte) error {
+		atomic.AddInt32(&numVerifyCalls, 1)
+		return nil
+	})
+
+	numRollBacks := 10
+	wg := sync.WaitGroup{}
+	wg.Add(numRollBacks)
+	for i := 0; i < numRollBacks; i++ {
+		go func() {
+			defer wg.Done()
+
+			err := scheduledTxsExec.RollBackToBlock(headerHash)
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&numVerifyCalls))
+	assert.Equal(t, scheduledSCRs.RootHash, scheduledTxsExec.GetScheduledRootHash())
+}
+
+func TestScheduledTxsExecution_RollBackToBlockShouldNotKeepTheCallerHeaderHash(t *testing.T) {
+	t.Parallel()
+
+	scheduledSCRs := &scheduled.ScheduledSCRs{
+		RootHash:   []byte("root hash"),
+		GasAndFees: &scheduled.GasAndFees{},
+	}
+	marshalledSCRsSavedData, _ := json.Marshal(scheduledSCRs)
+
+	numGetCalls := 0
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer: &storageMocks.StorerStub{
+			GetCalled: func(_ []byte) ([]byte, error) {
+				numGetCalls++
+				return marshalledSCRsSavedData, nil
+			},
+		},
+		Marshaller:       &testscommon.MarshalizerMock{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	headerHash := []byte("header hash 1")
+	err := scheduledTxsExec.RollBackToBlock(headerHash)
+	require.Nil(t, err)
+
+	copy(headerHash, "header hash 2")
+	err = scheduledTxsExec.RollBackToBlock(headerHash)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, numGetCalls)
+	assert.Equal(t, []byte("header hash 2"), scheduledTxsExec.lastRolledBackHeaderHash)
+}
+
+func TestScheduledTxsExecution_RollBackToBlockWithInfo(t *testing.T) {
+	t.Parallel()
+
+	headerHash := []byte("header hash")
+	expectedGasAndFees := scheduled.GasAndFees{
+		AccumulatedFees: big.NewInt(101),
+		DeveloperFees:   big.NewInt(102),
+		GasProvided:     103,
+	}
+	scheduledSCRs := &scheduled.ScheduledSCRs{
+		RootHash: []byte("root hash"),
+		Scrs: []*smartContractResult.SmartContractResult{
+			{
+				Nonce: 7,
+			},
+		},
+		GasAndFees: &expectedGasAndFees,
+	}
+	marshalledSCRsSavedData, _ := json.Marshal(scheduledSCRs)
+
+	numGetCalls := 0
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer: &storageMocks.StorerStub{
+			GetCalled: func(_ []byte) ([]byte, error) {
+				numGetCalls++
+				return marshalledSCRsSavedData, nil
+			},
+		},
+		Marshaller:       &testscommon.MarshalizerMock{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	scheduledInfo, err := scheduledTxsExec.RollBackToBlockWithInfo(headerHash)
+	require.Nil(t, err)
+	assert.Equal(t, 1, numGetCalls)
+	assert.Equal(t, scheduledSCRs.RootHash, scheduledInfo.RootHash)
+	assert.Equal(t, expectedGasAndFees, scheduledInfo.GasAndFees)
+	assert.Equal(t, scheduledSCRs.RootHash, scheduledTxsExec.GetScheduledRootHash())
+	assert.Equal(t, 1, len(scheduledInfo.IntermediateTxs[block.SmartContractResultBlock]))
+	assert.Equal(t, 1, len(scheduledTxsExec.GetScheduledIntermediateTxs()[block.SmartContractResultBlock]))
+
+	scheduledInfo, err = scheduledTxsExec.RollBackToBlockWithInfo(headerHash)
+	require.Nil(t, err)
+	assert.Equal(t, 1, numGetCalls)
+	assert.Equal(t, scheduledSCRs.RootHash, scheduledInfo.RootHash)
+	assert.Equal(t, expectedGasAndFees, scheduledInfo.GasAndFees)
+}
+
+func TestScheduledTxsExecution_RollBackToBlockWithScheduledInfoCache(t *testing.T) {
+	t.Parallel()
+
+	storedData := make(map[string][]byte)
+	numGetCalls := 0
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer: &storageMocks.StorerStub{
+			PutCalled: func(key, data []byte) error {
+				storedData[string(key)] = data
+				return nil
+			},
+			GetCalled: func(key []byte) ([]byte, error) {
+				numGetCalls++
+				data, ok := storedData[string(key)]
+				if !ok {
+					return nil, errors.New("key not found")
+				}
+				return data, nil
+			},
+		},
+		Marshaller:             &marshal.GogoProtoMarshalizer{},
+		Hasher:                 &hashingMocks.HasherMock{},
+		ShardCoordinator:       &mock.ShardCoordinatorStub{},
+		ScheduledInfoCacheSize: 1,
+	})
+
+	createScheduledInfo := func(rootHash string) *process.ScheduledInfo {
+		return &process.ScheduledInfo{
+			RootHash:        []byte(rootHash),
+			IntermediateTxs: make(map[block.Type][]data.TransactionHandler),
+			GasAndFees:      process.GetZeroGasAndFees(),
+			MiniBlocks:      block.MiniBlockSlice{},
+		}
+	}
+	scheduledTxsExec.SaveState([]byte("header hash 1"), createScheduledInfo("root hash 1"))
+	scheduledTxsExec.SaveState([]byte("header hash 2"), createScheduledInfo("root hash 2"))
+
+	t.Run("warmed cache should serve the roll back", func(t *testing.T) {
+		err := scheduledTxsExec.RollBackToBlock([]byte("header hash 2"))
+		require.Nil(t, err)
+		assert.Equal(t, 0, numGetCalls)
+		assert.Equal(t, []byte("root hash 2"), scheduledTxsExec.GetScheduledRootHash())
+	})
+	t.Run("cache miss should read from storage and cache the read info", func(t *testing.T) {
+		err := scheduledTxsExec.RollBackToBlock([]byte("header hash 1"))
+		require.Nil(t, err)
+		assert.Equal(t, 1, numGetCalls)
+		assert.Equal(t, []byte("root hash 1"), scheduledTxsExec.GetScheduledRootHash())
+
+		scheduledInfo, err := scheduledTxsExec.getScheduledInfoForHeader([]byte("header hash 1"), core.OptionalUint32{})
+		require.Nil(t, err)
+		assert.Equal(t, 1, numGetCalls)
+		assert.Equal(t, []byte("root hash 1"), scheduledInfo.RootHash)
+	})
+	t.Run("changing the returned info should not change the cached one", func(t *testing.T) {
+		scheduledInfo, err := scheduledTxsExec.getScheduledInfoForHeader([]byte("header hash 1"), core.OptionalUint32{})
+		require.Nil(t, err)
+		scheduledInfo.RootHash[0] = 'R'
+		scheduledInfo.GasAndFees.AccumulatedFees.SetInt64(100)
+
+		scheduledInfo, err = scheduledTxsExec.getScheduledInfoForHeader([]byte("header hash 1"), core.OptionalUint32{})
+		require.Nil(t, err)
+		assert.Equal(t, []byte("root hash 1"), scheduledInfo.RootHash)
+		assert.Equal(t, int64(0), scheduledInfo.GasAndFees.AccumulatedFees.Int64())
+	})
+}
+
+func TestScheduledTxsExecution_RollBackToBlockWithPostRollbackVerify(t *testing.T) {
+	t.Parallel()
+
+	headerHash := []byte("header hash")
+	scheduledSCRs := &scheduled.ScheduledSCRs{
+		RootHash:   []byte("root hash"),
+		GasAndFees: &scheduled.GasAndFees{},
+	}
+	marshalledSCRsSavedData, _ := json.Marshal(scheduledSCRs)
+
+	createScheduledTxsExecution := func() *scheduledTxsExecution {
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:   &testscommon.TxProcessorMock{},
+			TxCoordinator: &mock.TransactionCoordinatorMock{},
+			Storer: &storageMocks.StorerStub{
+				GetCalled: func(_ []byte) ([]byte, error) {
+					return marshalledSCRsSavedData, nil
+				},
+			},
+			Marshaller:       &testscommon.MarshalizerMock{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+		})
+
+		return scheduledTxsExec
+	}
+
+	t.Run("verification passes should work", func(t *testing.T) {
+		t.Parallel()
+
+		var verifiedRootHash []byte
+		scheduledTxsExec := createScheduledTxsExecution()
+		scheduledTxsExec.SetPostRollbackVerify(func(restoredRootHash []byte) error {
+			verifiedRootHash = restoredRootHash
+			return nil
+		})
+
+		err := scheduledTxsExec.RollBackToBlock(headerHash)
+		assert.Nil(t, err)
+		assert.Equal(t, scheduledSCRs.RootHash, verifiedRootHash)
+	})
+	t.Run("verification fails should restore the state and return error", func(t *testing.T) {
+		t.Parallel()
 
-	rootHash := []byte("root hash")
+		expectedErr := errors.New("root hash mismatch")
+		numVerifyCalls := 0
+		scheduledTxsExec := createScheduledTxsExecution()
+		scheduledTxsExec.SetPostRollbackVerify(func(restoredRootHash []byte) error {
+			numVerifyCalls++
+			return expectedErr
+		})
 
-	expectedErr := errors.New("local err")
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		&storageMocks.StorerStub{
-			GetCalled: func(_ []byte) ([]byte, error) {
-				return nil, expectedErr
-			},
-		},
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+		err := scheduledTxsExec.RollBackToBlock(headerHash)
+		assert.Equal(t, expectedErr, err)
+		assert.Equal(t, scheduledSCRs.RootHash, scheduledTxsExec.GetScheduledRootHash())
 
-	err := scheduledTxsExec.RollBackToBlock(rootHash)
-	assert.Equal(t, expectedErr, err)
+		err = scheduledTxsExec.RollBackToBlock(headerHash)
+		assert.Equal(t, expectedErr, err)
+		assert.Equal(t, 2, numVerifyCalls)
+	})
 }
 
-func TestScheduledTxsExecution_RollBackToBlockShouldWork(t *testing.T) {
+func TestScheduledTxsExecution_ForceRollBackToBlockShouldReapply(t *testing.T) {
 	t.Parallel()
 
-	headerHash := []byte("root hash")
-	expectedGasAndFees := scheduled.GasAndFees{
-		AccumulatedFees: big.NewInt(101),
-		DeveloperFees:   big.NewInt(102),
-		GasProvided:     103,
-		GasPenalized:    104,
-		GasRefunded:     105,
-	}
-
+	headerHash := []byte("header hash")
 	scheduledSCRs := &scheduled.ScheduledSCRs{
-		RootHash: headerHash,
-		Scrs: []*smartContractResult.SmartContractResult{
-			{
-				Nonce: 0,
-			},
-		},
-		GasAndFees: &expectedGasAndFees,
+		RootHash:   []byte("root hash"),
+		GasAndFees: &scheduled.GasAndFees{},
 	}
 	marshalledSCRsSavedData, _ := json.Marshal(scheduledSCRs)
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		&storageMocks.StorerStub{
+	numGetCalls := 0
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer: &storageMocks.StorerStub{
 			GetCalled: func(_ []byte) ([]byte, error) {
+				numGetCalls++
 				return marshalledSCRsSavedData, nil
 			},
 		},
-		&testscommon.MarshalizerMock{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+		Marshaller:       &testscommon.MarshalizerMock{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	err := scheduledTxsExec.RollBackToBlock(headerHash)
 	assert.Nil(t, err)
+	assert.Equal(t, 1, numGetCalls)
 
-	scheduledInfo, err := scheduledTxsExec.getScheduledInfoForHeader(headerHash, core.OptionalUint32{})
-	require.Nil(t, err)
-	assert.Equal(t, headerHash, scheduledInfo.RootHash)
-	assert.Equal(t, expectedGasAndFees, scheduledInfo.GasAndFees)
-	assert.NotNil(t, scheduledInfo.IntermediateTxs)
-	assert.Equal(t, block.MiniBlockSlice(nil), scheduledInfo.MiniBlocks)
+	err = scheduledTxsExec.ForceRollBackToBlock(headerHash)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, numGetCalls)
+	assert.Equal(t, scheduledSCRs.RootHash, scheduledTxsExec.GetScheduledRootHash())
+}
+
+type nonIterableStorerStub struct {
+	*storageMocks.StorerStub
+}
+
+func (s *nonIterableStorerStub) IsRangeKeysSupported() bool {
+	return false
+}
+
+func TestScheduledTxsExecution_GetAllScheduledStateKeys(t *testing.T) {
+	t.Parallel()
+
+	t.Run("storer without iteration support should err", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:   &testscommon.TxProcessorMock{},
+			TxCoordinator: &mock.TransactionCoordinatorMock{},
+			Storer: &nonIterableStorerStub{
+				StorerStub: &storageMocks.StorerStub{
+					RangeKeysCalled: func(handler func(key []byte, val []byte) bool) {
+						assert.Fail(t, "should have not been called")
+					},
+				},
+			},
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+		})
+
+		keys, err := scheduledTxsExec.GetAllScheduledStateKeys()
+		assert.Equal(t, process.ErrStorerIterationNotSupported, err)
+		assert.Nil(t, keys)
+	})
+	t.Run("nil storer should err", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:      &testscommon.TxProcessorMock{},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           storageUnit.NewNilStorer(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+		})
+
+		keys, err := scheduledTxsExec.GetAllScheduledStateKeys()
+		assert.Equal(t, process.ErrStorerIterationNotSupported, err)
+		assert.Nil(t, keys)
+	})
+	t.Run("should work", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:      &testscommon.TxProcessorMock{},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+		})
+
+		keys, err := scheduledTxsExec.GetAllScheduledStateKeys()
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(keys))
+
+		scheduledTxsExec.SaveState([]byte("header hash 1"), &process.ScheduledInfo{RootHash: []byte("root hash"), GasAndFees: process.GetZeroGasAndFees()})
+		scheduledTxsExec.SaveState([]byte("header hash 2"), &process.ScheduledInfo{RootHash: []byte("root hash"), GasAndFees: process.GetZeroGasAndFees()})
+
+		keys, err = scheduledTxsExec.GetAllScheduledStateKeys()
+		assert.Nil(t, err)
+		assert.ElementsMatch(t, [][]byte{[]byte("header hash 1"), []byte("header hash 2")}, keys)
+	})
 }
 
 func TestScheduledTxsExecution_SaveState(t *testing.T) {
@@ -1174,20 +4395,20 @@ func TestScheduledTxsExecution_SaveState(t *testing.T) {
 	marshalledScheduledData, err := json.Marshal(scheduledSCRs)
 	require.Nil(t, err)
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		&storageMocks.StorerStub{
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer: &storageMocks.StorerStub{
 			PutCalled: func(key, data []byte) error {
 				require.Equal(t, headerHash, key)
 				require.Equal(t, marshalledScheduledData, data)
 				return nil
 			},
 		},
-		&testscommon.MarshalizerMock{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+		Marshaller:       &testscommon.MarshalizerMock{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	scheduledInfo := &process.ScheduledInfo{
 		RootHash:        scheduledRootHash,
@@ -1197,26 +4418,171 @@ func TestScheduledTxsExecution_SaveState(t *testing.T) {
 	scheduledTxsExec.SaveState(headerHash, scheduledInfo)
 }
 
+func TestScheduledTxsExecution_SaveStateWithCompressor(t *testing.T) {
+	t.Parallel()
+
+	numSCRs := 200
+	scrs := make([]data.TransactionHandler, 0, numSCRs)
+	for i := 0; i < numSCRs; i++ {
+		scrs = append(scrs, &smartContractResult.SmartContractResult{
+			Nonce:          uint64(i),
+			Value:          big.NewInt(int64(i) * 1000000000),
+			RcvAddr:        bytes.Repeat([]byte{byte(i % 5)}, 32),
+			SndAddr:        bytes.Repeat([]byte{1}, 32),
+			Data:           []byte(fmt.Sprintf("@6f6b@%x", i)),
+			PrevTxHash:     bytes.Repeat([]byte{byte(i)}, 32),
+			OriginalTxHash: bytes.Repeat([]byte{2}, 32),
+			GasLimit:       uint64(i * 1000),
+			GasPrice:       1000000000,
+		})
+	}
+	scheduledInfo := &process.ScheduledInfo{
+		RootHash:        []byte("scheduled root hash"),
+		IntermediateTxs: map[block.Type][]data.TransactionHandler{block.SmartContractResultBlock: scrs},
+		GasAndFees: scheduled.GasAndFees{
+			AccumulatedFees: big.NewInt(100),
+			DeveloperFees:   big.NewInt(10),
+		},
+		MiniBlocks: block.MiniBlockSlice{},
+	}
+
+	createScheduledTxsExecution := func(storer *genericMocks.StorerMock, compressor process.DataCompressor) *scheduledTxsExecution {
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:      &testscommon.TxProcessorMock{},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           storer,
+			Marshaller:       &testscommon.MarshalizerMock{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+			Compressor:       compressor,
+		})
+
+		return scheduledTxsExec
+	}
+
+	headerHash := []byte("header hash")
+	uncompressedStorer := genericMocks.NewStorerMock()
+	uncompressedScheduledTxsExec := createScheduledTxsExecution(uncompressedStorer, nil)
+	uncompressedScheduledTxsExec.SaveState(headerHash, scheduledInfo)
+	uncompressedData, err := uncompressedStorer.Get(headerHash)
+	require.Nil(t, err)
+
+	compressor, _ := compression.NewGzipCompressor(gzip.DefaultCompression)
+	compressedStorer := genericMocks.NewStorerMock()
+	compressedScheduledTxsExec := createScheduledTxsExecution(compressedStorer, compressor)
+	compressedScheduledTxsExec.SaveState(headerHash, scheduledInfo)
+	compressedData, err := compressedStorer.Get(headerHash)
+	require.Nil(t, err)
+
+	t.Logf("scheduled info size: uncompressed %d bytes, compressed %d bytes (%.2f%%)",
+		len(uncompressedData), len(compressedData), float64(len(compressedData))*100/float64(len(uncompressedData)))
+	assert.True(t, len(compressedData) < len(uncompressedData)/2)
+
+	t.Run("compressed data should be read back", func(t *testing.T) {
+		readScheduledInfo, errGet := compressedScheduledTxsExec.getScheduledInfoForHeader(headerHash, core.OptionalUint32{})
+		require.Nil(t, errGet)
+		assert.Equal(t, scheduledInfo.RootHash, readScheduledInfo.RootHash)
+		assert.Equal(t, numSCRs, len(readScheduledInfo.IntermediateTxs[block.SmartContractResultBlock]))
+	})
+	t.Run("old uncompressed data should be read back", func(t *testing.T) {
+		scheduledTxsExec := createScheduledTxsExecution(uncompressedStorer, compressor)
+		readScheduledInfo, errGet := scheduledTxsExec.getScheduledInfoForHeader(headerHash, core.OptionalUint32{})
+		require.Nil(t, errGet)
+		assert.Equal(t, scheduledInfo.RootHash, readScheduledInfo.RootHash)
+		assert.Equal(t, numSCRs, len(readScheduledInfo.IntermediateTxs[block.SmartContractResultBlock]))
+	})
+	t.Run("compressed data without compressor should error", func(t *testing.T) {
+		scheduledTxsExec := createScheduledTxsExecution(compressedStorer, nil)
+		readScheduledInfo, errGet := scheduledTxsExec.getScheduledInfoForHeader(headerHash, core.OptionalUint32{})
+		assert.True(t, errors.Is(errGet, process.ErrNilDataCompressor))
+		assert.Nil(t, readScheduledInfo)
+	})
+}
+
+func TestScheduledTxsExecution_SaveStateWithScheduledSCRsMarshaller(t *testing.T) {
+	t.Parallel()
+
+	scheduledInfo := &process.ScheduledInfo{
+		RootHash: []byte("scheduled root hash"),
+		IntermediateTxs: map[block.Type][]data.TransactionHandler{
+			block.SmartContractResultBlock: {
+				&smartContractResult.SmartContractResult{Nonce: 1, Data: []byte("scr data")},
+			},
+		},
+		GasAndFees: scheduled.GasAndFees{
+			AccumulatedFees: big.NewInt(100),
+			DeveloperFees:   big.NewInt(10),
+		},
+		MiniBlocks: block.MiniBlockSlice{},
+	}
+
+	createScheduledTxsExecution := func(storer *genericMocks.StorerMock, scheduledSCRsMarshaller marshal.Marshalizer) *scheduledTxsExecution {
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:             &testscommon.TxProcessorMock{},
+			TxCoordinator:           &mock.TransactionCoordinatorMock{},
+			Storer:                  storer,
+			Marshaller:              &marshal.GogoProtoMarshalizer{},
+			Hasher:                  &hashingMocks.HasherMock{},
+			ShardCoordinator:        &mock.ShardCoordinatorStub{},
+			ScheduledSCRsMarshaller: scheduledSCRsMarshaller,
+		})
+
+		return scheduledTxsExec
+	}
+
+	headerHash := []byte("header hash")
+	jsonStorer := genericMocks.NewStorerMock()
+	jsonScheduledTxsExec := createScheduledTxsExecution(jsonStorer, &marshal.JsonMarshalizer{})
+	jsonScheduledTxsExec.SaveState(headerHash, scheduledInfo)
+	jsonData, err := jsonStorer.Get(headerHash)
+	require.Nil(t, err)
+	assert.True(t, json.Valid(jsonData))
+
+	t.Run("data should be read back with the same codec", func(t *testing.T) {
+		readScheduledInfo, errGet := jsonScheduledTxsExec.getScheduledInfoForHeader(headerHash, core.OptionalUint32{})
+		require.Nil(t, errGet)
+		assert.Equal(t, scheduledInfo.RootHash, readScheduledInfo.RootHash)
+		assert.Equal(t, 1, len(readScheduledInfo.IntermediateTxs[block.SmartContractResultBlock]))
+	})
+	t.Run("json data read with the block marshaller should error", func(t *testing.T) {
+		scheduledTxsExec := createScheduledTxsExecution(jsonStorer, nil)
+		readScheduledInfo, errGet := scheduledTxsExec.getScheduledInfoForHeader(headerHash, core.OptionalUint32{})
+		assert.True(t, errors.Is(errGet, process.ErrUnmarshalWithWrongVersion))
+		assert.Nil(t, readScheduledInfo)
+
+		err = scheduledTxsExec.RollBackToBlock(headerHash)
+		assert.True(t, errors.Is(err, process.ErrUnmarshalWithWrongVersion))
+	})
+	t.Run("proto data read with the json marshaller should error", func(t *testing.T) {
+		protoStorer := genericMocks.NewStorerMock()
+		createScheduledTxsExecution(protoStorer, nil).SaveState(headerHash, scheduledInfo)
+
+		scheduledTxsExec := createScheduledTxsExecution(protoStorer, &marshal.JsonMarshalizer{})
+		err = scheduledTxsExec.RollBackToBlock(headerHash)
+		assert.True(t, errors.Is(err, process.ErrUnmarshalWithWrongVersion))
+	})
+}
+
 func TestScheduledTxsExecution_SaveStateIfNeeded(t *testing.T) {
 	t.Parallel()
 
 	headerHash := []byte("header hash")
 
 	wasCalled := false
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		&storageMocks.StorerStub{
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer: &storageMocks.StorerStub{
 			PutCalled: func(key, _ []byte) error {
 				wasCalled = true
 				require.Equal(t, headerHash, key)
 				return nil
 			},
 		},
-		&testscommon.MarshalizerMock{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+		Marshaller:       &testscommon.MarshalizerMock{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	scheduledTxsExec.SaveStateIfNeeded(headerHash)
 	assert.False(t, wasCalled)
@@ -1233,14 +4599,14 @@ func TestScheduledTxsExecution_IsScheduledTx(t *testing.T) {
 	txHash1 := []byte("txHash1")
 	txHash2 := []byte("txHash2")
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 	scheduledTxsExec.AddScheduledTx(txHash1, &transaction.Transaction{Nonce: 0})
 
 	ok := scheduledTxsExec.IsScheduledTx(txHash1)
@@ -1250,18 +4616,45 @@ func TestScheduledTxsExecution_IsScheduledTx(t *testing.T) {
 	assert.False(t, ok)
 }
 
+func TestScheduledTxsExecution_AreScheduledTxs(t *testing.T) {
+	t.Parallel()
+
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	assert.Equal(t, 0, len(scheduledTxsExec.AreScheduledTxs(nil)))
+
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash3"), &transaction.Transaction{Nonce: 1})
+
+	txHashes := [][]byte{
+		[]byte("txHash1"),
+		[]byte("txHash2"),
+		[]byte("txHash3"),
+		[]byte("txHash4"),
+		[]byte("txHash1"),
+	}
+	assert.Equal(t, []bool{true, false, true, false, true}, scheduledTxsExec.AreScheduledTxs(txHashes))
+}
+
 func TestScheduledTxsExecution_AddMiniBlocksWithNilReservedNilTxHashes(t *testing.T) {
 	t.Run("nil Reserved", func(t *testing.T) {
 		t.Parallel()
 
-		scheduledTxsExec, _ := NewScheduledTxsExecution(
-			&testscommon.TxProcessorMock{},
-			&mock.TransactionCoordinatorMock{},
-			genericMocks.NewStorerMock(),
-			&marshal.GogoProtoMarshalizer{},
-			&hashingMocks.HasherMock{},
-			&mock.ShardCoordinatorStub{},
-		)
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:      &testscommon.TxProcessorMock{},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+		})
 
 		miniBlocks := block.MiniBlockSlice{}
 		mb := &block.MiniBlock{
@@ -1281,14 +4674,14 @@ func TestScheduledTxsExecution_AddMiniBlocksWithNilReservedNilTxHashes(t *testin
 	t.Run("nil TxHashes", func(t *testing.T) {
 		t.Parallel()
 
-		scheduledTxsExec, _ := NewScheduledTxsExecution(
-			&testscommon.TxProcessorMock{},
-			&mock.TransactionCoordinatorMock{},
-			genericMocks.NewStorerMock(),
-			&marshal.GogoProtoMarshalizer{},
-			&hashingMocks.HasherMock{},
-			&mock.ShardCoordinatorStub{},
-		)
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:      &testscommon.TxProcessorMock{},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+		})
 
 		miniBlocks := block.MiniBlockSlice{}
 		mb := &block.MiniBlock{
@@ -1309,14 +4702,14 @@ func TestScheduledTxsExecution_AddMiniBlocksWithNilReservedNilTxHashes(t *testin
 
 func TestScheduledTxsExecution_AddMiniBlocksShouldWork(t *testing.T) {
 	t.Parallel()
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	miniBlocks := block.MiniBlockSlice{}
 	mb1 := &block.MiniBlock{
@@ -1348,14 +4741,14 @@ func TestScheduledTxsExecution_AddMiniBlocksShouldWork(t *testing.T) {
 func TestScheduledTxsExecution_GetScheduledTxs(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 	firstTransaction := &transaction.Transaction{Nonce: 0}
 	secondTransaction := &transaction.Transaction{Nonce: 1}
 	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), firstTransaction)
@@ -1369,17 +4762,42 @@ func TestScheduledTxsExecution_GetScheduledTxs(t *testing.T) {
 	assert.True(t, reflect.DeepEqual(secondTransaction, scheduledTxs[1]))
 }
 
+func TestScheduledTxsExecution_GetScheduledTxHashes(t *testing.T) {
+	t.Parallel()
+
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+
+	scheduledTxHashes := scheduledTxsExec.GetScheduledTxHashes()
+	assert.Equal(t, [][]byte{[]byte("txHash2"), []byte("txHash1")}, scheduledTxHashes)
+
+	scheduledTxHashes[0] = []byte("changed hash")
+	assert.Equal(t, []byte("txHash2"), scheduledTxsExec.GetScheduledTxHashes()[0])
+
+	scheduledTxsExec.Init()
+	assert.Equal(t, 0, len(scheduledTxsExec.GetScheduledTxHashes()))
+	assert.Equal(t, 0, len(scheduledTxsExec.GetScheduledTxs()))
+}
+
 func TestScheduledTxsExecution_GetScheduledMBs(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	miniBlocks := block.MiniBlockSlice{}
 	mb1 := &block.MiniBlock{
@@ -1420,6 +4838,59 @@ func TestScheduledTxsExecution_GetScheduledMBs(t *testing.T) {
 	assert.True(t, reflect.DeepEqual(mb3, scheduledMBs[2]))
 }
 
+func TestScheduledTxsExecution_GetScheduledMBsFiltered(t *testing.T) {
+	t.Parallel()
+
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	mb1 := &block.MiniBlock{
+		TxHashes:        make([][]byte, 1),
+		ReceiverShardID: 1,
+		SenderShardID:   0,
+		Type:            block.TxBlock,
+	}
+	mb2 := &block.MiniBlock{
+		TxHashes:        make([][]byte, 2),
+		ReceiverShardID: 1,
+		SenderShardID:   0,
+		Type:            block.SmartContractResultBlock,
+	}
+	mb3 := &block.MiniBlock{
+		TxHashes:        make([][]byte, 3),
+		ReceiverShardID: 2,
+		SenderShardID:   0,
+		Type:            block.TxBlock,
+	}
+	mb4 := &block.MiniBlock{
+		TxHashes:        make([][]byte, 4),
+		ReceiverShardID: 1,
+		SenderShardID:   0,
+		Type:            block.InvalidBlock,
+	}
+	scheduledTxsExec.AddScheduledMiniBlocks(block.MiniBlockSlice{mb1, mb2, mb3, mb4})
+
+	scheduledMBs := scheduledTxsExec.GetScheduledMBsFiltered([]block.Type{block.TxBlock, block.InvalidBlock}, 1)
+	assert.Equal(t, block.MiniBlockSlice{mb1, mb4}, scheduledMBs)
+
+	scheduledMBs = scheduledTxsExec.GetScheduledMBsFiltered(nil, 1)
+	assert.Equal(t, block.MiniBlockSlice{mb1, mb2, mb4}, scheduledMBs)
+
+	scheduledMBs = scheduledTxsExec.GetScheduledMBsFiltered([]block.Type{block.SmartContractResultBlock}, 2)
+	assert.Equal(t, 0, len(scheduledMBs))
+
+	scheduledMBs = scheduledTxsExec.GetScheduledMBsFiltered([]block.Type{block.TxBlock}, 2)
+	require.Equal(t, 1, len(scheduledMBs))
+	scheduledMBs[0].TxHashes = nil
+	assert.Equal(t, 3, len(scheduledTxsExec.scheduledMbs[2].TxHashes))
+}
+
 func TestScheduledTxsExecution_GetScheduledRootHashForHeaderWithErrorShouldFail(t *testing.T) {
 	t.Parallel()
 
@@ -1427,10 +4898,10 @@ func TestScheduledTxsExecution_GetScheduledRootHashForHeaderWithErrorShouldFail(
 	storerGetErr := errors.New("storer.Get() error")
 	storerGetFromEpochErr := errors.New("storer.GetFromEpoch() err")
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		&storageMocks.StorerStub{
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer: &storageMocks.StorerStub{
 			GetCalled: func(_ []byte) ([]byte, error) {
 				return nil, storerGetErr
 			},
@@ -1438,10 +4909,10 @@ func TestScheduledTxsExecution_GetScheduledRootHashForHeaderWithErrorShouldFail(
 				return nil, storerGetFromEpochErr
 			},
 		},
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	t.Run("without epoch", func(t *testing.T) {
 		scheduledInfo, err := scheduledTxsExec.GetScheduledRootHashForHeader(headerHash)
@@ -1477,10 +4948,10 @@ func TestScheduledTxsExecution_GetScheduledRootHashForHeaderShouldWork(t *testin
 	var keyPassedToStorerGet []byte
 	var epochPassedToStorerGet uint32
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		&storageMocks.StorerStub{
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer: &storageMocks.StorerStub{
 			GetCalled: func(key []byte) ([]byte, error) {
 				keyPassedToStorerGet = key
 				return marshalledSCRsSavedData, nil
@@ -1491,10 +4962,10 @@ func TestScheduledTxsExecution_GetScheduledRootHashForHeaderShouldWork(t *testin
 				return marshalledSCRsSavedData, nil
 			},
 		},
-		&testscommon.MarshalizerMock{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+		Marshaller:       &testscommon.MarshalizerMock{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	t.Run("without epoch", func(t *testing.T) {
 		scheduledRootHash, err := scheduledTxsExec.GetScheduledRootHashForHeader([]byte("aabb"))
@@ -1512,17 +4983,152 @@ func TestScheduledTxsExecution_GetScheduledRootHashForHeaderShouldWork(t *testin
 	})
 }
 
+func TestScheduledTxsExecution_GetScheduledInfoForHeadersShouldSkipMissingHeaders(t *testing.T) {
+	t.Parallel()
+
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMockWithEpoch(7),
+		Marshaller:       &testscommon.MarshalizerMock{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	scheduledInfo1 := &process.ScheduledInfo{
+		RootHash:        []byte("root hash 1"),
+		IntermediateTxs: make(map[block.Type][]data.TransactionHandler),
+		GasAndFees:      scheduled.GasAndFees{AccumulatedFees: big.NewInt(1), DeveloperFees: big.NewInt(0)},
+	}
+	scheduledInfo2 := &process.ScheduledInfo{
+		RootHash:        []byte("root hash 2"),
+		IntermediateTxs: make(map[block.Type][]data.TransactionHandler),
+		GasAndFees:      scheduled.GasAndFees{AccumulatedFees: big.NewInt(2), DeveloperFees: big.NewInt(0)},
+	}
+	scheduledTxsExec.SaveState([]byte("header hash 1"), scheduledInfo1)
+	scheduledTxsExec.SaveState([]byte("header hash 2"), scheduledInfo2)
+
+	headerHashes := [][]byte{[]byte("header hash 1"), []byte("missing header hash"), []byte("header hash 2")}
+
+	t.Run("without epoch", func(t *testing.T) {
+		mapScheduledInfo, err := scheduledTxsExec.GetScheduledInfoForHeaders(headerHashes)
+		require.Nil(t, err)
+		require.Equal(t, 2, len(mapScheduledInfo))
+		assert.Equal(t, scheduledInfo1.RootHash, mapScheduledInfo["header hash 1"].RootHash)
+		assert.Equal(t, scheduledInfo2.RootHash, mapScheduledInfo["header hash 2"].RootHash)
+		assert.Equal(t, big.NewInt(2), mapScheduledInfo["header hash 2"].GasAndFees.AccumulatedFees)
+	})
+
+	t.Run("with epoch", func(t *testing.T) {
+		mapScheduledInfo, err := scheduledTxsExec.GetScheduledInfoForHeadersWithEpoch(headerHashes, 7)
+		require.Nil(t, err)
+		require.Equal(t, 2, len(mapScheduledInfo))
+		assert.Equal(t, scheduledInfo1.RootHash, mapScheduledInfo["header hash 1"].RootHash)
+		assert.Equal(t, scheduledInfo2.RootHash, mapScheduledInfo["header hash 2"].RootHash)
+
+		mapScheduledInfo, err = scheduledTxsExec.GetScheduledInfoForHeadersWithEpoch(headerHashes, 6)
+		require.Nil(t, err)
+		assert.Equal(t, 0, len(mapScheduledInfo))
+	})
+}
+
+func TestScheduledTxsExecution_GetScheduledInfoForHeadersShouldErrOnReadError(t *testing.T) {
+	t.Parallel()
+
+	expectedErr := errors.New("expected error")
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer: &storageMocks.StorerStub{
+			GetCalled: func(key []byte) ([]byte, error) {
+				if bytes.Equal(key, []byte("missing header hash")) {
+					return nil, fmt.Errorf("key %x not found", key)
+				}
+				return nil, expectedErr
+			},
+		},
+		Marshaller:       &testscommon.MarshalizerMock{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	mapScheduledInfo, err := scheduledTxsExec.GetScheduledInfoForHeaders([][]byte{[]byte("missing header hash")})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(mapScheduledInfo))
+
+	mapScheduledInfo, err = scheduledTxsExec.GetScheduledInfoForHeaders([][]byte{[]byte("missing header hash"), []byte("header hash")})
+	assert.Nil(t, mapScheduledInfo)
+	assert.True(t, errors.Is(err, expectedErr))
+}
+
+func TestScheduledTxsExecution_GetScheduledInfoForHeadersShouldUseTheScheduledInfoCache(t *testing.T) {
+	t.Parallel()
+
+	storer := genericMocks.NewStorerMock()
+	numGetCalls := 0
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer: &storageMocks.StorerStub{
+			PutCalled: storer.Put,
+			GetCalled: func(key []byte) ([]byte, error) {
+				numGetCalls++
+				return storer.Get(key)
+			},
+		},
+		Marshaller:             &testscommon.MarshalizerMock{},
+		Hasher:                 &hashingMocks.HasherMock{},
+		ShardCoordinator:       &mock.ShardCoordinatorStub{},
+		ScheduledInfoCacheSize: 10,
+	})
+
+	scheduledInfo := &process.ScheduledInfo{
+		RootHash:        []byte("root hash"),
+		IntermediateTxs: make(map[block.Type][]data.TransactionHandler),
+		GasAndFees:      scheduled.GasAndFees{AccumulatedFees: big.NewInt(1), DeveloperFees: big.NewInt(0)},
+	}
+	scheduledTxsExec.SaveState([]byte("header hash"), scheduledInfo)
+
+	mapScheduledInfo, err := scheduledTxsExec.GetScheduledInfoForHeaders([][]byte{[]byte("header hash")})
+	require.Nil(t, err)
+	require.Equal(t, 1, len(mapScheduledInfo))
+	assert.Equal(t, scheduledInfo.RootHash, mapScheduledInfo["header hash"].RootHash)
+	assert.Equal(t, 0, numGetCalls)
+}
+
+func TestScheduledTxsExecution_GetScheduledInfoForHeadersWithEpochShouldErrOnBulkGetError(t *testing.T) {
+	t.Parallel()
+
+	expectedErr := errors.New("expected error")
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer: &storageMocks.StorerStub{
+			GetBulkFromEpochCalled: func(keys [][]byte, epoch uint32) ([]storage.KeyValuePair, error) {
+				return nil, expectedErr
+			},
+		},
+		Marshaller:       &testscommon.MarshalizerMock{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+
+	mapScheduledInfo, err := scheduledTxsExec.GetScheduledInfoForHeadersWithEpoch([][]byte{[]byte("header hash")}, 7)
+	assert.Nil(t, mapScheduledInfo)
+	assert.Equal(t, expectedErr, err)
+}
+
 func TestScheduledTxsExecution_removeInvalidTxsFromScheduledMiniBlocks(t *testing.T) {
 	t.Parallel()
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&hashingMocks.HasherMock{},
-		&mock.ShardCoordinatorStub{},
-	)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      &testscommon.TxProcessorMock{},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	txHash1 := []byte("txHash1")
 	txHash2 := []byte("txHash2")
@@ -1596,18 +5202,18 @@ func TestScheduledTxsExecution_setScheduledMiniBlockHashes(t *testing.T) {
 
 	t.Run("fail to calculate hash", func(t *testing.T) {
 		expectedErr := errors.New("calculate hash err")
-		scheduledTxsExec, _ := NewScheduledTxsExecution(
-			&testscommon.TxProcessorMock{},
-			&mock.TransactionCoordinatorMock{},
-			genericMocks.NewStorerMock(),
-			&testscommon.MarshalizerStub{
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:   &testscommon.TxProcessorMock{},
+			TxCoordinator: &mock.TransactionCoordinatorMock{},
+			Storer:        genericMocks.NewStorerMock(),
+			Marshaller: &testscommon.MarshalizerStub{
 				MarshalCalled: func(obj interface{}) ([]byte, error) {
 					return nil, expectedErr
 				},
 			},
-			&mock.HasherStub{},
-			&mock.ShardCoordinatorStub{},
-		)
+			Hasher:           &mock.HasherStub{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+		})
 
 		miniBlocks := block.MiniBlockSlice{&block.MiniBlock{
 			TxHashes: [][]byte{[]byte("dummyhash")},
@@ -1623,23 +5229,23 @@ func TestScheduledTxsExecution_setScheduledMiniBlockHashes(t *testing.T) {
 			TxHashes: [][]byte{[]byte("dummyhash")},
 		}
 
-		scheduledTxsExec, _ := NewScheduledTxsExecution(
-			&testscommon.TxProcessorMock{},
-			&mock.TransactionCoordinatorMock{},
-			genericMocks.NewStorerMock(),
-			&testscommon.MarshalizerStub{
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:   &testscommon.TxProcessorMock{},
+			TxCoordinator: &mock.TransactionCoordinatorMock{},
+			Storer:        genericMocks.NewStorerMock(),
+			Marshaller: &testscommon.MarshalizerStub{
 				MarshalCalled: func(obj interface{}) ([]byte, error) {
 					assert.Equal(t, mb, obj)
 					return nil, nil
 				},
 			},
-			&mock.HasherStub{
+			Hasher: &mock.HasherStub{
 				ComputeCalled: func(s string) []byte {
 					return hash
 				},
 			},
-			&mock.ShardCoordinatorStub{},
-		)
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+		})
 
 		miniBlocks := block.MiniBlockSlice{mb}
 		scheduledTxsExec.AddScheduledMiniBlocks(miniBlocks)
@@ -1653,24 +5259,114 @@ func TestScheduledTxsExecution_setScheduledMiniBlockHashes(t *testing.T) {
 	})
 }
 
+func createScheduledMiniBlocksForHashing(numMiniBlocksPerType int, numTxsPerMiniBlock int) block.MiniBlockSlice {
+	miniBlockTypes := []block.Type{block.TxBlock, block.SmartContractResultBlock, block.InvalidBlock, block.ReceiptBlock}
+	miniBlocks := make(block.MiniBlockSlice, 0, len(miniBlockTypes)*numMiniBlocksPerType)
+	for _, miniBlockType := range miniBlockTypes {
+		for i := 0; i < numMiniBlocksPerType; i++ {
+			txHashes := make([][]byte, numTxsPerMiniBlock)
+			for j := 0; j < numTxsPerMiniBlock; j++ {
+				txHashes[j] = []byte(fmt.Sprintf("txHash_%d_%d_%d", miniBlockType, i, j))
+			}
+			miniBlocks = append(miniBlocks, &block.MiniBlock{
+				TxHashes:        txHashes,
+				ReceiverShardID: uint32(i),
+				Type:            miniBlockType,
+			})
+		}
+	}
+
+	return miniBlocks
+}
+
+func createScheduledTxsExecutionForHashing(parallelMiniBlocksHashing bool, marshaller marshal.Marshalizer) *scheduledTxsExecution {
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:               &testscommon.TxProcessorMock{},
+		TxCoordinator:             &mock.TransactionCoordinatorMock{},
+		Storer:                    genericMocks.NewStorerMock(),
+		Marshaller:                marshaller,
+		Hasher:                    &hashingMocks.HasherMock{},
+		ShardCoordinator:          &mock.ShardCoordinatorStub{},
+		ParallelMiniBlocksHashing: parallelMiniBlocksHashing,
+	})
+
+	return scheduledTxsExec
+}
+
+func TestScheduledTxsExecution_setScheduledMiniBlockHashesInParallel(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should compute the same hashes as the serial path", func(t *testing.T) {
+		t.Parallel()
+
+		miniBlocks := createScheduledMiniBlocksForHashing(10, 5)
+
+		serialScheduledTxsExec := createScheduledTxsExecutionForHashing(false, &marshal.GogoProtoMarshalizer{})
+		serialScheduledTxsExec.AddScheduledMiniBlocks(miniBlocks)
+		err := serialScheduledTxsExec.setScheduledMiniBlockHashes()
+		require.Nil(t, err)
+
+		parallelScheduledTxsExec := createScheduledTxsExecutionForHashing(true, &marshal.GogoProtoMarshalizer{})
+		parallelScheduledTxsExec.AddScheduledMiniBlocks(miniBlocks)
+		err = parallelScheduledTxsExec.setScheduledMiniBlockHashes()
+		require.Nil(t, err)
+
+		assert.Equal(t, len(miniBlocks), len(parallelScheduledTxsExec.mapScheduledMbHashes))
+		assert.Equal(t, serialScheduledTxsExec.mapScheduledMbHashes, parallelScheduledTxsExec.mapScheduledMbHashes)
+	})
+	t.Run("should return the error of the first mini block type", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec := createScheduledTxsExecutionForHashing(true, &testscommon.MarshalizerStub{
+			MarshalCalled: func(obj interface{}) ([]byte, error) {
+				miniBlock := obj.(*block.MiniBlock)
+				return nil, fmt.Errorf("marshal error for type %d", miniBlock.Type)
+			},
+		})
+		scheduledTxsExec.AddScheduledMiniBlocks(createScheduledMiniBlocksForHashing(3, 1))
+
+		err := scheduledTxsExec.setScheduledMiniBlockHashes()
+		assert.Equal(t, fmt.Sprintf("marshal error for type %d", block.TxBlock), err.Error())
+		assert.Equal(t, 0, len(scheduledTxsExec.mapScheduledMbHashes))
+	})
+}
+
+func BenchmarkScheduledTxsExecution_setScheduledMiniBlockHashesSerial(b *testing.B) {
+	benchmarkSetScheduledMiniBlockHashes(b, false)
+}
+
+func BenchmarkScheduledTxsExecution_setScheduledMiniBlockHashesParallel(b *testing.B) {
+	benchmarkSetScheduledMiniBlockHashes(b, true)
+}
+
+func benchmarkSetScheduledMiniBlockHashes(b *testing.B, parallelMiniBlocksHashing bool) {
+	scheduledTxsExec := createScheduledTxsExecutionForHashing(parallelMiniBlocksHashing, &marshal.GogoProtoMarshalizer{})
+	scheduledTxsExec.AddScheduledMiniBlocks(createScheduledMiniBlocksForHashing(3, 5000))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = scheduledTxsExec.setScheduledMiniBlockHashes()
+	}
+}
+
 func TestScheduledTxsExecution_IsMiniBlockExecuted(t *testing.T) {
 	t.Parallel()
 
 	hash1 := []byte("hash1")
 	hash2 := []byte("hash2")
 
-	scheduledTxsExec, _ := NewScheduledTxsExecution(
-		&testscommon.TxProcessorMock{},
-		&mock.TransactionCoordinatorMock{},
-		genericMocks.NewStorerMock(),
-		&marshal.GogoProtoMarshalizer{},
-		&mock.HasherStub{
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:   &testscommon.TxProcessorMock{},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Storer:        genericMocks.NewStorerMock(),
+		Marshaller:    &marshal.GogoProtoMarshalizer{},
+		Hasher: &mock.HasherStub{
 			ComputeCalled: func(s string) []byte {
 				return hash1
 			},
 		},
-		&mock.ShardCoordinatorStub{},
-	)
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
 
 	miniBlocks := block.MiniBlockSlice{&block.MiniBlock{
 		TxHashes: [][]byte{[]byte("dummyhash")},
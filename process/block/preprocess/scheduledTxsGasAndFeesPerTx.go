@@ -0,0 +1,141 @@
+package preprocess
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ElrondNetwork/elrond-go-core/core/check"
+	"github.com/ElrondNetwork/elrond-go-core/data/scheduled"
+	"github.com/ElrondNetwork/elrond-go/process"
+)
+
+// GetPerTxGasAndFees returns the gas and fees of each scheduled tx executed by the last execution, keyed by the tx
+// hash. The fees are recorded only if a fee handler was set and the gas only if a gas handler was set. Their sum is
+// the aggregate returned by GetScheduledGasAndFees, as the recorded values are dropped once the aggregate is set to
+// values they do not sum to: the scheduled info of another block, as after a roll back, or an aggregate computed
+// differently for the last execution, which is logged as an error
+func (ste *scheduledTxsExecution) GetPerTxGasAndFees() map[string]scheduled.GasAndFees {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	mapGasAndFees := make(map[string]scheduled.GasAndFees, len(ste.mapScheduledTxGasAndFees))
+	for txHash, gasAndFees := range ste.mapScheduledTxGasAndFees {
+		mapGasAndFees[txHash] = copyGasAndFees(gasAndFees)
+	}
+
+	return mapGasAndFees
+}
+
+// setGasAndFeesForTx records the gas and fees of the given executed tx: the fee already set for it, the given
+// developer fees and the gas handler values stored for its hash
+func (ste *scheduledTxsExecution) setGasAndFeesForTx(txHash []byte, developerFees *big.Int) {
+	gasAndFees := process.GetZeroGasAndFees()
+	fee, ok := ste.mapScheduledTxFees[string(txHash)]
+	if ok {
+		gasAndFees.AccumulatedFees.Set(fee)
+	}
+	if developerFees != nil {
+		gasAndFees.DeveloperFees.Set(developerFees)
+	}
+	if !check.IfNil(ste.gasHandler) {
+		gasAndFees.GasProvided = ste.gasHandler.GasProvidedAsScheduled(txHash)
+		gasAndFees.GasPenalized = ste.gasHandler.GasPenalized(txHash)
+		gasAndFees.GasRefunded = ste.gasHandler.GasRefunded(txHash)
+	}
+
+	ste.mapScheduledTxGasAndFees[string(txHash)] = gasAndFees
+}
+
+// sumPerTxGasAndFees returns the sum of the gas and fees recorded for each executed scheduled tx
+func (ste *scheduledTxsExecution) sumPerTxGasAndFees() scheduled.GasAndFees {
+	sum := process.GetZeroGasAndFees()
+	for _, gasAndFees := range ste.mapScheduledTxGasAndFees {
+		sum.AccumulatedFees.Add(sum.AccumulatedFees, gasAndFees.AccumulatedFees)
+		sum.DeveloperFees.Add(sum.DeveloperFees, gasAndFees.DeveloperFees)
+		sum.GasProvided += gasAndFees.GasProvided
+		sum.GasPenalized += gasAndFees.GasPenalized
+		sum.GasRefunded += gasAndFees.GasRefunded
+	}
+
+	return sum
+}
+
+// checkPerTxGasAndFees returns ErrScheduledGasAndFeesMismatch if the gas and fees recorded per tx do not sum to the
+// aggregate gas and fees. The fees are checked only if a fee handler was set and the gas only if a gas handler was set,
+// as they are not recorded otherwise
+func (ste *scheduledTxsExecution) checkPerTxGasAndFees() error {
+	if len(ste.mapScheduledTxGasAndFees) == 0 {
+		return nil
+	}
+
+	sum := ste.sumPerTxGasAndFees()
+	if !check.IfNil(ste.feeHandler) {
+		isFeesMatch := isBigIntEqual(sum.AccumulatedFees, ste.gasAndFees.AccumulatedFees) &&
+			isBigIntEqual(sum.DeveloperFees, ste.gasAndFees.DeveloperFees)
+		if !isFeesMatch {
+			return fmt.Errorf("%w: accumulated fees %s, aggregate %s, developer fees %s, aggregate %s",
+				process.ErrScheduledGasAndFeesMismatch,
+				sum.AccumulatedFees.String(), ste.gasAndFees.AccumulatedFees.String(),
+				sum.DeveloperFees.String(), ste.gasAndFees.DeveloperFees.String())
+		}
+	}
+	if !check.IfNil(ste.gasHandler) {
+		isGasMatch := sum.GasProvided == ste.gasAndFees.GasProvided &&
+			sum.GasPenalized == ste.gasAndFees.GasPenalized &&
+			sum.GasRefunded == ste.gasAndFees.GasRefunded
+		if !isGasMatch {
+			return fmt.Errorf("%w: gas provided %d, aggregate %d, gas penalized %d, aggregate %d, gas refunded %d, aggregate %d",
+				process.ErrScheduledGasAndFeesMismatch,
+				sum.GasProvided, ste.gasAndFees.GasProvided,
+				sum.GasPenalized, ste.gasAndFees.GasPenalized,
+				sum.GasRefunded, ste.gasAndFees.GasRefunded)
+		}
+	}
+
+	return nil
+}
+
+// setAggregateGasAndFeesFromScheduledInfo sets the aggregate gas and fees of a scheduled info, which usually belongs to
+// another block than the last execution, in which case the gas and fees recorded per tx are dropped
+func (ste *scheduledTxsExecution) setAggregateGasAndFeesFromScheduledInfo(gasAndFees scheduled.GasAndFees) {
+	ste.gasAndFees = gasAndFees
+	err := ste.checkPerTxGasAndFees()
+	if err != nil {
+		log.Debug("scheduledTxsExecution.SetScheduledInfo: dropped the per tx gas and fees of the last execution",
+			"num of txs", len(ste.mapScheduledTxGasAndFees), "reason", err.Error())
+		ste.mapScheduledTxGasAndFees = make(map[string]scheduled.GasAndFees)
+	}
+}
+
+// setAggregateGasAndFeesOfLastExecution sets the aggregate gas and fees computed for the last execution, which has to be
+// the sum of the gas and fees recorded per tx. Otherwise, the error is returned and the per tx values are dropped, so
+// that they are not reported as the breakdown of an aggregate they do not sum to
+func (ste *scheduledTxsExecution) setAggregateGasAndFeesOfLastExecution(gasAndFees scheduled.GasAndFees) error {
+	ste.gasAndFees = gasAndFees
+	err := ste.checkPerTxGasAndFees()
+	if err != nil {
+		ste.mapScheduledTxGasAndFees = make(map[string]scheduled.GasAndFees)
+	}
+
+	return err
+}
+
+func copyGasAndFees(gasAndFees scheduled.GasAndFees) scheduled.GasAndFees {
+	gasAndFeesCopy := gasAndFees
+	if gasAndFees.AccumulatedFees != nil {
+		gasAndFeesCopy.AccumulatedFees = big.NewInt(0).Set(gasAndFees.AccumulatedFees)
+	}
+	if gasAndFees.DeveloperFees != nil {
+		gasAndFeesCopy.DeveloperFees = big.NewInt(0).Set(gasAndFees.DeveloperFees)
+	}
+
+	return gasAndFeesCopy
+}
+
+func isBigIntEqual(a *big.Int, b *big.Int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Cmp(b) == 0
+}
@@ -0,0 +1,201 @@
+package preprocess
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ElrondNetwork/elrond-go-core/data/scheduled"
+	"github.com/ElrondNetwork/elrond-go-core/data/transaction"
+	"github.com/ElrondNetwork/elrond-go-core/marshal"
+	"github.com/ElrondNetwork/elrond-go/process"
+	"github.com/ElrondNetwork/elrond-go/process/mock"
+	"github.com/ElrondNetwork/elrond-go/testscommon"
+	"github.com/ElrondNetwork/elrond-go/testscommon/genericMocks"
+	"github.com/ElrondNetwork/elrond-go/testscommon/hashingMocks"
+	vmcommon "github.com/ElrondNetwork/elrond-vm-common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createScheduledTxsExecutionWithGasAndFees() *scheduledTxsExecution {
+	accumulatedFees := big.NewInt(0)
+	developerFees := big.NewInt(0)
+	mapNonces := make(map[string]uint64)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(tx *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				accumulatedFees.Add(accumulatedFees, big.NewInt(int64(tx.Nonce*10)))
+				developerFees.Add(developerFees, big.NewInt(int64(tx.Nonce)))
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+	scheduledTxsExec.SetFeeHandler(&mock.FeeAccumulatorStub{
+		GetAccumulatedFeesCalled: func() *big.Int {
+			return big.NewInt(0).Set(accumulatedFees)
+		},
+		GetDeveloperFeesCalled: func() *big.Int {
+			return big.NewInt(0).Set(developerFees)
+		},
+	})
+	scheduledTxsExec.SetGasHandler(&testscommon.GasHandlerStub{
+		GasProvidedAsScheduledCalled: func(hash []byte) uint64 {
+			return mapNonces[string(hash)] * 100
+		},
+		GasRefundedCalled: func(hash []byte) uint64 {
+			return mapNonces[string(hash)] * 10
+		},
+		GasPenalizedCalled: func(hash []byte) uint64 {
+			return mapNonces[string(hash)]
+		},
+	})
+
+	for nonce := uint64(1); nonce <= 3; nonce++ {
+		txHash := []byte{byte(nonce)}
+		mapNonces[string(txHash)] = nonce
+		scheduledTxsExec.AddScheduledTx(txHash, &transaction.Transaction{Nonce: nonce})
+	}
+
+	return scheduledTxsExec
+}
+
+func TestScheduledTxsExecution_GetPerTxGasAndFeesShouldRecordEachExecutedTx(t *testing.T) {
+	t.Parallel()
+
+	scheduledTxsExec := createScheduledTxsExecutionWithGasAndFees()
+	assert.Equal(t, 0, len(scheduledTxsExec.GetPerTxGasAndFees()))
+
+	err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	require.Nil(t, err)
+
+	perTxGasAndFees := scheduledTxsExec.GetPerTxGasAndFees()
+	require.Equal(t, 3, len(perTxGasAndFees))
+	expectedGasAndFees := scheduled.GasAndFees{
+		AccumulatedFees: big.NewInt(20),
+		DeveloperFees:   big.NewInt(2),
+		GasProvided:     200,
+		GasPenalized:    2,
+		GasRefunded:     20,
+	}
+	assert.Equal(t, expectedGasAndFees, perTxGasAndFees[string([]byte{2})])
+
+	perTxGasAndFees[string([]byte{2})].AccumulatedFees.SetInt64(0)
+	assert.Equal(t, big.NewInt(20), scheduledTxsExec.GetPerTxGasAndFees()[string([]byte{2})].AccumulatedFees)
+
+	scheduledTxsExec.Init()
+	assert.Equal(t, 0, len(scheduledTxsExec.GetPerTxGasAndFees()))
+}
+
+func TestScheduledTxsExecution_GetPerTxGasAndFeesShouldSumToTheAggregate(t *testing.T) {
+	t.Parallel()
+
+	scheduledTxsExec := createScheduledTxsExecutionWithGasAndFees()
+	err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	require.Nil(t, err)
+
+	scheduledTxsExec.SetScheduledInfo(&process.ScheduledInfo{
+		GasAndFees: scheduled.GasAndFees{
+			AccumulatedFees: big.NewInt(60),
+			DeveloperFees:   big.NewInt(6),
+			GasProvided:     600,
+			GasPenalized:    6,
+			GasRefunded:     60,
+		},
+	})
+
+	sum := process.GetZeroGasAndFees()
+	for _, gasAndFees := range scheduledTxsExec.GetPerTxGasAndFees() {
+		sum.AccumulatedFees.Add(sum.AccumulatedFees, gasAndFees.AccumulatedFees)
+		sum.DeveloperFees.Add(sum.DeveloperFees, gasAndFees.DeveloperFees)
+		sum.GasProvided += gasAndFees.GasProvided
+		sum.GasPenalized += gasAndFees.GasPenalized
+		sum.GasRefunded += gasAndFees.GasRefunded
+	}
+	assert.Equal(t, scheduledTxsExec.GetScheduledGasAndFees(), sum)
+	assert.Equal(t, sum, scheduledTxsExec.sumPerTxGasAndFees())
+}
+
+func TestScheduledTxsExecution_GetPerTxGasAndFeesShouldBeResetOnEachExecution(t *testing.T) {
+	t.Parallel()
+
+	scheduledTxsExec := createScheduledTxsExecutionWithGasAndFees()
+	err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	require.Nil(t, err)
+	require.Equal(t, 3, len(scheduledTxsExec.GetPerTxGasAndFees()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = scheduledTxsExec.ExecuteAllWithContext(ctx, func() time.Duration { return time.Second })
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 0, len(scheduledTxsExec.GetPerTxGasAndFees()))
+	_, found := scheduledTxsExec.GetScheduledTxFee([]byte{1})
+	assert.False(t, found)
+}
+
+func TestScheduledTxsExecution_GetPerTxGasAndFeesShouldBeDroppedIfNotSummingToTheAggregate(t *testing.T) {
+	t.Parallel()
+
+	createGasAndFees := func() scheduled.GasAndFees {
+		return scheduled.GasAndFees{
+			AccumulatedFees: big.NewInt(60),
+			DeveloperFees:   big.NewInt(6),
+			GasProvided:     600,
+			GasPenalized:    6,
+			GasRefunded:     60,
+		}
+	}
+
+	t.Run("aggregate of the last execution should error", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec := createScheduledTxsExecutionWithGasAndFees()
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		require.Nil(t, err)
+
+		err = scheduledTxsExec.setAggregateGasAndFeesOfLastExecution(createGasAndFees())
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(scheduledTxsExec.GetPerTxGasAndFees()))
+
+		gasAndFees := createGasAndFees()
+		gasAndFees.GasProvided = 500
+		err = scheduledTxsExec.setAggregateGasAndFeesOfLastExecution(gasAndFees)
+		assert.True(t, errors.Is(err, process.ErrScheduledGasAndFeesMismatch))
+		assert.Equal(t, 0, len(scheduledTxsExec.GetPerTxGasAndFees()))
+		assert.Equal(t, gasAndFees, scheduledTxsExec.GetScheduledGasAndFees())
+	})
+	t.Run("scheduled info of another block", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec := createScheduledTxsExecutionWithGasAndFees()
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		require.Nil(t, err)
+
+		gasAndFees := createGasAndFees()
+		gasAndFees.AccumulatedFees = big.NewInt(70)
+		scheduledTxsExec.SetScheduledInfo(&process.ScheduledInfo{
+			GasAndFees: gasAndFees,
+		})
+		assert.Equal(t, 0, len(scheduledTxsExec.GetPerTxGasAndFees()))
+		assert.Equal(t, gasAndFees, scheduledTxsExec.GetScheduledGasAndFees())
+	})
+	t.Run("gas not recorded without gas handler should not be checked", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec := createScheduledTxsExecutionWithGasAndFees()
+		scheduledTxsExec.gasHandler = nil
+		err := scheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+		require.Nil(t, err)
+
+		err = scheduledTxsExec.setAggregateGasAndFeesOfLastExecution(createGasAndFees())
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(scheduledTxsExec.GetPerTxGasAndFees()))
+	})
+}
@@ -0,0 +1,65 @@
+package preprocess
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ElrondNetwork/elrond-go-core/core/check"
+	"github.com/ElrondNetwork/elrond-go-core/data"
+	"github.com/ElrondNetwork/elrond-go/process"
+)
+
+// scheduledGasBudget holds the gas budget of the current scheduled txs execution and whether it stopped the execution
+type scheduledGasBudget struct {
+	maxGas      uint64
+	isExhausted bool
+}
+
+// ExecuteAllWithGasBudget executes all the scheduled transactions, as ExecuteAll does, and also stops before the
+// scheduled tx which could make the consumed gas exceed maxGas, each tx being bounded by its gas limit. The txs already
+// executed are kept, the not executed ones are removed from the scheduled txs and mini blocks and
+// process.ErrMaxGasLimitReached is returned. The time remains an independent stop condition. The consumed gas is
+// tracked with the gas handler, so it has to be set
+func (ste *scheduledTxsExecution) ExecuteAllWithGasBudget(haveTime func() time.Duration, maxGas uint64) error {
+	ste.mutScheduledTxs.Lock()
+	defer ste.mutScheduledTxs.Unlock()
+
+	if check.IfNil(ste.gasHandler) {
+		return fmt.Errorf("%w in scheduledTxsExecution.ExecuteAllWithGasBudget", process.ErrNilGasHandler)
+	}
+
+	ste.gasBudget = &scheduledGasBudget{
+		maxGas: maxGas,
+	}
+	defer func() {
+		ste.gasBudget = nil
+	}()
+
+	err := ste.executeAll(context.Background(), haveTime)
+	if err != nil {
+		return err
+	}
+	if ste.gasBudget.isExhausted {
+		return fmt.Errorf("%w, max gas %d, num of executed txs %d",
+			process.ErrMaxGasLimitReached, maxGas, len(ste.mapExecutionResults))
+	}
+
+	return nil
+}
+
+// fitsGasBudget returns true if the given scheduled tx can be executed without exceeding the gas budget, if any, when
+// consuming its whole gas limit. Otherwise, the gas budget is marked as exhausted
+func (ste *scheduledTxsExecution) fitsGasBudget(consumedGas uint64, txHandler data.TransactionHandler) bool {
+	if ste.gasBudget == nil {
+		return true
+	}
+
+	gasLimit := txHandler.GetGasLimit()
+	fits := consumedGas <= ste.gasBudget.maxGas && gasLimit <= ste.gasBudget.maxGas-consumedGas
+	if !fits {
+		ste.gasBudget.isExhausted = true
+	}
+
+	return fits
+}
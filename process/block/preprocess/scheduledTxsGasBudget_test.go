@@ -0,0 +1,105 @@
+package preprocess
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ElrondNetwork/elrond-go-core/data/transaction"
+	"github.com/ElrondNetwork/elrond-go-core/marshal"
+	"github.com/ElrondNetwork/elrond-go/process"
+	"github.com/ElrondNetwork/elrond-go/process/mock"
+	"github.com/ElrondNetwork/elrond-go/testscommon"
+	"github.com/ElrondNetwork/elrond-go/testscommon/genericMocks"
+	"github.com/ElrondNetwork/elrond-go/testscommon/hashingMocks"
+	vmcommon "github.com/ElrondNetwork/elrond-vm-common"
+	"github.com/stretchr/testify/assert"
+)
+
+func createScheduledTxsExecutionWithGasBudget(executedTxs *[]uint64, haveTime *time.Duration) *scheduledTxsExecution {
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(tx *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				*executedTxs = append(*executedTxs, tx.Nonce)
+				if tx.Nonce == 0 {
+					*haveTime -= time.Second
+				}
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+	scheduledTxsExec.SetGasHandler(&testscommon.GasHandlerStub{
+		GasProvidedAsScheduledCalled: func(hash []byte) uint64 {
+			return 50
+		},
+	})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0, GasLimit: 40})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1, GasLimit: 40})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash3"), &transaction.Transaction{Nonce: 2, GasLimit: 40})
+
+	return scheduledTxsExec
+}
+
+func TestScheduledTxsExecution_ExecuteAllWithGasBudgetShouldErr(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil gas handler", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+			TxProcessor:      &testscommon.TxProcessorMock{},
+			TxCoordinator:    &mock.TransactionCoordinatorMock{},
+			Storer:           genericMocks.NewStorerMock(),
+			Marshaller:       &marshal.GogoProtoMarshalizer{},
+			Hasher:           &hashingMocks.HasherMock{},
+			ShardCoordinator: &mock.ShardCoordinatorStub{},
+		})
+
+		err := scheduledTxsExec.ExecuteAllWithGasBudget(func() time.Duration { return time.Second }, 100)
+		assert.True(t, errors.Is(err, process.ErrNilGasHandler))
+	})
+}
+
+func TestScheduledTxsExecution_ExecuteAllWithGasBudget(t *testing.T) {
+	t.Parallel()
+
+	t.Run("gas budget exhausted should keep the txs which fit", func(t *testing.T) {
+		t.Parallel()
+
+		executedTxs := make([]uint64, 0)
+		haveTime := time.Hour
+		scheduledTxsExec := createScheduledTxsExecutionWithGasBudget(&executedTxs, &haveTime)
+
+		err := scheduledTxsExec.ExecuteAllWithGasBudget(func() time.Duration { return haveTime }, 100)
+		assert.True(t, errors.Is(err, process.ErrMaxGasLimitReached))
+		assert.Equal(t, []uint64{0, 1}, executedTxs)
+		assert.Nil(t, scheduledTxsExec.gasBudget)
+	})
+	t.Run("time out should stop before the gas budget", func(t *testing.T) {
+		t.Parallel()
+
+		executedTxs := make([]uint64, 0)
+		haveTime := time.Second
+		scheduledTxsExec := createScheduledTxsExecutionWithGasBudget(&executedTxs, &haveTime)
+
+		err := scheduledTxsExec.ExecuteAllWithGasBudget(func() time.Duration { return haveTime }, 100)
+		assert.Equal(t, process.ErrTimeIsOut, err)
+		assert.Equal(t, []uint64{0}, executedTxs)
+	})
+	t.Run("enough gas should execute all the txs", func(t *testing.T) {
+		t.Parallel()
+
+		executedTxs := make([]uint64, 0)
+		haveTime := time.Hour
+		scheduledTxsExec := createScheduledTxsExecutionWithGasBudget(&executedTxs, &haveTime)
+
+		err := scheduledTxsExec.ExecuteAllWithGasBudget(func() time.Duration { return haveTime }, 150)
+		assert.Nil(t, err)
+		assert.Equal(t, []uint64{0, 1, 2}, executedTxs)
+	})
+}
@@ -0,0 +1,95 @@
+package preprocess
+
+import (
+	"time"
+
+	"github.com/ElrondNetwork/elrond-go-core/core"
+	"github.com/ElrondNetwork/elrond-go/common"
+)
+
+type scheduledTxsProgress struct {
+	numExecutedTxs int
+	numTotalTxs    int
+	remainingTime  time.Duration
+}
+
+// scheduledTxsProgressReporter publishes the progress of the scheduled txs execution on the status handler. The status
+// handler is called from a separate go routine, so that the execution does not wait for it while holding its lock.
+// Only the latest progress is kept when the status handler falls behind
+type scheduledTxsProgressReporter struct {
+	statusHandler  core.AppStatusHandler
+	updateInterval int
+	chanProgress   chan scheduledTxsProgress
+}
+
+func newScheduledTxsProgressReporter(statusHandler core.AppStatusHandler, updateInterval int) *scheduledTxsProgressReporter {
+	reporter := &scheduledTxsProgressReporter{
+		statusHandler:  statusHandler,
+		updateInterval: updateInterval,
+		chanProgress:   make(chan scheduledTxsProgress, 1),
+	}
+	go reporter.publishLoop()
+
+	return reporter
+}
+
+// report sends the progress every update interval executed txs and when all the txs were executed
+func (reporter *scheduledTxsProgressReporter) report(numExecutedTxs int, numTotalTxs int, remainingTime time.Duration) {
+	if reporter == nil {
+		return
+	}
+	if numExecutedTxs%reporter.updateInterval != 0 && numExecutedTxs != numTotalTxs {
+		return
+	}
+
+	progress := scheduledTxsProgress{
+		numExecutedTxs: numExecutedTxs,
+		numTotalTxs:    numTotalTxs,
+		remainingTime:  remainingTime,
+	}
+
+	select {
+	case reporter.chanProgress <- progress:
+		return
+	default:
+	}
+
+	// replaces the progress not yet published
+	select {
+	case <-reporter.chanProgress:
+	default:
+	}
+	select {
+	case reporter.chanProgress <- progress:
+	default:
+	}
+}
+
+func (reporter *scheduledTxsProgressReporter) close() {
+	if reporter == nil {
+		return
+	}
+
+	close(reporter.chanProgress)
+}
+
+func (reporter *scheduledTxsProgressReporter) publishLoop() {
+	for progress := range reporter.chanProgress {
+		reporter.publish(progress)
+	}
+}
+
+func (reporter *scheduledTxsProgressReporter) publish(progress scheduledTxsProgress) {
+	executedPercent := uint64(100)
+	if progress.numTotalTxs > 0 {
+		executedPercent = uint64(progress.numExecutedTxs) * 100 / uint64(progress.numTotalTxs)
+	}
+
+	remainingTime := progress.remainingTime
+	if remainingTime < 0 {
+		remainingTime = 0
+	}
+
+	reporter.statusHandler.SetUInt64Value(common.MetricScheduledTxsExecutedPercent, executedPercent)
+	reporter.statusHandler.SetUInt64Value(common.MetricScheduledTxsRemainingTimeMs, uint64(remainingTime.Milliseconds()))
+}
@@ -0,0 +1,141 @@
+package preprocess
+
+import (
+	"container/heap"
+
+	"github.com/ElrondNetwork/elrond-go-core/data"
+)
+
+// scheduledTxsIterator gives the scheduled txs in their execution order
+type scheduledTxsIterator interface {
+	next() (*scheduledTxInfo, bool)
+	numRemaining() int
+}
+
+type sliceScheduledTxsIterator struct {
+	scheduledTxsInfo []*scheduledTxInfo
+	index            int
+}
+
+func newSliceScheduledTxsIterator(scheduledTxsInfo []*scheduledTxInfo) *sliceScheduledTxsIterator {
+	return &sliceScheduledTxsIterator{
+		scheduledTxsInfo: scheduledTxsInfo,
+	}
+}
+
+func (iterator *sliceScheduledTxsIterator) next() (*scheduledTxInfo, bool) {
+	if iterator.index >= len(iterator.scheduledTxsInfo) {
+		return nil, false
+	}
+
+	txInfo := iterator.scheduledTxsInfo[iterator.index]
+	iterator.index++
+
+	return txInfo, true
+}
+
+func (iterator *sliceScheduledTxsIterator) numRemaining() int {
+	return len(iterator.scheduledTxsInfo) - iterator.index
+}
+
+type prioritizedScheduledTx struct {
+	txInfo   *scheduledTxInfo
+	priority uint64
+	sequence uint64
+}
+
+// scheduledTxsPriorityQueue is a heap giving the highest priority tx first. The txs with the same priority are given in
+// the order they were pushed
+type scheduledTxsPriorityQueue []*prioritizedScheduledTx
+
+// Len returns the number of txs in the queue
+func (queue scheduledTxsPriorityQueue) Len() int {
+	return len(queue)
+}
+
+// Less returns true if the tx at index a should be executed before the tx at index b
+func (queue scheduledTxsPriorityQueue) Less(a, b int) bool {
+	if queue[a].priority != queue[b].priority {
+		return queue[a].priority > queue[b].priority
+	}
+
+	return queue[a].sequence < queue[b].sequence
+}
+
+// Swap swaps the txs at the given indexes
+func (queue scheduledTxsPriorityQueue) Swap(a, b int) {
+	queue[a], queue[b] = queue[b], queue[a]
+}
+
+// Push adds a tx to the queue
+func (queue *scheduledTxsPriorityQueue) Push(x interface{}) {
+	*queue = append(*queue, x.(*prioritizedScheduledTx))
+}
+
+// Pop removes the last tx from the queue
+func (queue *scheduledTxsPriorityQueue) Pop() interface{} {
+	old := *queue
+	lastIndex := len(old) - 1
+	item := old[lastIndex]
+	old[lastIndex] = nil
+	*queue = old[:lastIndex]
+
+	return item
+}
+
+// priorityScheduledTxsIterator gives the scheduled txs by priority. The txs added while the iteration is in progress
+// are pulled before giving each tx, so they can jump ahead of the lower priority txs not started yet
+type priorityScheduledTxsIterator struct {
+	queue           scheduledTxsPriorityQueue
+	nextSequence    uint64
+	priorityHandler func(tx data.TransactionHandler) uint64
+	pullPendingTxs  func() []*scheduledTxInfo
+}
+
+func newPriorityScheduledTxsIterator(
+	scheduledTxsInfo []*scheduledTxInfo,
+	priorityHandler func(tx data.TransactionHandler) uint64,
+	pullPendingTxs func() []*scheduledTxInfo,
+) *priorityScheduledTxsIterator {
+	iterator := &priorityScheduledTxsIterator{
+		queue:           make(scheduledTxsPriorityQueue, 0, len(scheduledTxsInfo)),
+		priorityHandler: priorityHandler,
+		pullPendingTxs:  pullPendingTxs,
+	}
+	for _, txInfo := range scheduledTxsInfo {
+		iterator.push(txInfo)
+	}
+
+	return iterator
+}
+
+func (iterator *priorityScheduledTxsIterator) push(txInfo *scheduledTxInfo) {
+	priority := uint64(0)
+	if iterator.priorityHandler != nil {
+		priority = iterator.priorityHandler(txInfo.txHandler)
+	}
+
+	heap.Push(&iterator.queue, &prioritizedScheduledTx{
+		txInfo:   txInfo,
+		priority: priority,
+		sequence: iterator.nextSequence,
+	})
+	iterator.nextSequence++
+}
+
+func (iterator *priorityScheduledTxsIterator) next() (*scheduledTxInfo, bool) {
+	for _, txInfo := range iterator.pullPendingTxs() {
+		iterator.push(txInfo)
+	}
+	if iterator.queue.Len() == 0 {
+		return nil, false
+	}
+
+	item := heap.Pop(&iterator.queue).(*prioritizedScheduledTx)
+
+	return item.txInfo, true
+}
+
+func (iterator *priorityScheduledTxsIterator) numRemaining() int {
+	return iterator.queue.Len()
+}
@@ -0,0 +1,114 @@
+package preprocess
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ElrondNetwork/elrond-go-core/data"
+	"github.com/ElrondNetwork/elrond-go-core/data/block"
+	"github.com/ElrondNetwork/elrond-go/process"
+)
+
+// resumableScheduledExecution holds the state of a scheduled txs execution spread over several calls
+type resumableScheduledExecution struct {
+	mapIntermediateTxsBefore map[block.Type]map[string]data.TransactionHandler
+}
+
+// ExecuteAllResumable executes the scheduled transactions, as ExecuteAll does, but pauses instead of aborting when the
+// time is out, so that a later call resumes from the first not executed tx. It returns the number of scheduled txs
+// executed so far, which has to be provided as resumeIndex on the next call, together with process.ErrTimeIsOut while
+// the execution is paused. A zero resumeIndex starts a new execution. Once all the txs are executed, the scheduled
+// intermediate txs, mini blocks and root hash are computed and a nil error is returned. A paused execution is dropped
+// by any other execution and by any change of the scheduled txs or of the scheduled info, in which case the next call
+// has to start a new execution. The intermediate txs streaming and the retry on root hash mismatch are not supported
+func (ste *scheduledTxsExecution) ExecuteAllResumable(haveTime func() time.Duration, resumeIndex int) (int, error) {
+	ste.mutScheduledTxs.Lock()
+	defer ste.mutScheduledTxs.Unlock()
+
+	if haveTime == nil {
+		return 0, process.ErrNilHaveTimeHandler
+	}
+	if ste.streamIntermediateTxs {
+		return 0, fmt.Errorf("%w in scheduledTxsExecution.ExecuteAllResumable, the intermediate txs streaming is not supported",
+			process.ErrInvalidValue)
+	}
+
+	numExecutedTxs := 0
+	if ste.resumableExecution != nil {
+		numExecutedTxs = len(ste.mapExecutionResults)
+	}
+	if resumeIndex != numExecutedTxs {
+		return numExecutedTxs, fmt.Errorf("%w in scheduledTxsExecution.ExecuteAllResumable for resume index %d, num of executed txs %d",
+			process.ErrInvalidValue, resumeIndex, numExecutedTxs)
+	}
+
+	if resumeIndex == 0 {
+		ste.resumableExecution = nil
+		if len(ste.scheduledTxs) == 0 {
+			return 0, ste.executeAll(context.Background(), haveTime)
+		}
+
+		ste.lastRolledBackHeaderHash = nil
+		ste.resetExecutionState()
+		ste.resumableExecution = &resumableScheduledExecution{
+			mapIntermediateTxsBefore: ste.txCoordinator.GetAllIntermediateTxs(),
+		}
+	}
+
+	return ste.executeResumable(haveTime)
+}
+
+func (ste *scheduledTxsExecution) executeResumable(haveTime func() time.Duration) (int, error) {
+	defer ste.publishExecutionMetrics(time.Now())
+
+	stopAccountsWarming := ste.startAccountsWarming(haveTime)
+	defer stopAccountsWarming()
+
+	isPaused, err := ste.executeScheduledTxs(context.Background(), haveTime, ste.resumableExecution.mapIntermediateTxsBefore, true)
+	numExecutedTxs := len(ste.mapExecutionResults)
+	if err != nil {
+		ste.resumableExecution = nil
+		return numExecutedTxs, err
+	}
+	if isPaused {
+		log.Debug("scheduledTxsExecution.ExecuteAllResumable: paused",
+			"num of executed txs", numExecutedTxs,
+			"num of scheduled txs", len(ste.scheduledTxs))
+		return numExecutedTxs, process.ErrTimeIsOut
+	}
+
+	mapIntermediateTxsBefore := ste.resumableExecution.mapIntermediateTxsBefore
+	ste.resumableExecution = nil
+	err = ste.completeExecution(mapIntermediateTxsBefore)
+	if err != nil {
+		return numExecutedTxs, err
+	}
+	if ste.rootHashVerifier != nil {
+		err = ste.rootHashVerifier(ste.computedScheduledRootHash)
+	}
+
+	return numExecutedTxs, err
+}
+
+// isExecutedBeforeResume returns true if the scheduled tx was executed by a previous call of a resumed execution
+func (ste *scheduledTxsExecution) isExecutedBeforeResume(txHash []byte) bool {
+	if ste.resumableExecution == nil {
+		return false
+	}
+
+	_, isExecuted := ste.mapExecutionResults[string(txHash)]
+	return isExecuted
+}
+
+// dropResumableExecution drops the paused resumable execution, if any, as the state it was started from changed
+func (ste *scheduledTxsExecution) dropResumableExecution(reason string) {
+	if ste.resumableExecution == nil {
+		return
+	}
+
+	log.Debug("scheduledTxsExecution: paused resumable execution dropped",
+		"reason", reason,
+		"num of executed txs", len(ste.mapExecutionResults))
+	ste.resumableExecution = nil
+}
@@ -0,0 +1,224 @@
+package preprocess
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ElrondNetwork/elrond-go-core/data"
+	"github.com/ElrondNetwork/elrond-go-core/data/block"
+	"github.com/ElrondNetwork/elrond-go-core/data/transaction"
+	"github.com/ElrondNetwork/elrond-go-core/marshal"
+	"github.com/ElrondNetwork/elrond-go/process"
+	"github.com/ElrondNetwork/elrond-go/process/mock"
+	"github.com/ElrondNetwork/elrond-go/testscommon"
+	"github.com/ElrondNetwork/elrond-go/testscommon/genericMocks"
+	"github.com/ElrondNetwork/elrond-go/testscommon/hashingMocks"
+	vmcommon "github.com/ElrondNetwork/elrond-vm-common"
+	"github.com/stretchr/testify/assert"
+)
+
+func createResumableScheduledTxsExecution(executedTxs *[]uint64, haveTime *time.Duration) *scheduledTxsExecution {
+	scheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(tx *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				*executedTxs = append(*executedTxs, tx.Nonce)
+				if tx.Nonce == 0 {
+					*haveTime -= time.Second
+				}
+				return vmcommon.Ok, nil
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+	})
+	scheduledTxsExec.SetGasHandler(&testscommon.GasHandlerStub{})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1})
+	scheduledTxsExec.AddScheduledTx([]byte("txHash3"), &transaction.Transaction{Nonce: 2})
+
+	return scheduledTxsExec
+}
+
+func TestScheduledTxsExecution_ExecuteAllResumableShouldErr(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil have time handler", func(t *testing.T) {
+		t.Parallel()
+
+		executedTxs := make([]uint64, 0)
+		haveTime := time.Hour
+		scheduledTxsExec := createResumableScheduledTxsExecution(&executedTxs, &haveTime)
+
+		numExecutedTxs, err := scheduledTxsExec.ExecuteAllResumable(nil, 0)
+		assert.Equal(t, process.ErrNilHaveTimeHandler, err)
+		assert.Equal(t, 0, numExecutedTxs)
+	})
+	t.Run("resume index without paused execution", func(t *testing.T) {
+		t.Parallel()
+
+		executedTxs := make([]uint64, 0)
+		haveTime := time.Hour
+		scheduledTxsExec := createResumableScheduledTxsExecution(&executedTxs, &haveTime)
+
+		numExecutedTxs, err := scheduledTxsExec.ExecuteAllResumable(func() time.Duration { return haveTime }, 1)
+		assert.True(t, errors.Is(err, process.ErrInvalidValue))
+		assert.Equal(t, 0, numExecutedTxs)
+		assert.Equal(t, 0, len(executedTxs))
+	})
+}
+
+func TestScheduledTxsExecution_ExecuteAllResumable(t *testing.T) {
+	t.Parallel()
+
+	t.Run("enough time should execute all the txs", func(t *testing.T) {
+		t.Parallel()
+
+		executedTxs := make([]uint64, 0)
+		haveTime := time.Hour
+		scheduledTxsExec := createResumableScheduledTxsExecution(&executedTxs, &haveTime)
+
+		numExecutedTxs, err := scheduledTxsExec.ExecuteAllResumable(func() time.Duration { return haveTime }, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, numExecutedTxs)
+		assert.Equal(t, []uint64{0, 1, 2}, executedTxs)
+		assert.Nil(t, scheduledTxsExec.resumableExecution)
+	})
+	t.Run("time out should pause and resume from the first not executed tx", func(t *testing.T) {
+		t.Parallel()
+
+		executedTxs := make([]uint64, 0)
+		haveTime := time.Second
+		scheduledTxsExec := createResumableScheduledTxsExecution(&executedTxs, &haveTime)
+
+		numExecutedTxs, err := scheduledTxsExec.ExecuteAllResumable(func() time.Duration { return haveTime }, 0)
+		assert.Equal(t, process.ErrTimeIsOut, err)
+		assert.Equal(t, 1, numExecutedTxs)
+		assert.Equal(t, []uint64{0}, executedTxs)
+
+		numExecutedTxs, err = scheduledTxsExec.ExecuteAllResumable(func() time.Duration { return haveTime }, 2)
+		assert.True(t, errors.Is(err, process.ErrInvalidValue))
+		assert.Equal(t, 1, numExecutedTxs)
+
+		haveTime = time.Hour
+		numExecutedTxs, err = scheduledTxsExec.ExecuteAllResumable(func() time.Duration { return haveTime }, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, numExecutedTxs)
+		assert.Equal(t, []uint64{0, 1, 2}, executedTxs)
+		assert.Equal(t, 3, len(scheduledTxsExec.GetScheduledTxsByBlockType()[block.TxBlock]))
+		assert.Nil(t, scheduledTxsExec.resumableExecution)
+	})
+	t.Run("zero resume index should not restart the paused execution", func(t *testing.T) {
+		t.Parallel()
+
+		executedTxs := make([]uint64, 0)
+		haveTime := time.Second
+		scheduledTxsExec := createResumableScheduledTxsExecution(&executedTxs, &haveTime)
+
+		_, err := scheduledTxsExec.ExecuteAllResumable(func() time.Duration { return haveTime }, 0)
+		assert.Equal(t, process.ErrTimeIsOut, err)
+
+		numExecutedTxs, err := scheduledTxsExec.ExecuteAllResumable(func() time.Duration { return haveTime }, 0)
+		assert.True(t, errors.Is(err, process.ErrInvalidValue))
+		assert.Equal(t, 1, numExecutedTxs)
+	})
+}
+
+func TestScheduledTxsExecution_ExecuteAllAfterPausedExecutionShouldTimeOut(t *testing.T) {
+	t.Parallel()
+
+	executedTxs := make([]uint64, 0)
+	haveTime := time.Second
+	scheduledTxsExec := createResumableScheduledTxsExecution(&executedTxs, &haveTime)
+
+	_, err := scheduledTxsExec.ExecuteAllResumable(func() time.Duration { return haveTime }, 0)
+	assert.Equal(t, process.ErrTimeIsOut, err)
+
+	err = scheduledTxsExec.ExecuteAll(func() time.Duration { return haveTime })
+	assert.Equal(t, process.ErrTimeIsOut, err)
+	assert.Nil(t, scheduledTxsExec.resumableExecution)
+
+	numExecutedTxs, err := scheduledTxsExec.ExecuteAllResumable(func() time.Duration { return haveTime }, 1)
+	assert.True(t, errors.Is(err, process.ErrInvalidValue))
+	assert.Equal(t, 0, numExecutedTxs)
+}
+
+func TestScheduledTxsExecution_PausedExecutionShouldBeDroppedOnStateChange(t *testing.T) {
+	t.Parallel()
+
+	testStateChange := func(t *testing.T, changeState func(scheduledTxsExec *scheduledTxsExecution)) {
+		executedTxs := make([]uint64, 0)
+		haveTime := time.Second
+		scheduledTxsExec := createResumableScheduledTxsExecution(&executedTxs, &haveTime)
+		scheduledTxsExec.SaveState([]byte("header hash"), &process.ScheduledInfo{
+			RootHash:   []byte("root hash"),
+			GasAndFees: process.GetZeroGasAndFees(),
+		})
+
+		_, err := scheduledTxsExec.ExecuteAllResumable(func() time.Duration { return haveTime }, 0)
+		assert.Equal(t, process.ErrTimeIsOut, err)
+		assert.NotNil(t, scheduledTxsExec.resumableExecution)
+
+		changeState(scheduledTxsExec)
+		assert.Nil(t, scheduledTxsExec.resumableExecution)
+
+		haveTime = time.Hour
+		numExecutedTxs, err := scheduledTxsExec.ExecuteAllResumable(func() time.Duration { return haveTime }, 1)
+		assert.True(t, errors.Is(err, process.ErrInvalidValue))
+		assert.Equal(t, 0, numExecutedTxs)
+	}
+
+	t.Run("add scheduled tx", func(t *testing.T) {
+		t.Parallel()
+
+		testStateChange(t, func(scheduledTxsExec *scheduledTxsExecution) {
+			scheduledTxsExec.AddScheduledTx([]byte("txHash4"), &transaction.Transaction{Nonce: 3})
+		})
+	})
+	t.Run("add scheduled txs", func(t *testing.T) {
+		t.Parallel()
+
+		testStateChange(t, func(scheduledTxsExec *scheduledTxsExecution) {
+			scheduledTxsExec.AddScheduledTxs([][]byte{[]byte("txHash4")}, []data.TransactionHandler{&transaction.Transaction{Nonce: 3}})
+		})
+	})
+	t.Run("add scheduled mini blocks", func(t *testing.T) {
+		t.Parallel()
+
+		testStateChange(t, func(scheduledTxsExec *scheduledTxsExecution) {
+			scheduledTxsExec.AddScheduledMiniBlocks(block.MiniBlockSlice{&block.MiniBlock{}})
+		})
+	})
+	t.Run("roll back to block", func(t *testing.T) {
+		t.Parallel()
+
+		testStateChange(t, func(scheduledTxsExec *scheduledTxsExecution) {
+			err := scheduledTxsExec.RollBackToBlock([]byte("header hash"))
+			assert.Nil(t, err)
+		})
+	})
+	t.Run("set scheduled info", func(t *testing.T) {
+		t.Parallel()
+
+		testStateChange(t, func(scheduledTxsExec *scheduledTxsExecution) {
+			scheduledTxsExec.SetScheduledInfo(&process.ScheduledInfo{GasAndFees: process.GetZeroGasAndFees()})
+		})
+	})
+	t.Run("set scheduled root hash", func(t *testing.T) {
+		t.Parallel()
+
+		testStateChange(t, func(scheduledTxsExec *scheduledTxsExecution) {
+			scheduledTxsExec.SetScheduledRootHash([]byte("root hash"))
+		})
+	})
+	t.Run("set scheduled gas and fees", func(t *testing.T) {
+		t.Parallel()
+
+		testStateChange(t, func(scheduledTxsExec *scheduledTxsExecution) {
+			scheduledTxsExec.SetScheduledGasAndFees(process.GetZeroGasAndFees())
+		})
+	})
+}
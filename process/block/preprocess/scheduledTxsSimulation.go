@@ -0,0 +1,140 @@
+package preprocess
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ElrondNetwork/elrond-go-core/core/check"
+	"github.com/ElrondNetwork/elrond-go/process"
+	"github.com/ElrondNetwork/elrond-go/state"
+)
+
+// ScheduledTxsSimulationComponents holds the components used to simulate the execution of the scheduled txs. The tx
+// processor and the tx coordinator should be wired on the given accounts, which should be a read-only or a
+// copy-on-write view of the live accounts
+type ScheduledTxsSimulationComponents struct {
+	TxProcessor   process.TransactionProcessor
+	TxCoordinator process.TransactionCoordinator
+	Accounts      state.AccountsAdapter
+	// GasHandler is used to get the gas of each simulated tx. Nil means the gas is not reported
+	GasHandler process.GasHandler
+	// FeeHandler is used to get the fees of each simulated tx. Nil means the fees are not reported
+	FeeHandler process.TransactionFeeHandler
+}
+
+func checkScheduledTxsSimulationComponents(components *ScheduledTxsSimulationComponents) error {
+	if components == nil {
+		return nil
+	}
+	if check.IfNil(components.TxProcessor) {
+		return fmt.Errorf("%w in NewScheduledTxsExecution for simulation", process.ErrNilTxProcessor)
+	}
+	if check.IfNil(components.TxCoordinator) {
+		return fmt.Errorf("%w in NewScheduledTxsExecution for simulation", process.ErrNilTransactionCoordinator)
+	}
+	if check.IfNil(components.Accounts) {
+		return fmt.Errorf("%w in NewScheduledTxsExecution for simulation", process.ErrNilAccountsAdapter)
+	}
+
+	return nil
+}
+
+// SimulateAll executes the scheduled txs with the simulation components, through the same pipeline as ExecuteAll, and
+// returns the scheduled info the execution would have produced: the root hash, the intermediate txs, the gas and fees
+// and the mini blocks. The simulation accounts are reverted at the end, so no state is persisted, and the state of the
+// live execution is not changed. The simulations are executed one at a time, as they share the simulation accounts
+func (ste *scheduledTxsExecution) SimulateAll(haveTime func() time.Duration) (*process.ScheduledInfo, error) {
+	if haveTime == nil {
+		return nil, process.ErrNilHaveTimeHandler
+	}
+
+	ste.mutSimulation.Lock()
+	defer ste.mutSimulation.Unlock()
+
+	simulationExec, err := ste.createSimulationExecution()
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := ste.simulation.Accounts
+	snapshot := accounts.JournalLen()
+	defer func() {
+		errRevert := accounts.RevertToSnapshot(snapshot)
+		if errRevert != nil {
+			log.Warn("scheduledTxsExecution.SimulateAll: RevertToSnapshot", "snapshot", snapshot, "error", errRevert)
+		}
+	}()
+
+	err = simulationExec.ExecuteAll(haveTime)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduledInfo := &process.ScheduledInfo{
+		RootHash:        simulationExec.computedScheduledRootHash,
+		IntermediateTxs: simulationExec.GetScheduledIntermediateTxs(),
+		GasAndFees:      simulationExec.sumPerTxGasAndFees(),
+		MiniBlocks:      simulationExec.GetScheduledMiniBlocks(),
+	}
+
+	log.Debug("scheduledTxsExecution.SimulateAll",
+		"num of simulated txs", len(simulationExec.mapExecutionResults),
+		"num of failed txs", len(simulationExec.failedScheduledTxHashes),
+		"scheduled root hash", scheduledInfo.RootHash,
+		"num of scheduled intermediate txs", getNumScheduledIntermediateTxs(scheduledInfo.IntermediateTxs))
+
+	return scheduledInfo, nil
+}
+
+// createSimulationExecution creates a scheduled txs execution wired on the simulation components, with the execution
+// options and the scheduled txs and mini blocks of this one. The created execution does not stream the intermediate
+// txs and does not publish anything
+func (ste *scheduledTxsExecution) createSimulationExecution() (*scheduledTxsExecution, error) {
+	ste.mutScheduledTxs.RLock()
+	defer ste.mutScheduledTxs.RUnlock()
+
+	if ste.simulation == nil {
+		return nil, process.ErrScheduledTxsSimulationNotEnabled
+	}
+
+	simulationExec, err := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:                      ste.simulation.TxProcessor,
+		TxCoordinator:                    ste.simulation.TxCoordinator,
+		Storer:                           ste.storer,
+		Marshaller:                       ste.marshaller,
+		Hasher:                           ste.hasher,
+		ShardCoordinator:                 ste.shardCoordinator,
+		MaxIntermediateTxs:               ste.maxIntermediateTxs,
+		ExecutionGracePeriod:             ste.executionGracePeriod,
+		FailedScheduledTxsMode:           ste.failedScheduledTxsMode,
+		Accounts:                         ste.simulation.Accounts,
+		ParallelMiniBlocksHashing:        ste.parallelMiniBlocksHashing,
+		MaxProjectedMiniBlockSize:        ste.maxProjectedMiniBlockSize,
+		GenerateReceipts:                 ste.generateReceipts,
+		OrderBySenderNonceHash:           ste.orderBySenderNonceHash,
+		RejectDuplicateIntermediateTxs:   ste.rejectDuplicateInterTxs,
+		MaxIntermediateTxsMarshalledSize: ste.maxInterTxsMarshalledSize,
+		UsePriorityQueue:                 ste.usePriorityQueue,
+		MaxFailureRatio:                  ste.maxFailureRatio,
+		MinFailureSample:                 ste.minFailureSample,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	simulationExec.gasHandler = ste.simulation.GasHandler
+	simulationExec.feeHandler = ste.simulation.FeeHandler
+	simulationExec.interTxsSizeEstimator = ste.interTxsSizeEstimator
+	simulationExec.executionBudget = ste.executionBudget
+	simulationExec.executionPriorityHandler = ste.executionPriorityHandler
+	simulationExec.executionOrderComparator = ste.executionOrderComparator
+	simulationExec.canonicalExecutionOrder = ste.canonicalExecutionOrder
+	for index, txHandler := range ste.scheduledTxs {
+		simulationExec.addScheduledTx(ste.scheduledTxHashes[index], txHandler)
+	}
+	for _, miniBlock := range ste.scheduledMbs {
+		simulationExec.scheduledMbs = append(simulationExec.scheduledMbs, miniBlock.Clone())
+	}
+
+	return simulationExec, nil
+}
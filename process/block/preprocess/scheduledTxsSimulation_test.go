@@ -0,0 +1,245 @@
+package preprocess
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ElrondNetwork/elrond-go-core/data"
+	"github.com/ElrondNetwork/elrond-go-core/data/block"
+	"github.com/ElrondNetwork/elrond-go-core/data/scheduled"
+	"github.com/ElrondNetwork/elrond-go-core/data/smartContractResult"
+	"github.com/ElrondNetwork/elrond-go-core/data/transaction"
+	"github.com/ElrondNetwork/elrond-go-core/marshal"
+	"github.com/ElrondNetwork/elrond-go/process"
+	"github.com/ElrondNetwork/elrond-go/process/mock"
+	"github.com/ElrondNetwork/elrond-go/testscommon"
+	"github.com/ElrondNetwork/elrond-go/testscommon/genericMocks"
+	"github.com/ElrondNetwork/elrond-go/testscommon/hashingMocks"
+	stateMock "github.com/ElrondNetwork/elrond-go/testscommon/state"
+	vmcommon "github.com/ElrondNetwork/elrond-vm-common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createMockArgsScheduledTxsExecutionForSimulation(simulation *ScheduledTxsSimulationComponents) ArgsScheduledTxsExecution {
+	return ArgsScheduledTxsExecution{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(_ *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				return vmcommon.ExecutionFailed, errors.New("live tx processor should not have been called")
+			},
+		},
+		TxCoordinator:    &mock.TransactionCoordinatorMock{},
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: &mock.ShardCoordinatorStub{},
+		Simulation:       simulation,
+	}
+}
+
+func TestNewScheduledTxsExecution_InvalidSimulationComponentsShouldErr(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil tx processor", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec, err := NewScheduledTxsExecution(createMockArgsScheduledTxsExecutionForSimulation(&ScheduledTxsSimulationComponents{
+			TxCoordinator: &mock.TransactionCoordinatorMock{},
+			Accounts:      &stateMock.AccountsStub{},
+		}))
+		assert.True(t, errors.Is(err, process.ErrNilTxProcessor))
+		assert.Nil(t, scheduledTxsExec)
+	})
+	t.Run("nil tx coordinator", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec, err := NewScheduledTxsExecution(createMockArgsScheduledTxsExecutionForSimulation(&ScheduledTxsSimulationComponents{
+			TxProcessor: &testscommon.TxProcessorMock{},
+			Accounts:    &stateMock.AccountsStub{},
+		}))
+		assert.True(t, errors.Is(err, process.ErrNilTransactionCoordinator))
+		assert.Nil(t, scheduledTxsExec)
+	})
+	t.Run("nil accounts", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledTxsExec, err := NewScheduledTxsExecution(createMockArgsScheduledTxsExecutionForSimulation(&ScheduledTxsSimulationComponents{
+			TxProcessor:   &testscommon.TxProcessorMock{},
+			TxCoordinator: &mock.TransactionCoordinatorMock{},
+		}))
+		assert.True(t, errors.Is(err, process.ErrNilAccountsAdapter))
+		assert.Nil(t, scheduledTxsExec)
+	})
+}
+
+func TestScheduledTxsExecution_SimulateAllNotEnabledShouldErr(t *testing.T) {
+	t.Parallel()
+
+	scheduledTxsExec, _ := NewScheduledTxsExecution(createMockArgsScheduledTxsExecutionForSimulation(nil))
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+
+	scheduledInfo, err := scheduledTxsExec.SimulateAll(func() time.Duration { return time.Second })
+	assert.Equal(t, process.ErrScheduledTxsSimulationNotEnabled, err)
+	assert.Nil(t, scheduledInfo)
+}
+
+func TestScheduledTxsExecution_SimulateAllShouldRevertTheAccounts(t *testing.T) {
+	t.Parallel()
+
+	expectedErr := errors.New("expected error")
+	journalLen := 3
+	revertedSnapshots := make([]int, 0)
+	scheduledTxsExec, _ := NewScheduledTxsExecution(createMockArgsScheduledTxsExecutionForSimulation(&ScheduledTxsSimulationComponents{
+		TxProcessor: &testscommon.TxProcessorMock{
+			ProcessTransactionCalled: func(_ *transaction.Transaction) (vmcommon.ReturnCode, error) {
+				journalLen++
+				return vmcommon.ExecutionFailed, expectedErr
+			},
+		},
+		TxCoordinator: &mock.TransactionCoordinatorMock{},
+		Accounts: &stateMock.AccountsStub{
+			JournalLenCalled: func() int {
+				return journalLen
+			},
+			RevertToSnapshotCalled: func(snapshot int) error {
+				revertedSnapshots = append(revertedSnapshots, snapshot)
+				return nil
+			},
+		},
+	}))
+	scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+
+	scheduledInfo, err := scheduledTxsExec.SimulateAll(func() time.Duration { return time.Second })
+	assert.Equal(t, expectedErr, err)
+	assert.Nil(t, scheduledInfo)
+	assert.Equal(t, []int{3}, revertedSnapshots)
+}
+
+func TestScheduledTxsExecution_SimulateAllShouldMatchExecuteAll(t *testing.T) {
+	t.Parallel()
+
+	scr := &smartContractResult.SmartContractResult{Nonce: 2, SndAddr: []byte("sender"), RcvAddr: []byte("receiver")}
+	createComponents := func() (*ScheduledTxsSimulationComponents, *int) {
+		mapIntermediateTxs := map[block.Type]map[string]data.TransactionHandler{
+			block.SmartContractResultBlock: {
+				"previousScrHash": &smartContractResult.SmartContractResult{Nonce: 1},
+			},
+		}
+		accumulatedFees := big.NewInt(0)
+		developerFees := big.NewInt(0)
+		numReverts := 0
+		return &ScheduledTxsSimulationComponents{
+			TxProcessor: &testscommon.TxProcessorMock{
+				ProcessTransactionCalled: func(tx *transaction.Transaction) (vmcommon.ReturnCode, error) {
+					accumulatedFees.Add(accumulatedFees, big.NewInt(10))
+					if tx.Nonce == 1 {
+						return vmcommon.UserError, process.ErrFailedTransaction
+					}
+
+					developerFees.Add(developerFees, big.NewInt(1))
+					mapIntermediateTxs[block.SmartContractResultBlock] = map[string]data.TransactionHandler{
+						"previousScrHash": &smartContractResult.SmartContractResult{Nonce: 1},
+						"scrHash":         scr,
+					}
+					return vmcommon.Ok, nil
+				},
+			},
+			TxCoordinator: &mock.TransactionCoordinatorMock{
+				GetAllIntermediateTxsCalled: func() map[block.Type]map[string]data.TransactionHandler {
+					mapCopy := make(map[block.Type]map[string]data.TransactionHandler)
+					for blockType, txs := range mapIntermediateTxs {
+						mapCopy[blockType] = txs
+					}
+					return mapCopy
+				},
+			},
+			Accounts: &stateMock.AccountsStub{
+				RootHashCalled: func() ([]byte, error) {
+					return []byte("scheduled root hash"), nil
+				},
+				RevertToSnapshotCalled: func(_ int) error {
+					numReverts++
+					return nil
+				},
+			},
+			GasHandler: &testscommon.GasHandlerStub{
+				GasProvidedAsScheduledCalled: func(hash []byte) uint64 {
+					return 100
+				},
+				GasRefundedCalled: func(hash []byte) uint64 {
+					return 40
+				},
+			},
+			FeeHandler: &mock.FeeAccumulatorStub{
+				GetAccumulatedFeesCalled: func() *big.Int {
+					return big.NewInt(0).Set(accumulatedFees)
+				},
+				GetDeveloperFeesCalled: func() *big.Int {
+					return big.NewInt(0).Set(developerFees)
+				},
+			},
+		}, &numReverts
+	}
+	shardCoordinator := &mock.ShardCoordinatorStub{
+		SameShardCalled: func(_, _ []byte) bool {
+			return false
+		},
+	}
+	miniBlocks := block.MiniBlockSlice{
+		{TxHashes: [][]byte{[]byte("txHash1"), []byte("txHash2")}, Type: block.TxBlock},
+	}
+	addScheduledTxs := func(scheduledTxsExec *scheduledTxsExecution) {
+		scheduledTxsExec.AddScheduledTx([]byte("txHash1"), &transaction.Transaction{Nonce: 0})
+		scheduledTxsExec.AddScheduledTx([]byte("txHash2"), &transaction.Transaction{Nonce: 1})
+		scheduledTxsExec.AddScheduledMiniBlocks(miniBlocks)
+	}
+
+	simulationComponents, numReverts := createComponents()
+	args := createMockArgsScheduledTxsExecutionForSimulation(simulationComponents)
+	args.ShardCoordinator = shardCoordinator
+	scheduledTxsExec, _ := NewScheduledTxsExecution(args)
+	addScheduledTxs(scheduledTxsExec)
+
+	scheduledInfo, err := scheduledTxsExec.SimulateAll(func() time.Duration { return time.Second })
+	require.Nil(t, err)
+	assert.Equal(t, 1, *numReverts)
+	assert.Equal(t, []byte("scheduled root hash"), scheduledInfo.RootHash)
+	assert.Equal(t, map[block.Type][]data.TransactionHandler{block.SmartContractResultBlock: {scr}}, scheduledInfo.IntermediateTxs)
+	require.Equal(t, 1, len(scheduledInfo.MiniBlocks))
+	assert.Equal(t, miniBlocks[0].TxHashes, scheduledInfo.MiniBlocks[0].TxHashes)
+	expectedGasAndFees := scheduled.GasAndFees{
+		AccumulatedFees: big.NewInt(20),
+		DeveloperFees:   big.NewInt(1),
+		GasProvided:     200,
+		GasRefunded:     80,
+	}
+	assert.Equal(t, expectedGasAndFees, scheduledInfo.GasAndFees)
+
+	assert.Equal(t, 0, len(scheduledTxsExec.GetScheduledIntermediateTxs()))
+	assert.Equal(t, 0, len(scheduledTxsExec.GetFailedScheduledTxHashes()))
+	assert.Nil(t, scheduledTxsExec.computedScheduledRootHash)
+	assert.Equal(t, 2, len(scheduledTxsExec.GetScheduledTxs()))
+
+	liveComponents, _ := createComponents()
+	liveScheduledTxsExec, _ := NewScheduledTxsExecution(ArgsScheduledTxsExecution{
+		TxProcessor:      liveComponents.TxProcessor,
+		TxCoordinator:    liveComponents.TxCoordinator,
+		Storer:           genericMocks.NewStorerMock(),
+		Marshaller:       &marshal.GogoProtoMarshalizer{},
+		Hasher:           &hashingMocks.HasherMock{},
+		ShardCoordinator: shardCoordinator,
+		Accounts:         liveComponents.Accounts,
+	})
+	liveScheduledTxsExec.SetGasHandler(liveComponents.GasHandler)
+	liveScheduledTxsExec.SetFeeHandler(liveComponents.FeeHandler)
+	addScheduledTxs(liveScheduledTxsExec)
+
+	err = liveScheduledTxsExec.ExecuteAll(func() time.Duration { return time.Second })
+	require.Nil(t, err)
+	assert.Equal(t, liveScheduledTxsExec.computedScheduledRootHash, scheduledInfo.RootHash)
+	assert.Equal(t, liveScheduledTxsExec.GetScheduledIntermediateTxs(), scheduledInfo.IntermediateTxs)
+	assert.Equal(t, liveScheduledTxsExec.GetScheduledMiniBlocks(), scheduledInfo.MiniBlocks)
+	assert.Equal(t, liveScheduledTxsExec.sumPerTxGasAndFees(), scheduledInfo.GasAndFees)
+}
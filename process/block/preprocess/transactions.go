@@ -1595,9 +1595,9 @@ func (txs *transactions) ProcessMiniBlock(
 	txs.blockSizeComputation.AddNumTxs(numTxs)
 
 	if scheduledMode {
-		for index := indexOfFirstTxToBeProcessed; index <= txIndex-1; index++ {
-			txs.scheduledTxsExecutionHandler.AddScheduledTx(miniBlockTxHashes[index], miniBlockTxs[index])
-		}
+		scheduledTxHashes := miniBlockTxHashes[indexOfFirstTxToBeProcessed:txIndex]
+		scheduledTxs := miniBlockTxs[indexOfFirstTxToBeProcessed:txIndex]
+		txs.scheduledTxsExecutionHandler.AddScheduledTxs(scheduledTxHashes, scheduledTxs)
 	}
 
 	return nil, txIndex - 1, false, err
@@ -822,6 +822,12 @@ type ScheduledInfo struct {
 	MiniBlocks      block.MiniBlockSlice
 }
 
+// StorageAccessStat holds the number of storage reads and writes
+type StorageAccessStat struct {
+	NumReads  uint64
+	NumWrites uint64
+}
+
 // GetFinalCrossMiniBlockHashes returns all the finalized miniblocks hashes, from the given header and with the given destination
 func GetFinalCrossMiniBlockHashes(header data.HeaderHandler, shardID uint32) map[string]uint32 {
 	crossMiniBlockHashes := header.GetMiniBlockHeadersWithDst(shardID)
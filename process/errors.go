@@ -1142,3 +1142,64 @@ var ErrNilCrawlerAllowedAddress = errors.New("nil crawler allowed address")
 
 // ErrNilPayloadValidator signals that a nil payload validator was provided
 var ErrNilPayloadValidator = errors.New("nil payload validator")
+
+// ErrTooManyScheduledIntermediateTxs signals that the execution of scheduled txs produced too many intermediate txs
+var ErrTooManyScheduledIntermediateTxs = errors.New("too many scheduled intermediate txs")
+
+// ErrNilPartialChunksHandler signals that a nil partial chunks handler has been provided
+var ErrNilPartialChunksHandler = errors.New("nil partial chunks handler")
+
+// ErrNilDataCompressor signals that a nil data compressor has been provided
+var ErrNilDataCompressor = errors.New("nil data compressor")
+
+// ErrUnknownScheduledInfoFormat signals that the stored scheduled info has an unknown format
+var ErrUnknownScheduledInfoFormat = errors.New("unknown scheduled info format")
+
+// ErrScheduledTxsSimulationNotEnabled signals that the scheduled txs simulation was requested without its components
+var ErrScheduledTxsSimulationNotEnabled = errors.New("scheduled txs simulation not enabled")
+
+// ErrScheduledStateAccess signals that the accounts could not be accessed during the execution of scheduled txs
+var ErrScheduledStateAccess = errors.New("scheduled state access error")
+
+// ErrScheduledGasAndFeesMismatch signals that the gas and fees recorded for each scheduled tx do not sum to the aggregate
+// gas and fees of the scheduled txs
+var ErrScheduledGasAndFeesMismatch = errors.New("scheduled gas and fees mismatch")
+
+// ErrStorerIterationNotSupported signals that the storer is not able to iterate over its keys
+var ErrStorerIterationNotSupported = errors.New("storer iteration not supported")
+
+// ErrChunkIndexOutOfWindow signals that a chunk index is outside the accepted window of chunk indexes
+var ErrChunkIndexOutOfWindow = errors.New("chunk index out of window")
+
+// ErrNilIntermediateTxsConsumer signals that the intermediate txs are streamed without an intermediate txs consumer
+var ErrNilIntermediateTxsConsumer = errors.New("nil intermediate txs consumer")
+
+// ErrInvalidGasRefund signals that the gas refunded for a transaction is larger than the gas provided for it
+var ErrInvalidGasRefund = errors.New("invalid gas refund")
+
+// ErrDuplicateIntermediateTx signals that an intermediate transaction hash was produced more than once
+var ErrDuplicateIntermediateTx = errors.New("duplicate intermediate transaction")
+
+// ErrChunkMemoryBudgetExceeded signals that a received chunk does not fit in the memory budget of the pending chunks
+var ErrChunkMemoryBudgetExceeded = errors.New("chunk memory budget exceeded")
+
+// ErrScheduledFailureThresholdExceeded signals that too many of the executed scheduled transactions have failed
+var ErrScheduledFailureThresholdExceeded = errors.New("scheduled failure threshold exceeded")
+
+// ErrUnexpectedScheduledTx signals that the execution order of the scheduled transactions references an unknown transaction
+var ErrUnexpectedScheduledTx = errors.New("unexpected scheduled transaction")
+
+// ErrUnmarshalWithWrongVersion signals that the stored data could not be unmarshalled with the configured codec
+var ErrUnmarshalWithWrongVersion = errors.New("unmarshal with wrong version")
+
+// ErrNilContext signals that a nil context was provided
+var ErrNilContext = errors.New("nil context")
+
+// ErrRequestTimeout signals that a request was not handled in the given time
+var ErrRequestTimeout = errors.New("request timeout")
+
+// ErrMaxGasLimitReached signals that the given gas budget was consumed
+var ErrMaxGasLimitReached = errors.New("max gas limit reached")
+
+// ErrMissingScheduledInfoDeltaBase signals that the base scheduled info of a stored scheduled info delta was not found
+var ErrMissingScheduledInfoDeltaBase = errors.New("missing scheduled info delta base")
@@ -556,6 +556,7 @@ func (bicf *baseInterceptorsContainerFactory) createOneTrieNodesInterceptor(topi
 		RequestInterval: chunksProcessorRequestInterval,
 		RequestHandler:  bicf.requestHandler,
 		Topic:           topic,
+		ShardID:         bicf.shardCoordinator.SelfId(),
 	}
 
 	chunkProcessor, err := processor.NewTrieNodeChunksProcessor(argChunkProcessor)
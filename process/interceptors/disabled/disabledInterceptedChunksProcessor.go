@@ -1,6 +1,7 @@
 package disabled
 
 import (
+	"github.com/ElrondNetwork/elrond-go-core/core"
 	"github.com/ElrondNetwork/elrond-go-core/data/batch"
 	"github.com/ElrondNetwork/elrond-go/process"
 )
@@ -14,7 +15,7 @@ func NewDisabledInterceptedChunksProcessor() *disabledInterceptedChunksProcessor
 }
 
 // CheckBatch returns a checked chunk result that signals that no chunk has been received
-func (d *disabledInterceptedChunksProcessor) CheckBatch(_ *batch.Batch, _ process.WhiteListHandler) (process.CheckedChunkResult, error) {
+func (d *disabledInterceptedChunksProcessor) CheckBatch(_ *batch.Batch, _ process.WhiteListHandler, _ core.PeerID) (process.CheckedChunkResult, error) {
 	return process.CheckedChunkResult{
 		IsChunk:        false,
 		HaveAllChunks:  false,
@@ -391,7 +391,7 @@ func TestMultiDataInterceptor_ProcessReceivedMessageCheckBatchErrors(t *testing.
 	expectedErr := errors.New("expected error")
 	_ = mdi.SetChunkProcessor(
 		&mock.ChunkProcessorStub{
-			CheckBatchCalled: func(b *batch.Batch, w process.WhiteListHandler) (process.CheckedChunkResult, error) {
+			CheckBatchCalled: func(b *batch.Batch, w process.WhiteListHandler, sender core.PeerID) (process.CheckedChunkResult, error) {
 				return process.CheckedChunkResult{}, expectedErr
 			},
 		},
@@ -429,7 +429,7 @@ func TestMultiDataInterceptor_ProcessReceivedMessageCheckBatchIsIncomplete(t *te
 	mdi, _ := interceptors.NewMultiDataInterceptor(arg)
 	_ = mdi.SetChunkProcessor(
 		&mock.ChunkProcessorStub{
-			CheckBatchCalled: func(b *batch.Batch, w process.WhiteListHandler) (process.CheckedChunkResult, error) {
+			CheckBatchCalled: func(b *batch.Batch, w process.WhiteListHandler, sender core.PeerID) (process.CheckedChunkResult, error) {
 				return process.CheckedChunkResult{
 					IsChunk:        true,
 					HaveAllChunks:  false,
@@ -482,7 +482,7 @@ func TestMultiDataInterceptor_ProcessReceivedMessageCheckBatchIsComplete(t *test
 	mdi, _ := interceptors.NewMultiDataInterceptor(arg)
 	_ = mdi.SetChunkProcessor(
 		&mock.ChunkProcessorStub{
-			CheckBatchCalled: func(b *batch.Batch, w process.WhiteListHandler) (process.CheckedChunkResult, error) {
+			CheckBatchCalled: func(b *batch.Batch, w process.WhiteListHandler, sender core.PeerID) (process.CheckedChunkResult, error) {
 				return process.CheckedChunkResult{
 					IsChunk:        true,
 					HaveAllChunks:  true,
@@ -7,13 +7,17 @@ import (
 var log = logger.GetOrCreate("process/interceptors/processor")
 
 type chunk struct {
-	reference []byte
-	maxChunks uint32
-	data      map[uint32][]byte
-	size      int
+	reference       []byte
+	maxChunks       uint32
+	data            map[uint32][]byte
+	size            int
+	numPrefixChunks uint32
+	assembled       []byte
 }
 
-// NewChunk creates a new chunk instance able to account for the existing and missing chunks of a larger buffer
+// NewChunk creates a new chunk instance able to account for the existing and missing chunks of a larger buffer.
+// The assembly buffer grows with the contiguous prefix of received chunks and is allocated with its final size only
+// once all the chunks were received, so that its size never depends on what other peers claim
 // Not a concurrent safe component
 func NewChunk(maxChunks uint32, reference []byte) *chunk {
 	return &chunk{
@@ -32,6 +36,12 @@ func (c *chunk) Put(chunkIndex uint32, buff []byte) {
 	existing := c.data[chunkIndex]
 	c.data[chunkIndex] = buff
 	c.size = c.size - len(existing) + len(buff)
+
+	isRewritingAssembledChunk := chunkIndex < c.numPrefixChunks
+	if isRewritingAssembledChunk {
+		c.numPrefixChunks = 0
+		c.assembled = c.assembled[:0]
+	}
 }
 
 // TryAssembleAllChunks will try to assemble the original payload by iterating all available chunks
@@ -43,13 +53,26 @@ func (c *chunk) TryAssembleAllChunks() []byte {
 		return nil
 	}
 
-	buff := make([]byte, 0, c.size)
-	for i := uint32(0); i < c.maxChunks; i++ {
-		part := c.data[i]
-		buff = append(buff, part...)
+	if cap(c.assembled) < c.size {
+		assembled := make([]byte, len(c.assembled), c.size)
+		copy(assembled, c.assembled)
+		c.assembled = assembled
 	}
+	c.appendContiguousChunks()
+
+	return c.assembled
+}
+
+// appendContiguousChunks appends to the assembly buffer the chunks which extend the contiguous prefix of chunks
+func (c *chunk) appendContiguousChunks() {
+	for ; c.numPrefixChunks < c.maxChunks; c.numPrefixChunks++ {
+		part, partFound := c.data[c.numPrefixChunks]
+		if !partFound {
+			break
+		}
 
-	return buff
+		c.assembled = append(c.assembled, part...)
+	}
 }
 
 // GetAllMissingChunkIndexes returns all missing chunk indexes
@@ -67,6 +90,58 @@ func (c *chunk) GetAllMissingChunkIndexes() []uint32 {
 	return missing
 }
 
+// GetAllPresentChunkIndexes returns all the received chunk indexes, in ascending order
+func (c *chunk) GetAllPresentChunkIndexes() []uint32 {
+	present := make([]uint32, 0, len(c.data))
+	for i := uint32(0); i < c.maxChunks; i++ {
+		_, partFound := c.data[i]
+		if partFound {
+			present = append(present, i)
+		}
+	}
+
+	return present
+}
+
+// GetChunk returns the chunk stored at the given index, or nil if the chunk is missing
+func (c *chunk) GetChunk(chunkIndex uint32) []byte {
+	return c.data[chunkIndex]
+}
+
+// GetMaxChunks returns the number of chunks of the larger buffer
+func (c *chunk) GetMaxChunks() uint32 {
+	return c.maxChunks
+}
+
+// GetContiguousFrontier returns the first missing chunk index, which is the number of chunks of the contiguous prefix
+// starting from index 0
+func (c *chunk) GetContiguousFrontier() uint32 {
+	frontier := c.numPrefixChunks
+	for ; frontier < c.maxChunks; frontier++ {
+		_, partFound := c.data[frontier]
+		if !partFound {
+			break
+		}
+	}
+
+	return frontier
+}
+
+// GetNewContiguousPrefix returns the bytes that extended the contiguous prefix of chunks (starting from index 0) since
+// the last call, along with their offset in the original payload. It returns a nil buffer if the prefix did not grow
+func (c *chunk) GetNewContiguousPrefix() (int, []byte) {
+	offset := len(c.assembled)
+	c.appendContiguousChunks()
+	if len(c.assembled) == offset {
+		return offset, nil
+	}
+
+	newPrefix := make([]byte, len(c.assembled)-offset)
+	copy(newPrefix, c.assembled[offset:])
+
+	return offset, newPrefix
+}
+
 // Size returns the size in bytes stored in the values of the inner map
 func (c *chunk) Size() int {
 	return c.size
@@ -100,3 +100,133 @@ func TestChunk_GetAllMissingChunkIndexes(t *testing.T) {
 	missing = c.GetAllMissingChunkIndexes()
 	assert.Equal(t, 0, len(missing))
 }
+
+func TestChunk_GetAllPresentChunkIndexes(t *testing.T) {
+	t.Parallel()
+
+	c := NewChunk(4, []byte("reference"))
+	present := c.GetAllPresentChunkIndexes()
+	assert.Equal(t, 0, len(present))
+
+	c.Put(5, []byte("buff5"))
+	present = c.GetAllPresentChunkIndexes()
+	assert.Equal(t, 0, len(present))
+
+	c.Put(2, []byte("buff2"))
+	c.Put(0, []byte("buff0"))
+	present = c.GetAllPresentChunkIndexes()
+	assert.Equal(t, []uint32{0, 2}, present)
+
+	c.Put(1, []byte("buff1"))
+	c.Put(3, []byte("buff3"))
+	present = c.GetAllPresentChunkIndexes()
+	assert.Equal(t, []uint32{0, 1, 2, 3}, present)
+}
+
+func TestChunk_GetChunk(t *testing.T) {
+	t.Parallel()
+
+	c := NewChunk(4, []byte("reference"))
+	assert.Equal(t, uint32(4), c.GetMaxChunks())
+	assert.Nil(t, c.GetChunk(0))
+
+	c.Put(2, []byte("buff2"))
+	c.Put(5, []byte("buff5"))
+	assert.Nil(t, c.GetChunk(0))
+	assert.Equal(t, []byte("buff2"), c.GetChunk(2))
+	assert.Nil(t, c.GetChunk(5))
+}
+
+func TestChunk_GetNewContiguousPrefix(t *testing.T) {
+	t.Parallel()
+
+	c := NewChunk(4, []byte("reference"))
+	offset, buff := c.GetNewContiguousPrefix()
+	assert.Equal(t, 0, offset)
+	assert.Nil(t, buff)
+
+	c.Put(1, []byte("buff1"))
+	offset, buff = c.GetNewContiguousPrefix()
+	assert.Equal(t, 0, offset)
+	assert.Nil(t, buff)
+
+	c.Put(0, []byte("buff0"))
+	offset, buff = c.GetNewContiguousPrefix()
+	assert.Equal(t, 0, offset)
+	assert.Equal(t, []byte("buff0buff1"), buff)
+
+	c.Put(3, []byte("buff3"))
+	offset, buff = c.GetNewContiguousPrefix()
+	assert.Equal(t, 10, offset)
+	assert.Nil(t, buff)
+
+	c.Put(2, []byte("buff2"))
+	offset, buff = c.GetNewContiguousPrefix()
+	assert.Equal(t, 10, offset)
+	assert.Equal(t, []byte("buff2buff3"), buff)
+}
+
+func TestChunk_GetContiguousFrontier(t *testing.T) {
+	t.Parallel()
+
+	c := NewChunk(4, []byte("reference"))
+	assert.Equal(t, uint32(0), c.GetContiguousFrontier())
+
+	c.Put(1, []byte("buff1"))
+	assert.Equal(t, uint32(0), c.GetContiguousFrontier())
+
+	c.Put(0, []byte("buff0"))
+	assert.Equal(t, uint32(2), c.GetContiguousFrontier())
+
+	_, _ = c.GetNewContiguousPrefix()
+	c.Put(3, []byte("buff3"))
+	assert.Equal(t, uint32(2), c.GetContiguousFrontier())
+
+	c.Put(2, []byte("buff2"))
+	assert.Equal(t, uint32(4), c.GetContiguousFrontier())
+}
+
+func TestChunk_TryAssembleAllChunksShouldAllocateTheAssemblyBufferOnce(t *testing.T) {
+	t.Parallel()
+
+	c := NewChunk(3, []byte("reference"))
+	assert.Equal(t, 0, cap(c.assembled))
+
+	c.Put(0, []byte("buff0"))
+	_, _ = c.GetNewContiguousPrefix()
+	assert.Equal(t, []byte("buff0"), c.assembled)
+	c.Put(2, []byte("buff2"))
+	c.Put(1, []byte("buff1"))
+	completeBuff := c.TryAssembleAllChunks()
+	assert.Equal(t, []byte("buff0buff1buff2"), completeBuff)
+	assert.Equal(t, 15, cap(completeBuff))
+}
+
+func TestChunk_PutOverAssembledChunkShouldReassemble(t *testing.T) {
+	t.Parallel()
+
+	c := NewChunk(2, []byte("reference"))
+	c.Put(0, []byte("buff0"))
+	offset, buff := c.GetNewContiguousPrefix()
+	assert.Equal(t, 0, offset)
+	assert.Equal(t, []byte("buff0"), buff)
+
+	c.Put(0, []byte("new buff0"))
+	c.Put(1, []byte("buff1"))
+	assert.Equal(t, []byte("new buff0buff1"), c.TryAssembleAllChunks())
+}
+
+func BenchmarkChunk_AssembleLargeNode(b *testing.B) {
+	numChunks := uint32(64)
+	chunkBuff := make([]byte, 1024*1024)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c := NewChunk(numChunks, []byte("reference"))
+		for chunkIndex := uint32(0); chunkIndex < numChunks; chunkIndex++ {
+			c.Put(chunkIndex, chunkBuff)
+			_, _ = c.GetNewContiguousPrefix()
+		}
+		_ = c.TryAssembleAllChunks()
+	}
+}
@@ -0,0 +1,370 @@
+package processor
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ElrondNetwork/elrond-go-core/core/check"
+	"github.com/ElrondNetwork/elrond-go-core/data/batch"
+	"github.com/ElrondNetwork/elrond-go/process"
+	"github.com/ElrondNetwork/elrond-go/process/interceptors/processor/chunk"
+)
+
+// checkpointWritesQueueSize is the number of checkpoint writes batches queued for the pending storer, after which the
+// process loop waits for the checkpoint writer
+const checkpointWritesQueueSize = 100
+
+// checkpointWrite is a put of a value in the pending storer, or the removal of the key
+type checkpointWrite struct {
+	key      []byte
+	value    []byte
+	isRemove bool
+}
+
+// LoadPendingFromStorage restores the large trie nodes checkpointed in the pending storer, so that after a restart only
+// their missing chunks are requested. The references already being assembled are not overwritten. It does nothing if
+// no pending storer was provided
+func (proc *trieNodeChunksProcessor) LoadPendingFromStorage() error {
+	respChan := make(chan error, 1)
+	req := loadRequest{
+		chanResponse: respChan,
+	}
+
+	select {
+	case proc.chanLoadRequests <- req:
+	case <-proc.chanClose:
+		return process.ErrProcessClosed
+	}
+
+	select {
+	case err := <-respChan:
+		return err
+	case <-proc.chanClose:
+		return process.ErrProcessClosed
+	}
+}
+
+func (proc *trieNodeChunksProcessor) loadPendingFromStorage() error {
+	if check.IfNil(proc.pendingStorer) {
+		return nil
+	}
+
+	checkpoints := make(map[string][]byte)
+	checkpointedChunks := make(map[string]map[uint32][]byte)
+	proc.pendingStorer.RangeKeys(func(key []byte, val []byte) bool {
+		reference, chunkIndex, isChunkKey := proc.parseCheckpointChunkKey(key)
+		if !isChunkKey {
+			checkpoints[string(key)] = append([]byte{}, val...)
+			return true
+		}
+
+		_, found := checkpointedChunks[reference]
+		if !found {
+			checkpointedChunks[reference] = make(map[uint32][]byte)
+		}
+		checkpointedChunks[reference][chunkIndex] = append([]byte{}, val...)
+		return true
+	})
+
+	numRestoredReferences := 0
+	for reference, buff := range checkpoints {
+		chunks := checkpointedChunks[reference]
+		delete(checkpointedChunks, reference)
+		if proc.chunksCacher.Has([]byte(reference)) {
+			// the reference is already being assembled, so its stored chunks are only recorded, to be removed with it
+			proc.recordCheckpointedChunks(reference, chunks)
+			continue
+		}
+
+		proc.mapCheckpointedChunks[reference] = getChunkIndexes(chunks)
+		chunkData, err := proc.restoreCheckpoint([]byte(reference), buff, chunks)
+		if err != nil {
+			proc.logDebug("trieNodeChunksProcessor.LoadPendingFromStorage: dropped checkpoint",
+				"reference", []byte(reference), "error", err.Error())
+			proc.removeCheckpoint(reference)
+			continue
+		}
+
+		size := chunkData.Size()
+		isOverBudget := proc.globalMemoryBudget > 0 && proc.pendingReferencesSize+uint64(size) > proc.globalMemoryBudget
+		if isOverBudget {
+			proc.logDebug("trieNodeChunksProcessor.LoadPendingFromStorage: checkpoint over the memory budget",
+				"reference", []byte(reference), "size", size)
+			proc.removeCheckpoint(reference)
+			continue
+		}
+
+		proc.chunksCacher.Put([]byte(reference), chunkData, size)
+		proc.markAssemblyStart([]byte(reference))
+		proc.markReferenceFirstSeen([]byte(reference))
+		proc.updatePendingReference([]byte(reference), size)
+		numRestoredReferences++
+	}
+
+	for reference, chunks := range checkpointedChunks {
+		proc.logDebug("trieNodeChunksProcessor.LoadPendingFromStorage: dropped chunks without checkpoint",
+			"reference", []byte(reference), "num chunks", len(chunks))
+		proc.mapCheckpointedChunks[reference] = getChunkIndexes(chunks)
+		proc.removeCheckpoint(reference)
+	}
+
+	proc.logDebug("trieNodeChunksProcessor.LoadPendingFromStorage",
+		"num checkpoints", len(checkpoints), "num restored references", numRestoredReferences)
+
+	return nil
+}
+
+// restoreCheckpoint rebuilds the chunks of a reference from its checkpoint, which holds the max chunks, and from its
+// checkpointed chunks
+func (proc *trieNodeChunksProcessor) restoreCheckpoint(reference []byte, buff []byte, chunks map[uint32][]byte) (chunkHandler, error) {
+	if len(reference) != proc.hasher.Size() {
+		return nil, process.ErrIncompatibleReference
+	}
+
+	b := &batch.Batch{}
+	err := proc.marshaller.Unmarshal(b, buff)
+	if err != nil {
+		return nil, err
+	}
+
+	isValidMaxChunks := b.MaxChunks >= 2 && b.MaxChunks <= proc.maxChunksAllowed
+	if !isValidMaxChunks {
+		return nil, fmt.Errorf("%w for checkpointed max chunks %d", process.ErrInvalidValue, b.MaxChunks)
+	}
+
+	chunkData := chunk.NewChunk(b.MaxChunks, reference)
+	for chunkIndex, data := range chunks {
+		if chunkIndex >= b.MaxChunks {
+			return nil, fmt.Errorf("%w for checkpointed chunk index %d, max chunks %d",
+				process.ErrInvalidValue, chunkIndex, b.MaxChunks)
+		}
+
+		chunkData.Put(chunkIndex, data)
+	}
+
+	numMissingChunks := len(chunkData.GetAllMissingChunkIndexes())
+	if numMissingChunks == 0 || numMissingChunks == int(b.MaxChunks) {
+		return nil, fmt.Errorf("%w for checkpointed missing chunks %d, max chunks %d",
+			process.ErrInvalidValue, numMissingChunks, b.MaxChunks)
+	}
+
+	return chunkData, nil
+}
+
+// recordCheckpointedChunks adds the given stored chunks to the checkpointed chunks of the reference
+func (proc *trieNodeChunksProcessor) recordCheckpointedChunks(reference string, chunks map[uint32][]byte) {
+	checkpointedChunks, found := proc.mapCheckpointedChunks[reference]
+	if !found {
+		proc.mapCheckpointedChunks[reference] = getChunkIndexes(chunks)
+		return
+	}
+
+	for chunkIndex := range chunks {
+		checkpointedChunks[chunkIndex] = struct{}{}
+	}
+}
+
+// checkpointPendingReferences saves the chunks received since the last checkpoint by the references being assembled
+// and removes the checkpoints of the references no longer being assembled. The storer is written by the checkpoint
+// writer, so that the process loop does not wait for it
+func (proc *trieNodeChunksProcessor) checkpointPendingReferences() {
+	if check.IfNil(proc.pendingStorer) {
+		return
+	}
+
+	writes := make([]checkpointWrite, 0)
+	pendingReferences := make(map[string]struct{})
+	for _, reference := range proc.chunksCacher.Keys() {
+		data, found := proc.chunksCacher.Get(reference)
+		if !found {
+			continue
+		}
+
+		chunkData, ok := data.(chunkHandler)
+		if !ok {
+			continue
+		}
+
+		pendingReferences[string(reference)] = struct{}{}
+		referenceWrites, err := proc.checkpointReference(reference, chunkData)
+		if err != nil {
+			proc.logDebug("trieNodeChunksProcessor.checkpointReference", "reference", reference, "error", err.Error())
+			continue
+		}
+		writes = append(writes, referenceWrites...)
+	}
+
+	for reference := range proc.mapCheckpointedChunks {
+		_, isPending := pendingReferences[reference]
+		if !isPending {
+			writes = append(writes, proc.createRemoveCheckpointWrites(reference)...)
+		}
+	}
+
+	proc.queueCheckpointWrites(writes)
+}
+
+// checkpointReference returns the writes saving the checkpoint of the reference, holding its max chunks, the first
+// time it is called for the reference, and then only the chunks not saved already, each one under its own key. The
+// chunks are recorded as checkpointed once their writes are created
+func (proc *trieNodeChunksProcessor) checkpointReference(reference []byte, chunkData chunkHandler) ([]checkpointWrite, error) {
+	writes := make([]checkpointWrite, 0)
+	checkpointedChunks, found := proc.mapCheckpointedChunks[string(reference)]
+	if !found {
+		buff, err := proc.marshaller.Marshal(&batch.Batch{
+			Reference: reference,
+			MaxChunks: chunkData.GetMaxChunks(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		writes = append(writes, checkpointWrite{key: reference, value: buff})
+		checkpointedChunks = make(map[uint32]struct{})
+		proc.mapCheckpointedChunks[string(reference)] = checkpointedChunks
+	}
+
+	for _, chunkIndex := range chunkData.GetAllPresentChunkIndexes() {
+		_, isCheckpointed := checkpointedChunks[chunkIndex]
+		if isCheckpointed {
+			continue
+		}
+
+		writes = append(writes, checkpointWrite{
+			key:   createCheckpointChunkKey(reference, chunkIndex),
+			value: chunkData.GetChunk(chunkIndex),
+		})
+		checkpointedChunks[chunkIndex] = struct{}{}
+	}
+
+	return writes, nil
+}
+
+// createCheckpointChunkKey returns the key of a checkpointed chunk, which is the reference followed by the chunk index
+func createCheckpointChunkKey(reference []byte, chunkIndex uint32) []byte {
+	key := make([]byte, len(reference)+4)
+	copy(key, reference)
+	binary.BigEndian.PutUint32(key[len(reference):], chunkIndex)
+
+	return key
+}
+
+// parseCheckpointChunkKey returns the reference and the chunk index of a checkpointed chunk key and false for the keys
+// of the checkpoints, which are only the references
+func (proc *trieNodeChunksProcessor) parseCheckpointChunkKey(key []byte) (string, uint32, bool) {
+	referenceLength := proc.hasher.Size()
+	if len(key) != referenceLength+4 {
+		return "", 0, false
+	}
+
+	return string(key[:referenceLength]), binary.BigEndian.Uint32(key[referenceLength:]), true
+}
+
+func getChunkIndexes(chunks map[uint32][]byte) map[uint32]struct{} {
+	chunkIndexes := make(map[uint32]struct{}, len(chunks))
+	for chunkIndex := range chunks {
+		chunkIndexes[chunkIndex] = struct{}{}
+	}
+
+	return chunkIndexes
+}
+
+func (proc *trieNodeChunksProcessor) removeCheckpoint(reference string) {
+	if check.IfNil(proc.pendingStorer) {
+		return
+	}
+
+	proc.queueCheckpointWrites(proc.createRemoveCheckpointWrites(reference))
+}
+
+// createRemoveCheckpointWrites returns the writes removing the checkpoint of the reference and its checkpointed chunks,
+// which are no longer recorded
+func (proc *trieNodeChunksProcessor) createRemoveCheckpointWrites(reference string) []checkpointWrite {
+	checkpointedChunks := proc.mapCheckpointedChunks[reference]
+	delete(proc.mapCheckpointedChunks, reference)
+
+	writes := make([]checkpointWrite, 0, len(checkpointedChunks)+1)
+	writes = append(writes, checkpointWrite{key: []byte(reference), isRemove: true})
+	for chunkIndex := range checkpointedChunks {
+		writes = append(writes, checkpointWrite{key: createCheckpointChunkKey([]byte(reference), chunkIndex), isRemove: true})
+	}
+
+	return writes
+}
+
+func (proc *trieNodeChunksProcessor) removeAllCheckpoints() {
+	if check.IfNil(proc.pendingStorer) {
+		return
+	}
+
+	writes := make([]checkpointWrite, 0)
+	for reference := range proc.mapCheckpointedChunks {
+		writes = append(writes, proc.createRemoveCheckpointWrites(reference)...)
+	}
+
+	proc.queueCheckpointWrites(writes)
+}
+
+// queueCheckpointWrites hands the writes to the checkpoint writer. The writes are dropped if the processor is closed
+func (proc *trieNodeChunksProcessor) queueCheckpointWrites(writes []checkpointWrite) {
+	if len(writes) == 0 {
+		return
+	}
+
+	select {
+	case proc.chanCheckpointWrites <- writes:
+	case <-proc.chanClose:
+	}
+}
+
+// checkpointWriteLoop applies the queued writes on the pending storer, in the order they were queued. The writes
+// already queued when the processor is closed are still applied
+func (proc *trieNodeChunksProcessor) checkpointWriteLoop() {
+	defer close(proc.chanCheckpointWriterDone)
+
+	for {
+		select {
+		case writes := <-proc.chanCheckpointWrites:
+			proc.applyCheckpointWrites(writes)
+		case <-proc.chanClose:
+			proc.applyQueuedCheckpointWrites()
+			return
+		}
+	}
+}
+
+func (proc *trieNodeChunksProcessor) applyQueuedCheckpointWrites() {
+	for {
+		select {
+		case writes := <-proc.chanCheckpointWrites:
+			proc.applyCheckpointWrites(writes)
+		default:
+			return
+		}
+	}
+}
+
+// applyCheckpointWrites writes the pending storer. A failed write is only logged, as the chunk not checkpointed is
+// requested again after a restart
+func (proc *trieNodeChunksProcessor) applyCheckpointWrites(writes []checkpointWrite) {
+	for _, write := range writes {
+		var err error
+		if write.isRemove {
+			err = proc.pendingStorer.Remove(write.key)
+		} else {
+			err = proc.pendingStorer.Put(write.key, write.value)
+		}
+		if err != nil {
+			proc.logDebug("trieNodeChunksProcessor.applyCheckpointWrites", "key", write.key,
+				"is remove", write.isRemove, "error", err.Error())
+		}
+	}
+}
+
+func (proc *trieNodeChunksProcessor) waitCheckpointWriter() {
+	if proc.chanCheckpointWriterDone == nil {
+		return
+	}
+
+	<-proc.chanCheckpointWriterDone
+}
@@ -0,0 +1,350 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ElrondNetwork/elrond-go-core/core/check"
+	"github.com/ElrondNetwork/elrond-go-core/data/batch"
+	"github.com/ElrondNetwork/elrond-go-core/marshal"
+	"github.com/ElrondNetwork/elrond-go/process"
+	"github.com/ElrondNetwork/elrond-go/storage"
+	"github.com/ElrondNetwork/elrond-go/storage/storageUnit"
+	"github.com/ElrondNetwork/elrond-go/testscommon"
+	storageMocks "github.com/ElrondNetwork/elrond-go/testscommon/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createMockTrieNodesChunksProcessorArgsWithStorer(pendingStorer storage.Storer) TrieNodesChunksProcessorArgs {
+	args := createMockTrieNodesChunksProcessorArgs()
+	// the requests rounds are simulated, so the process loop should not trigger any
+	args.RequestInterval = time.Hour
+	args.PendingStorer = pendingStorer
+	args.Marshaller = &marshal.GogoProtoMarshalizer{}
+
+	return args
+}
+
+// waitStorerKey waits for the checkpoint writer to put or to remove the key in the pending storer
+func waitStorerKey(t *testing.T, pendingStorer storage.Storer, key []byte, shouldHave bool) {
+	hasKey := func() bool {
+		return (pendingStorer.Has(key) == nil) == shouldHave
+	}
+	assert.Eventually(t, hasKey, time.Second*2, time.Millisecond*10)
+}
+
+func TestNewTrieNodeChunksProcessor_PendingStorerWithNilMarshaller(t *testing.T) {
+	t.Parallel()
+
+	args := createMockTrieNodesChunksProcessorArgsWithStorer(testscommon.CreateMemUnit())
+	args.Marshaller = nil
+	tncp, err := NewTrieNodeChunksProcessor(args)
+	assert.True(t, errors.Is(err, process.ErrNilMarshalizer))
+	assert.True(t, check.IfNil(tncp))
+}
+
+func TestNewTrieNodeChunksProcessor_PendingStorerWithoutRangeKeysSupport(t *testing.T) {
+	t.Parallel()
+
+	tncp, err := NewTrieNodeChunksProcessor(createMockTrieNodesChunksProcessorArgsWithStorer(storageUnit.NewNilStorer()))
+	assert.True(t, errors.Is(err, process.ErrStorerIterationNotSupported))
+	assert.True(t, check.IfNil(tncp))
+}
+
+func TestTrieNodeChunksProcessor_LoadPendingFromStorageWithoutStorerShouldDoNothing(t *testing.T) {
+	t.Parallel()
+
+	tncp, _ := NewTrieNodeChunksProcessor(createMockTrieNodesChunksProcessorArgs())
+
+	err := tncp.LoadPendingFromStorage()
+	assert.Nil(t, err)
+	numReferences, _ := tncp.Stats()
+	assert.Equal(t, 0, numReferences)
+
+	_ = tncp.Close()
+	assert.Equal(t, process.ErrProcessClosed, tncp.LoadPendingFromStorage())
+}
+
+func TestTrieNodeChunksProcessor_LoadPendingFromStorageShouldRestoreTheCheckpointedChunks(t *testing.T) {
+	t.Parallel()
+
+	pendingStorer := testscommon.CreateMemUnit()
+	checkBatch := func(tncp *trieNodeChunksProcessor, chunkIndex uint32, buff string) process.CheckedChunkResult {
+		result, err := tncp.CheckBatch(
+			&batch.Batch{
+				Data:       [][]byte{[]byte(buff)},
+				Reference:  reference,
+				ChunkIndex: chunkIndex,
+				MaxChunks:  3,
+			},
+			createMockWhiteLister(true),
+			"pid",
+		)
+		assert.Nil(t, err)
+
+		return result
+	}
+
+	tncp, _ := NewTrieNodeChunksProcessor(createMockTrieNodesChunksProcessorArgsWithStorer(pendingStorer))
+	checkBatch(tncp, 0, "buff0")
+	checkBatch(tncp, 2, "buff2")
+	tncp.doRequests(context.Background())
+	_ = tncp.Close()
+	assert.Nil(t, pendingStorer.Has(reference))
+
+	numRequests := make(map[uint32]int)
+	args := createMockTrieNodesChunksProcessorArgsWithStorer(pendingStorer)
+	args.RequestHandler = &testscommon.RequestHandlerStub{
+		RequestTrieNodeCalled: func(_ []byte, _ string, chunkIndex uint32) {
+			numRequests[chunkIndex]++
+		},
+	}
+	tncp, _ = NewTrieNodeChunksProcessor(args)
+	defer func() {
+		_ = tncp.Close()
+	}()
+
+	err := tncp.LoadPendingFromStorage()
+	require.Nil(t, err)
+	numReferences, numMissingChunks := tncp.Stats()
+	assert.Equal(t, 1, numReferences)
+	assert.Equal(t, 1, numMissingChunks)
+
+	tncp.doRequests(context.Background())
+	assert.Equal(t, map[uint32]int{1: 1}, numRequests)
+
+	result := checkBatch(tncp, 1, "buff1")
+	assert.True(t, result.HaveAllChunks)
+	assert.Equal(t, []byte("buff0buff1buff2"), result.CompleteBuffer)
+	waitStorerKey(t, pendingStorer, reference, false)
+	waitStorerKey(t, pendingStorer, createCheckpointChunkKey(reference, 0), false)
+	waitStorerKey(t, pendingStorer, createCheckpointChunkKey(reference, 2), false)
+}
+
+func TestTrieNodeChunksProcessor_CheckpointShouldSaveOnlyTheNewChunks(t *testing.T) {
+	t.Parallel()
+
+	var mutPutKeys sync.Mutex
+	putKeys := make([][]byte, 0)
+	pendingStorer := &storageMocks.StorerStub{
+		PutCalled: func(key, data []byte) error {
+			mutPutKeys.Lock()
+			putKeys = append(putKeys, key)
+			mutPutKeys.Unlock()
+			return nil
+		},
+	}
+	getPutKeys := func() [][]byte {
+		mutPutKeys.Lock()
+		defer mutPutKeys.Unlock()
+
+		result := putKeys
+		putKeys = make([][]byte, 0)
+		return result
+	}
+	tncp, _ := NewTrieNodeChunksProcessor(createMockTrieNodesChunksProcessorArgsWithStorer(pendingStorer))
+	defer func() {
+		_ = tncp.Close()
+	}()
+	checkBatch := func(chunkIndex uint32) {
+		_, err := tncp.CheckBatch(
+			&batch.Batch{
+				Data:       [][]byte{[]byte("buff")},
+				Reference:  reference,
+				ChunkIndex: chunkIndex,
+				MaxChunks:  4,
+			},
+			createMockWhiteLister(true),
+			"pid",
+		)
+		assert.Nil(t, err)
+	}
+
+	checkBatch(0)
+	tncp.doRequests(context.Background())
+	checkBatch(2)
+	tncp.doRequests(context.Background())
+	tncp.doRequests(context.Background())
+	_ = tncp.Close()
+	expectedPutKeys := [][]byte{reference, createCheckpointChunkKey(reference, 0), createCheckpointChunkKey(reference, 2)}
+	assert.Equal(t, expectedPutKeys, getPutKeys())
+}
+
+func TestTrieNodeChunksProcessor_LoadPendingFromStorageShouldDropTheChunksWithoutCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	pendingStorer := testscommon.CreateMemUnit()
+	chunkKey := createCheckpointChunkKey(reference, 1)
+	_ = pendingStorer.Put(chunkKey, []byte("buff1"))
+
+	tncp, _ := NewTrieNodeChunksProcessor(createMockTrieNodesChunksProcessorArgsWithStorer(pendingStorer))
+	defer func() {
+		_ = tncp.Close()
+	}()
+
+	err := tncp.LoadPendingFromStorage()
+	assert.Nil(t, err)
+	numReferences, _ := tncp.Stats()
+	assert.Equal(t, 0, numReferences)
+	waitStorerKey(t, pendingStorer, chunkKey, false)
+}
+
+func TestTrieNodeChunksProcessor_LoadPendingFromStorageShouldDropTheInvalidCheckpoints(t *testing.T) {
+	t.Parallel()
+
+	pendingStorer := testscommon.CreateMemUnit()
+	invalidReference := []byte("invalid reference")
+	_ = pendingStorer.Put(invalidReference, []byte("checkpoint"))
+	_ = pendingStorer.Put(reference, []byte("invalid checkpoint"))
+
+	tncp, _ := NewTrieNodeChunksProcessor(createMockTrieNodesChunksProcessorArgsWithStorer(pendingStorer))
+	defer func() {
+		_ = tncp.Close()
+	}()
+
+	err := tncp.LoadPendingFromStorage()
+	assert.Nil(t, err)
+	numReferences, _ := tncp.Stats()
+	assert.Equal(t, 0, numReferences)
+	waitStorerKey(t, pendingStorer, invalidReference, false)
+	waitStorerKey(t, pendingStorer, reference, false)
+}
+
+func TestTrieNodeChunksProcessor_ResetShouldRemoveTheCheckpoints(t *testing.T) {
+	t.Parallel()
+
+	pendingStorer := testscommon.CreateMemUnit()
+	tncp, _ := NewTrieNodeChunksProcessor(createMockTrieNodesChunksProcessorArgsWithStorer(pendingStorer))
+	defer func() {
+		_ = tncp.Close()
+	}()
+
+	_, err := tncp.CheckBatch(
+		&batch.Batch{
+			Data:       [][]byte{[]byte("buff0")},
+			Reference:  reference,
+			ChunkIndex: 0,
+			MaxChunks:  3,
+		},
+		createMockWhiteLister(true),
+		"pid",
+	)
+	assert.Nil(t, err)
+	tncp.doRequests(context.Background())
+	waitStorerKey(t, pendingStorer, reference, true)
+
+	tncp.Reset()
+	waitStorerKey(t, pendingStorer, reference, false)
+}
+
+func TestTrieNodeChunksProcessor_RemovedReferenceShouldRemoveItsCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	pendingStorer := testscommon.CreateMemUnit()
+	args := createMockTrieNodesChunksProcessorArgsWithStorer(pendingStorer)
+	args.MaxWaitTime = time.Millisecond
+	tncp, _ := NewTrieNodeChunksProcessor(args)
+	defer func() {
+		_ = tncp.Close()
+	}()
+
+	_, err := tncp.CheckBatch(
+		&batch.Batch{
+			Data:       [][]byte{[]byte("buff0")},
+			Reference:  reference,
+			ChunkIndex: 0,
+			MaxChunks:  3,
+		},
+		createMockWhiteLister(true),
+		"pid",
+	)
+	assert.Nil(t, err)
+	tncp.checkpointPendingReferences()
+	waitStorerKey(t, pendingStorer, reference, true)
+	waitStorerKey(t, pendingStorer, createCheckpointChunkKey(reference, 0), true)
+
+	time.Sleep(time.Millisecond * 10)
+	tncp.removeExpiredReferences()
+	waitStorerKey(t, pendingStorer, reference, false)
+	waitStorerKey(t, pendingStorer, createCheckpointChunkKey(reference, 0), false)
+}
+
+func TestTrieNodeChunksProcessor_CheckpointShouldNotWaitForThePendingStorer(t *testing.T) {
+	t.Parallel()
+
+	chanPutStarted := make(chan struct{}, 1)
+	chanReleasePut := make(chan struct{})
+	pendingStorer := &storageMocks.StorerStub{
+		PutCalled: func(key, data []byte) error {
+			select {
+			case chanPutStarted <- struct{}{}:
+			default:
+			}
+			<-chanReleasePut
+			return nil
+		},
+	}
+	tncp, _ := NewTrieNodeChunksProcessor(createMockTrieNodesChunksProcessorArgsWithStorer(pendingStorer))
+	defer func() {
+		close(chanReleasePut)
+		_ = tncp.Close()
+	}()
+
+	_, err := tncp.CheckBatch(
+		&batch.Batch{
+			Data:       [][]byte{[]byte("buff0")},
+			Reference:  reference,
+			ChunkIndex: 0,
+			MaxChunks:  3,
+		},
+		createMockWhiteLister(true),
+		"pid",
+	)
+	assert.Nil(t, err)
+	tncp.doRequests(context.Background())
+	<-chanPutStarted
+
+	numReferences, numMissingChunks := tncp.Stats()
+	assert.Equal(t, 1, numReferences)
+	assert.Equal(t, 2, numMissingChunks)
+}
+
+func TestTrieNodeChunksProcessor_LoadPendingFromStorageShouldRecordTheCheckpointsOfTheReferencesBeingAssembled(t *testing.T) {
+	t.Parallel()
+
+	pendingStorer := testscommon.CreateMemUnit()
+	checkpoint, _ := (&marshal.GogoProtoMarshalizer{}).Marshal(&batch.Batch{Reference: reference, MaxChunks: 3})
+	_ = pendingStorer.Put(reference, checkpoint)
+	_ = pendingStorer.Put(createCheckpointChunkKey(reference, 2), []byte("buff2"))
+
+	tncp, _ := NewTrieNodeChunksProcessor(createMockTrieNodesChunksProcessorArgsWithStorer(pendingStorer))
+	defer func() {
+		_ = tncp.Close()
+	}()
+
+	_, err := tncp.CheckBatch(
+		&batch.Batch{
+			Data:       [][]byte{[]byte("buff0")},
+			Reference:  reference,
+			ChunkIndex: 0,
+			MaxChunks:  3,
+		},
+		createMockWhiteLister(true),
+		"pid",
+	)
+	assert.Nil(t, err)
+
+	err = tncp.LoadPendingFromStorage()
+	assert.Nil(t, err)
+	numReferences, numMissingChunks := tncp.Stats()
+	assert.Equal(t, 1, numReferences)
+	assert.Equal(t, 2, numMissingChunks)
+
+	tncp.Reset()
+	waitStorerKey(t, pendingStorer, reference, false)
+	waitStorerKey(t, pendingStorer, createCheckpointChunkKey(reference, 2), false)
+}
@@ -1,13 +1,20 @@
 package processor
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ElrondNetwork/elrond-go-core/core"
 	"github.com/ElrondNetwork/elrond-go-core/core/check"
 	"github.com/ElrondNetwork/elrond-go-core/data/batch"
 	"github.com/ElrondNetwork/elrond-go-core/hashing"
+	"github.com/ElrondNetwork/elrond-go-core/marshal"
+	logger "github.com/ElrondNetwork/elrond-go-logger"
+	"github.com/ElrondNetwork/elrond-go/common"
 	"github.com/ElrondNetwork/elrond-go/process"
 	"github.com/ElrondNetwork/elrond-go/process/interceptors/processor/chunk"
 	"github.com/ElrondNetwork/elrond-go/storage"
@@ -15,37 +22,184 @@ import (
 
 const minimumRequestTimeInterval = time.Millisecond * 200
 
+// defaultMaxChunksAllowed is the maximum number of chunks of a large trie node used when none is configured
+const defaultMaxChunksAllowed = 1000
+
+// chunkCompletionsQueueSize is the number of assembled large trie nodes queued for the OnChunkComplete handler, after
+// which the process loop waits for the handler
+const chunkCompletionsQueueSize = 100
+
+// maxBackoffExponent bounds the configured backoff exponent, so that the backoff interval does not overflow
+const maxBackoffExponent = 16
+
+// latencySmoothingFactor is the weight of the current request interval when adapting it to a new assembly latency
+const latencySmoothingFactor = 3
+
 type chunkHandler interface {
 	Put(chunkIndex uint32, buff []byte)
 	TryAssembleAllChunks() []byte
 	GetAllMissingChunkIndexes() []uint32
+	GetAllPresentChunkIndexes() []uint32
+	GetChunk(chunkIndex uint32) []byte
+	GetMaxChunks() uint32
+	GetNewContiguousPrefix() (int, []byte)
+	GetContiguousFrontier() uint32
 	Size() int
 	IsInterfaceNil() bool
 }
 
 type checkRequest struct {
 	batch        *batch.Batch
-	chanResponse chan process.CheckedChunkResult
+	sender       core.PeerID
+	chanResponse chan checkResponse
+}
+
+type checkResponse struct {
+	result process.CheckedChunkResult
+	err    error
+}
+
+// pendingReference holds the memory accounting of a large trie node being assembled
+type pendingReference struct {
+	size     uint64
+	sequence uint64
+}
+
+// requestBackoff holds the number of consecutive requests made for a large trie node without receiving new chunks
+type requestBackoff struct {
+	numRequests     uint32
+	nextRequestTime time.Time
+}
+
+// chunkCompletion is an assembled large trie node queued for the OnChunkComplete handler
+type chunkCompletion struct {
+	reference []byte
+	buff      []byte
+}
+
+type presentRangesRequest struct {
+	reference    []byte
+	chanResponse chan []Range
+}
+
+type statsRequest struct {
+	chanResponse chan chunksStats
+}
+
+type resetRequest struct {
+	chanResponse chan struct{}
+}
+
+type loadRequest struct {
+	chanResponse chan error
+}
+
+type chunksStats struct {
+	numReferences    int
+	numMissingChunks int
+}
+
+// Range defines an interval of chunk indexes, both ends being inclusive
+type Range struct {
+	Start uint32
+	End   uint32
 }
 
 // TrieNodesChunksProcessorArgs is the argument DTO used in the trieNodeChunksProcessor constructor
 type TrieNodesChunksProcessorArgs struct {
-	Hasher          hashing.Hasher
-	ChunksCacher    storage.Cacher
-	RequestInterval time.Duration
-	RequestHandler  process.RequestHandler
-	Topic           string
+	Hasher             hashing.Hasher
+	ChunksCacher       storage.Cacher
+	RequestInterval    time.Duration
+	RequestHandler     process.RequestHandler
+	Topic              string
+	DeliverPartialData bool
+	PartialDataHandler func(reference []byte, offsetStart int, data []byte)
+	MinRequestInterval time.Duration
+	MaxRequestInterval time.Duration
+	Logger             logger.Logger
+	ShardID            uint32
+	// ChunkIndexAcceptWindow is the maximum distance between an accepted chunk index and the first missing chunk index
+	// of its reference. Zero means that all the chunk indexes are accepted
+	ChunkIndexAcceptWindow uint32
+	// OnAssemblyComplete is called with the number of chunks supplied by each peer, when a large trie node is assembled.
+	// Nil means that the contributing peers are not tracked
+	OnAssemblyComplete func(reference []byte, contributors map[core.PeerID]int)
+	// GlobalMemoryBudget is the maximum number of bytes held by all the large trie nodes being assembled. When a chunk
+	// does not fit, the least complete and then the oldest references are evicted. Zero means that only the chunks
+	// cacher bounds the memory
+	GlobalMemoryBudget uint64
+	// MaxChunksAllowed is the maximum number of chunks a batch can declare for its large trie node. Zero means that
+	// defaultMaxChunksAllowed is used
+	MaxChunksAllowed uint32
+	// MaxWaitTime is the maximum time a large trie node is assembled, since its first chunk was received. The incomplete
+	// references exceeding it are dropped and no longer requested. Zero means that the references are not timed out
+	MaxWaitTime time.Duration
+	// AppStatusHandler is used to publish the number of large trie nodes being assembled and their missing chunks, on
+	// each requests round. Nil means the stats are not published
+	AppStatusHandler core.AppStatusHandler
+	// MaxBackoffExponent enables the per reference request backoff: a reference requested N times without receiving
+	// new chunks is requested again after RequestInterval * 2^min(N, MaxBackoffExponent), capped at MaxRequestInterval
+	// when set. Zero means that all the references are requested on each requests round
+	MaxBackoffExponent uint32
+	// CheckBatchTimeout is the maximum time CheckBatch waits for the batch to be handled, after which it returns
+	// ErrRequestTimeout. Zero means CheckBatch waits until the batch is handled or the processor is closed
+	CheckBatchTimeout time.Duration
+	// OnChunkComplete is called with the assembled buffer when a large trie node is assembled, so that the trie sync can
+	// request the dependent trie nodes right away. It is called on a single go routine, in the order the large trie
+	// nodes are assembled. Nil means no notification is sent
+	OnChunkComplete func(reference []byte, buff []byte)
+	// PendingStorer is used to checkpoint, on each requests round, the chunks of the large trie nodes being assembled, so
+	// that they can be restored with LoadPendingFromStorage after a restart. It is written on its own go routine and it
+	// has to be able to iterate over its keys, so the pruning storer can not be used. Nil means the chunks are kept only
+	// in memory
+	PendingStorer storage.Storer
+	// Marshaller is used to serialize the checkpointed chunks. It is required only when PendingStorer is set
+	Marshaller marshal.Marshalizer
 }
 
 type trieNodeChunksProcessor struct {
-	hasher            hashing.Hasher
-	chunksCacher      storage.Cacher
-	chanCheckRequests chan checkRequest
-	requestInterval   time.Duration
-	requestHandler    process.RequestHandler
-	topic             string
-	cancel            func()
-	chanClose         chan struct{}
+	hasher                    hashing.Hasher
+	chunksCacher              storage.Cacher
+	chanCheckRequests         chan checkRequest
+	chanPresentRangesRequests chan presentRangesRequest
+	chanStatsRequests         chan statsRequest
+	chanResetRequests         chan resetRequest
+	chanLoadRequests          chan loadRequest
+	requestInterval           int64
+	isAdaptiveInterval        bool
+	minRequestInterval        time.Duration
+	maxRequestInterval        time.Duration
+	mapAssemblyStartTimes     map[string]time.Time
+	requestHandler            process.RequestHandler
+	topic                     string
+	deliverPartialData        bool
+	partialDataHandler        func(reference []byte, offsetStart int, data []byte)
+	chunkIndexAcceptWindow    uint32
+	onAssemblyComplete        func(reference []byte, contributors map[core.PeerID]int)
+	onChunkComplete           func(reference []byte, buff []byte)
+	chanChunkCompletions      chan chunkCompletion
+	mapChunkContributors      map[string]map[uint32]core.PeerID
+	globalMemoryBudget        uint64
+	pendingReferencesSize     uint64
+	mapPendingReferences      map[string]*pendingReference
+	nextReferenceSequence     uint64
+	maxChunksAllowed          uint32
+	maxWaitTime               time.Duration
+	mapReferenceFirstSeen     map[string]time.Time
+	statusHandler             core.AppStatusHandler
+	maxBackoffExponent        uint32
+	mapRequestBackoffs        map[string]*requestBackoff
+	checkBatchTimeout         time.Duration
+	pendingStorer             storage.Storer
+	marshaller                marshal.Marshalizer
+	mapCheckpointedChunks     map[string]map[uint32]struct{}
+	chanCheckpointWrites      chan []checkpointWrite
+	chanCheckpointWriterDone  chan struct{}
+	logger                    logger.Logger
+	logContext                []interface{}
+	cancel                    func()
+	chanClose                 chan struct{}
+	closeOnce                 sync.Once
 }
 
 // NewTrieNodeChunksProcessor creates a new trieNodeChunksProcessor instance
@@ -66,43 +220,147 @@ func NewTrieNodeChunksProcessor(arg TrieNodesChunksProcessorArgs) (*trieNodeChun
 	if len(arg.Topic) == 0 {
 		return nil, fmt.Errorf("%w in NewTrieNodeChunksProcessor", process.ErrEmptyTopic)
 	}
+	if arg.DeliverPartialData && arg.PartialDataHandler == nil {
+		return nil, fmt.Errorf("%w in NewTrieNodeChunksProcessor", process.ErrNilPartialChunksHandler)
+	}
+	if arg.CheckBatchTimeout < 0 {
+		return nil, fmt.Errorf("%w in NewTrieNodeChunksProcessor for CheckBatchTimeout", process.ErrInvalidValue)
+	}
+	if arg.MaxWaitTime < 0 {
+		return nil, fmt.Errorf("%w in NewTrieNodeChunksProcessor for MaxWaitTime", process.ErrInvalidValue)
+	}
+	if arg.MaxBackoffExponent > maxBackoffExponent {
+		return nil, fmt.Errorf("%w in NewTrieNodeChunksProcessor for MaxBackoffExponent, maximum is %d",
+			process.ErrInvalidValue, maxBackoffExponent)
+	}
+	if !check.IfNil(arg.PendingStorer) && check.IfNil(arg.Marshaller) {
+		return nil, fmt.Errorf("%w in NewTrieNodeChunksProcessor", process.ErrNilMarshalizer)
+	}
+	rangeKeysSupportChecker, ok := arg.PendingStorer.(process.RangeKeysSupportChecker)
+	if ok && !check.IfNil(arg.PendingStorer) && !rangeKeysSupportChecker.IsRangeKeysSupported() {
+		return nil, fmt.Errorf("%w in NewTrieNodeChunksProcessor for PendingStorer", process.ErrStorerIterationNotSupported)
+	}
+	err := checkAdaptiveRequestInterval(arg)
+	if err != nil {
+		return nil, err
+	}
+	instanceLogger := arg.Logger
+	if check.IfNil(instanceLogger) {
+		instanceLogger = log
+	}
+	maxChunksAllowed := arg.MaxChunksAllowed
+	if maxChunksAllowed == 0 {
+		maxChunksAllowed = defaultMaxChunksAllowed
+	}
 
 	tncp := &trieNodeChunksProcessor{
-		hasher:            arg.Hasher,
-		chunksCacher:      arg.ChunksCacher,
-		chanCheckRequests: make(chan checkRequest),
-		requestInterval:   arg.RequestInterval,
-		requestHandler:    arg.RequestHandler,
-		topic:             arg.Topic,
-		chanClose:         make(chan struct{}),
+		hasher:                    arg.Hasher,
+		chunksCacher:              arg.ChunksCacher,
+		chanCheckRequests:         make(chan checkRequest),
+		chanPresentRangesRequests: make(chan presentRangesRequest),
+		chanStatsRequests:         make(chan statsRequest),
+		chanResetRequests:         make(chan resetRequest),
+		chanLoadRequests:          make(chan loadRequest),
+		requestInterval:           int64(arg.RequestInterval),
+		isAdaptiveInterval:        arg.MaxRequestInterval > 0,
+		minRequestInterval:        arg.MinRequestInterval,
+		maxRequestInterval:        arg.MaxRequestInterval,
+		mapAssemblyStartTimes:     make(map[string]time.Time),
+		requestHandler:            arg.RequestHandler,
+		topic:                     arg.Topic,
+		deliverPartialData:        arg.DeliverPartialData,
+		partialDataHandler:        arg.PartialDataHandler,
+		chunkIndexAcceptWindow:    arg.ChunkIndexAcceptWindow,
+		onAssemblyComplete:        arg.OnAssemblyComplete,
+		onChunkComplete:           arg.OnChunkComplete,
+		mapChunkContributors:      make(map[string]map[uint32]core.PeerID),
+		globalMemoryBudget:        arg.GlobalMemoryBudget,
+		mapPendingReferences:      make(map[string]*pendingReference),
+		maxChunksAllowed:          maxChunksAllowed,
+		maxWaitTime:               arg.MaxWaitTime,
+		mapReferenceFirstSeen:     make(map[string]time.Time),
+		statusHandler:             arg.AppStatusHandler,
+		maxBackoffExponent:        arg.MaxBackoffExponent,
+		mapRequestBackoffs:        make(map[string]*requestBackoff),
+		checkBatchTimeout:         arg.CheckBatchTimeout,
+		pendingStorer:             arg.PendingStorer,
+		marshaller:                arg.Marshaller,
+		mapCheckpointedChunks:     make(map[string]map[uint32]struct{}),
+		logger:                    instanceLogger,
+		logContext:                []interface{}{"topic", arg.Topic, "shard", arg.ShardID},
+		chanClose:                 make(chan struct{}),
+	}
+	if tncp.onChunkComplete != nil {
+		tncp.chanChunkCompletions = make(chan chunkCompletion, chunkCompletionsQueueSize)
+		go tncp.chunkCompletionLoop()
+	}
+	if !check.IfNil(tncp.pendingStorer) {
+		tncp.chanCheckpointWrites = make(chan []checkpointWrite, checkpointWritesQueueSize)
+		tncp.chanCheckpointWriterDone = make(chan struct{})
+		go tncp.checkpointWriteLoop()
 	}
 	var ctx context.Context
 	ctx, tncp.cancel = context.WithCancel(context.Background())
 	go tncp.processLoop(ctx)
 
-	log.Debug("NewTrieNodeChunksProcessor", "key", arg.Topic)
+	tncp.logDebug("NewTrieNodeChunksProcessor")
 
 	return tncp, nil
 }
 
+// checkAdaptiveRequestInterval validates the request interval bounds. A zero maximum request interval means that the
+// fixed request interval is used
+func checkAdaptiveRequestInterval(arg TrieNodesChunksProcessorArgs) error {
+	if arg.MaxRequestInterval == 0 {
+		return nil
+	}
+	if arg.MinRequestInterval < minimumRequestTimeInterval {
+		return fmt.Errorf("%w in NewTrieNodeChunksProcessor, minimum request interval is %v",
+			process.ErrInvalidValue, minimumRequestTimeInterval)
+	}
+	if arg.MaxRequestInterval < arg.MinRequestInterval {
+		return fmt.Errorf("%w in NewTrieNodeChunksProcessor, max request interval %v is lower than min request interval %v",
+			process.ErrInvalidValue, arg.MaxRequestInterval, arg.MinRequestInterval)
+	}
+
+	return nil
+}
+
 func (proc *trieNodeChunksProcessor) processLoop(ctx context.Context) {
-	chanDoRequests := time.After(proc.requestInterval)
+	chanDoRequests := time.After(proc.getRequestInterval())
 	for {
 		select {
 		case <-ctx.Done():
-			log.Debug("trieNodeChunksProcessor.processLoop go routine is stopping...")
+			proc.logDebug("trieNodeChunksProcessor.processLoop go routine is stopping...")
 			return
 		case request := <-proc.chanCheckRequests:
 			proc.processCheckRequest(request)
+		case request := <-proc.chanPresentRangesRequests:
+			proc.processPresentRangesRequest(request)
+		case request := <-proc.chanStatsRequests:
+			request.chanResponse <- proc.computeStats()
+		case request := <-proc.chanResetRequests:
+			proc.processResetRequest(request)
+		case request := <-proc.chanLoadRequests:
+			request.chanResponse <- proc.loadPendingFromStorage()
 		case <-chanDoRequests:
 			proc.doRequests(ctx)
-			chanDoRequests = time.After(proc.requestInterval)
+			chanDoRequests = time.After(proc.getRequestInterval())
 		}
 	}
 }
 
-// CheckBatch will check the batch returning a checked chunk result containing result processing
-func (proc *trieNodeChunksProcessor) CheckBatch(b *batch.Batch, whiteListHandler process.WhiteListHandler) (process.CheckedChunkResult, error) {
+// CheckBatch will check the batch, received from the given sender, returning a checked chunk result containing result
+// processing
+func (proc *trieNodeChunksProcessor) CheckBatch(
+	b *batch.Batch,
+	whiteListHandler process.WhiteListHandler,
+	sender core.PeerID,
+) (process.CheckedChunkResult, error) {
+	if proc.isClosed() {
+		return process.CheckedChunkResult{}, process.ErrProcessClosed
+	}
+
 	batchValid, err := proc.batchIsValid(b, whiteListHandler)
 	if !batchValid {
 		return process.CheckedChunkResult{
@@ -112,23 +370,38 @@ func (proc *trieNodeChunksProcessor) CheckBatch(b *batch.Batch, whiteListHandler
 		}, err
 	}
 
-	respChan := make(chan process.CheckedChunkResult, 1)
+	respChan := make(chan checkResponse, 1)
 	req := checkRequest{
 		batch:        b,
+		sender:       sender,
 		chanResponse: respChan,
 	}
 
+	// a nil channel never fires, so CheckBatch waits without a timeout
+	var chanTimeout <-chan time.Time
+	if proc.checkBatchTimeout > 0 {
+		timer := time.NewTimer(proc.checkBatchTimeout)
+		defer timer.Stop()
+		chanTimeout = timer.C
+	}
+
 	select {
 	case proc.chanCheckRequests <- req:
 	case <-proc.chanClose:
 		return process.CheckedChunkResult{}, process.ErrProcessClosed
+	case <-chanTimeout:
+		return process.CheckedChunkResult{}, fmt.Errorf("%w in trieNodeChunksProcessor.CheckBatch for reference %x, chunk index %d",
+			process.ErrRequestTimeout, b.Reference, b.ChunkIndex)
 	}
 
 	select {
 	case response := <-respChan:
-		return response, nil
+		return response.result, response.err
 	case <-proc.chanClose:
 		return process.CheckedChunkResult{}, process.ErrProcessClosed
+	case <-chanTimeout:
+		return process.CheckedChunkResult{}, fmt.Errorf("%w in trieNodeChunksProcessor.CheckBatch for reference %x, chunk index %d",
+			process.ErrRequestTimeout, b.Reference, b.ChunkIndex)
 	}
 }
 
@@ -142,48 +415,545 @@ func (proc *trieNodeChunksProcessor) processCheckRequest(cr checkRequest) {
 		if shouldNotCreateChunk {
 			//we received other chunks from a previous, completed large trie node, return
 
-			proc.writeCheckedChunkResultOnChan(cr, result)
+			proc.writeCheckedChunkResultOnChan(cr, result, nil)
 			return
 		}
 
 		chunkObject = chunk.NewChunk(cr.batch.MaxChunks, cr.batch.Reference)
+		proc.markAssemblyStart(cr.batch.Reference)
+		proc.markReferenceFirstSeen(cr.batch.Reference)
+		proc.resetChunkContributors(cr.batch.Reference)
+		result.FirstChunkForReference = true
 	}
 	chunkData, ok := chunkObject.(chunkHandler)
 	if !ok {
 		if shouldNotCreateChunk {
 			//we received other chunks from a previous, completed large trie node, return
-			proc.writeCheckedChunkResultOnChan(cr, result)
+			proc.writeCheckedChunkResultOnChan(cr, result, nil)
 			return
 		}
 
 		chunkData = chunk.NewChunk(cr.batch.MaxChunks, cr.batch.Reference)
+		proc.markAssemblyStart(cr.batch.Reference)
+		proc.markReferenceFirstSeen(cr.batch.Reference)
+		proc.resetChunkContributors(cr.batch.Reference)
+		result.FirstChunkForReference = true
+	}
+
+	err := proc.checkChunkIndexInWindow(cr.batch, chunkData)
+	if err != nil {
+		proc.writeCheckedChunkResultOnChan(cr, process.CheckedChunkResult{}, err)
+		return
+	}
+
+	err = proc.reserveMemoryForChunk(cr.batch, chunkData)
+	if err != nil {
+		proc.writeCheckedChunkResultOnChan(cr, process.CheckedChunkResult{}, err)
+		return
 	}
 
 	chunkData.Put(cr.batch.ChunkIndex, cr.batch.Data[0])
+	proc.recordChunkContributor(cr)
+	delete(proc.mapRequestBackoffs, string(cr.batch.Reference))
 
 	result.CompleteBuffer = chunkData.TryAssembleAllChunks()
 	result.HaveAllChunks = len(result.CompleteBuffer) > 0
+	if result.HaveAllChunks && !proc.isAssemblyMatchingReference(cr.batch.Reference, result.CompleteBuffer) {
+		proc.removeReference(string(cr.batch.Reference))
+		proc.logDebug("trieNodeChunksProcessor: discarded assembly not matching its reference", "reference", cr.batch.Reference,
+			"size", len(result.CompleteBuffer))
+
+		result.CompleteBuffer = nil
+		result.HaveAllChunks = false
+		proc.writeCheckedChunkResultOnChan(cr, result, nil)
+		return
+	}
 	if result.HaveAllChunks {
 		proc.chunksCacher.Remove(cr.batch.Reference)
+		proc.removePendingReference(cr.batch.Reference)
+		proc.markAssemblyEnd(cr.batch.Reference)
+		delete(proc.mapReferenceFirstSeen, string(cr.batch.Reference))
+		proc.notifyAssemblyComplete(cr.batch.Reference)
+		proc.notifyChunkComplete(cr.batch.Reference, result.CompleteBuffer)
+		proc.removeCheckpoint(string(cr.batch.Reference))
 	} else {
 		proc.chunksCacher.Put(cr.batch.Reference, chunkData, chunkData.Size())
+		proc.updatePendingReference(cr.batch.Reference, chunkData.Size())
+		proc.deliverNewPrefix(cr.batch.Reference, chunkData)
+	}
+
+	proc.writeCheckedChunkResultOnChan(cr, result, nil)
+}
+
+// isAssemblyMatchingReference returns true if the assembled large trie node hashes to its reference, so that the chunks
+// sent by a peer can not make the processor deliver a different trie node
+func (proc *trieNodeChunksProcessor) isAssemblyMatchingReference(reference []byte, buff []byte) bool {
+	return bytes.Equal(proc.hasher.Compute(string(buff)), reference)
+}
+
+// removeReference drops the chunks received for the reference together with all its bookkeeping and its checkpoint
+func (proc *trieNodeChunksProcessor) removeReference(reference string) {
+	proc.chunksCacher.Remove([]byte(reference))
+	proc.removePendingReference([]byte(reference))
+	proc.removeCheckpoint(reference)
+	delete(proc.mapAssemblyStartTimes, reference)
+	delete(proc.mapChunkContributors, reference)
+	delete(proc.mapReferenceFirstSeen, reference)
+	delete(proc.mapRequestBackoffs, reference)
+}
+
+// checkChunkIndexInWindow rejects the chunk indexes which are too far ahead of the contiguous frontier of the received
+// chunks, so that a peer can not make the assembly of a large trie node arbitrarily sparse
+func (proc *trieNodeChunksProcessor) checkChunkIndexInWindow(b *batch.Batch, chunkData chunkHandler) error {
+	if proc.chunkIndexAcceptWindow == 0 {
+		return nil
+	}
+	if b.ChunkIndex >= b.MaxChunks {
+		return fmt.Errorf("%w for reference %x, chunk index %d, max chunks %d",
+			process.ErrChunkIndexOutOfWindow, b.Reference, b.ChunkIndex, b.MaxChunks)
+	}
+
+	frontier := chunkData.GetContiguousFrontier()
+	if uint64(b.ChunkIndex) > uint64(frontier)+uint64(proc.chunkIndexAcceptWindow) {
+		return fmt.Errorf("%w for reference %x, chunk index %d, contiguous frontier %d, window %d",
+			process.ErrChunkIndexOutOfWindow, b.Reference, b.ChunkIndex, frontier, proc.chunkIndexAcceptWindow)
+	}
+
+	return nil
+}
+
+// reserveMemoryForChunk evicts other pending references until the received chunk fits in the global memory budget. The
+// chunk is rejected if it does not fit even after all the other references were evicted
+func (proc *trieNodeChunksProcessor) reserveMemoryForChunk(b *batch.Batch, chunkData chunkHandler) error {
+	if proc.globalMemoryBudget == 0 {
+		return nil
+	}
+
+	// upper bound, as the chunk might replace a chunk with the same index
+	requiredSize := uint64(chunkData.Size()) + uint64(len(b.Data[0]))
+	if requiredSize > proc.globalMemoryBudget {
+		return fmt.Errorf("%w for reference %x, chunk index %d, required size %d, budget %d",
+			process.ErrChunkMemoryBudgetExceeded, b.Reference, b.ChunkIndex, requiredSize, proc.globalMemoryBudget)
+	}
+
+	usedSize := proc.pendingReferencesSize
+	pending, found := proc.mapPendingReferences[string(b.Reference)]
+	if found {
+		usedSize -= pending.size
+	}
+
+	for usedSize+requiredSize > proc.globalMemoryBudget {
+		evictedSize, evicted := proc.evictPendingReference(b.Reference)
+		if !evicted {
+			return fmt.Errorf("%w for reference %x, chunk index %d, required size %d, used size %d, budget %d",
+				process.ErrChunkMemoryBudgetExceeded, b.Reference, b.ChunkIndex, requiredSize, usedSize, proc.globalMemoryBudget)
+		}
+
+		usedSize -= evictedSize
+	}
+
+	return nil
+}
+
+// evictPendingReference removes the least complete pending reference, other than the provided one. The oldest reference
+// is evicted between the references with the same completeness
+func (proc *trieNodeChunksProcessor) evictPendingReference(keptReference []byte) (uint64, bool) {
+	var candidate string
+	var candidatePending *pendingReference
+	var candidatePresent, candidateTotal uint64
+	for reference, pending := range proc.mapPendingReferences {
+		if reference == string(keptReference) {
+			continue
+		}
+
+		present, total := proc.getReferenceCompleteness([]byte(reference))
+		if candidatePending != nil {
+			// compares present/total against candidatePresent/candidateTotal without divisions
+			isLessComplete := present*candidateTotal < candidatePresent*total
+			isSameCompleteness := present*candidateTotal == candidatePresent*total
+			isOlder := pending.sequence < candidatePending.sequence
+			if !isLessComplete && !(isSameCompleteness && isOlder) {
+				continue
+			}
+		}
+
+		candidate = reference
+		candidatePending = pending
+		candidatePresent, candidateTotal = present, total
+	}
+	if candidatePending == nil {
+		return 0, false
+	}
+
+	proc.removeReference(candidate)
+	proc.logTrace("trieNodeChunksProcessor.evictPendingReference", "reference", []byte(candidate),
+		"size", candidatePending.size, "present chunks", candidatePresent, "total chunks", candidateTotal)
+
+	return candidatePending.size, true
+}
+
+// getReferenceCompleteness returns the number of present chunks and the total number of chunks of a reference. A
+// reference no longer held by the cacher has no present chunks
+func (proc *trieNodeChunksProcessor) getReferenceCompleteness(reference []byte) (uint64, uint64) {
+	data, found := proc.chunksCacher.Get(reference)
+	if !found {
+		return 0, 1
+	}
+
+	chunkData, ok := data.(chunkHandler)
+	if !ok {
+		return 0, 1
+	}
+
+	present := uint64(len(chunkData.GetAllPresentChunkIndexes()))
+	total := present + uint64(len(chunkData.GetAllMissingChunkIndexes()))
+	if total == 0 {
+		return 0, 1
+	}
+
+	return present, total
+}
+
+func (proc *trieNodeChunksProcessor) updatePendingReference(reference []byte, size int) {
+	if proc.globalMemoryBudget == 0 {
+		return
+	}
+
+	pending, found := proc.mapPendingReferences[string(reference)]
+	if !found {
+		pending = &pendingReference{
+			sequence: proc.nextReferenceSequence,
+		}
+		proc.nextReferenceSequence++
+		proc.mapPendingReferences[string(reference)] = pending
+	}
+
+	proc.pendingReferencesSize -= pending.size
+	pending.size = uint64(size)
+	proc.pendingReferencesSize += pending.size
+}
+
+func (proc *trieNodeChunksProcessor) removePendingReference(reference []byte) {
+	pending, found := proc.mapPendingReferences[string(reference)]
+	if !found {
+		return
+	}
+
+	proc.pendingReferencesSize -= pending.size
+	delete(proc.mapPendingReferences, string(reference))
+}
+
+func (proc *trieNodeChunksProcessor) resetChunkContributors(reference []byte) {
+	if proc.onAssemblyComplete == nil {
+		return
+	}
+
+	delete(proc.mapChunkContributors, string(reference))
+}
+
+// recordChunkContributor saves the sender of the received chunk. A chunk received again is attributed to its last sender,
+// as its data is the one used in the assembly
+func (proc *trieNodeChunksProcessor) recordChunkContributor(cr checkRequest) {
+	if proc.onAssemblyComplete == nil || cr.batch.ChunkIndex >= cr.batch.MaxChunks {
+		return
+	}
+
+	contributors, found := proc.mapChunkContributors[string(cr.batch.Reference)]
+	if !found {
+		contributors = make(map[uint32]core.PeerID)
+		proc.mapChunkContributors[string(cr.batch.Reference)] = contributors
+	}
+
+	contributors[cr.batch.ChunkIndex] = cr.sender
+}
+
+func (proc *trieNodeChunksProcessor) notifyAssemblyComplete(reference []byte) {
+	if proc.onAssemblyComplete == nil {
+		return
+	}
+
+	chunkContributors := proc.mapChunkContributors[string(reference)]
+	delete(proc.mapChunkContributors, string(reference))
+
+	contributors := make(map[core.PeerID]int)
+	for _, sender := range chunkContributors {
+		contributors[sender]++
+	}
+
+	proc.onAssemblyComplete(reference, contributors)
+}
+
+// notifyChunkComplete queues the assembled buffer for the handler, so that a slow handler does not block the process
+// loop until the queue is full. The notification is dropped if the processor is closed
+func (proc *trieNodeChunksProcessor) notifyChunkComplete(reference []byte, buff []byte) {
+	if proc.onChunkComplete == nil {
+		return
+	}
+
+	select {
+	case proc.chanChunkCompletions <- chunkCompletion{reference: reference, buff: buff}:
+	case <-proc.chanClose:
+	}
+}
+
+// chunkCompletionLoop calls the handler for the queued assembled buffers, one at a time, until the processor is closed
+func (proc *trieNodeChunksProcessor) chunkCompletionLoop() {
+	for {
+		select {
+		case completion := <-proc.chanChunkCompletions:
+			proc.onChunkComplete(completion.reference, completion.buff)
+		case <-proc.chanClose:
+			return
+		}
+	}
+}
+
+func (proc *trieNodeChunksProcessor) markAssemblyStart(reference []byte) {
+	if !proc.isAdaptiveInterval {
+		return
+	}
+
+	proc.mapAssemblyStartTimes[string(reference)] = time.Now()
+}
+
+func (proc *trieNodeChunksProcessor) markReferenceFirstSeen(reference []byte) {
+	if proc.maxWaitTime == 0 {
+		return
 	}
 
-	proc.writeCheckedChunkResultOnChan(cr, result)
+	proc.mapReferenceFirstSeen[string(reference)] = time.Now()
+}
+
+func (proc *trieNodeChunksProcessor) markAssemblyEnd(reference []byte) {
+	if !proc.isAdaptiveInterval {
+		return
+	}
+
+	startTime, found := proc.mapAssemblyStartTimes[string(reference)]
+	if !found {
+		return
+	}
+
+	delete(proc.mapAssemblyStartTimes, string(reference))
+	proc.adaptRequestInterval(time.Since(startTime))
+}
+
+// adaptRequestInterval moves the request interval towards the observed assembly latency, within the configured bounds:
+// faster assemblies shorten the interval while slower ones lengthen it
+func (proc *trieNodeChunksProcessor) adaptRequestInterval(assemblyLatency time.Duration) {
+	currentInterval := proc.getRequestInterval()
+	newInterval := (currentInterval*latencySmoothingFactor + assemblyLatency) / (latencySmoothingFactor + 1)
+	if newInterval < proc.minRequestInterval {
+		newInterval = proc.minRequestInterval
+	}
+	if newInterval > proc.maxRequestInterval {
+		newInterval = proc.maxRequestInterval
+	}
+
+	atomic.StoreInt64(&proc.requestInterval, int64(newInterval))
+	proc.logTrace("trieNodeChunksProcessor.adaptRequestInterval", "assembly latency", assemblyLatency, "request interval", newInterval)
+}
+
+// logDebug logs the message together with the topic and shard of this instance, so the log lines of the processors
+// syncing different tries can be told apart
+func (proc *trieNodeChunksProcessor) logDebug(message string, args ...interface{}) {
+	proc.logger.Debug(message, proc.withLogContext(args)...)
+}
+
+func (proc *trieNodeChunksProcessor) logTrace(message string, args ...interface{}) {
+	proc.logger.Trace(message, proc.withLogContext(args)...)
 }
 
-func (proc *trieNodeChunksProcessor) writeCheckedChunkResultOnChan(cr checkRequest, result process.CheckedChunkResult) {
+func (proc *trieNodeChunksProcessor) withLogContext(args []interface{}) []interface{} {
+	argsWithContext := make([]interface{}, 0, len(proc.logContext)+len(args))
+	argsWithContext = append(argsWithContext, proc.logContext...)
+
+	return append(argsWithContext, args...)
+}
+
+func (proc *trieNodeChunksProcessor) getRequestInterval() time.Duration {
+	return time.Duration(atomic.LoadInt64(&proc.requestInterval))
+}
+
+func (proc *trieNodeChunksProcessor) deliverNewPrefix(reference []byte, chunkData chunkHandler) {
+	if !proc.deliverPartialData {
+		return
+	}
+
+	offsetStart, data := chunkData.GetNewContiguousPrefix()
+	if data == nil {
+		return
+	}
+
+	proc.partialDataHandler(reference, offsetStart, data)
+}
+
+func (proc *trieNodeChunksProcessor) writeCheckedChunkResultOnChan(cr checkRequest, result process.CheckedChunkResult, err error) {
+	response := checkResponse{
+		result: result,
+		err:    err,
+	}
+
 	select {
-	case cr.chanResponse <- result:
+	case cr.chanResponse <- response:
 	default:
-		log.Trace("trieNodeChunksProcessor.processCheckRequest - no one is listening on the end chan")
+		proc.logTrace("trieNodeChunksProcessor.processCheckRequest - no one is listening on the end chan")
+	}
+}
+
+// GetPresentRanges returns the contiguous ranges of chunk indexes already received for the provided reference
+func (proc *trieNodeChunksProcessor) GetPresentRanges(reference []byte) []Range {
+	respChan := make(chan []Range, 1)
+	req := presentRangesRequest{
+		reference:    reference,
+		chanResponse: respChan,
+	}
+
+	select {
+	case proc.chanPresentRangesRequests <- req:
+	case <-proc.chanClose:
+		return make([]Range, 0)
+	}
+
+	select {
+	case response := <-respChan:
+		return response
+	case <-proc.chanClose:
+		return make([]Range, 0)
+	}
+}
+
+func (proc *trieNodeChunksProcessor) processPresentRangesRequest(req presentRangesRequest) {
+	ranges := make([]Range, 0)
+	defer func() {
+		req.chanResponse <- ranges
+	}()
+
+	data, found := proc.chunksCacher.Get(req.reference)
+	if !found {
+		return
+	}
+
+	chunkData, ok := data.(chunkHandler)
+	if !ok {
+		return
+	}
+
+	ranges = computeRanges(chunkData.GetAllPresentChunkIndexes())
+}
+
+// Stats returns the number of large trie nodes being assembled and the total number of their missing chunks
+func (proc *trieNodeChunksProcessor) Stats() (numReferences int, numMissingChunks int) {
+	respChan := make(chan chunksStats, 1)
+	req := statsRequest{
+		chanResponse: respChan,
+	}
+
+	select {
+	case proc.chanStatsRequests <- req:
+	case <-proc.chanClose:
+		return 0, 0
+	}
+
+	select {
+	case response := <-respChan:
+		return response.numReferences, response.numMissingChunks
+	case <-proc.chanClose:
+		return 0, 0
+	}
+}
+
+func (proc *trieNodeChunksProcessor) computeStats() chunksStats {
+	stats := chunksStats{}
+	for _, reference := range proc.chunksCacher.Keys() {
+		data, found := proc.chunksCacher.Get(reference)
+		if !found {
+			continue
+		}
+
+		chunkData, ok := data.(chunkHandler)
+		if !ok {
+			continue
+		}
+
+		stats.numReferences++
+		stats.numMissingChunks += len(chunkData.GetAllMissingChunkIndexes())
 	}
+
+	return stats
+}
+
+// Reset drops all the large trie nodes being assembled, together with their request backoffs and timestamps, so that
+// a trie sync restarted from scratch does not request them anymore
+func (proc *trieNodeChunksProcessor) Reset() {
+	respChan := make(chan struct{}, 1)
+	req := resetRequest{
+		chanResponse: respChan,
+	}
+
+	select {
+	case proc.chanResetRequests <- req:
+	case <-proc.chanClose:
+		return
+	}
+
+	select {
+	case <-respChan:
+	case <-proc.chanClose:
+	}
+}
+
+func (proc *trieNodeChunksProcessor) processResetRequest(req resetRequest) {
+	numReferences := proc.chunksCacher.Len()
+	proc.chunksCacher.Clear()
+	proc.removeAllCheckpoints()
+	proc.mapAssemblyStartTimes = make(map[string]time.Time)
+	proc.mapChunkContributors = make(map[string]map[uint32]core.PeerID)
+	proc.mapPendingReferences = make(map[string]*pendingReference)
+	proc.pendingReferencesSize = 0
+	proc.mapReferenceFirstSeen = make(map[string]time.Time)
+	proc.mapRequestBackoffs = make(map[string]*requestBackoff)
+	proc.logDebug("trieNodeChunksProcessor.Reset", "num dropped references", numReferences)
+
+	req.chanResponse <- struct{}{}
+}
+
+func (proc *trieNodeChunksProcessor) publishStats() {
+	if check.IfNil(proc.statusHandler) {
+		return
+	}
+
+	stats := proc.computeStats()
+	proc.statusHandler.SetUInt64Value(common.MetricTrieNodeChunksReferences, uint64(stats.numReferences))
+	proc.statusHandler.SetUInt64Value(common.MetricTrieNodeChunksMissing, uint64(stats.numMissingChunks))
+}
+
+func computeRanges(sortedIndexes []uint32) []Range {
+	ranges := make([]Range, 0)
+	for _, index := range sortedIndexes {
+		lastPos := len(ranges) - 1
+		if lastPos >= 0 && ranges[lastPos].End+1 == index {
+			ranges[lastPos].End = index
+			continue
+		}
+
+		ranges = append(ranges, Range{
+			Start: index,
+			End:   index,
+		})
+	}
+
+	return ranges
 }
 
 func (proc *trieNodeChunksProcessor) batchIsValid(b *batch.Batch, whiteListHandler process.WhiteListHandler) (bool, error) {
 	if b.MaxChunks < 2 {
 		return false, nil
 	}
+	if b.MaxChunks > proc.maxChunksAllowed {
+		return false, fmt.Errorf("%w for reference %x, max chunks %d, max chunks allowed %d",
+			process.ErrInvalidValue, b.Reference, b.MaxChunks, proc.maxChunksAllowed)
+	}
 	if len(b.Reference) != proc.hasher.Size() {
 		return false, process.ErrIncompatibleReference
 	}
@@ -206,6 +976,15 @@ func (proc *trieNodeChunksProcessor) batchIsValid(b *batch.Batch, whiteListHandl
 }
 
 func (proc *trieNodeChunksProcessor) doRequests(ctx context.Context) {
+	proc.removeExpiredReferences()
+	proc.removeStaleAssemblyStartTimes()
+	proc.removeStaleChunkContributors()
+	proc.removeStalePendingReferences()
+	proc.removeStaleRequestBackoffs()
+	proc.publishStats()
+	proc.checkpointPendingReferences()
+
+	now := time.Now()
 	references := proc.chunksCacher.Keys()
 	for _, ref := range references {
 		select {
@@ -215,10 +994,104 @@ func (proc *trieNodeChunksProcessor) doRequests(ctx context.Context) {
 		default:
 		}
 
+		if !proc.shouldRequestReference(ref, now) {
+			continue
+		}
+
 		proc.requestMissingForReference(ref, ctx)
 	}
 }
 
+// shouldRequestReference returns true if the backoff of the reference elapsed at the given time, in which case the
+// request is also recorded, doubling the backoff interval for the next one
+func (proc *trieNodeChunksProcessor) shouldRequestReference(reference []byte, now time.Time) bool {
+	if proc.maxBackoffExponent == 0 {
+		return true
+	}
+
+	backoff, found := proc.mapRequestBackoffs[string(reference)]
+	if !found {
+		backoff = &requestBackoff{}
+		proc.mapRequestBackoffs[string(reference)] = backoff
+	}
+	if now.Before(backoff.nextRequestTime) {
+		return false
+	}
+
+	backoff.numRequests++
+	backoff.nextRequestTime = now.Add(proc.computeBackoffInterval(backoff.numRequests))
+
+	return true
+}
+
+func (proc *trieNodeChunksProcessor) computeBackoffInterval(numRequests uint32) time.Duration {
+	exponent := numRequests
+	if exponent > proc.maxBackoffExponent {
+		exponent = proc.maxBackoffExponent
+	}
+
+	interval := proc.getRequestInterval() * time.Duration(uint64(1)<<exponent)
+	if proc.maxRequestInterval > 0 && interval > proc.maxRequestInterval {
+		interval = proc.maxRequestInterval
+	}
+
+	return interval
+}
+
+// removeExpiredReferences drops the incomplete references assembled for more than the max wait time, so that they are no
+// longer requested
+func (proc *trieNodeChunksProcessor) removeExpiredReferences() {
+	for reference, firstSeen := range proc.mapReferenceFirstSeen {
+		if !proc.chunksCacher.Has([]byte(reference)) {
+			delete(proc.mapReferenceFirstSeen, reference)
+			continue
+		}
+
+		waitTime := time.Since(firstSeen)
+		if waitTime <= proc.maxWaitTime {
+			continue
+		}
+
+		present, total := proc.getReferenceCompleteness([]byte(reference))
+		proc.removeReference(reference)
+		proc.logDebug("trieNodeChunksProcessor: abandoned incomplete reference", "reference", []byte(reference),
+			"wait time", waitTime, "present chunks", present, "total chunks", total)
+	}
+}
+
+func (proc *trieNodeChunksProcessor) removeStaleAssemblyStartTimes() {
+	for reference := range proc.mapAssemblyStartTimes {
+		if !proc.chunksCacher.Has([]byte(reference)) {
+			delete(proc.mapAssemblyStartTimes, reference)
+		}
+	}
+}
+
+func (proc *trieNodeChunksProcessor) removeStaleChunkContributors() {
+	for reference := range proc.mapChunkContributors {
+		if !proc.chunksCacher.Has([]byte(reference)) {
+			delete(proc.mapChunkContributors, reference)
+		}
+	}
+}
+
+// removeStalePendingReferences releases the memory accounted for the references evicted by the chunks cacher
+func (proc *trieNodeChunksProcessor) removeStalePendingReferences() {
+	for reference := range proc.mapPendingReferences {
+		if !proc.chunksCacher.Has([]byte(reference)) {
+			proc.removePendingReference([]byte(reference))
+		}
+	}
+}
+
+func (proc *trieNodeChunksProcessor) removeStaleRequestBackoffs() {
+	for reference := range proc.mapRequestBackoffs {
+		if !proc.chunksCacher.Has([]byte(reference)) {
+			delete(proc.mapRequestBackoffs, reference)
+		}
+	}
+}
+
 func (proc *trieNodeChunksProcessor) requestMissingForReference(reference []byte, ctx context.Context) {
 	data, found := proc.chunksCacher.Get(reference)
 	if !found {
@@ -243,15 +1116,26 @@ func (proc *trieNodeChunksProcessor) requestMissingForReference(reference []byte
 	}
 }
 
-// Close will close the process go routine
+func (proc *trieNodeChunksProcessor) isClosed() bool {
+	select {
+	case <-proc.chanClose:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close will close the process go routine. Subsequent calls are no-ops and return nil
 func (proc *trieNodeChunksProcessor) Close() error {
-	log.Debug("trieNodeChunkProcessor.Close()", "key", proc.topic)
-	defer func() {
+	proc.closeOnce.Do(func() {
+		proc.logDebug("trieNodeChunkProcessor.Close()")
+		proc.cancel()
+
 		//this instruction should be called last as to release hanging go routines
 		close(proc.chanClose)
-	}()
+		proc.waitCheckpointWriter()
+	})
 
-	proc.cancel()
 	return nil
 }
 
@@ -2,15 +2,21 @@ package processor
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/ElrondNetwork/elrond-go-core/core"
 	"github.com/ElrondNetwork/elrond-go-core/core/check"
 	"github.com/ElrondNetwork/elrond-go-core/data/batch"
+	"github.com/ElrondNetwork/elrond-go/common"
 	"github.com/ElrondNetwork/elrond-go/process"
 	"github.com/ElrondNetwork/elrond-go/testscommon"
+	"github.com/ElrondNetwork/elrond-go/testscommon/statusHandler"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -30,6 +36,9 @@ func createMockTrieNodesChunksProcessorArgs() TrieNodesChunksProcessorArgs {
 			SizeCalled: func() int {
 				return 32
 			},
+			ComputeCalled: func(s string) []byte {
+				return reference
+			},
 		},
 		ChunksCacher:    testscommon.NewCacherMock(),
 		RequestInterval: time.Second,
@@ -88,6 +97,52 @@ func TestNewTrieNodeChunksProcessor_EmptyTopic(t *testing.T) {
 	assert.True(t, check.IfNil(tncp))
 }
 
+func TestNewTrieNodeChunksProcessor_NilPartialDataHandler(t *testing.T) {
+	t.Parallel()
+
+	args := createMockTrieNodesChunksProcessorArgs()
+	args.DeliverPartialData = true
+	tncp, err := NewTrieNodeChunksProcessor(args)
+	assert.True(t, errors.Is(err, process.ErrNilPartialChunksHandler))
+	assert.True(t, check.IfNil(tncp))
+}
+
+func TestNewTrieNodeChunksProcessor_InvalidAdaptiveInterval(t *testing.T) {
+	t.Parallel()
+
+	args := createMockTrieNodesChunksProcessorArgs()
+	args.MinRequestInterval = minimumRequestTimeInterval - time.Nanosecond
+	args.MaxRequestInterval = time.Second
+	tncp, err := NewTrieNodeChunksProcessor(args)
+	assert.True(t, errors.Is(err, process.ErrInvalidValue))
+	assert.True(t, check.IfNil(tncp))
+
+	args.MinRequestInterval = time.Second * 2
+	tncp, err = NewTrieNodeChunksProcessor(args)
+	assert.True(t, errors.Is(err, process.ErrInvalidValue))
+	assert.True(t, check.IfNil(tncp))
+}
+
+func TestNewTrieNodeChunksProcessor_InvalidMaxWaitTime(t *testing.T) {
+	t.Parallel()
+
+	args := createMockTrieNodesChunksProcessorArgs()
+	args.MaxWaitTime = -time.Second
+	tncp, err := NewTrieNodeChunksProcessor(args)
+	assert.True(t, errors.Is(err, process.ErrInvalidValue))
+	assert.True(t, check.IfNil(tncp))
+}
+
+func TestNewTrieNodeChunksProcessor_InvalidCheckBatchTimeout(t *testing.T) {
+	t.Parallel()
+
+	args := createMockTrieNodesChunksProcessorArgs()
+	args.CheckBatchTimeout = -time.Second
+	tncp, err := NewTrieNodeChunksProcessor(args)
+	assert.True(t, errors.Is(err, process.ErrInvalidValue))
+	assert.True(t, check.IfNil(tncp))
+}
+
 func TestNewTrieNodeChunksProcessor_ShouldWork(t *testing.T) {
 	t.Parallel()
 
@@ -98,6 +153,30 @@ func TestNewTrieNodeChunksProcessor_ShouldWork(t *testing.T) {
 	assert.Nil(t, tncp.Close())
 }
 
+func TestNewTrieNodeChunksProcessor_ShouldLogWithInstanceContext(t *testing.T) {
+	t.Parallel()
+
+	mutLoggedArgs := sync.Mutex{}
+	loggedArgs := make(map[string][]interface{})
+	args := createMockTrieNodesChunksProcessorArgs()
+	args.ShardID = 2
+	args.Logger = &testscommon.LoggerStub{
+		DebugCalled: func(message string, args ...interface{}) {
+			mutLoggedArgs.Lock()
+			loggedArgs[message] = args
+			mutLoggedArgs.Unlock()
+		},
+	}
+	tncp, _ := NewTrieNodeChunksProcessor(args)
+	assert.Nil(t, tncp.Close())
+
+	expectedArgs := []interface{}{"topic", "topic", "shard", uint32(2)}
+	mutLoggedArgs.Lock()
+	defer mutLoggedArgs.Unlock()
+	assert.Equal(t, expectedArgs, loggedArgs["NewTrieNodeChunksProcessor"])
+	assert.Equal(t, expectedArgs, loggedArgs["trieNodeChunkProcessor.Close()"])
+}
+
 func TestTrieNodeChunksProcessor_CheckBatchInvalidBatch(t *testing.T) {
 	t.Parallel()
 
@@ -113,6 +192,7 @@ func TestTrieNodeChunksProcessor_CheckBatchInvalidBatch(t *testing.T) {
 			MaxChunks:  1,
 		},
 		createMockWhiteLister(true),
+		"pid",
 	)
 	assert.Nil(t, err)
 	assert.Equal(t, emptyCheckedChunkResult, chunkResult)
@@ -125,6 +205,7 @@ func TestTrieNodeChunksProcessor_CheckBatchInvalidBatch(t *testing.T) {
 			MaxChunks:  2,
 		},
 		createMockWhiteLister(true),
+		"pid",
 	)
 	assert.Equal(t, err, process.ErrIncompatibleReference)
 	assert.Equal(t, emptyCheckedChunkResult, chunkResult)
@@ -137,12 +218,60 @@ func TestTrieNodeChunksProcessor_CheckBatchInvalidBatch(t *testing.T) {
 			MaxChunks:  2,
 		},
 		createMockWhiteLister(true),
+		"pid",
 	)
 	assert.Nil(t, err)
 	assert.Equal(t, emptyCheckedChunkResult, chunkResult)
 	_ = tncp.Close()
 }
 
+func TestTrieNodeChunksProcessor_CheckBatchWithMaxChunksAllowed(t *testing.T) {
+	t.Parallel()
+
+	createBatch := func(maxChunks uint32) *batch.Batch {
+		return &batch.Batch{
+			Data:       [][]byte{[]byte("buff1")},
+			Reference:  reference,
+			ChunkIndex: 0,
+			MaxChunks:  maxChunks,
+		}
+	}
+
+	t.Run("configured limit", func(t *testing.T) {
+		t.Parallel()
+
+		args := createMockTrieNodesChunksProcessorArgs()
+		args.MaxChunksAllowed = 10
+		tncp, _ := NewTrieNodeChunksProcessor(args)
+
+		chunkResult, err := tncp.CheckBatch(createBatch(10), createMockWhiteLister(true), "pid")
+		assert.Nil(t, err)
+		assert.True(t, chunkResult.IsChunk)
+
+		chunkResult, err = tncp.CheckBatch(createBatch(11), createMockWhiteLister(true), "pid")
+		assert.True(t, errors.Is(err, process.ErrInvalidValue))
+		assert.Equal(t, process.CheckedChunkResult{}, chunkResult)
+
+		_ = tncp.Close()
+	})
+	t.Run("default limit", func(t *testing.T) {
+		t.Parallel()
+
+		args := createMockTrieNodesChunksProcessorArgs()
+		tncp, _ := NewTrieNodeChunksProcessor(args)
+
+		chunkResult, err := tncp.CheckBatch(createBatch(defaultMaxChunksAllowed), createMockWhiteLister(true), "pid")
+		assert.Nil(t, err)
+		assert.True(t, chunkResult.IsChunk)
+
+		chunkResult, err = tncp.CheckBatch(createBatch(4000000000), createMockWhiteLister(true), "pid")
+		assert.True(t, errors.Is(err, process.ErrInvalidValue))
+		assert.Equal(t, process.CheckedChunkResult{}, chunkResult)
+
+		_ = tncp.Close()
+	})
+}
+
 func TestTrieNodeChunksProcessor_NilWhitelistHandler(t *testing.T) {
 	t.Parallel()
 
@@ -158,6 +287,7 @@ func TestTrieNodeChunksProcessor_NilWhitelistHandler(t *testing.T) {
 			MaxChunks:  2,
 		},
 		nil,
+		"pid",
 	)
 	assert.Equal(t, process.ErrNilWhiteListHandler, err)
 	assert.Equal(t, emptyCheckedChunkResult, chunkResult)
@@ -180,6 +310,7 @@ func TestTrieNodeChunksProcessor_NotWhiteListed(t *testing.T) {
 			MaxChunks:  2,
 		},
 		createMockWhiteLister(false),
+		"pid",
 	)
 	assert.Equal(t, process.ErrTrieNodeIsNotWhitelisted, err)
 	assert.Equal(t, emptyCheckedChunkResult, chunkResult)
@@ -191,9 +322,10 @@ func TestTrieNodeChunksProcessor_CheckBatchShouldWork(t *testing.T) {
 	t.Parallel()
 
 	expectedCheckedChunkResult := process.CheckedChunkResult{
-		IsChunk:        true,
-		HaveAllChunks:  false,
-		CompleteBuffer: nil,
+		IsChunk:                true,
+		HaveAllChunks:          false,
+		FirstChunkForReference: true,
+		CompleteBuffer:         nil,
 	}
 
 	args := createMockTrieNodesChunksProcessorArgs()
@@ -206,6 +338,7 @@ func TestTrieNodeChunksProcessor_CheckBatchShouldWork(t *testing.T) {
 			MaxChunks:  2,
 		},
 		createMockWhiteLister(true),
+		"pid",
 	)
 	assert.Nil(t, err)
 	assert.Equal(t, expectedCheckedChunkResult, chunkResult)
@@ -219,6 +352,7 @@ func TestTrieNodeChunksProcessor_CheckBatchShouldWork(t *testing.T) {
 			MaxChunks:  2,
 		},
 		createMockWhiteLister(true),
+		"pid",
 	)
 	assert.Nil(t, err)
 
@@ -233,6 +367,63 @@ func TestTrieNodeChunksProcessor_CheckBatchShouldWork(t *testing.T) {
 	_ = tncp.Close()
 }
 
+func TestTrieNodeChunksProcessor_CheckBatchShouldVerifyTheAssemblyAgainstTheReference(t *testing.T) {
+	t.Parallel()
+
+	goodBuffer := []byte("buff1buff2")
+	args := createMockTrieNodesChunksProcessorArgs()
+	args.Hasher = &testscommon.HasherStub{
+		SizeCalled: func() int {
+			return 32
+		},
+		ComputeCalled: func(s string) []byte {
+			if s == string(goodBuffer) {
+				return reference
+			}
+
+			return bytes.Repeat([]byte{2}, 32)
+		},
+	}
+	args.MaxWaitTime = time.Hour
+	args.MaxBackoffExponent = 1
+	tncp, _ := NewTrieNodeChunksProcessor(args)
+	defer func() {
+		_ = tncp.Close()
+	}()
+
+	checkBatch := func(chunkIndex uint32, data string) process.CheckedChunkResult {
+		chunkResult, err := tncp.CheckBatch(
+			&batch.Batch{
+				Data:       [][]byte{[]byte(data)},
+				Reference:  reference,
+				ChunkIndex: chunkIndex,
+				MaxChunks:  2,
+			},
+			createMockWhiteLister(true),
+			"pid",
+		)
+		assert.Nil(t, err)
+
+		return chunkResult
+	}
+
+	t.Run("hash mismatch should discard the assembly", func(t *testing.T) {
+		checkBatch(0, "buff1")
+		chunkResult := checkBatch(1, "garbage")
+		assert.Equal(t, process.CheckedChunkResult{IsChunk: true}, chunkResult)
+		assert.Equal(t, 0, args.ChunksCacher.Len())
+		assert.Equal(t, 0, len(tncp.mapReferenceFirstSeen))
+		assert.Equal(t, 0, len(tncp.mapRequestBackoffs))
+	})
+	t.Run("matching hash should deliver the assembly", func(t *testing.T) {
+		checkBatch(0, "buff1")
+		chunkResult := checkBatch(1, "buff2")
+		assert.True(t, chunkResult.HaveAllChunks)
+		assert.Equal(t, goodBuffer, chunkResult.CompleteBuffer)
+		assert.Equal(t, 0, args.ChunksCacher.Len())
+	})
+}
+
 func TestTrieNodeChunksProcessor_CheckBatchNotTheFirstBatch(t *testing.T) {
 	t.Parallel()
 
@@ -252,6 +443,7 @@ func TestTrieNodeChunksProcessor_CheckBatchNotTheFirstBatch(t *testing.T) {
 			MaxChunks:  2,
 		},
 		createMockWhiteLister(true),
+		"pid",
 	)
 	assert.Nil(t, err)
 	assert.Equal(t, expectedCheckedChunkResult, chunkResult)
@@ -267,12 +459,579 @@ func TestTrieNodeChunksProcessor_CheckBatchNotTheFirstBatch(t *testing.T) {
 			MaxChunks:  2,
 		},
 		createMockWhiteLister(true),
+		"pid",
 	)
 	assert.Nil(t, err)
 	assert.Equal(t, expectedCheckedChunkResult, chunkResult)
 	assert.Equal(t, 1, args.ChunksCacher.Len())
 }
 
+func TestTrieNodeChunksProcessor_CheckBatchShouldReportFirstChunkForReference(t *testing.T) {
+	t.Parallel()
+
+	args := createMockTrieNodesChunksProcessorArgs()
+	tncp, _ := NewTrieNodeChunksProcessor(args)
+
+	checkBatch := func(chunkIndex uint32) process.CheckedChunkResult {
+		chunkResult, err := tncp.CheckBatch(
+			&batch.Batch{
+				Data:       [][]byte{[]byte("buff")},
+				Reference:  reference,
+				ChunkIndex: chunkIndex,
+				MaxChunks:  3,
+			},
+			createMockWhiteLister(true),
+			"pid",
+		)
+		assert.Nil(t, err)
+
+		return chunkResult
+	}
+
+	assert.True(t, checkBatch(0).FirstChunkForReference)
+	assert.False(t, checkBatch(2).FirstChunkForReference)
+	chunkResult := checkBatch(1)
+	assert.False(t, chunkResult.FirstChunkForReference)
+	assert.True(t, chunkResult.HaveAllChunks)
+
+	assert.False(t, checkBatch(1).FirstChunkForReference)
+	assert.True(t, checkBatch(0).FirstChunkForReference)
+
+	_ = tncp.Close()
+}
+
+func TestTrieNodeChunksProcessor_CheckBatchWithChunkIndexAcceptWindow(t *testing.T) {
+	t.Parallel()
+
+	checkBatch := func(tncp *trieNodeChunksProcessor, chunkIndex uint32) error {
+		_, err := tncp.CheckBatch(
+			&batch.Batch{
+				Data:       [][]byte{[]byte("buff")},
+				Reference:  reference,
+				ChunkIndex: chunkIndex,
+				MaxChunks:  10,
+			},
+			createMockWhiteLister(true),
+			"pid",
+		)
+
+		return err
+	}
+
+	t.Run("full range window should accept all chunk indexes", func(t *testing.T) {
+		t.Parallel()
+
+		tncp, _ := NewTrieNodeChunksProcessor(createMockTrieNodesChunksProcessorArgs())
+		assert.Nil(t, checkBatch(tncp, 0))
+		assert.Nil(t, checkBatch(tncp, 9))
+		assert.Nil(t, checkBatch(tncp, 10))
+
+		_ = tncp.Close()
+	})
+	t.Run("should reject the chunk indexes too far ahead of the contiguous frontier", func(t *testing.T) {
+		t.Parallel()
+
+		args := createMockTrieNodesChunksProcessorArgs()
+		args.ChunkIndexAcceptWindow = 2
+		tncp, _ := NewTrieNodeChunksProcessor(args)
+		assert.Nil(t, checkBatch(tncp, 0))
+		assert.Nil(t, checkBatch(tncp, 3))
+		assert.True(t, errors.Is(checkBatch(tncp, 4), process.ErrChunkIndexOutOfWindow))
+
+		assert.Nil(t, checkBatch(tncp, 1))
+		assert.Nil(t, checkBatch(tncp, 2))
+		assert.Nil(t, checkBatch(tncp, 6))
+		assert.True(t, errors.Is(checkBatch(tncp, 7), process.ErrChunkIndexOutOfWindow))
+		assert.True(t, errors.Is(checkBatch(tncp, 10), process.ErrChunkIndexOutOfWindow))
+
+		_ = tncp.Close()
+	})
+}
+
+func TestTrieNodeChunksProcessor_CheckBatchShouldAttributeTheAssemblyToTheContributingPeers(t *testing.T) {
+	t.Parallel()
+
+	type assemblyAttribution struct {
+		reference    []byte
+		contributors map[core.PeerID]int
+	}
+
+	chanAttributions := make(chan assemblyAttribution, 1)
+	args := createMockTrieNodesChunksProcessorArgs()
+	args.OnAssemblyComplete = func(reference []byte, contributors map[core.PeerID]int) {
+		chanAttributions <- assemblyAttribution{
+			reference:    reference,
+			contributors: contributors,
+		}
+	}
+	tncp, _ := NewTrieNodeChunksProcessor(args)
+
+	checkBatch := func(chunkIndex uint32, sender core.PeerID) {
+		_, err := tncp.CheckBatch(
+			&batch.Batch{
+				Data:       [][]byte{[]byte("buff")},
+				Reference:  reference,
+				ChunkIndex: chunkIndex,
+				MaxChunks:  4,
+			},
+			createMockWhiteLister(true),
+			sender,
+		)
+		assert.Nil(t, err)
+	}
+
+	checkBatch(0, "pid1")
+	checkBatch(1, "pid2")
+	checkBatch(1, "pid1")
+	checkBatch(5, "pid3")
+	checkBatch(2, "pid2")
+	checkBatch(3, "pid1")
+
+	select {
+	case attribution := <-chanAttributions:
+		assert.Equal(t, reference, attribution.reference)
+		assert.Equal(t, map[core.PeerID]int{"pid1": 3, "pid2": 1}, attribution.contributors)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout while waiting for the assembly attribution")
+	}
+	assert.Equal(t, 0, len(tncp.mapChunkContributors))
+
+	_ = tncp.Close()
+}
+
+func TestTrieNodeChunksProcessor_CheckBatchShouldNotifyTheAssembledBuffer(t *testing.T) {
+	t.Parallel()
+
+	type chunkCompletion struct {
+		reference []byte
+		buff      []byte
+	}
+
+	chanCompletions := make(chan chunkCompletion, 1)
+	chanRelease := make(chan struct{})
+	args := createMockTrieNodesChunksProcessorArgs()
+	args.OnChunkComplete = func(reference []byte, buff []byte) {
+		chanCompletions <- chunkCompletion{
+			reference: reference,
+			buff:      buff,
+		}
+		// a blocked handler should not block the process loop
+		<-chanRelease
+	}
+	tncp, _ := NewTrieNodeChunksProcessor(args)
+	defer func() {
+		close(chanRelease)
+		_ = tncp.Close()
+	}()
+
+	checkBatch := func(chunkIndex uint32, buff string) process.CheckedChunkResult {
+		result, err := tncp.CheckBatch(
+			&batch.Batch{
+				Data:       [][]byte{[]byte(buff)},
+				Reference:  reference,
+				ChunkIndex: chunkIndex,
+				MaxChunks:  2,
+			},
+			createMockWhiteLister(true),
+			"pid",
+		)
+		assert.Nil(t, err)
+
+		return result
+	}
+
+	result := checkBatch(0, "buff0")
+	assert.False(t, result.HaveAllChunks)
+	select {
+	case <-chanCompletions:
+		assert.Fail(t, "should not have notified an incomplete assembly")
+	default:
+	}
+
+	result = checkBatch(1, "buff1")
+	assert.True(t, result.HaveAllChunks)
+	select {
+	case completion := <-chanCompletions:
+		assert.Equal(t, reference, completion.reference)
+		assert.Equal(t, []byte("buff0buff1"), completion.buff)
+	case <-time.After(time.Second):
+		assert.Fail(t, "timeout while waiting for the chunk completion")
+	}
+
+	numReferences, _ := tncp.Stats()
+	assert.Equal(t, 0, numReferences)
+}
+
+func TestTrieNodeChunksProcessor_CheckBatchShouldNotifyTheAssembledBuffersOneAtATime(t *testing.T) {
+	t.Parallel()
+
+	numRunningHandlers := int32(0)
+	maxRunningHandlers := int32(0)
+	chanNotifiedBuffers := make(chan string, 3)
+	chanRelease := make(chan struct{})
+	args := createMockTrieNodesChunksProcessorArgs()
+	args.OnChunkComplete = func(reference []byte, buff []byte) {
+		numRunning := atomic.AddInt32(&numRunningHandlers, 1)
+		if numRunning > atomic.LoadInt32(&maxRunningHandlers) {
+			atomic.StoreInt32(&maxRunningHandlers, numRunning)
+		}
+		<-chanRelease
+		atomic.AddInt32(&numRunningHandlers, -1)
+		chanNotifiedBuffers <- string(buff)
+	}
+	tncp, _ := NewTrieNodeChunksProcessor(args)
+	defer func() {
+		_ = tncp.Close()
+	}()
+
+	checkBatch := func(chunkIndex uint32, buff string) {
+		_, err := tncp.CheckBatch(
+			&batch.Batch{
+				Data:       [][]byte{[]byte(buff)},
+				Reference:  reference,
+				ChunkIndex: chunkIndex,
+				MaxChunks:  2,
+			},
+			createMockWhiteLister(true),
+			"pid",
+		)
+		assert.Nil(t, err)
+	}
+
+	// the same reference is assembled again after each completion, while the handler is blocked
+	for i := 0; i < 3; i++ {
+		checkBatch(0, fmt.Sprintf("a%d", i))
+		checkBatch(1, fmt.Sprintf("b%d", i))
+	}
+	close(chanRelease)
+
+	for i := 0; i < 3; i++ {
+		select {
+		case buff := <-chanNotifiedBuffers:
+			assert.Equal(t, fmt.Sprintf("a%db%d", i, i), buff)
+		case <-time.After(time.Second):
+			assert.Fail(t, "timeout while waiting for the chunk completion")
+			return
+		}
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxRunningHandlers))
+}
+
+func TestTrieNodeChunksProcessor_CheckBatchWithGlobalMemoryBudgetShouldEvict(t *testing.T) {
+	t.Parallel()
+
+	reference1 := bytes.Repeat([]byte{1}, 32)
+	reference2 := bytes.Repeat([]byte{2}, 32)
+	reference3 := bytes.Repeat([]byte{3}, 32)
+	reference4 := bytes.Repeat([]byte{4}, 32)
+
+	args := createMockTrieNodesChunksProcessorArgs()
+	args.GlobalMemoryBudget = 16
+	tncp, _ := NewTrieNodeChunksProcessor(args)
+
+	checkBatch := func(ref []byte, chunkIndex uint32, buff []byte) error {
+		_, err := tncp.CheckBatch(
+			&batch.Batch{
+				Data:       [][]byte{buff},
+				Reference:  ref,
+				ChunkIndex: chunkIndex,
+				MaxChunks:  4,
+			},
+			createMockWhiteLister(true),
+			"pid",
+		)
+
+		return err
+	}
+
+	buff := []byte("buff")
+	assert.Nil(t, checkBatch(reference1, 0, buff))
+	assert.Nil(t, checkBatch(reference2, 0, buff))
+	assert.Nil(t, checkBatch(reference2, 1, buff))
+	assert.Nil(t, checkBatch(reference3, 0, buff))
+	assert.Equal(t, 3, args.ChunksCacher.Len())
+	assert.Equal(t, uint64(16), tncp.pendingReferencesSize)
+
+	// the least complete reference is evicted
+	assert.Nil(t, checkBatch(reference3, 1, buff))
+	assert.False(t, args.ChunksCacher.Has(reference1))
+	assert.True(t, args.ChunksCacher.Has(reference2))
+	assert.True(t, args.ChunksCacher.Has(reference3))
+	assert.Equal(t, uint64(16), tncp.pendingReferencesSize)
+
+	// the oldest reference is evicted between the references with the same completeness
+	assert.Nil(t, checkBatch(reference4, 0, buff))
+	assert.False(t, args.ChunksCacher.Has(reference2))
+	assert.True(t, args.ChunksCacher.Has(reference3))
+	assert.True(t, args.ChunksCacher.Has(reference4))
+	assert.Equal(t, uint64(12), tncp.pendingReferencesSize)
+
+	err := checkBatch(reference1, 0, bytes.Repeat([]byte{1}, 17))
+	assert.True(t, errors.Is(err, process.ErrChunkMemoryBudgetExceeded))
+	assert.False(t, args.ChunksCacher.Has(reference1))
+	assert.Equal(t, uint64(12), tncp.pendingReferencesSize)
+
+	_ = tncp.Close()
+}
+
+func TestTrieNodeChunksProcessor_IncompleteReferenceShouldBeDroppedAfterMaxWaitTime(t *testing.T) {
+	t.Parallel()
+
+	numRequests := uint32(0)
+	args := createMockTrieNodesChunksProcessorArgs()
+	args.RequestInterval = minimumRequestTimeInterval
+	args.MaxWaitTime = minimumRequestTimeInterval * 2
+	args.RequestHandler = &testscommon.RequestHandlerStub{
+		RequestTrieNodeCalled: func(requestHash []byte, topic string, chunkIndex uint32) {
+			atomic.AddUint32(&numRequests, 1)
+		},
+	}
+	tncp, _ := NewTrieNodeChunksProcessor(args)
+	defer func() {
+		_ = tncp.Close()
+	}()
+
+	_, err := tncp.CheckBatch(
+		&batch.Batch{
+			Data:       [][]byte{[]byte("buff1")},
+			Reference:  reference,
+			ChunkIndex: 0,
+			MaxChunks:  3,
+		},
+		createMockWhiteLister(true),
+		"pid",
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, []Range{{Start: 0, End: 0}}, tncp.GetPresentRanges(reference))
+
+	isDropped := func() bool {
+		return len(tncp.GetPresentRanges(reference)) == 0
+	}
+	assert.Eventually(t, isDropped, time.Second*2, time.Millisecond*50)
+
+	numRequestsAfterDrop := atomic.LoadUint32(&numRequests)
+	assert.True(t, numRequestsAfterDrop > 0)
+	time.Sleep(minimumRequestTimeInterval * 2)
+	assert.Equal(t, numRequestsAfterDrop, atomic.LoadUint32(&numRequests))
+}
+
+func TestTrieNodeChunksProcessor_Stats(t *testing.T) {
+	t.Parallel()
+
+	mutMetrics := sync.Mutex{}
+	metrics := make(map[string]uint64)
+	args := createMockTrieNodesChunksProcessorArgs()
+	args.RequestInterval = minimumRequestTimeInterval
+	args.AppStatusHandler = &statusHandler.AppStatusHandlerStub{
+		SetUInt64ValueHandler: func(key string, value uint64) {
+			mutMetrics.Lock()
+			metrics[key] = value
+			mutMetrics.Unlock()
+		},
+	}
+	tncp, _ := NewTrieNodeChunksProcessor(args)
+	defer func() {
+		_ = tncp.Close()
+	}()
+
+	numReferences, numMissingChunks := tncp.Stats()
+	assert.Equal(t, 0, numReferences)
+	assert.Equal(t, 0, numMissingChunks)
+
+	otherReference := bytes.Repeat([]byte{2}, 32)
+	for _, b := range []*batch.Batch{
+		{Data: [][]byte{[]byte("buff0")}, Reference: reference, ChunkIndex: 0, MaxChunks: 3},
+		{Data: [][]byte{[]byte("buff1")}, Reference: reference, ChunkIndex: 1, MaxChunks: 3},
+		{Data: [][]byte{[]byte("buff0")}, Reference: otherReference, ChunkIndex: 0, MaxChunks: 5},
+	} {
+		_, err := tncp.CheckBatch(b, createMockWhiteLister(true), "pid")
+		assert.Nil(t, err)
+	}
+
+	numReferences, numMissingChunks = tncp.Stats()
+	assert.Equal(t, 2, numReferences)
+	assert.Equal(t, 5, numMissingChunks)
+
+	isPublished := func() bool {
+		mutMetrics.Lock()
+		defer mutMetrics.Unlock()
+
+		return metrics[common.MetricTrieNodeChunksReferences] == 2 && metrics[common.MetricTrieNodeChunksMissing] == 5
+	}
+	assert.Eventually(t, isPublished, time.Second*2, time.Millisecond*50)
+}
+
+func TestTrieNodeChunksProcessor_StatsOnClosedProcessorShouldNotBlock(t *testing.T) {
+	t.Parallel()
+
+	tncp, _ := NewTrieNodeChunksProcessor(createMockTrieNodesChunksProcessorArgs())
+	_ = tncp.Close()
+
+	numReferences, numMissingChunks := tncp.Stats()
+	assert.Equal(t, 0, numReferences)
+	assert.Equal(t, 0, numMissingChunks)
+}
+
+func TestTrieNodeChunksProcessor_ResetShouldDropAllTheReferences(t *testing.T) {
+	t.Parallel()
+
+	args := createMockTrieNodesChunksProcessorArgs()
+	args.GlobalMemoryBudget = 1024
+	args.MaxWaitTime = time.Hour
+	args.MaxBackoffExponent = 1
+	tncp, _ := NewTrieNodeChunksProcessor(args)
+	defer func() {
+		_ = tncp.Close()
+	}()
+
+	for i := byte(1); i <= 3; i++ {
+		_, err := tncp.CheckBatch(
+			&batch.Batch{
+				Data:       [][]byte{[]byte("buff")},
+				Reference:  bytes.Repeat([]byte{i}, 32),
+				ChunkIndex: 0,
+				MaxChunks:  3,
+			},
+			createMockWhiteLister(true),
+			"pid",
+		)
+		assert.Nil(t, err)
+	}
+	numReferences, numMissingChunks := tncp.Stats()
+	assert.Equal(t, 3, numReferences)
+	assert.Equal(t, 6, numMissingChunks)
+
+	tncp.Reset()
+
+	numReferences, numMissingChunks = tncp.Stats()
+	assert.Equal(t, 0, numReferences)
+	assert.Equal(t, 0, numMissingChunks)
+	assert.Equal(t, 0, args.ChunksCacher.Len())
+	assert.Equal(t, 0, len(tncp.mapPendingReferences))
+	assert.Equal(t, uint64(0), tncp.pendingReferencesSize)
+	assert.Equal(t, 0, len(tncp.mapReferenceFirstSeen))
+}
+
+func TestTrieNodeChunksProcessor_ResetOnClosedProcessorShouldNotBlock(t *testing.T) {
+	t.Parallel()
+
+	tncp, _ := NewTrieNodeChunksProcessor(createMockTrieNodesChunksProcessorArgs())
+	_ = tncp.Close()
+
+	tncp.Reset()
+}
+
+func TestTrieNodeChunksProcessor_RequestBackoff(t *testing.T) {
+	t.Parallel()
+
+	// the requests rounds are simulated, so the process loop should not trigger any
+	requestInterval := time.Hour
+	numTicks := 64
+	simulateRequestRounds := func(tncp *trieNodeChunksProcessor, start time.Time) int {
+		numRounds := 0
+		for tick := 0; tick < numTicks; tick++ {
+			now := start.Add(requestInterval * time.Duration(tick))
+			if tncp.shouldRequestReference(reference, now) {
+				tncp.requestMissingForReference(reference, context.Background())
+				numRounds++
+			}
+		}
+
+		return numRounds
+	}
+	createProcessorWithStuckReference := func(args TrieNodesChunksProcessorArgs, numRequests *uint32) *trieNodeChunksProcessor {
+		args.RequestInterval = requestInterval
+		args.RequestHandler = &testscommon.RequestHandlerStub{
+			RequestTrieNodeCalled: func(_ []byte, _ string, _ uint32) {
+				atomic.AddUint32(numRequests, 1)
+			},
+		}
+		tncp, _ := NewTrieNodeChunksProcessor(args)
+		_, err := tncp.CheckBatch(
+			&batch.Batch{
+				Data:       [][]byte{[]byte("buff0")},
+				Reference:  reference,
+				ChunkIndex: 0,
+				MaxChunks:  3,
+			},
+			createMockWhiteLister(true),
+			"pid",
+		)
+		assert.Nil(t, err)
+
+		return tncp
+	}
+
+	t.Run("invalid max backoff exponent should error", func(t *testing.T) {
+		t.Parallel()
+
+		args := createMockTrieNodesChunksProcessorArgs()
+		args.MaxBackoffExponent = maxBackoffExponent + 1
+		tncp, err := NewTrieNodeChunksProcessor(args)
+		assert.True(t, errors.Is(err, process.ErrInvalidValue))
+		assert.True(t, check.IfNil(tncp))
+	})
+	t.Run("without backoff should request on each round", func(t *testing.T) {
+		t.Parallel()
+
+		numRequests := uint32(0)
+		tncp := createProcessorWithStuckReference(createMockTrieNodesChunksProcessorArgs(), &numRequests)
+		defer func() {
+			_ = tncp.Close()
+		}()
+
+		assert.Equal(t, numTicks, simulateRequestRounds(tncp, time.Now()))
+		assert.Equal(t, uint32(numTicks*2), atomic.LoadUint32(&numRequests))
+	})
+	t.Run("with backoff should request exponentially less often", func(t *testing.T) {
+		t.Parallel()
+
+		numRequests := uint32(0)
+		args := createMockTrieNodesChunksProcessorArgs()
+		args.MaxBackoffExponent = 10
+		tncp := createProcessorWithStuckReference(args, &numRequests)
+		defer func() {
+			_ = tncp.Close()
+		}()
+
+		// rounds on ticks 0, 2, 6, 14, 30 and 62
+		start := time.Now()
+		assert.Equal(t, 6, simulateRequestRounds(tncp, start))
+		assert.Equal(t, uint32(12), atomic.LoadUint32(&numRequests))
+
+		// a new chunk resets the backoff
+		_, err := tncp.CheckBatch(
+			&batch.Batch{
+				Data:       [][]byte{[]byte("buff1")},
+				Reference:  reference,
+				ChunkIndex: 1,
+				MaxChunks:  3,
+			},
+			createMockWhiteLister(true),
+			"pid",
+		)
+		assert.Nil(t, err)
+		assert.True(t, tncp.shouldRequestReference(reference, start.Add(requestInterval*time.Duration(numTicks))))
+	})
+	t.Run("with backoff should be capped at the max request interval", func(t *testing.T) {
+		t.Parallel()
+
+		numRequests := uint32(0)
+		args := createMockTrieNodesChunksProcessorArgs()
+		args.MaxBackoffExponent = 10
+		args.MinRequestInterval = requestInterval
+		args.MaxRequestInterval = requestInterval * 4
+		tncp := createProcessorWithStuckReference(args, &numRequests)
+		defer func() {
+			_ = tncp.Close()
+		}()
+
+		// rounds on ticks 0, 2 and then every 4 ticks
+		assert.Equal(t, 17, simulateRequestRounds(tncp, time.Now()))
+		assert.Equal(t, uint32(34), atomic.LoadUint32(&numRequests))
+	})
+}
+
 func TestTrieNodeChunksProcessor_CheckBatchComponentClosed(t *testing.T) {
 	t.Parallel()
 
@@ -295,11 +1054,78 @@ func TestTrieNodeChunksProcessor_CheckBatchComponentClosed(t *testing.T) {
 			MaxChunks:  2,
 		},
 		createMockWhiteLister(true),
+		"pid",
 	)
 	assert.Equal(t, process.ErrProcessClosed, err)
 	assert.Equal(t, expectedCheckedChunkResult, chunkResult)
 }
 
+func TestTrieNodeChunksProcessor_CloseTwiceShouldNotPanic(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		r := recover()
+		if r != nil {
+			assert.Fail(t, "should not have panic")
+		}
+	}()
+
+	tncp, _ := NewTrieNodeChunksProcessor(createMockTrieNodesChunksProcessorArgs())
+	assert.Nil(t, tncp.Close())
+	assert.Nil(t, tncp.Close())
+
+	chunkResult, err := tncp.CheckBatch(
+		&batch.Batch{
+			Data:       [][]byte{[]byte("buff1")},
+			Reference:  reference,
+			ChunkIndex: 0,
+			MaxChunks:  2,
+		},
+		createMockWhiteLister(true),
+		"pid",
+	)
+	assert.Equal(t, process.ErrProcessClosed, err)
+	assert.Equal(t, process.CheckedChunkResult{}, chunkResult)
+}
+
+func TestTrieNodeChunksProcessor_CheckBatchWithTimeoutOnBusyProcessLoop(t *testing.T) {
+	t.Parallel()
+
+	chanUnblock := make(chan struct{})
+	args := createMockTrieNodesChunksProcessorArgs()
+	args.CheckBatchTimeout = time.Millisecond * 100
+	args.DeliverPartialData = true
+	args.PartialDataHandler = func(_ []byte, _ int, _ []byte) {
+		<-chanUnblock
+	}
+	tncp, _ := NewTrieNodeChunksProcessor(args)
+	defer func() {
+		_ = tncp.Close()
+	}()
+
+	createBatch := func(ref []byte) *batch.Batch {
+		return &batch.Batch{
+			Data:       [][]byte{[]byte("buff0")},
+			Reference:  ref,
+			ChunkIndex: 0,
+			MaxChunks:  2,
+		}
+	}
+
+	// the first batch blocks the process loop in the partial data handler, until released
+	_, err := tncp.CheckBatch(createBatch(reference), createMockWhiteLister(true), "pid")
+	assert.True(t, errors.Is(err, process.ErrRequestTimeout))
+
+	chunkResult, err := tncp.CheckBatch(createBatch(bytes.Repeat([]byte{2}, 32)), createMockWhiteLister(true), "pid")
+	assert.True(t, errors.Is(err, process.ErrRequestTimeout))
+	assert.Equal(t, process.CheckedChunkResult{}, chunkResult)
+
+	close(chanUnblock)
+	chunkResult, err = tncp.CheckBatch(createBatch(bytes.Repeat([]byte{3}, 32)), createMockWhiteLister(true), "pid")
+	assert.Nil(t, err)
+	assert.True(t, chunkResult.IsChunk)
+}
+
 func TestTrieNodeChunksProcessor_RequestShouldWork(t *testing.T) {
 	t.Parallel()
 
@@ -322,6 +1148,7 @@ func TestTrieNodeChunksProcessor_RequestShouldWork(t *testing.T) {
 			MaxChunks:  3,
 		},
 		createMockWhiteLister(true),
+		"pid",
 	)
 	assert.Nil(t, err)
 
@@ -332,3 +1159,194 @@ func TestTrieNodeChunksProcessor_RequestShouldWork(t *testing.T) {
 
 	_ = tncp.Close()
 }
+
+func TestTrieNodeChunksProcessor_DoRequestsShouldRequestEachMissingChunkOncePerRound(t *testing.T) {
+	t.Parallel()
+
+	args := createMockTrieNodesChunksProcessorArgs()
+	// the requests rounds are simulated, so the process loop should not trigger any
+	args.RequestInterval = time.Hour
+	mutRequests := sync.Mutex{}
+	numRequests := make(map[string]int)
+	args.RequestHandler = &testscommon.RequestHandlerStub{
+		RequestTrieNodeCalled: func(requestHash []byte, _ string, chunkIndex uint32) {
+			mutRequests.Lock()
+			numRequests[fmt.Sprintf("%x-%d", requestHash, chunkIndex)]++
+			mutRequests.Unlock()
+		},
+	}
+	tncp, _ := NewTrieNodeChunksProcessor(args)
+	otherReference := bytes.Repeat([]byte{2}, 32)
+	for _, ref := range [][]byte{reference, otherReference} {
+		_, err := tncp.CheckBatch(
+			&batch.Batch{
+				Data:       [][]byte{[]byte("buff0")},
+				Reference:  ref,
+				ChunkIndex: 0,
+				MaxChunks:  3,
+			},
+			createMockWhiteLister(true),
+			"pid",
+		)
+		assert.Nil(t, err)
+	}
+
+	expectedNumRequests := func(numRounds int) map[string]int {
+		return map[string]int{
+			fmt.Sprintf("%x-1", reference):      numRounds,
+			fmt.Sprintf("%x-2", reference):      numRounds,
+			fmt.Sprintf("%x-1", otherReference): numRounds,
+			fmt.Sprintf("%x-2", otherReference): numRounds,
+		}
+	}
+
+	tncp.doRequests(context.Background())
+	mutRequests.Lock()
+	assert.Equal(t, expectedNumRequests(1), numRequests)
+	mutRequests.Unlock()
+
+	tncp.doRequests(context.Background())
+	mutRequests.Lock()
+	assert.Equal(t, expectedNumRequests(2), numRequests)
+	mutRequests.Unlock()
+
+	_ = tncp.Close()
+}
+
+func TestTrieNodeChunksProcessor_GetPresentRanges(t *testing.T) {
+	t.Parallel()
+
+	args := createMockTrieNodesChunksProcessorArgs()
+	tncp, _ := NewTrieNodeChunksProcessor(args)
+
+	assert.Equal(t, make([]Range, 0), tncp.GetPresentRanges(reference))
+
+	for _, chunkIndex := range []uint32{0, 5, 1, 2, 8, 7} {
+		_, err := tncp.CheckBatch(
+			&batch.Batch{
+				Data:       [][]byte{[]byte("buff")},
+				Reference:  reference,
+				ChunkIndex: chunkIndex,
+				MaxChunks:  10,
+			},
+			createMockWhiteLister(true),
+			"pid",
+		)
+		assert.Nil(t, err)
+	}
+
+	expectedRanges := []Range{
+		{Start: 0, End: 2},
+		{Start: 5, End: 5},
+		{Start: 7, End: 8},
+	}
+	assert.Equal(t, expectedRanges, tncp.GetPresentRanges(reference))
+
+	_ = tncp.Close()
+	assert.Equal(t, make([]Range, 0), tncp.GetPresentRanges(reference))
+}
+
+func TestTrieNodeChunksProcessor_CheckBatchShouldDeliverPartialData(t *testing.T) {
+	t.Parallel()
+
+	type partialData struct {
+		offsetStart int
+		data        []byte
+	}
+	delivered := make([]partialData, 0)
+	args := createMockTrieNodesChunksProcessorArgs()
+	args.DeliverPartialData = true
+	args.PartialDataHandler = func(ref []byte, offsetStart int, data []byte) {
+		assert.Equal(t, reference, ref)
+		delivered = append(delivered, partialData{
+			offsetStart: offsetStart,
+			data:        data,
+		})
+	}
+	tncp, _ := NewTrieNodeChunksProcessor(args)
+
+	checkBatch := func(chunkIndex uint32, data string) process.CheckedChunkResult {
+		chunkResult, err := tncp.CheckBatch(
+			&batch.Batch{
+				Data:       [][]byte{[]byte(data)},
+				Reference:  reference,
+				ChunkIndex: chunkIndex,
+				MaxChunks:  4,
+			},
+			createMockWhiteLister(true),
+			"pid",
+		)
+		assert.Nil(t, err)
+
+		return chunkResult
+	}
+
+	checkBatch(0, "buff0")
+	checkBatch(2, "buff2")
+	checkBatch(1, "buff1")
+	chunkResult := checkBatch(3, "buff3")
+	assert.Equal(t, []byte("buff0buff1buff2buff3"), chunkResult.CompleteBuffer)
+
+	expectedDelivered := []partialData{
+		{offsetStart: 0, data: []byte("buff0")},
+		{offsetStart: 5, data: []byte("buff1buff2")},
+	}
+	assert.Equal(t, expectedDelivered, delivered)
+
+	_ = tncp.Close()
+}
+
+func TestTrieNodeChunksProcessor_AdaptiveRequestInterval(t *testing.T) {
+	t.Parallel()
+
+	args := createMockTrieNodesChunksProcessorArgs()
+	args.MinRequestInterval = minimumRequestTimeInterval
+	args.MaxRequestInterval = time.Second * 2
+	tncp, _ := NewTrieNodeChunksProcessor(args)
+	assert.Equal(t, time.Second, tncp.getRequestInterval())
+
+	for chunkIndex := uint32(0); chunkIndex < 2; chunkIndex++ {
+		_, err := tncp.CheckBatch(
+			&batch.Batch{
+				Data:       [][]byte{[]byte("buff")},
+				Reference:  reference,
+				ChunkIndex: chunkIndex,
+				MaxChunks:  2,
+			},
+			createMockWhiteLister(true),
+			"pid",
+		)
+		assert.Nil(t, err)
+	}
+
+	requestInterval := tncp.getRequestInterval()
+	assert.True(t, requestInterval < time.Second)
+	assert.True(t, requestInterval >= time.Second*3/4)
+
+	_ = tncp.Close()
+}
+
+func TestTrieNodeChunksProcessor_adaptRequestIntervalShouldStayWithinBounds(t *testing.T) {
+	t.Parallel()
+
+	tncp := &trieNodeChunksProcessor{
+		requestInterval:    int64(time.Second),
+		isAdaptiveInterval: true,
+		minRequestInterval: time.Millisecond * 500,
+		maxRequestInterval: time.Second * 2,
+	}
+
+	tncp.adaptRequestInterval(0)
+	assert.Equal(t, time.Millisecond*750, tncp.getRequestInterval())
+	for i := 0; i < 10; i++ {
+		tncp.adaptRequestInterval(0)
+	}
+	assert.Equal(t, time.Millisecond*500, tncp.getRequestInterval())
+
+	tncp.adaptRequestInterval(time.Millisecond * 4500)
+	assert.Equal(t, time.Millisecond*1500, tncp.getRequestInterval())
+	for i := 0; i < 10; i++ {
+		tncp.adaptRequestInterval(time.Second * 10)
+	}
+	assert.Equal(t, time.Second*2, tncp.getRequestInterval())
+}
@@ -1156,14 +1156,15 @@ type NumConnectedPeersProvider interface {
 
 // CheckedChunkResult is the DTO used to hold the results after checking a chunk of intercepted data
 type CheckedChunkResult struct {
-	IsChunk        bool
-	HaveAllChunks  bool
-	CompleteBuffer []byte
+	IsChunk                bool
+	HaveAllChunks          bool
+	FirstChunkForReference bool
+	CompleteBuffer         []byte
 }
 
 // InterceptedChunksProcessor defines the component that is able to process chunks of intercepted data
 type InterceptedChunksProcessor interface {
-	CheckBatch(b *batch.Batch, whiteListHandler WhiteListHandler) (CheckedChunkResult, error)
+	CheckBatch(b *batch.Batch, whiteListHandler WhiteListHandler, sender core.PeerID) (CheckedChunkResult, error)
 	Close() error
 	IsInterfaceNil() bool
 }
@@ -1184,6 +1185,7 @@ type CurrentNetworkEpochProviderHandler interface {
 type ScheduledTxsExecutionHandler interface {
 	Init()
 	AddScheduledTx(txHash []byte, tx data.TransactionHandler) bool
+	AddScheduledTxs(txHashes [][]byte, txs []data.TransactionHandler) int
 	AddScheduledMiniBlocks(miniBlocks block.MiniBlockSlice)
 	Execute(txHash []byte) error
 	ExecuteAll(haveTime func() time.Duration) error
@@ -1206,6 +1208,25 @@ type ScheduledTxsExecutionHandler interface {
 	IsInterfaceNil() bool
 }
 
+// AccountsWarmer defines the component able to load an account in the accounts cache ahead of its usage
+type AccountsWarmer interface {
+	WarmAccount(address []byte)
+	IsInterfaceNil() bool
+}
+
+// StorageAccessMeter defines the component able to count the storage reads and writes done since its creation
+type StorageAccessMeter interface {
+	GetStorageAccessStat() StorageAccessStat
+	IsInterfaceNil() bool
+}
+
+// DataCompressor defines the component able to compress and decompress data
+type DataCompressor interface {
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+	IsInterfaceNil() bool
+}
+
 // DoubleTransactionDetector is able to detect if a transaction hash is present more than once in a block body
 type DoubleTransactionDetector interface {
 	ProcessBlockBody(body *block.Body)
@@ -1246,3 +1267,9 @@ type PeerAuthenticationPayloadValidator interface {
 	ValidateTimestamp(payloadTimestamp int64) error
 	IsInterfaceNil() bool
 }
+
+// RangeKeysSupportChecker defines a storer which is able to tell if it can iterate over its keys. The storers which do
+// not implement it are considered able to iterate
+type RangeKeysSupportChecker interface {
+	IsRangeKeysSupported() bool
+}
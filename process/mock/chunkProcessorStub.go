@@ -1,20 +1,21 @@
 package mock
 
 import (
+	"github.com/ElrondNetwork/elrond-go-core/core"
 	"github.com/ElrondNetwork/elrond-go-core/data/batch"
 	"github.com/ElrondNetwork/elrond-go/process"
 )
 
 // ChunkProcessorStub -
 type ChunkProcessorStub struct {
-	CheckBatchCalled func(b *batch.Batch, w process.WhiteListHandler) (process.CheckedChunkResult, error)
+	CheckBatchCalled func(b *batch.Batch, w process.WhiteListHandler, sender core.PeerID) (process.CheckedChunkResult, error)
 	CloseCalled      func() error
 }
 
 // CheckBatch -
-func (c *ChunkProcessorStub) CheckBatch(b *batch.Batch, w process.WhiteListHandler) (process.CheckedChunkResult, error) {
+func (c *ChunkProcessorStub) CheckBatch(b *batch.Batch, w process.WhiteListHandler, sender core.PeerID) (process.CheckedChunkResult, error) {
 	if c.CheckBatchCalled != nil {
-		return c.CheckBatchCalled(b, w)
+		return c.CheckBatchCalled(b, w, sender)
 	}
 
 	return process.CheckedChunkResult{}, nil
@@ -0,0 +1,145 @@
+package process
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/ElrondNetwork/elrond-go-core/core"
+	"github.com/ElrondNetwork/elrond-go-core/core/check"
+	"github.com/ElrondNetwork/elrond-go-core/data"
+	"github.com/ElrondNetwork/elrond-go-core/data/block"
+	"github.com/ElrondNetwork/elrond-go-core/hashing"
+	"github.com/ElrondNetwork/elrond-go-core/marshal"
+)
+
+// ScheduledInfoDiff holds the differences between two scheduled infos. The intermediate txs are identified by their
+// hashes and all the deltas are computed as the other scheduled info minus the compared one
+type ScheduledInfoDiff struct {
+	RootHashChanged        bool
+	AddedIntermediateTxs   map[block.Type][][]byte
+	RemovedIntermediateTxs map[block.Type][][]byte
+	AccumulatedFeesDelta   *big.Int
+	DeveloperFeesDelta     *big.Int
+	GasProvidedDelta       *big.Int
+	GasPenalizedDelta      *big.Int
+	GasRefundedDelta       *big.Int
+	MiniBlocksCountDelta   int
+}
+
+// IsEmpty returns true if the compared scheduled infos have no differences
+func (diff *ScheduledInfoDiff) IsEmpty() bool {
+	return !diff.RootHashChanged &&
+		len(diff.AddedIntermediateTxs) == 0 &&
+		len(diff.RemovedIntermediateTxs) == 0 &&
+		diff.AccumulatedFeesDelta.Sign() == 0 &&
+		diff.DeveloperFeesDelta.Sign() == 0 &&
+		diff.GasProvidedDelta.Sign() == 0 &&
+		diff.GasPenalizedDelta.Sign() == 0 &&
+		diff.GasRefundedDelta.Sign() == 0 &&
+		diff.MiniBlocksCountDelta == 0
+}
+
+// Diff returns the differences between this scheduled info and the other one, which is useful when debugging reorgs.
+// The marshaller and the hasher are used to compute the hashes of the intermediate txs. A nil scheduled info, as well as
+// its nil fields, are treated as empty
+func (si *ScheduledInfo) Diff(other *ScheduledInfo, marshaller marshal.Marshalizer, hasher hashing.Hasher) (*ScheduledInfoDiff, error) {
+	if check.IfNil(marshaller) {
+		return nil, ErrNilMarshalizer
+	}
+	if check.IfNil(hasher) {
+		return nil, ErrNilHasher
+	}
+
+	current := si
+	if current == nil {
+		current = &ScheduledInfo{}
+	}
+	if other == nil {
+		other = &ScheduledInfo{}
+	}
+
+	currentTxHashes, err := computeIntermediateTxHashes(current.IntermediateTxs, marshaller, hasher)
+	if err != nil {
+		return nil, err
+	}
+	otherTxHashes, err := computeIntermediateTxHashes(other.IntermediateTxs, marshaller, hasher)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScheduledInfoDiff{
+		RootHashChanged:        !bytes.Equal(current.RootHash, other.RootHash),
+		AddedIntermediateTxs:   subtractTxHashes(otherTxHashes, currentTxHashes),
+		RemovedIntermediateTxs: subtractTxHashes(currentTxHashes, otherTxHashes),
+		AccumulatedFeesDelta:   computeBigIntDelta(current.GasAndFees.AccumulatedFees, other.GasAndFees.AccumulatedFees),
+		DeveloperFeesDelta:     computeBigIntDelta(current.GasAndFees.DeveloperFees, other.GasAndFees.DeveloperFees),
+		GasProvidedDelta:       computeUint64Delta(current.GasAndFees.GasProvided, other.GasAndFees.GasProvided),
+		GasPenalizedDelta:      computeUint64Delta(current.GasAndFees.GasPenalized, other.GasAndFees.GasPenalized),
+		GasRefundedDelta:       computeUint64Delta(current.GasAndFees.GasRefunded, other.GasAndFees.GasRefunded),
+		MiniBlocksCountDelta:   len(other.MiniBlocks) - len(current.MiniBlocks),
+	}, nil
+}
+
+func computeIntermediateTxHashes(
+	intermediateTxs map[block.Type][]data.TransactionHandler,
+	marshaller marshal.Marshalizer,
+	hasher hashing.Hasher,
+) (map[block.Type][][]byte, error) {
+	txHashes := make(map[block.Type][][]byte, len(intermediateTxs))
+	for blockType, txs := range intermediateTxs {
+		for _, tx := range txs {
+			if check.IfNil(tx) {
+				continue
+			}
+
+			txHash, err := core.CalculateHash(marshaller, hasher, tx)
+			if err != nil {
+				return nil, err
+			}
+
+			txHashes[blockType] = append(txHashes[blockType], txHash)
+		}
+	}
+
+	return txHashes, nil
+}
+
+// subtractTxHashes returns, for each block type, the tx hashes found in the first map and missing from the second one
+func subtractTxHashes(txHashes map[block.Type][][]byte, subtractedTxHashes map[block.Type][][]byte) map[block.Type][][]byte {
+	result := make(map[block.Type][][]byte)
+	for blockType, hashes := range txHashes {
+		subtracted := make(map[string]struct{}, len(subtractedTxHashes[blockType]))
+		for _, hash := range subtractedTxHashes[blockType] {
+			subtracted[string(hash)] = struct{}{}
+		}
+
+		for _, hash := range hashes {
+			_, found := subtracted[string(hash)]
+			if found {
+				continue
+			}
+
+			result[blockType] = append(result[blockType], hash)
+		}
+	}
+
+	return result
+}
+
+func computeBigIntDelta(value *big.Int, otherValue *big.Int) *big.Int {
+	delta := big.NewInt(0)
+	if otherValue != nil {
+		delta.Set(otherValue)
+	}
+	if value != nil {
+		delta.Sub(delta, value)
+	}
+
+	return delta
+}
+
+func computeUint64Delta(value uint64, otherValue uint64) *big.Int {
+	delta := big.NewInt(0).SetUint64(otherValue)
+
+	return delta.Sub(delta, big.NewInt(0).SetUint64(value))
+}
@@ -0,0 +1,100 @@
+package process_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ElrondNetwork/elrond-go-core/core"
+	"github.com/ElrondNetwork/elrond-go-core/data"
+	"github.com/ElrondNetwork/elrond-go-core/data/block"
+	"github.com/ElrondNetwork/elrond-go-core/data/scheduled"
+	"github.com/ElrondNetwork/elrond-go-core/data/smartContractResult"
+	"github.com/ElrondNetwork/elrond-go/process"
+	"github.com/ElrondNetwork/elrond-go/process/mock"
+	"github.com/ElrondNetwork/elrond-go/testscommon/hashingMocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduledInfo_DiffNilMarshallerOrHasherShouldErr(t *testing.T) {
+	t.Parallel()
+
+	si := &process.ScheduledInfo{}
+
+	diff, err := si.Diff(&process.ScheduledInfo{}, nil, &hashingMocks.HasherMock{})
+	assert.Equal(t, process.ErrNilMarshalizer, err)
+	assert.Nil(t, diff)
+
+	diff, err = si.Diff(&process.ScheduledInfo{}, &mock.MarshalizerMock{}, nil)
+	assert.Equal(t, process.ErrNilHasher, err)
+	assert.Nil(t, diff)
+}
+
+func TestScheduledInfo_DiffNilScheduledInfosShouldBeEmpty(t *testing.T) {
+	t.Parallel()
+
+	var si *process.ScheduledInfo
+
+	diff, err := si.Diff(nil, &mock.MarshalizerMock{}, &hashingMocks.HasherMock{})
+	require.Nil(t, err)
+	assert.True(t, diff.IsEmpty())
+
+	diff, err = si.Diff(&process.ScheduledInfo{GasAndFees: process.GetZeroGasAndFees()}, &mock.MarshalizerMock{}, &hashingMocks.HasherMock{})
+	require.Nil(t, err)
+	assert.True(t, diff.IsEmpty())
+}
+
+func TestScheduledInfo_DiffShouldWork(t *testing.T) {
+	t.Parallel()
+
+	marshaller := &mock.MarshalizerMock{}
+	hasher := &hashingMocks.HasherMock{}
+	scr1 := &smartContractResult.SmartContractResult{Nonce: 1}
+	scr2 := &smartContractResult.SmartContractResult{Nonce: 2}
+	scr3 := &smartContractResult.SmartContractResult{Nonce: 3}
+	scr2Hash, _ := core.CalculateHash(marshaller, hasher, scr2)
+	scr3Hash, _ := core.CalculateHash(marshaller, hasher, scr3)
+
+	si := &process.ScheduledInfo{
+		RootHash: []byte("rootHash1"),
+		IntermediateTxs: map[block.Type][]data.TransactionHandler{
+			block.SmartContractResultBlock: {scr1, scr2},
+		},
+		GasAndFees: scheduled.GasAndFees{
+			AccumulatedFees: big.NewInt(100),
+			GasProvided:     20,
+			GasRefunded:     5,
+		},
+		MiniBlocks: block.MiniBlockSlice{&block.MiniBlock{}},
+	}
+	other := &process.ScheduledInfo{
+		RootHash: []byte("rootHash2"),
+		IntermediateTxs: map[block.Type][]data.TransactionHandler{
+			block.SmartContractResultBlock: {scr1, scr3},
+		},
+		GasAndFees: scheduled.GasAndFees{
+			AccumulatedFees: big.NewInt(70),
+			DeveloperFees:   big.NewInt(10),
+			GasProvided:     30,
+			GasRefunded:     5,
+		},
+		MiniBlocks: block.MiniBlockSlice{&block.MiniBlock{}, &block.MiniBlock{}},
+	}
+
+	diff, err := si.Diff(other, marshaller, hasher)
+	require.Nil(t, err)
+	assert.False(t, diff.IsEmpty())
+	assert.True(t, diff.RootHashChanged)
+	assert.Equal(t, map[block.Type][][]byte{block.SmartContractResultBlock: {scr3Hash}}, diff.AddedIntermediateTxs)
+	assert.Equal(t, map[block.Type][][]byte{block.SmartContractResultBlock: {scr2Hash}}, diff.RemovedIntermediateTxs)
+	assert.Equal(t, int64(-30), diff.AccumulatedFeesDelta.Int64())
+	assert.Equal(t, int64(10), diff.DeveloperFeesDelta.Int64())
+	assert.Equal(t, int64(10), diff.GasProvidedDelta.Int64())
+	assert.Equal(t, int64(0), diff.GasPenalizedDelta.Int64())
+	assert.Equal(t, int64(0), diff.GasRefundedDelta.Int64())
+	assert.Equal(t, 1, diff.MiniBlocksCountDelta)
+
+	diff, err = si.Diff(si, marshaller, hasher)
+	require.Nil(t, err)
+	assert.True(t, diff.IsEmpty())
+}
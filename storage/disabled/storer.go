@@ -75,6 +75,11 @@ func (s *storer) GetOldestEpoch() (uint32, error) {
 func (s *storer) RangeKeys(_ func(key []byte, val []byte) bool) {
 }
 
+// IsRangeKeysSupported returns false, as RangeKeys does not iterate over anything
+func (s *storer) IsRangeKeysSupported() bool {
+	return false
+}
+
 // Close returns nil
 func (s *storer) Close() error {
 	return nil
@@ -51,4 +51,5 @@ func TestStorer_MethodsDoNotPanic(t *testing.T) {
 
 	s.ClearCache()
 	s.RangeKeys(nil)
+	assert.False(t, s.IsRangeKeysSupported())
 }
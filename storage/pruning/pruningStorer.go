@@ -1000,6 +1000,11 @@ func (ps *PruningStorer) RangeKeys(_ func(key []byte, val []byte) bool) {
 	debug.PrintStack()
 }
 
+// IsRangeKeysSupported returns false, as RangeKeys is unable to iterate over multiple persisters
+func (ps *PruningStorer) IsRangeKeysSupported() bool {
+	return false
+}
+
 // IsInterfaceNil returns true if there is no value under the interface
 func (ps *PruningStorer) IsInterfaceNil() bool {
 	return ps == nil
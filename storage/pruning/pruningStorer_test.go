@@ -821,6 +821,15 @@ func TestPruningStorer_ChangeEpochWithExisting(t *testing.T) {
 	require.Equal(t, val2, restoredVal2)
 }
 
+func TestPruningStorer_IsRangeKeysSupported(t *testing.T) {
+	t.Parallel()
+
+	args := getDefaultArgs()
+	ps, _ := pruning.NewPruningStorer(args)
+
+	assert.False(t, ps.IsRangeKeysSupported())
+}
+
 func TestPruningStorer_ClosePersisters(t *testing.T) {
 	t.Parallel()
 
@@ -82,6 +82,11 @@ func (ns *NilStorer) DestroyUnit() error {
 func (ns *NilStorer) RangeKeys(_ func(key []byte, val []byte) bool) {
 }
 
+// IsRangeKeysSupported returns false, as RangeKeys does not iterate over anything
+func (ns *NilStorer) IsRangeKeysSupported() bool {
+	return false
+}
+
 // IsInterfaceNil returns true if there is no value under the interface
 func (ns *NilStorer) IsInterfaceNil() bool {
 	return ns == nil
@@ -0,0 +1,18 @@
+package testscommon
+
+// AccountsWarmerStub -
+type AccountsWarmerStub struct {
+	WarmAccountCalled func(address []byte)
+}
+
+// WarmAccount -
+func (stub *AccountsWarmerStub) WarmAccount(address []byte) {
+	if stub.WarmAccountCalled != nil {
+		stub.WarmAccountCalled(address)
+	}
+}
+
+// IsInterfaceNil -
+func (stub *AccountsWarmerStub) IsInterfaceNil() bool {
+	return stub == nil
+}
@@ -15,6 +15,7 @@ import (
 type ScheduledTxsExecutionStub struct {
 	InitCalled                                   func()
 	AddScheduledTxCalled                         func([]byte, data.TransactionHandler) bool
+	AddScheduledTxsCalled                        func([][]byte, []data.TransactionHandler) int
 	AddScheduledMiniBlocksCalled                 func(miniBlocks block.MiniBlockSlice)
 	ExecuteCalled                                func([]byte) error
 	ExecuteAllCalled                             func(func() time.Duration) error
@@ -53,6 +54,14 @@ func (stes *ScheduledTxsExecutionStub) AddScheduledTx(txHash []byte, tx data.Tra
 	return true
 }
 
+// AddScheduledTxs -
+func (stes *ScheduledTxsExecutionStub) AddScheduledTxs(txHashes [][]byte, txs []data.TransactionHandler) int {
+	if stes.AddScheduledTxsCalled != nil {
+		return stes.AddScheduledTxsCalled(txHashes, txs)
+	}
+	return len(txHashes)
+}
+
 // AddScheduledMiniBlocks -
 func (stes *ScheduledTxsExecutionStub) AddScheduledMiniBlocks(miniBlocks block.MiniBlockSlice) {
 	if stes.AddScheduledMiniBlocksCalled != nil {
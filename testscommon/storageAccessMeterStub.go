@@ -0,0 +1,22 @@
+package testscommon
+
+import "github.com/ElrondNetwork/elrond-go/process"
+
+// StorageAccessMeterStub -
+type StorageAccessMeterStub struct {
+	GetStorageAccessStatCalled func() process.StorageAccessStat
+}
+
+// GetStorageAccessStat -
+func (stub *StorageAccessMeterStub) GetStorageAccessStat() process.StorageAccessStat {
+	if stub.GetStorageAccessStatCalled != nil {
+		return stub.GetStorageAccessStatCalled()
+	}
+
+	return process.StorageAccessStat{}
+}
+
+// IsInterfaceNil -
+func (stub *StorageAccessMeterStub) IsInterfaceNil() bool {
+	return stub == nil
+}